@@ -0,0 +1,195 @@
+package kraken
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+type BackfillConfig struct {
+	// AssetPair is required
+	AssetPair AssetPair
+
+	// Interval is optional
+	// Default: 1min
+	Interval Interval
+
+	// Start is required
+	// Time of the first candle to rebuild
+	Start time.Time
+
+	// End is optional
+	// Candles starting at or after End are dropped
+	// Default: now
+	End time.Time
+
+	// Throttle is optional
+	// Pause between two pages of trades, to stay under the public rate limit
+	// Default: 1s
+	Throttle time.Duration
+
+	// Checkpoint is optional
+	// Resumes a previous backfill where it stopped
+	Checkpoint *BackfillCheckpoint
+
+	// OnCheckpoint is optional
+	// Called after each page of trades, including the last one, so the progress can be persisted
+	OnCheckpoint func(checkpoint BackfillCheckpoint) error
+}
+
+// BackfillCheckpoint is the progress of a backfill
+type BackfillCheckpoint struct {
+	// AssetPair being backfilled
+	AssetPair AssetPair `json:"asset_pair"`
+	// Interval of the candles
+	Interval Interval `json:"interval"`
+	// Cursor is the `last` cursor of the last page of trades processed
+	Cursor time.Time `json:"cursor"`
+	// LastTradeID is the ID of the last trade aggregated
+	LastTradeID int64 `json:"last_trade_id"`
+	// Candles rebuilt so far, the last one may still be incomplete
+	Candles []OHLCData `json:"candles"`
+}
+
+// BackfillOHLC
+// Rebuilds the candles older than the 720 ones returned by OHLC, by walking the pages of
+// RecentTrades with the `last` cursor and aggregating the trades into the requested interval.
+// The rebuilt candles are merged with the ones of OHLC: from the first candle returned by OHLC,
+// the candles of OHLC are used.
+// The intervals without trades are filled with flat candles at the previous close, without
+// volume. The series starts at the first interval with a trade, which may be after Start.
+// It stops when ctx is done, cancelling the request in flight.
+func (c *Client) BackfillOHLC(ctx context.Context, config BackfillConfig) ([]OHLCData, error) {
+	if config.AssetPair == "" {
+		return nil, fmt.Errorf("AssetPair is required")
+	}
+	if config.Start.IsZero() {
+		return nil, fmt.Errorf("Start is required")
+	}
+	if config.Interval == "" {
+		config.Interval = Interval1min
+	}
+	duration := config.Interval.Duration()
+	if duration == 0 {
+		return nil, fmt.Errorf("Interval %q is invalid", config.Interval)
+	}
+	// The first candle is rebuilt from its beginning
+	config.Start = truncateUnix(config.Start, duration)
+	if config.End.IsZero() {
+		config.End = time.Now()
+	}
+	if config.Throttle == 0 {
+		config.Throttle = time.Second
+	}
+
+	checkpoint := BackfillCheckpoint{
+		AssetPair: config.AssetPair,
+		Interval:  config.Interval,
+		Cursor:    config.Start,
+	}
+	if config.Checkpoint != nil {
+		if config.Checkpoint.AssetPair != config.AssetPair || config.Checkpoint.Interval != config.Interval {
+			return nil, fmt.Errorf("Checkpoint is for %s/%s, not %s/%s", config.Checkpoint.AssetPair, config.Checkpoint.Interval, config.AssetPair, config.Interval)
+		}
+		checkpoint = *config.Checkpoint
+		checkpoint.Candles = append([]OHLCData{}, config.Checkpoint.Candles...)
+	}
+
+	// The candles of OHLC are used from the first one returned
+	results, _, err := c.ohlc(ctx, OHLCConfig{
+		AssetPair: config.AssetPair,
+		Interval:  config.Interval,
+	})
+	if err != nil {
+		return nil, err
+	}
+	recent := results[config.AssetPair].Data
+
+	boundary := config.End
+	if len(recent) > 0 && recent[0].Time.Before(boundary) {
+		boundary = recent[0].Time
+	}
+
+	for checkpoint.Cursor.Before(boundary) {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+
+		results, last, err := c.recentTrades(ctx, RecentTradesConfig{
+			AssetPair: config.AssetPair,
+			Since:     checkpoint.Cursor,
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		trades := []TradeData{}
+		for _, trade := range results[config.AssetPair].Data {
+			// Pages may overlap
+			if trade.TradeID != 0 && trade.TradeID <= checkpoint.LastTradeID {
+				continue
+			}
+			if trade.Time.Before(config.Start) {
+				continue
+			}
+			if !trade.Time.Before(boundary) {
+				break
+			}
+			trades = append(trades, trade)
+		}
+
+		checkpoint.Candles = aggregateTrades(checkpoint.Candles, trades, duration)
+		if len(trades) > 0 {
+			checkpoint.LastTradeID = trades[len(trades)-1].TradeID
+		}
+
+		// No more trades when the cursor does not move
+		done := !last.After(checkpoint.Cursor)
+		if !done {
+			checkpoint.Cursor = last
+		}
+
+		// The progress is persisted before leaving the loop as well, so a resume does not
+		// fetch the last page again
+		if config.OnCheckpoint != nil {
+			if err := config.OnCheckpoint(checkpoint); err != nil {
+				return nil, err
+			}
+		}
+
+		if done || !checkpoint.Cursor.Before(boundary) {
+			break
+		}
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(config.Throttle):
+		}
+	}
+
+	return fillGaps(mergeCandles(checkpoint.Candles, recent, config.Start, config.End), duration), nil
+}
+
+// mergeCandles merges the candles rebuilt from the trades with the candles returned by OHLC,
+// which take precedence from their first candle
+func mergeCandles(rebuilt, recent []OHLCData, start, end time.Time) []OHLCData {
+	candles := []OHLCData{}
+
+	for _, candle := range rebuilt {
+		if len(recent) > 0 && !candle.Time.Before(recent[0].Time) {
+			break
+		}
+		candles = append(candles, candle)
+	}
+	candles = append(candles, recent...)
+
+	// Keep the candles within [start, end)
+	filtered := candles[:0]
+	for _, candle := range candles {
+		if candle.Time.Before(start) || !candle.Time.Before(end) {
+			continue
+		}
+		filtered = append(filtered, candle)
+	}
+	return filtered
+}
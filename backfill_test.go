@@ -0,0 +1,104 @@
+package kraken_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	kraken "github.com/astaluego/golang-kraken"
+	"github.com/astaluego/golang-kraken/krakentest"
+)
+
+func TestBackfillOHLC(t *testing.T) {
+	server := krakentest.NewServer()
+	defer server.Close()
+
+	// OHLC answers with its default candles, from 1688671200
+	server.Enqueue("Trades",
+		krakentest.Response{Result: json.RawMessage(`{
+			"XXBTZUSD": [
+				["100.0", "1.0", 1688670010.5, "b", "m", "", 1],
+				["110.0", "1.0", 1688670020.5, "s", "l", "", 2],
+				["105.0", "2.0", 1688670190.5, "b", "l", "", 3]
+			],
+			"last": "1688670190500000000"
+		}`)},
+		// The last page repeats the tail and does not move the cursor
+		krakentest.Response{Result: json.RawMessage(`{
+			"XXBTZUSD": [
+				["105.0", "2.0", 1688670190.5, "b", "l", "", 3]
+			],
+			"last": "1688670190500000000"
+		}`)},
+	)
+
+	checkpoints := []kraken.BackfillCheckpoint{}
+	candles, err := server.NewClient().BackfillOHLC(context.Background(), kraken.BackfillConfig{
+		AssetPair: kraken.XBT_USD,
+		Interval:  kraken.Interval1min,
+		Start:     time.Unix(1688670000, 0),
+		End:       time.Unix(1688671320, 0),
+		Throttle:  time.Millisecond,
+		OnCheckpoint: func(checkpoint kraken.BackfillCheckpoint) error {
+			checkpoints = append(checkpoints, checkpoint)
+			return nil
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(checkpoints) != len(server.Calls("Trades")) {
+		t.Fatalf("expected a checkpoint per page, got %d for %d pages", len(checkpoints), len(server.Calls("Trades")))
+	}
+	last := checkpoints[len(checkpoints)-1]
+	if last.LastTradeID != 3 || last.Cursor.UnixNano() != 1688670190500000000 || len(last.Candles) != 2 {
+		t.Errorf("unexpected last checkpoint: %+v", last)
+	}
+
+	// One candle per minute from the first trade to the last candle of OHLC
+	if len(candles) != 22 {
+		t.Fatalf("expected 22 candles, got %d", len(candles))
+	}
+	for i, candle := range candles {
+		if expected := time.Unix(1688670000+int64(i)*60, 0); !candle.Time.Equal(expected) {
+			t.Errorf("candle %d: expected %s, got %s", i, expected, candle.Time)
+		}
+	}
+
+	first := candles[0]
+	if first.Open.String() != "100" || first.High.String() != "110" || first.Close.String() != "110" || first.Volume.String() != "2" || first.Count != 2 {
+		t.Errorf("unexpected first candle: %+v", first)
+	}
+	gap := candles[1]
+	if gap.Open.String() != "110" || gap.Close.String() != "110" || !gap.Volume.IsZero() || gap.Count != 0 {
+		t.Errorf("unexpected empty interval: %+v", gap)
+	}
+	if candles[3].Close.String() != "105" || candles[20].Close.String() != "30305.7" {
+		t.Errorf("unexpected candles: %+v, %+v", candles[3], candles[20])
+	}
+}
+
+func TestBackfillOHLCCancelsRequestInFlight(t *testing.T) {
+	server := krakentest.NewServer()
+	defer server.Close()
+
+	server.Enqueue("Trades", krakentest.Response{Result: json.RawMessage(`{"XXBTZUSD": [], "last": "1688670190500000000"}`), Latency: 10 * time.Second})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	_, err := server.NewClient().BackfillOHLC(ctx, kraken.BackfillConfig{
+		AssetPair: kraken.XBT_USD,
+		Start:     time.Unix(1688670000, 0),
+	})
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("expected context.DeadlineExceeded, got %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("the request in flight was not cancelled, returned after %s", elapsed)
+	}
+}
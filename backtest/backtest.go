@@ -0,0 +1,236 @@
+// Package backtest replays historical candles or trades into a strategy and simulates its orders.
+//
+// The strategy places its orders through kraken.Trader, so the same code runs against
+// kraken.Client, paper.Trader or a backtest. Orders are simulated by paper.Trader: each candle
+// is replayed as a path of prices (open, low, high, close for a bullish candle; open, high,
+// low, close for a bearish one), quoted with the configured slippage on both sides. Market
+// orders fill at the current price, limit orders at their price once the path reaches it,
+// and stop-loss and take-profit orders once the path crosses their trigger.
+package backtest
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	kraken "github.com/astaluego/golang-kraken"
+	"github.com/astaluego/golang-kraken/paper"
+	"github.com/shopspring/decimal"
+)
+
+type Config struct {
+	// AssetPair is required
+	AssetPair kraken.AssetPair
+
+	// Info is required
+	// Trading rules and fee schedule of the pair
+	Info kraken.AssetPairsInfo
+
+	// Balances is required
+	// Initial balances of the account
+	Balances map[kraken.Asset]decimal.Decimal
+
+	// Strategy is required
+	// Called for each bar, once the orders were matched against it
+	Strategy func(bar Bar, trader kraken.Trader) error
+
+	// Slippage is optional
+	// Fraction of the price paid above it on buys and received below it on sells (e.g. 0.0005)
+	Slippage decimal.Decimal
+
+	// Fee is optional
+	// Percent charged on every fill, instead of the fee schedule of the pair
+	Fee decimal.Decimal
+
+	// FeeVolume is optional
+	// 30-day trade volume used to pick the tier of the fee schedule
+	FeeVolume decimal.Decimal
+
+	// PeriodsPerYear is optional
+	// Number of bars in a year, used to annualize the Sharpe ratio
+	// Default: computed from the median interval between bars
+	PeriodsPerYear float64
+}
+
+// Bar is a replayed candle, or a replayed trade whose prices are all the trade price
+type Bar struct {
+	Time   time.Time
+	Open   decimal.Decimal
+	High   decimal.Decimal
+	Low    decimal.Decimal
+	Close  decimal.Decimal
+	Volume decimal.Decimal
+}
+
+// Candles returns the bars of candles, e.g. the result of OHLC or store.Candles
+func Candles(candles []kraken.OHLCData) []Bar {
+	bars := make([]Bar, 0, len(candles))
+	for _, candle := range candles {
+		bars = append(bars, Bar{
+			Time:   candle.Time,
+			Open:   candle.Open,
+			High:   candle.High,
+			Low:    candle.Low,
+			Close:  candle.Close,
+			Volume: candle.Volume,
+		})
+	}
+	return bars
+}
+
+// Trades returns the bars of trades, e.g. the result of RecentTrades or store.Trades
+func Trades(trades []kraken.TradeData) []Bar {
+	bars := make([]Bar, 0, len(trades))
+	for _, trade := range trades {
+		bars = append(bars, Bar{
+			Time:   trade.Time,
+			Open:   trade.Price,
+			High:   trade.Price,
+			Low:    trade.Price,
+			Close:  trade.Price,
+			Volume: trade.Volume,
+		})
+	}
+	return bars
+}
+
+// path returns the prices a bar goes through, stopping at the given prices on the way so
+// the stop-loss and take-profit orders trigger at their price rather than at the extremes
+func (bar Bar) path(stops []decimal.Decimal) []decimal.Decimal {
+	points := []decimal.Decimal{bar.Open, bar.High, bar.Low, bar.Close}
+	if bar.Close.GreaterThanOrEqual(bar.Open) {
+		points = []decimal.Decimal{bar.Open, bar.Low, bar.High, bar.Close}
+	}
+
+	path := []decimal.Decimal{bar.Open}
+	for i := 1; i < len(points); i++ {
+		from, to := points[i-1], points[i]
+		if from.Equal(to) {
+			continue
+		}
+
+		between := []decimal.Decimal{}
+		for _, stop := range stops {
+			if stop.GreaterThan(decimal.Min(from, to)) && stop.LessThan(decimal.Max(from, to)) {
+				between = append(between, stop)
+			}
+		}
+		sort.Slice(between, func(i, j int) bool {
+			if from.LessThan(to) {
+				return between[i].LessThan(between[j])
+			}
+			return between[i].GreaterThan(between[j])
+		})
+		path = append(append(path, between...), to)
+	}
+	return path
+}
+
+// orderPrices returns the trigger prices of the open stop-loss and take-profit orders
+func orderPrices(trader *paper.Trader) []decimal.Decimal {
+	orders, _ := trader.OpenOrders(kraken.OpenOrdersConfig{})
+
+	prices := []decimal.Decimal{}
+	for _, order := range orders {
+		switch order.OrderDescription.Ordertype {
+		case kraken.StopLoss, kraken.TakeProfit, kraken.StopLossLimit, kraken.TakeProfitLimit:
+			prices = append(prices, order.OrderDescription.Price)
+		}
+	}
+	return prices
+}
+
+// EquityPoint is the value of the account at the close of a bar, in quote currency
+type EquityPoint struct {
+	Time   time.Time
+	Equity decimal.Decimal
+}
+
+type Result struct {
+	// Equity curve, one point per bar
+	Equity []EquityPoint
+	// Fills of the orders, in order
+	Fills []paper.Fill
+	// Trades are the fills matched into round trips
+	Trades []RoundTrip
+	// Orders placed by the strategy, by transaction ID
+	Orders map[string]kraken.Order
+	// Final balances of the account
+	Balances   map[kraken.Asset]decimal.Decimal
+	Statistics Statistics
+}
+
+// Run replays the bars chronologically into the strategy
+func Run(config Config, bars []Bar) (*Result, error) {
+	if config.AssetPair == "" {
+		return nil, fmt.Errorf("AssetPair is required")
+	}
+	if config.Info.BaseAsset == "" || config.Info.QuoteAsset == "" {
+		return nil, fmt.Errorf("Info is required")
+	}
+	if len(config.Balances) == 0 {
+		return nil, fmt.Errorf("Balances is required")
+	}
+	if config.Strategy == nil {
+		return nil, fmt.Errorf("Strategy is required")
+	}
+
+	info := config.Info
+	if config.Fee.IsPositive() {
+		info.Fees = []kraken.Fee{{Percent: config.Fee}}
+		info.FeesMaker = nil
+	}
+
+	bars = append([]Bar{}, bars...)
+	sort.SliceStable(bars, func(i, j int) bool { return bars[i].Time.Before(bars[j].Time) })
+
+	result := &Result{}
+	var now time.Time
+	trader, err := paper.New(paper.Config{
+		Pairs:     map[kraken.AssetPair]kraken.AssetPairsInfo{config.AssetPair: info},
+		Balances:  config.Balances,
+		FeeVolume: config.FeeVolume,
+		Now:       func() time.Time { return now },
+		OnFill: func(fill paper.Fill) {
+			result.Fills = append(result.Fills, fill)
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	one := decimal.NewFromInt(1)
+	for _, bar := range bars {
+		now = bar.Time
+		for _, price := range bar.path(orderPrices(trader)) {
+			ticker := kraken.AssetTickerInfo{}
+			ticker.Ask.Price = price.Mul(one.Add(config.Slippage))
+			ticker.Bid.Price = price.Mul(one.Sub(config.Slippage))
+			ticker.LastTradeClosed.Price = price
+			trader.UpdateTicker(config.AssetPair, ticker)
+		}
+
+		if err := config.Strategy(bar, trader); err != nil {
+			return nil, fmt.Errorf("strategy failed at %s: %w", bar.Time, err)
+		}
+
+		balances := trader.Balances()
+		result.Equity = append(result.Equity, EquityPoint{
+			Time:   bar.Time,
+			Equity: balances[info.QuoteAsset].Add(balances[info.BaseAsset].Mul(bar.Close)),
+		})
+	}
+
+	result.Orders = make(map[string]kraken.Order)
+	open, _ := trader.OpenOrders(kraken.OpenOrdersConfig{})
+	closed, _ := trader.ClosedOrders(kraken.ClosedOrdersConfig{})
+	for _, orders := range []map[string]kraken.Order{open, closed} {
+		for txid, order := range orders {
+			result.Orders[txid] = order
+		}
+	}
+	result.Balances = trader.Balances()
+	result.Trades = roundTrips(result.Fills)
+	result.Statistics = statistics(config, bars, result)
+	return result, nil
+}
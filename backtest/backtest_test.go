@@ -0,0 +1,280 @@
+package backtest
+
+import (
+	"math"
+	"strings"
+	"testing"
+	"time"
+
+	kraken "github.com/astaluego/golang-kraken"
+	"github.com/astaluego/golang-kraken/paper"
+	"github.com/shopspring/decimal"
+)
+
+var d = decimal.RequireFromString
+
+var start = time.Date(2023, 7, 6, 10, 0, 0, 0, time.UTC)
+
+var xbtUSD = kraken.AssetPairsInfo{
+	Altname:      "XBTUSD",
+	BaseAsset:    kraken.XBT,
+	QuoteAsset:   kraken.USD,
+	PairDecimals: 1,
+	CostDecimals: 8,
+	LotDecimals:  8,
+	OrderMin:     d("0.0001"),
+	TickSize:     d("0.01"),
+	Status:       kraken.AssetPairOnline,
+}
+
+func bar(hour int, open, high, low, close string) Bar {
+	return Bar{
+		Time:  start.Add(time.Duration(hour) * time.Hour),
+		Open:  d(open),
+		High:  d(high),
+		Low:   d(low),
+		Close: d(close),
+	}
+}
+
+func prices(values []decimal.Decimal) string {
+	parts := []string{}
+	for _, value := range values {
+		parts = append(parts, value.String())
+	}
+	return strings.Join(parts, " ")
+}
+
+func TestBarPath(t *testing.T) {
+	tests := []struct {
+		name     string
+		bar      Bar
+		stops    []string
+		expected string
+	}{
+		{"bullish", bar(0, "100", "110", "90", "105"), nil, "100 90 110 105"},
+		{"bearish", bar(0, "100", "110", "90", "95"), nil, "100 110 90 95"},
+		{"trade", bar(0, "100", "100", "100", "100"), nil, "100"},
+		{"without wick", bar(0, "100", "100", "90", "100"), nil, "100 90 100"},
+		{
+			// Each stop is inserted on every leg crossing it, in the direction of the leg
+			name:     "stops",
+			bar:      bar(0, "100", "110", "90", "105"),
+			stops:    []string{"108", "95", "92"},
+			expected: "100 95 92 90 92 95 108 110 108 105",
+		},
+		{
+			// The extremes are already on the path, the stops outside of the bar are not reached
+			name:     "stops on or outside of the path",
+			bar:      bar(0, "100", "110", "90", "95"),
+			stops:    []string{"110", "90", "120", "80"},
+			expected: "100 110 90 95",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			stops := []decimal.Decimal{}
+			for _, stop := range tt.stops {
+				stops = append(stops, d(stop))
+			}
+			if path := prices(tt.bar.path(stops)); path != tt.expected {
+				t.Errorf("expected %s, got %s", tt.expected, path)
+			}
+		})
+	}
+}
+
+func TestRunValidation(t *testing.T) {
+	valid := Config{
+		AssetPair: kraken.XBT_USD,
+		Info:      xbtUSD,
+		Balances:  map[kraken.Asset]decimal.Decimal{kraken.USD: d("1000")},
+		Strategy:  func(Bar, kraken.Trader) error { return nil },
+	}
+
+	tests := []struct {
+		name     string
+		modify   func(config *Config)
+		expected string
+	}{
+		{"AssetPair", func(config *Config) { config.AssetPair = "" }, "AssetPair is required"},
+		{"Info", func(config *Config) { config.Info = kraken.AssetPairsInfo{} }, "Info is required"},
+		{"Balances", func(config *Config) { config.Balances = nil }, "Balances is required"},
+		{"Strategy", func(config *Config) { config.Strategy = nil }, "Strategy is required"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := valid
+			tt.modify(&config)
+			if _, err := Run(config, nil); err == nil || err.Error() != tt.expected {
+				t.Errorf("expected %q, got %v", tt.expected, err)
+			}
+		})
+	}
+}
+
+func TestRunFills(t *testing.T) {
+	place := func(trader kraken.Trader, config kraken.AddOrderConfig) error {
+		config.AssetPair = kraken.XBT_USD
+		_, err := trader.AddOrder(config)
+		return err
+	}
+
+	bars := []Bar{
+		bar(0, "100", "100", "100", "100"),
+		bar(1, "100", "101", "90", "100"),
+		bar(2, "100", "125", "91", "110"),
+	}
+	result, err := Run(Config{
+		AssetPair: kraken.XBT_USD,
+		Info:      xbtUSD,
+		Balances:  map[kraken.Asset]decimal.Decimal{kraken.USD: d("10000")},
+		Slippage:  d("0.01"),
+		Fee:       d("0.1"),
+		Strategy: func(bar Bar, trader kraken.Trader) error {
+			switch bar.Time {
+			case bars[0].Time:
+				if err := place(trader, kraken.AddOrderConfig{Type: kraken.Buy, OrderType: kraken.Market, Volume: d("1")}); err != nil {
+					return err
+				}
+				return place(trader, kraken.AddOrderConfig{Type: kraken.Buy, OrderType: kraken.Limit, Volume: d("1"), Price: d("95")})
+			case bars[1].Time:
+				if err := place(trader, kraken.AddOrderConfig{Type: kraken.Sell, OrderType: kraken.StopLoss, Volume: d("1"), Price: d("92")}); err != nil {
+					return err
+				}
+				return place(trader, kraken.AddOrderConfig{Type: kraken.Sell, OrderType: kraken.TakeProfit, Volume: d("1"), Price: d("120")})
+			}
+			return nil
+		},
+	}, bars)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The market order pays the slippage, the limit order fills at its price once the low
+	// reaches it, the stop-loss and the take-profit at their trigger less the slippage
+	expected := []struct {
+		typ   kraken.Type
+		price string
+		fee   string
+		maker bool
+		hour  int
+	}{
+		{kraken.Buy, "101", "0.101", false, 0},
+		{kraken.Buy, "95", "0.095", true, 1},
+		{kraken.Sell, "91.08", "0.09108", false, 2},
+		{kraken.Sell, "118.8", "0.1188", false, 2},
+	}
+	if len(result.Fills) != len(expected) {
+		t.Fatalf("expected %d fills, got %+v", len(expected), result.Fills)
+	}
+	for i, fill := range result.Fills {
+		e := expected[i]
+		if fill.Type != e.typ || !fill.Price.Equal(d(e.price)) || !fill.Fee.Equal(d(e.fee)) || fill.Maker != e.maker || !fill.Time.Equal(bars[e.hour].Time) {
+			t.Errorf("fill %d: expected %+v, got %+v", i, e, fill)
+		}
+	}
+
+	// The stop-loss closed the first buy, the take-profit the second one
+	if len(result.Trades) != 2 || !result.Trades[0].Profit.Equal(d("-10.11208")) || !result.Trades[1].Profit.Equal(d("23.5862")) {
+		t.Errorf("unexpected round trips: %+v", result.Trades)
+	}
+	if !result.Balances[kraken.USD].Equal(d("10013.47412")) || !result.Balances[kraken.XBT].IsZero() {
+		t.Errorf("unexpected balances: %v", result.Balances)
+	}
+	if len(result.Orders) != 4 || len(result.Equity) != 3 || !result.Equity[1].Equity.Equal(d("10003.804")) {
+		t.Errorf("unexpected result: %d orders, equity %+v", len(result.Orders), result.Equity)
+	}
+	if !result.Statistics.WinRate.Equal(d("0.5")) || !result.Statistics.Fees.Equal(d("0.40588")) {
+		t.Errorf("unexpected statistics: %+v", result.Statistics)
+	}
+}
+
+func TestRoundTrips(t *testing.T) {
+	fill := func(typ kraken.Type, hour int, volume, price, fee string) paper.Fill {
+		return paper.Fill{Type: typ, Time: start.Add(time.Duration(hour) * time.Hour), Volume: d(volume), Price: d(price), Fee: d(fee)}
+	}
+
+	trips := roundTrips([]paper.Fill{
+		// A sell of the initial balance is not a round trip
+		fill(kraken.Sell, 0, "1", "95", "1"),
+		fill(kraken.Buy, 1, "1", "100", "1"),
+		fill(kraken.Buy, 2, "2", "110", "2"),
+		fill(kraken.Sell, 3, "1.5", "120", "1.5"),
+		// Only 1.5 is left of the buys, the rest comes from the initial balance
+		fill(kraken.Sell, 4, "2", "90", "2"),
+	})
+
+	expected := []struct {
+		entry, exit        int
+		volume, entryPrice string
+		exitPrice          string
+		fees, profit       string
+	}{
+		{1, 3, "1", "100", "120", "2", "18"},
+		{2, 3, "0.5", "110", "120", "1", "4"},
+		{2, 4, "1.5", "110", "90", "3", "-33"},
+	}
+	if len(trips) != len(expected) {
+		t.Fatalf("expected %d round trips, got %+v", len(expected), trips)
+	}
+	for i, trip := range trips {
+		e := expected[i]
+		if !trip.EntryTime.Equal(start.Add(time.Duration(e.entry)*time.Hour)) || !trip.ExitTime.Equal(start.Add(time.Duration(e.exit)*time.Hour)) ||
+			!trip.Volume.Equal(d(e.volume)) || !trip.EntryPrice.Equal(d(e.entryPrice)) || !trip.ExitPrice.Equal(d(e.exitPrice)) ||
+			!trip.Fees.Equal(d(e.fees)) || !trip.Profit.Equal(d(e.profit)) {
+			t.Errorf("round trip %d: expected %+v, got %+v", i, e, trip)
+		}
+	}
+}
+
+func TestStatistics(t *testing.T) {
+	bars := []Bar{
+		bar(0, "100", "110", "100", "110"),
+		bar(1, "110", "110", "99", "99"),
+		bar(2, "99", "121", "99", "121"),
+		bar(3, "121", "121", "96.8", "96.8"),
+	}
+	result := &Result{
+		Fills: []paper.Fill{{Fee: d("0.5")}, {Fee: d("0.25")}},
+		Trades: []RoundTrip{
+			{Profit: d("5")},
+			{Profit: d("-3")},
+			{Profit: d("1")},
+			// Breaking even is not a win
+			{Profit: d("0")},
+		},
+	}
+	// 1 XBT held from an equity of 100 at the open
+	for _, bar := range bars {
+		result.Equity = append(result.Equity, EquityPoint{Time: bar.Time, Equity: bar.Close})
+	}
+	config := Config{Info: xbtUSD, Balances: map[kraken.Asset]decimal.Decimal{kraken.XBT: d("1")}}
+
+	stats := statistics(config, bars, result)
+	if !stats.InitialEquity.Equal(d("100")) || !stats.FinalEquity.Equal(d("96.8")) || !stats.TotalReturn.Equal(d("-0.032")) {
+		t.Errorf("unexpected equity: %+v", stats)
+	}
+	// From the peak of 121 down to 96.8
+	if !stats.MaxDrawdown.Equal(d("0.2")) {
+		t.Errorf("expected a max drawdown of 0.2, got %s", stats.MaxDrawdown)
+	}
+	if !stats.WinRate.Equal(d("0.5")) || !stats.Fees.Equal(d("0.75")) {
+		t.Errorf("unexpected win rate %s or fees %s", stats.WinRate, stats.Fees)
+	}
+
+	// The returns are 0.1, -0.1, 0.2222 and -0.2: a mean of 0.005556 and a deviation of 0.190840,
+	// annualized from the hourly bars
+	if math.Abs(stats.Sharpe-2.724652) > 1e-6 {
+		t.Errorf("expected a Sharpe ratio of 2.724652, got %f", stats.Sharpe)
+	}
+	config.PeriodsPerYear = 1
+	if sharpe := statistics(config, bars, result).Sharpe; math.Abs(sharpe-0.029111) > 1e-6 {
+		t.Errorf("expected a Sharpe ratio of 0.029111, got %f", sharpe)
+	}
+
+	if stats := statistics(config, nil, &Result{}); !stats.MaxDrawdown.IsZero() || stats.Sharpe != 0 || !stats.WinRate.IsZero() {
+		t.Errorf("expected empty statistics, got %+v", stats)
+	}
+}
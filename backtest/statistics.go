@@ -0,0 +1,184 @@
+package backtest
+
+import (
+	"math"
+	"sort"
+	"time"
+
+	kraken "github.com/astaluego/golang-kraken"
+	"github.com/astaluego/golang-kraken/paper"
+	"github.com/shopspring/decimal"
+)
+
+const year = 365 * 24 * time.Hour
+
+// RoundTrip is a buy closed by a sell, matched first in first out.
+// Sells of the initial balance, and buys still held at the end, are not round trips.
+type RoundTrip struct {
+	EntryTime  time.Time
+	ExitTime   time.Time
+	Volume     decimal.Decimal
+	EntryPrice decimal.Decimal
+	ExitPrice  decimal.Decimal
+	// Fees of the entry and the exit, prorated to the volume
+	Fees decimal.Decimal
+	// Profit is the net profit, in quote currency
+	Profit decimal.Decimal
+}
+
+type Statistics struct {
+	InitialEquity decimal.Decimal
+	FinalEquity   decimal.Decimal
+	// TotalReturn is FinalEquity / InitialEquity - 1
+	TotalReturn decimal.Decimal
+	// MaxDrawdown is the largest decline from a peak of the equity curve, as a fraction of the peak
+	MaxDrawdown decimal.Decimal
+	// Sharpe is the annualized Sharpe ratio of the returns between bars, with a zero risk-free rate
+	Sharpe float64
+	// WinRate is the fraction of the round trips with a positive profit
+	WinRate decimal.Decimal
+	// Fees paid on every fill
+	Fees decimal.Decimal
+}
+
+// roundTrips matches the sells with the previous buys, first in first out
+func roundTrips(fills []paper.Fill) []RoundTrip {
+	type lot struct {
+		fill   paper.Fill
+		volume decimal.Decimal
+	}
+
+	trips := []RoundTrip{}
+	lots := []*lot{}
+	for _, fill := range fills {
+		if fill.Type == kraken.Buy {
+			lots = append(lots, &lot{fill: fill, volume: fill.Volume})
+			continue
+		}
+
+		remaining := fill.Volume
+		for len(lots) > 0 && remaining.IsPositive() {
+			entry := lots[0]
+			volume := decimal.Min(entry.volume, remaining)
+			fees := entry.fill.Fee.Mul(volume).Div(entry.fill.Volume).
+				Add(fill.Fee.Mul(volume).Div(fill.Volume))
+
+			trips = append(trips, RoundTrip{
+				EntryTime:  entry.fill.Time,
+				ExitTime:   fill.Time,
+				Volume:     volume,
+				EntryPrice: entry.fill.Price,
+				ExitPrice:  fill.Price,
+				Fees:       fees,
+				Profit:     fill.Price.Sub(entry.fill.Price).Mul(volume).Sub(fees),
+			})
+
+			entry.volume = entry.volume.Sub(volume)
+			remaining = remaining.Sub(volume)
+			if !entry.volume.IsPositive() {
+				lots = lots[1:]
+			}
+		}
+	}
+	return trips
+}
+
+func statistics(config Config, bars []Bar, result *Result) Statistics {
+	stats := Statistics{}
+	for _, fill := range result.Fills {
+		stats.Fees = stats.Fees.Add(fill.Fee)
+	}
+
+	if len(result.Trades) > 0 {
+		wins := 0
+		for _, trip := range result.Trades {
+			if trip.Profit.IsPositive() {
+				wins++
+			}
+		}
+		stats.WinRate = decimal.NewFromInt(int64(wins)).Div(decimal.NewFromInt(int64(len(result.Trades))))
+	}
+
+	if len(result.Equity) == 0 {
+		return stats
+	}
+
+	// The initial equity is valued at the open of the first bar
+	info := config.Info
+	stats.InitialEquity = config.Balances[info.QuoteAsset].Add(config.Balances[info.BaseAsset].Mul(bars[0].Open))
+	stats.FinalEquity = result.Equity[len(result.Equity)-1].Equity
+	if stats.InitialEquity.IsPositive() {
+		stats.TotalReturn = stats.FinalEquity.Div(stats.InitialEquity).Sub(decimal.NewFromInt(1))
+	}
+
+	peak := stats.InitialEquity
+	returns := []float64{}
+	previous := stats.InitialEquity
+	for _, point := range result.Equity {
+		if point.Equity.GreaterThan(peak) {
+			peak = point.Equity
+		}
+		if peak.IsPositive() {
+			drawdown := peak.Sub(point.Equity).Div(peak)
+			if drawdown.GreaterThan(stats.MaxDrawdown) {
+				stats.MaxDrawdown = drawdown
+			}
+		}
+
+		if previous.IsPositive() {
+			r, _ := point.Equity.Div(previous).Sub(decimal.NewFromInt(1)).Float64()
+			returns = append(returns, r)
+		}
+		previous = point.Equity
+	}
+
+	periods := config.PeriodsPerYear
+	if periods == 0 {
+		periods = periodsPerYear(bars)
+	}
+	stats.Sharpe = sharpe(returns, periods)
+	return stats
+}
+
+func sharpe(returns []float64, periodsPerYear float64) float64 {
+	if len(returns) < 2 {
+		return 0
+	}
+
+	mean := 0.0
+	for _, r := range returns {
+		mean += r
+	}
+	mean /= float64(len(returns))
+
+	variance := 0.0
+	for _, r := range returns {
+		variance += (r - mean) * (r - mean)
+	}
+	deviation := math.Sqrt(variance / float64(len(returns)-1))
+	if deviation == 0 {
+		return 0
+	}
+
+	ratio := mean / deviation
+	if periodsPerYear > 0 {
+		ratio *= math.Sqrt(periodsPerYear)
+	}
+	return ratio
+}
+
+// periodsPerYear returns the number of bars in a year from the median interval between bars
+func periodsPerYear(bars []Bar) float64 {
+	intervals := []time.Duration{}
+	for i := 1; i < len(bars); i++ {
+		if interval := bars[i].Time.Sub(bars[i-1].Time); interval > 0 {
+			intervals = append(intervals, interval)
+		}
+	}
+	if len(intervals) == 0 {
+		return 0
+	}
+
+	sort.Slice(intervals, func(i, j int) bool { return intervals[i] < intervals[j] })
+	return float64(year) / float64(intervals[len(intervals)/2])
+}
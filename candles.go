@@ -0,0 +1,94 @@
+package kraken
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+// Duration returns the duration of the interval, or 0 if the interval is unknown
+func (i Interval) Duration() time.Duration {
+	minutes, err := strconv.ParseInt(string(i), 10, 64)
+	if err != nil || minutes <= 0 {
+		return 0
+	}
+	return time.Duration(minutes) * time.Minute
+}
+
+// aggregateTrades adds trades to candles of the given duration. Trades must be sorted by time,
+// the last candle is extended when the first trades belong to it.
+func aggregateTrades(candles []OHLCData, trades []TradeData, duration time.Duration) []OHLCData {
+	// Sum of price * volume of each candle, to compute the VWAP
+	var notional decimal.Decimal
+	if len(candles) > 0 {
+		last := candles[len(candles)-1]
+		notional = last.VWAP.Mul(last.Volume)
+	}
+
+	for _, trade := range trades {
+		bucket := truncateUnix(trade.Time, duration)
+
+		if len(candles) == 0 || !candles[len(candles)-1].Time.Equal(bucket) {
+			candles = append(candles, OHLCData{
+				Time:  bucket,
+				Open:  trade.Price,
+				High:  trade.Price,
+				Low:   trade.Price,
+				Close: trade.Price,
+			})
+			notional = decimal.Zero
+		}
+
+		candle := &candles[len(candles)-1]
+		if trade.Price.GreaterThan(candle.High) {
+			candle.High = trade.Price
+		}
+		if trade.Price.LessThan(candle.Low) {
+			candle.Low = trade.Price
+		}
+		candle.Close = trade.Price
+		candle.Volume = candle.Volume.Add(trade.Volume)
+		candle.Count++
+
+		notional = notional.Add(trade.Price.Mul(trade.Volume))
+		if !candle.Volume.IsZero() {
+			candle.VWAP = notional.DivRound(candle.Volume, 10)
+		}
+	}
+
+	return candles
+}
+
+// fillGaps inserts a candle for each interval without trades between two candles:
+// flat at the previous close, without volume
+func fillGaps(candles []OHLCData, duration time.Duration) []OHLCData {
+	filled := make([]OHLCData, 0, len(candles))
+	for _, candle := range candles {
+		if len(filled) > 0 {
+			previous := filled[len(filled)-1]
+			for t := previous.Time.Add(duration); t.Before(candle.Time); t = t.Add(duration) {
+				filled = append(filled, OHLCData{
+					Time:  t,
+					Open:  previous.Close,
+					High:  previous.Close,
+					Low:   previous.Close,
+					Close: previous.Close,
+					VWAP:  previous.Close,
+				})
+			}
+		}
+		filled = append(filled, candle)
+	}
+	return filled
+}
+
+// truncateUnix rounds t down to a multiple of d since the unix epoch, like Kraken does for its candles
+func truncateUnix(t time.Time, d time.Duration) time.Time {
+	nanoseconds := t.UnixNano()
+	remainder := nanoseconds % int64(d)
+	if remainder < 0 {
+		remainder += int64(d)
+	}
+	return time.Unix(0, nanoseconds-remainder).In(t.Location())
+}
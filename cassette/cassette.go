@@ -0,0 +1,350 @@
+// Package cassette records the HTTP interactions of a kraken.Client once and replays them,
+// so integration tests are deterministic and run without network access.
+//
+//	recorder, err := cassette.New(cassette.Config{Path: "testdata/ticker.json", Mode: cassette.Replay})
+//	client := kraken.NewWithOptions(kraken.WithTransport(recorder))
+package cassette
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Version of the on-disk format
+const Version = 1
+
+// Mode of a Recorder
+type Mode int
+
+const (
+	// Replay answers with the recorded interactions and never hits the network
+	Replay Mode = iota
+	// Record sends the requests and stores the interactions
+	Record
+)
+
+// Form fields which change on every request, or which are secret
+var ignoredFields = map[string]bool{
+	"nonce": true,
+	"otp":   true,
+}
+
+// Headers never stored
+var ignoredHeaders = map[string]bool{
+	"Api-Key":    true,
+	"Api-Sign":   true,
+	"Set-Cookie": true,
+}
+
+// Cassette is the on-disk format of the recorded interactions
+type Cassette struct {
+	Version      int           `json:"version"`
+	Interactions []Interaction `json:"interactions"`
+}
+
+// Interaction is a recorded request/response pair
+type Interaction struct {
+	Request  Request  `json:"request"`
+	Response Response `json:"response"`
+}
+
+// Request is a recorded request, without credentials nor nonce
+type Request struct {
+	Method string `json:"method"`
+	// Path of the endpoint (e.g. /0/public/Ticker)
+	Path string `json:"path"`
+	// Form is the normalized form body
+	Form string `json:"form"`
+}
+
+// Response is a recorded response
+type Response struct {
+	Status int         `json:"status"`
+	Header http.Header `json:"header,omitempty"`
+	Body   string      `json:"body"`
+}
+
+type Config struct {
+	// Path is required
+	// File storing the cassette
+	Path string
+
+	// Mode is optional
+	// Default: Replay
+	Mode Mode
+
+	// Transport is optional
+	// Used to send the requests in Record mode
+	// Default: http.DefaultTransport
+	Transport http.RoundTripper
+}
+
+// Recorder is an http.RoundTripper recording or replaying the interactions of a cassette
+type Recorder struct {
+	config Config
+
+	mu       sync.Mutex
+	cassette Cassette
+	replayed []bool
+}
+
+// New inits a new Recorder. In Replay mode the cassette must exist, in Record mode it is
+// created (or overwritten) on the first interaction.
+func New(config Config) (*Recorder, error) {
+	if config.Path == "" {
+		return nil, fmt.Errorf("Path is required")
+	}
+	if config.Transport == nil {
+		config.Transport = http.DefaultTransport
+	}
+
+	r := &Recorder{
+		config:   config,
+		cassette: Cassette{Version: Version},
+	}
+
+	if config.Mode == Replay {
+		data, err := ioutil.ReadFile(config.Path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read cassette: %s", err.Error())
+		}
+		if err := json.Unmarshal(data, &r.cassette); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal cassette %s: %s", config.Path, err.Error())
+		}
+		if r.cassette.Version != Version {
+			return nil, fmt.Errorf("failed to load cassette %s: unsupported version %d (expected %d)", config.Path, r.cassette.Version, Version)
+		}
+		r.replayed = make([]bool, len(r.cassette.Interactions))
+	}
+
+	return r, nil
+}
+
+// RoundTrip implements http.RoundTripper. The request is not modified: its body is consumed
+// and closed, and a clone of the request is sent in Record mode.
+func (r *Recorder) RoundTrip(req *http.Request) (*http.Response, error) {
+	request, body, err := newRequest(req)
+	if err != nil {
+		return nil, err
+	}
+
+	if r.config.Mode == Record {
+		return r.record(req, body, request)
+	}
+	return r.replay(req, request)
+}
+
+func (r *Recorder) record(req *http.Request, body []byte, request Request) (*http.Response, error) {
+	out := req.Clone(req.Context())
+	if req.Body != nil {
+		out.Body = ioutil.NopCloser(bytes.NewReader(body))
+		out.GetBody = func() (io.ReadCloser, error) {
+			return ioutil.NopCloser(bytes.NewReader(body)), nil
+		}
+	}
+
+	resp, err := r.config.Transport.RoundTrip(out)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	respBody, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response body: %s", err.Error())
+	}
+
+	header := http.Header{}
+	for key, values := range resp.Header {
+		if ignoredHeaders[http.CanonicalHeaderKey(key)] {
+			continue
+		}
+		header[key] = values
+	}
+
+	response := Response{
+		Status: resp.StatusCode,
+		Header: header,
+		Body:   string(respBody),
+	}
+
+	r.mu.Lock()
+	r.cassette.Interactions = append(r.cassette.Interactions, Interaction{Request: request, Response: response})
+	err = r.save()
+	r.mu.Unlock()
+	if err != nil {
+		return nil, err
+	}
+
+	return response.toHTTP(req), nil
+}
+
+func (r *Recorder) replay(req *http.Request, request Request) (*http.Response, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	// Identical requests are replayed in the order they were recorded,
+	// then the last one is replayed again
+	match := -1
+	for i, interaction := range r.cassette.Interactions {
+		if interaction.Request != request {
+			continue
+		}
+		match = i
+		if !r.replayed[i] {
+			break
+		}
+	}
+
+	if match == -1 {
+		return nil, r.missing(request)
+	}
+
+	r.replayed[match] = true
+	return r.cassette.Interactions[match].Response.toHTTP(req), nil
+}
+
+// missing builds a helpful error when no interaction matches the request
+func (r *Recorder) missing(request Request) error {
+	var closest *Request
+	for i, interaction := range r.cassette.Interactions {
+		if interaction.Request.Method == request.Method && interaction.Request.Path == request.Path {
+			closest = &r.cassette.Interactions[i].Request
+			break
+		}
+	}
+
+	msg := fmt.Sprintf("cassette %s: no recording for %s %s %s", r.config.Path, request.Method, request.Path, request.Form)
+	if closest == nil {
+		return fmt.Errorf("%s (no recording for this endpoint)", msg)
+	}
+
+	return fmt.Errorf("%s\nclosest recording differs:\n%s", msg, diffForms(closest.Form, request.Form))
+}
+
+func (r *Recorder) save() error {
+	var buf bytes.Buffer
+	encoder := json.NewEncoder(&buf)
+	encoder.SetEscapeHTML(false)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(r.cassette); err != nil {
+		return fmt.Errorf("failed to marshal cassette: %s", err.Error())
+	}
+
+	if err := os.MkdirAll(filepath.Dir(r.config.Path), 0755); err != nil {
+		return fmt.Errorf("failed to save cassette: %s", err.Error())
+	}
+
+	tmp := r.config.Path + ".tmp"
+	if err := ioutil.WriteFile(tmp, buf.Bytes(), 0644); err != nil {
+		return fmt.Errorf("failed to save cassette: %s", err.Error())
+	}
+	return os.Rename(tmp, r.config.Path)
+}
+
+// newRequest reads and closes the body of a request, and returns the request to record
+// along with the body read
+func newRequest(req *http.Request) (Request, []byte, error) {
+	var (
+		form string
+		body []byte
+	)
+	if req.Body != nil {
+		var err error
+		body, err = ioutil.ReadAll(req.Body)
+		req.Body.Close()
+		if err != nil {
+			return Request{}, nil, fmt.Errorf("failed to read request body: %s", err.Error())
+		}
+
+		form, err = normalizeForm(string(body))
+		if err != nil {
+			return Request{}, nil, err
+		}
+	}
+
+	return Request{
+		Method: req.Method,
+		Path:   req.URL.Path,
+		Form:   form,
+	}, body, nil
+}
+
+// normalizeForm sorts the form fields and strips the nonce and the OTP
+func normalizeForm(body string) (string, error) {
+	values, err := url.ParseQuery(body)
+	if err != nil {
+		return "", fmt.Errorf("failed to parse request body: %s", err.Error())
+	}
+
+	for field := range ignoredFields {
+		values.Del(field)
+	}
+
+	// url.Values.Encode sorts by key
+	return values.Encode(), nil
+}
+
+func diffForms(recorded, requested string) string {
+	a, _ := url.ParseQuery(recorded)
+	b, _ := url.ParseQuery(requested)
+
+	keys := map[string]bool{}
+	for key := range a {
+		keys[key] = true
+	}
+	for key := range b {
+		keys[key] = true
+	}
+
+	sorted := []string{}
+	for key := range keys {
+		sorted = append(sorted, key)
+	}
+	sort.Strings(sorted)
+
+	var lines []string
+	for _, key := range sorted {
+		recordedValue, requestedValue := strings.Join(a[key], ","), strings.Join(b[key], ",")
+		if recordedValue == requestedValue {
+			continue
+		}
+		if _, ok := a[key]; ok {
+			lines = append(lines, fmt.Sprintf("- %s=%s", key, recordedValue))
+		}
+		if _, ok := b[key]; ok {
+			lines = append(lines, fmt.Sprintf("+ %s=%s", key, requestedValue))
+		}
+	}
+
+	return strings.Join(lines, "\n")
+}
+
+func (response Response) toHTTP(req *http.Request) *http.Response {
+	header := response.Header.Clone()
+	if header == nil {
+		header = http.Header{}
+	}
+
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", response.Status, http.StatusText(response.Status)),
+		StatusCode:    response.Status,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        header,
+		Body:          ioutil.NopCloser(strings.NewReader(response.Body)),
+		ContentLength: int64(len(response.Body)),
+		Request:       req,
+	}
+}
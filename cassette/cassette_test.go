@@ -0,0 +1,144 @@
+package cassette_test
+
+import (
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	kraken "github.com/astaluego/golang-kraken"
+	"github.com/astaluego/golang-kraken/cassette"
+	"github.com/astaluego/golang-kraken/krakentest"
+)
+
+func newClient(server *krakentest.Server, url string, recorder *cassette.Recorder) *kraken.Client {
+	secret := "c2VjcmV0"
+	if server != nil {
+		secret = server.Secret()
+	}
+	return kraken.NewWithOptions(
+		kraken.WithBaseURL(url),
+		kraken.WithCredentials(krakentest.Key, secret),
+		kraken.WithOTP(kraken.StaticPassword("otp-123456")),
+		kraken.WithTransport(recorder),
+	)
+}
+
+func TestRecordReplay(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "cassettes", "kraken.json")
+
+	// Record against the fake server
+	server := krakentest.NewServer()
+	recorder, err := cassette.New(cassette.Config{Path: path, Mode: cassette.Record, Transport: http.DefaultTransport})
+	if err != nil {
+		t.Fatal(err)
+	}
+	client := newClient(server, server.URL(), recorder)
+
+	recordedBalance, err := client.Balance()
+	if err != nil {
+		t.Fatal(err)
+	}
+	recordedTicker, err := client.TickerInformation(kraken.TickerInformationConfig{AssetPairs: []kraken.AssetPair{kraken.XBT_USD}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	server.Close()
+
+	// Neither the credentials, the signatures, the nonces nor the OTP are stored
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, secret := range []string{krakentest.Key, server.Secret(), "Api-Sign", "Api-Key", "nonce", "otp-123456"} {
+		if strings.Contains(string(data), secret) {
+			t.Errorf("the cassette contains %q", secret)
+		}
+	}
+
+	// Replay without the server, with another secret and other nonces
+	replayer, err := cassette.New(cassette.Config{Path: path})
+	if err != nil {
+		t.Fatal(err)
+	}
+	client = newClient(nil, "http://127.0.0.1:1", replayer)
+
+	balance, err := client.Balance()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(balance) != len(recordedBalance) || !balance[kraken.XBT].Equal(recordedBalance[kraken.XBT]) {
+		t.Errorf("expected %v, got %v", recordedBalance, balance)
+	}
+	ticker, err := client.TickerInformation(kraken.TickerInformationConfig{AssetPairs: []kraken.AssetPair{kraken.XBT_USD}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !ticker[kraken.XBT_USD].Ask.Price.Equal(recordedTicker[kraken.XBT_USD].Ask.Price) {
+		t.Errorf("expected %+v, got %+v", recordedTicker, ticker)
+	}
+
+	// A request never recorded fails with the difference with the closest recording
+	_, err = client.TickerInformation(kraken.TickerInformationConfig{AssetPairs: []kraken.AssetPair{kraken.ETH_EUR}})
+	if err == nil {
+		t.Fatal("expected an error for a missing recording")
+	}
+	for _, line := range []string{"no recording for POST /0/public/Ticker", "- pair=XXBTZUSD", "+ pair=XETHZEUR"} {
+		if !strings.Contains(err.Error(), line) {
+			t.Errorf("expected %q in %v", line, err)
+		}
+	}
+	if _, err := client.ServerTime(); err == nil || !strings.Contains(err.Error(), "no recording for this endpoint") {
+		t.Errorf("expected an error for an endpoint never recorded, got %v", err)
+	}
+}
+
+func TestRoundTripDoesNotModifyRequest(t *testing.T) {
+	server := krakentest.NewServer()
+	defer server.Close()
+
+	path := filepath.Join(t.TempDir(), "kraken.json")
+	recorder, err := cassette.New(cassette.Config{Path: path, Mode: cassette.Record, Transport: http.DefaultTransport})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	body := &closeRecorder{Reader: strings.NewReader("pair=XXBTZUSD")}
+	req, err := http.NewRequest(http.MethodPost, server.URL()+"/0/public/Ticker", body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	header := req.Header.Clone()
+
+	resp, err := recorder.RoundTrip(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+
+	if req.Body != io.ReadCloser(body) {
+		t.Error("the body of the request was replaced")
+	}
+	if !body.closed {
+		t.Error("the body of the request was not closed")
+	}
+	if len(req.Header) != len(header) || req.Header.Get("Content-Type") != header.Get("Content-Type") {
+		t.Errorf("the headers of the request were modified: %v", req.Header)
+	}
+	if resp.Request != req {
+		t.Error("the response does not point to the request")
+	}
+}
+
+type closeRecorder struct {
+	io.Reader
+	closed bool
+}
+
+func (c *closeRecorder) Close() error {
+	c.closed = true
+	return nil
+}
@@ -1,8 +1,9 @@
-//go:generate go run generate/generate.go generate/template_asset.go
+//go:generate go run generate/generate.go generate/template_asset.go -record generate/fixture.json
 
 package kraken
 
 import (
+	"context"
 	"crypto/hmac"
 	"crypto/sha256"
 	"crypto/sha512"
@@ -14,6 +15,8 @@ import (
 	"net/http"
 	"net/url"
 	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
 )
 
@@ -30,95 +33,226 @@ type Response struct {
 	Result interface{} `json:"result"`
 }
 
+// APIError is returned when Kraken answers with a list of errors (e.g. EGeneral:Invalid arguments)
+type APIError struct {
+	Errors []string
+}
+
+func (e *APIError) Error() string {
+	return fmt.Sprintf("got server errors: %+v", e.Errors)
+}
+
+// IsUnknownOrder returns true if err is an *APIError for an order Kraken does not know, or
+// no longer considers open (e.g. canceling an order already closed)
+func IsUnknownOrder(err error) bool {
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) {
+		return false
+	}
+	for _, code := range apiErr.Errors {
+		if strings.HasPrefix(code, "EOrder:Unknown order") {
+			return true
+		}
+	}
+	return false
+}
+
+// Client is safe for concurrent use by multiple goroutines
 type Client struct {
 	httpClient *http.Client
-	apiKey     string
-	apiSecret  string
+	baseURL    string
+	apiVersion string
+	userAgent  string
+	headers    http.Header
+
+	// Credentials are set by the options of NewWithOptions and never change afterwards
+	apiKey    string
+	apiSecret string
+	otp       OTP
+
+	// mu protects the location, which can be changed by SetTimezone
+	mu       sync.RWMutex
+	location *time.Location
+
+	lastNonce int64
+
+	middlewares []Middleware
+}
+
+type credentials struct {
+	apiKey    string
+	apiSecret string
+	otp       OTP
 }
 
 // New inits a new Client
 func New() *Client {
-	return &Client{
+	return NewWithOptions()
+}
+
+// NewWithOptions inits a new Client configured with the given options
+func NewWithOptions(options ...Option) *Client {
+	c := &Client{
 		httpClient: http.DefaultClient,
+		baseURL:    apiURL,
+		apiVersion: apiVersion,
+		headers:    http.Header{},
+		location:   time.Local,
 	}
-}
 
-// WithAuthentification allows to specify a custom secret in order to execute private requests
-func (c *Client) WithAuthentification(key, secret string) {
-	c.apiKey = key
-	c.apiSecret = secret
+	for _, option := range options {
+		option(c)
+	}
+
+	return c
 }
 
+// SetTimezone configures the location of the time.Time objects returned by this client.
+// The process-wide time.Local is left untouched.
 func (c *Client) SetTimezone(location string) error {
 	loc, err := time.LoadLocation(location)
 	if err != nil {
-		return nil
+		return fmt.Errorf("failed to load location %q: %s", location, err.Error())
 	}
-	time.Local = loc
 
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.location = loc
 	return nil
 }
 
+func (c *Client) getCredentials() credentials {
+	return credentials{
+		apiKey:    c.apiKey,
+		apiSecret: c.apiSecret,
+		otp:       c.otp,
+	}
+}
+
+// in converts a parsed timestamp into the location of the client
+func (c *Client) in(t time.Time) time.Time {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	return t.In(c.location)
+}
+
+// nonce returns a strictly increasing nonce, even when requests are sent concurrently
+// within the same millisecond
+func (c *Client) nonce(now time.Time) int64 {
+	for {
+		last := atomic.LoadInt64(&c.lastNonce)
+		next := now.UnixMilli()
+		if next <= last {
+			next = last + 1
+		}
+		if atomic.CompareAndSwapInt64(&c.lastNonce, last, next) {
+			return next
+		}
+	}
+}
+
 func (c *Client) doRequest(endpoint string, isPrivate bool, data url.Values, respType interface{}) error {
+	return c.doRequestContext(context.Background(), endpoint, isPrivate, data, respType)
+}
+
+// doRequestContext sends a request which is cancelled with ctx, even while in flight
+func (c *Client) doRequestContext(ctx context.Context, endpoint string, isPrivate bool, data url.Values, respType interface{}) error {
 	var (
 		req *http.Request
 		err error
 	)
 
 	if isPrivate {
-		req, err = c.buildPrivateRequest(endpoint, data)
+		req, err = c.buildPrivateRequest(ctx, endpoint, data)
 		if err != nil {
 			return err
 		}
 	} else {
-		req, err = c.buildPublicRequest(endpoint, data)
+		req, err = c.buildPublicRequest(ctx, endpoint, data)
 		if err != nil {
 			return err
 		}
 	}
 
-	resp, err := c.httpClient.Do(req)
-	if err != nil {
-		return errors.New("failed to make http request")
+	call := &Call{
+		Endpoint: endpoint,
+		Private:  isPrivate,
+		Request:  req,
+		Form:     data,
+		Cost:     cost(endpoint, isPrivate),
 	}
-	defer resp.Body.Close()
 
-	return c.parseResponse(resp, respType)
+	handler := func(call *Call) error {
+		resp, err := c.httpClient.Do(call.Request)
+		if err != nil {
+			return fmt.Errorf("failed to make http request: %w", err)
+		}
+		defer resp.Body.Close()
+
+		call.StatusCode = resp.StatusCode
+
+		err = c.parseResponse(resp, respType)
+		var apiErr *APIError
+		if errors.As(err, &apiErr) {
+			call.Errors = apiErr.Errors
+		}
+		return err
+	}
+
+	for i := len(c.middlewares) - 1; i >= 0; i-- {
+		handler = c.middlewares[i](handler)
+	}
+
+	return handler(call)
 }
 
-func (c *Client) buildPublicRequest(endpoint string, data url.Values) (*http.Request, error) {
+func (c *Client) buildPublicRequest(ctx context.Context, endpoint string, data url.Values) (*http.Request, error) {
 	if data == nil {
 		data = url.Values{}
 	}
 
-	URL := fmt.Sprintf("%s/%s/public/%s", apiURL, apiVersion, endpoint)
-	req, err := http.NewRequest("POST", URL, strings.NewReader(data.Encode()))
+	URL := fmt.Sprintf("%s/%s/public/%s", c.baseURL, c.apiVersion, endpoint)
+	req, err := http.NewRequestWithContext(ctx, "POST", URL, strings.NewReader(data.Encode()))
 	if err != nil {
 		return nil, fmt.Errorf("failed to create public request: %s", err.Error())
 	}
+	c.setHeaders(req)
 
 	return req, nil
 }
 
-func (c *Client) buildPrivateRequest(endpoint string, data url.Values) (*http.Request, error) {
-	if c.apiKey == "" || c.apiSecret == "" {
+func (c *Client) buildPrivateRequest(ctx context.Context, endpoint string, data url.Values) (*http.Request, error) {
+	creds := c.getCredentials()
+	if creds.apiKey == "" || creds.apiSecret == "" {
 		return nil, fmt.Errorf("failed to create private request: key or secret is empty")
 	}
 
 	if data == nil {
 		data = url.Values{}
 	}
-	data.Set("nonce", fmt.Sprintf("%d", time.Now().UTC().UnixMilli()))
+	now := time.Now().UTC()
+	data.Set("nonce", fmt.Sprintf("%d", c.nonce(now)))
+
+	if data.Get("otp") == "" && creds.otp != nil {
+		otp, err := creds.otp.Password(now)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create private request: %s", err.Error())
+		}
+		data.Set("otp", otp)
+	}
 
-	URL := fmt.Sprintf("%s/%s/private/%s", apiURL, apiVersion, endpoint)
-	req, err := http.NewRequest("POST", URL, strings.NewReader(data.Encode()))
+	URL := fmt.Sprintf("%s/%s/private/%s", c.baseURL, c.apiVersion, endpoint)
+	req, err := http.NewRequestWithContext(ctx, "POST", URL, strings.NewReader(data.Encode()))
 	if err != nil {
 		return nil, fmt.Errorf("failed to create private request: %s", err.Error())
 	}
+	c.setHeaders(req)
 
-	req.Header.Add("API-Key", c.apiKey)
+	req.Header.Add("API-Key", creds.apiKey)
 
-	signature, err := c.getSignature(fmt.Sprintf("/%s/private/%s", apiVersion, endpoint), data)
+	signature, err := getSignature(creds.apiSecret, fmt.Sprintf("/%s/private/%s", c.apiVersion, endpoint), data)
 	if err != nil {
 		return nil, fmt.Errorf("failed to sign private request: %s", err.Error())
 	}
@@ -127,7 +261,20 @@ func (c *Client) buildPrivateRequest(endpoint string, data url.Values) (*http.Re
 	return req, nil
 }
 
-func (c *Client) getSignature(requestURL string, data url.Values) (string, error) {
+func (c *Client) setHeaders(req *http.Request) {
+	for key, values := range c.headers {
+		for _, value := range values {
+			req.Header.Add(key, value)
+		}
+	}
+
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	if c.userAgent != "" {
+		req.Header.Set("User-Agent", c.userAgent)
+	}
+}
+
+func getSignature(apiSecret string, requestURL string, data url.Values) (string, error) {
 	sha := sha256.New()
 	_, err := sha.Write([]byte(data.Get("nonce") + data.Encode()))
 	if err != nil {
@@ -135,7 +282,7 @@ func (c *Client) getSignature(requestURL string, data url.Values) (string, error
 	}
 	shasum := sha.Sum(nil)
 
-	secret, err := base64.StdEncoding.DecodeString(c.apiSecret)
+	secret, err := base64.StdEncoding.DecodeString(apiSecret)
 	if err != nil {
 		return "", err
 	}
@@ -171,11 +318,11 @@ func (c *Client) parseResponse(response *http.Response, respType interface{}) er
 
 	err = json.Unmarshal(body, &resp)
 	if err != nil {
-		return fmt.Errorf("failed to unmarshal response: %+v", err)
+		return fmt.Errorf("failed to unmarshal response: %w", err)
 	}
 
 	if len(resp.Error) > 0 {
-		return fmt.Errorf("got server errors: %+v", resp.Error)
+		return &APIError{Errors: resp.Error}
 	}
 
 	return nil
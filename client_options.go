@@ -0,0 +1,89 @@
+package kraken
+
+import (
+	"net/http"
+	"strings"
+	"time"
+)
+
+// Option configures a Client built with NewWithOptions
+type Option func(c *Client)
+
+// WithBaseURL overrides the Kraken API endpoint (e.g. a local mock, a proxy or a recording server)
+// Default: https://api.kraken.com
+func WithBaseURL(baseURL string) Option {
+	return func(c *Client) {
+		c.baseURL = strings.TrimRight(baseURL, "/")
+	}
+}
+
+// WithAPIVersion overrides the Kraken API version number
+// Default: 0
+func WithAPIVersion(version string) Option {
+	return func(c *Client) {
+		c.apiVersion = version
+	}
+}
+
+// WithHTTPClient allows to use a custom http.Client (timeouts, proxies, ...)
+// Default: http.DefaultClient
+func WithHTTPClient(httpClient *http.Client) Option {
+	return func(c *Client) {
+		if httpClient == nil {
+			return
+		}
+		c.httpClient = httpClient
+	}
+}
+
+// WithTransport allows to use a custom http.RoundTripper.
+// The http.Client in use is copied, so http.DefaultClient is never modified.
+func WithTransport(transport http.RoundTripper) Option {
+	return func(c *Client) {
+		httpClient := *c.httpClient
+		httpClient.Transport = transport
+		c.httpClient = &httpClient
+	}
+}
+
+// WithUserAgent sets the User-Agent header sent with every request
+func WithUserAgent(userAgent string) Option {
+	return func(c *Client) {
+		c.userAgent = userAgent
+	}
+}
+
+// WithHeader adds a header sent with every request
+func WithHeader(key, value string) Option {
+	return func(c *Client) {
+		c.headers.Add(key, value)
+	}
+}
+
+// WithCredentials sets the API key and secret used to execute private requests
+// To generate a new one --> https://www.kraken.com/u/security/api
+func WithCredentials(key, secret string) Option {
+	return func(c *Client) {
+		c.apiKey = key
+		c.apiSecret = secret
+	}
+}
+
+// WithOTP sets the second factor (static password or TOTP) protecting the API key.
+// It is sent with every private request which does not provide its own OTP.
+func WithOTP(otp OTP) Option {
+	return func(c *Client) {
+		c.otp = otp
+	}
+}
+
+// WithLocation sets the location of the time.Time objects returned by the client
+// Default: time.Local
+func WithLocation(location *time.Location) Option {
+	return func(c *Client) {
+		if location == nil {
+			return
+		}
+		c.location = location
+	}
+}
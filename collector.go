@@ -0,0 +1,298 @@
+package kraken
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+type CollectorConfig struct {
+	// AssetPairs is required
+	AssetPairs []AssetPair
+
+	// PollInterval is optional
+	// Pause between two polls of the same pair
+	// Default: 5s
+	PollInterval time.Duration
+
+	// Since is optional
+	// Cursors to resume from, e.g. the ones saved by a previous run
+	// Default: the most recent data returned by Kraken
+	Since map[AssetPair]time.Time
+
+	// RateLimiter is optional
+	// Waited on before each poll, so a cancelled context stops the collector without delay.
+	// Leave it empty if the client already uses this limiter through the RateLimit middleware,
+	// otherwise each poll is counted twice.
+	RateLimiter *RateLimiter
+
+	// Buffer is optional
+	// Size of the events channel
+	// Default: 0 (unbuffered)
+	Buffer int
+}
+
+func (config CollectorConfig) validate() (CollectorConfig, error) {
+	if len(config.AssetPairs) == 0 {
+		return config, fmt.Errorf("AssetPairs is required")
+	}
+	if config.PollInterval == 0 {
+		config.PollInterval = 5 * time.Second
+	}
+	return config, nil
+}
+
+// TradeEvent is emitted by a TradeCollector after each poll of a pair returning new trades,
+// or failing
+type TradeEvent struct {
+	AssetPair AssetPair
+	// Trades not emitted before, in the order returned by Kraken
+	Trades []TradeData
+	// Last is the cursor to resume from
+	Last time.Time
+	// Err is set when the poll failed, the collector tries again at the next poll
+	Err error
+}
+
+// SpreadEvent is emitted by a SpreadCollector after each poll of a pair returning new spreads,
+// or failing
+type SpreadEvent struct {
+	AssetPair AssetPair
+	// Spreads not emitted before, in the order returned by Kraken
+	Spreads []SpreadData
+	// Last is the cursor to resume from
+	Last time.Time
+	// Err is set when the poll failed, the collector tries again at the next poll
+	Err error
+}
+
+// TradeCollector polls RecentTrades for a set of pairs, feeding each `last` cursor back as Since
+type TradeCollector struct {
+	client *Client
+	config CollectorConfig
+	events chan TradeEvent
+}
+
+// NewTradeCollector inits a new TradeCollector, started with Run
+func (c *Client) NewTradeCollector(config CollectorConfig) (*TradeCollector, error) {
+	config, err := config.validate()
+	if err != nil {
+		return nil, err
+	}
+
+	return &TradeCollector{
+		client: c,
+		config: config,
+		events: make(chan TradeEvent, config.Buffer),
+	}, nil
+}
+
+// Events returns the channel of the events, closed when Run returns
+func (tc *TradeCollector) Events() <-chan TradeEvent {
+	return tc.events
+}
+
+// Run polls the pairs until ctx is done, and returns ctx.Err().
+// A request in flight is cancelled as well.
+func (tc *TradeCollector) Run(ctx context.Context) error {
+	defer close(tc.events)
+
+	cursors := newCursors(tc.config.Since)
+	return poll(ctx, tc.config, func(pair AssetPair) bool {
+		cursor := cursors.get(pair)
+
+		results, last, err := tc.client.recentTrades(ctx, RecentTradesConfig{
+			AssetPair: pair,
+			Since:     cursor.since,
+		})
+		if err != nil {
+			// The request was cancelled by ctx
+			if ctx.Err() != nil {
+				return false
+			}
+			return emit(ctx, tc.events, TradeEvent{AssetPair: pair, Last: cursor.since, Err: err})
+		}
+
+		trades := []TradeData{}
+		for _, trade := range results[pair].Data {
+			if cursor.seen(trade.Time, tradeKey(trade)) {
+				continue
+			}
+			trades = append(trades, trade)
+		}
+		if !last.IsZero() {
+			cursor.since = last
+		}
+
+		if len(trades) == 0 {
+			return true
+		}
+		return emit(ctx, tc.events, TradeEvent{AssetPair: pair, Trades: trades, Last: cursor.since})
+	})
+}
+
+// SpreadCollector polls RecentSpreads for a set of pairs, feeding each `last` cursor back as Since
+type SpreadCollector struct {
+	client *Client
+	config CollectorConfig
+	events chan SpreadEvent
+}
+
+// NewSpreadCollector inits a new SpreadCollector, started with Run
+func (c *Client) NewSpreadCollector(config CollectorConfig) (*SpreadCollector, error) {
+	config, err := config.validate()
+	if err != nil {
+		return nil, err
+	}
+
+	return &SpreadCollector{
+		client: c,
+		config: config,
+		events: make(chan SpreadEvent, config.Buffer),
+	}, nil
+}
+
+// Events returns the channel of the events, closed when Run returns
+func (sc *SpreadCollector) Events() <-chan SpreadEvent {
+	return sc.events
+}
+
+// Run polls the pairs until ctx is done, and returns ctx.Err().
+// A request in flight is cancelled as well.
+func (sc *SpreadCollector) Run(ctx context.Context) error {
+	defer close(sc.events)
+
+	cursors := newCursors(sc.config.Since)
+	return poll(ctx, sc.config, func(pair AssetPair) bool {
+		cursor := cursors.get(pair)
+
+		results, last, err := sc.client.recentSpreads(ctx, RecentSpreadsConfig{
+			AssetPair: pair,
+			Since:     cursor.since,
+		})
+		if err != nil {
+			// The request was cancelled by ctx
+			if ctx.Err() != nil {
+				return false
+			}
+			return emit(ctx, sc.events, SpreadEvent{AssetPair: pair, Last: cursor.since, Err: err})
+		}
+
+		spreads := []SpreadData{}
+		for _, spread := range results[pair].Data {
+			key := spread.Bid.String() + "/" + spread.Ask.String()
+			if cursor.seen(spread.Time, key) {
+				continue
+			}
+			spreads = append(spreads, spread)
+		}
+		if !last.IsZero() {
+			cursor.since = last
+		}
+
+		if len(spreads) == 0 {
+			return true
+		}
+		return emit(ctx, sc.events, SpreadEvent{AssetPair: pair, Spreads: spreads, Last: cursor.since})
+	})
+}
+
+// poll calls fn for each pair in turn, every PollInterval, until ctx is done or fn returns false
+func poll(ctx context.Context, config CollectorConfig, fn func(pair AssetPair) bool) error {
+	next := make(map[AssetPair]time.Time)
+
+	for {
+		for _, pair := range config.AssetPairs {
+			if wait := time.Until(next[pair]); wait > 0 {
+				if !sleep(ctx, wait) {
+					return ctx.Err()
+				}
+			}
+			if config.RateLimiter != nil {
+				if err := config.RateLimiter.Wait(ctx, false, 0); err != nil {
+					return err
+				}
+			}
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+
+			next[pair] = time.Now().Add(config.PollInterval)
+			if !fn(pair) {
+				return ctx.Err()
+			}
+		}
+	}
+}
+
+// emit sends the event unless ctx is done, and returns false in this case
+func emit[T any](ctx context.Context, events chan<- T, event T) bool {
+	select {
+	case events <- event:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
+
+func sleep(ctx context.Context, d time.Duration) bool {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
+
+func tradeKey(trade TradeData) string {
+	if trade.TradeID != 0 {
+		return fmt.Sprintf("%d", trade.TradeID)
+	}
+	return fmt.Sprintf("%s/%s/%s/%s", trade.Price, trade.Volume, trade.Type, trade.OrderType)
+}
+
+// cursor is the polling state of a pair
+type cursor struct {
+	since time.Time
+
+	// Entries already emitted at the most recent time, as the next page starts with them again
+	tail     time.Time
+	tailKeys map[string]bool
+}
+
+// seen returns true if the entry is part of the overlapping tail of the previous page
+func (c *cursor) seen(t time.Time, key string) bool {
+	switch {
+	case t.Before(c.tail):
+		return true
+	case t.Equal(c.tail):
+		if c.tailKeys[key] {
+			return true
+		}
+	default:
+		c.tail = t
+		c.tailKeys = make(map[string]bool)
+	}
+	c.tailKeys[key] = true
+	return false
+}
+
+type cursors map[AssetPair]*cursor
+
+func newCursors(since map[AssetPair]time.Time) cursors {
+	cs := cursors{}
+	for pair, t := range since {
+		cs[pair] = &cursor{since: t}
+	}
+	return cs
+}
+
+func (cs cursors) get(pair AssetPair) *cursor {
+	if _, ok := cs[pair]; !ok {
+		cs[pair] = &cursor{}
+	}
+	return cs[pair]
+}
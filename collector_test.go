@@ -0,0 +1,143 @@
+package kraken_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	kraken "github.com/astaluego/golang-kraken"
+	"github.com/astaluego/golang-kraken/krakentest"
+)
+
+func TestCollectorCancelsRequestInFlight(t *testing.T) {
+	server := krakentest.NewServer()
+	defer server.Close()
+	server.Enqueue("Trades", krakentest.Response{Latency: 10 * time.Second})
+
+	collector, err := server.NewClient().NewTradeCollector(kraken.CollectorConfig{
+		AssetPairs: []kraken.AssetPair{kraken.XBT_USD},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() { done <- collector.Run(ctx) }()
+	go func() {
+		for event := range collector.Events() {
+			t.Errorf("unexpected event: %+v", event)
+		}
+	}()
+
+	time.Sleep(100 * time.Millisecond)
+	cancel()
+
+	select {
+	case err := <-done:
+		if !errors.Is(err, context.Canceled) {
+			t.Errorf("expected context.Canceled, got %v", err)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("Run did not return after the context was cancelled")
+	}
+}
+
+func TestTradeCollectorDedupesOverlappingPolls(t *testing.T) {
+	server := krakentest.NewServer()
+	defer server.Close()
+	// Each page starts again with the trades at the `last` cursor of the previous one
+	server.Enqueue("Trades",
+		krakentest.Response{Result: json.RawMessage(`{
+			"XXBTZUSD": [
+				["30000.0", "0.1", 1688671200, "b", "m", "", 1],
+				["30001.0", "0.1", 1688671201, "b", "m", "", 2],
+				["30002.0", "0.1", 1688671201, "s", "l", "", 3]
+			],
+			"last": "1688671201000000000"
+		}`)},
+		krakentest.Response{Result: json.RawMessage(`{
+			"XXBTZUSD": [
+				["30001.0", "0.1", 1688671201, "b", "m", "", 2],
+				["30002.0", "0.1", 1688671201, "s", "l", "", 3],
+				["30003.0", "0.1", 1688671201, "b", "l", "", 4],
+				["30004.0", "0.1", 1688671202, "b", "m", "", 5]
+			],
+			"last": "1688671202000000000"
+		}`)},
+		krakentest.Response{Result: json.RawMessage(`{
+			"XXBTZUSD": [
+				["30004.0", "0.1", 1688671202, "b", "m", "", 5]
+			],
+			"last": "1688671202000000000"
+		}`)},
+		krakentest.Response{Result: json.RawMessage(`{
+			"XXBTZUSD": [
+				["30004.0", "0.1", 1688671202, "b", "m", "", 5],
+				["30005.0", "0.1", 1688671203, "s", "m", "", 6]
+			],
+			"last": "1688671203000000000"
+		}`)},
+	)
+	server.Handle("Trades", krakentest.Response{Result: json.RawMessage(`{"XXBTZUSD": [], "last": "1688671203000000000"}`)})
+
+	collector, err := server.NewClient().NewTradeCollector(kraken.CollectorConfig{
+		AssetPairs:   []kraken.AssetPair{kraken.XBT_USD},
+		PollInterval: time.Millisecond,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	done := make(chan error)
+	go func() { done <- collector.Run(ctx) }()
+
+	ids := []int64{}
+	lasts := []int64{}
+	for len(ids) < 6 {
+		select {
+		case event := <-collector.Events():
+			if event.Err != nil {
+				t.Fatal(event.Err)
+			}
+			for _, trade := range event.Trades {
+				ids = append(ids, trade.TradeID)
+			}
+			lasts = append(lasts, event.Last.Unix())
+		case <-time.After(2 * time.Second):
+			t.Fatalf("expected 6 trades, got %v", ids)
+		}
+	}
+	// Let the collector poll the empty pages
+	time.Sleep(50 * time.Millisecond)
+	cancel()
+	for event := range collector.Events() {
+		if len(event.Trades) > 0 {
+			t.Errorf("unexpected trades: %+v", event.Trades)
+		}
+	}
+	<-done
+
+	if !reflect.DeepEqual(ids, []int64{1, 2, 3, 4, 5, 6}) {
+		t.Errorf("expected each trade once, got %v", ids)
+	}
+	// The third page had no new trade
+	if !reflect.DeepEqual(lasts, []int64{1688671201, 1688671202, 1688671203}) {
+		t.Errorf("unexpected cursors: %v", lasts)
+	}
+
+	calls := server.Calls("Trades")
+	if len(calls) < 5 {
+		t.Fatalf("expected at least 5 polls, got %d", len(calls))
+	}
+	for i, since := range []string{"", "1688671201000000000", "1688671202000000000", "1688671202000000000", "1688671203000000000"} {
+		if got := calls[i].Get("since"); got != since {
+			t.Errorf("poll %d: expected since %q, got %q", i, since, got)
+		}
+	}
+}
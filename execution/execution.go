@@ -0,0 +1,607 @@
+// Package execution slices large parent orders into child orders, so they do not move the
+// market when sent in one shot.
+//
+// Three algorithms are provided: TWAP spreads the volume evenly over a duration, VWAP spreads
+// it following the volume profile of historical candles, and Iceberg shows a limited volume
+// at a time. The children are sent through kraken.Trader, so an execution runs against
+// kraken.Client or paper.Trader alike.
+package execution
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	kraken "github.com/astaluego/golang-kraken"
+	"github.com/shopspring/decimal"
+)
+
+// closedOrdersPage is the number of orders returned by a call to ClosedOrders
+const closedOrdersPage = 50
+
+// Algorithm slicing the parent order
+type Algorithm string
+
+const (
+	TWAP    Algorithm = "twap"
+	VWAP    Algorithm = "vwap"
+	Iceberg Algorithm = "iceberg"
+)
+
+// Status of an execution
+type Status string
+
+const (
+	Running   Status = "running"
+	Paused    Status = "paused"
+	Completed Status = "completed"
+	Canceled  Status = "canceled"
+)
+
+type Config struct {
+	// Algorithm is required
+	Algorithm Algorithm
+
+	// AssetPair is required
+	AssetPair kraken.AssetPair
+
+	// Info is required
+	// Trading rules of the pair, used to round the children
+	Info kraken.AssetPairsInfo
+
+	// Type is required
+	Type kraken.Type
+
+	// Volume is required
+	// Total volume of the parent order, in base currency
+	Volume decimal.Decimal
+
+	// OrderType is optional
+	// Type of the children: limit orders at the best opposite price, or market orders
+	// Iceberg children are always limit orders at LimitPrice
+	// Default: limit
+	OrderType kraken.OrderType
+
+	// LimitPrice is optional, required for Iceberg
+	// Worst price of the children, no child is sent while the market is beyond it
+	LimitPrice decimal.Decimal
+
+	// Start is optional
+	// Default: now
+	Start time.Time
+
+	// Duration is required for TWAP and VWAP
+	Duration time.Duration
+
+	// Slices is optional
+	// Number of children of TWAP and VWAP (more when a slice is not fully executed)
+	// Default: 10
+	Slices int
+
+	// Profile is required for VWAP
+	// Historical candles, typically the result of OHLC, whose volume by time of day
+	// (in UTC) shapes the slices
+	Profile []kraken.OHLCData
+
+	// VisibleVolume is required for Iceberg
+	VisibleVolume decimal.Decimal
+
+	// MaxParticipation is optional
+	// Maximum fraction of the market volume fed by UpdateTrades since Start (e.g. 0.1)
+	MaxParticipation decimal.Decimal
+
+	// Interval is optional
+	// Pause between two steps done by Run
+	// Default: 5s
+	Interval time.Duration
+
+	// OnProgress is optional
+	// Called after each step which changed the progress
+	OnProgress func(progress Progress)
+
+	// Now is optional
+	// Default: time.Now
+	Now func() time.Time
+}
+
+// Child is an order sent by an execution
+type Child struct {
+	TransactionID  string
+	Slice          int
+	Volume         decimal.Decimal
+	Price          decimal.Decimal
+	VolumeExecuted decimal.Decimal
+	Cost           decimal.Decimal
+	Fee            decimal.Decimal
+	Status         kraken.OrderStatus
+	PlacedAt       time.Time
+}
+
+func (c Child) isDone() bool {
+	return c.Status == kraken.Closed || c.Status == kraken.Canceled || c.Status == kraken.Expired
+}
+
+// Progress of an execution, aggregated from its children
+type Progress struct {
+	Status   Status
+	Volume   decimal.Decimal
+	Executed decimal.Decimal
+	// Target is the volume which should be executed by now
+	Target       decimal.Decimal
+	Cost         decimal.Decimal
+	Fee          decimal.Decimal
+	AveragePrice decimal.Decimal
+	Children     []Child
+	UpdatedAt    time.Time
+}
+
+// Remaining returns the volume left to execute
+func (p Progress) Remaining() decimal.Decimal {
+	return p.Volume.Sub(p.Executed)
+}
+
+// Execution is a parent order being executed.
+// It is safe for concurrent use by multiple goroutines.
+type Execution struct {
+	trader kraken.Trader
+	config Config
+	// targets are the cumulative volumes to execute by the end of each slice
+	targets []decimal.Decimal
+
+	mu           sync.Mutex
+	status       Status
+	children     []Child
+	bid, ask     decimal.Decimal
+	marketVolume decimal.Decimal
+	changed      bool
+}
+
+// New inits a new execution, which sends its children once Run or Step is called
+func New(trader kraken.Trader, config Config) (*Execution, error) {
+	if trader == nil {
+		return nil, fmt.Errorf("trader is required")
+	}
+	if config.AssetPair == "" {
+		return nil, fmt.Errorf("AssetPair is required")
+	}
+	if config.Type != kraken.Buy && config.Type != kraken.Sell {
+		return nil, fmt.Errorf("Type is required")
+	}
+	if !config.Volume.IsPositive() {
+		return nil, fmt.Errorf("Volume is required")
+	}
+	if config.OrderType == "" {
+		config.OrderType = kraken.Limit
+	}
+	if config.OrderType != kraken.Limit && config.OrderType != kraken.Market {
+		return nil, fmt.Errorf("OrderType must be limit or market")
+	}
+	if config.Slices == 0 {
+		config.Slices = 10
+	}
+	if config.Interval == 0 {
+		config.Interval = 5 * time.Second
+	}
+	if config.Now == nil {
+		config.Now = time.Now
+	}
+	if config.Start.IsZero() {
+		config.Start = config.Now()
+	}
+
+	e := &Execution{
+		trader: trader,
+		config: config,
+		status: Running,
+	}
+
+	switch config.Algorithm {
+	case TWAP:
+		if config.Duration <= 0 {
+			return nil, fmt.Errorf("Duration is required")
+		}
+		e.targets = cumulate(config.Volume, uniformWeights(config.Slices))
+	case VWAP:
+		if config.Duration <= 0 {
+			return nil, fmt.Errorf("Duration is required")
+		}
+		if len(config.Profile) == 0 {
+			return nil, fmt.Errorf("Profile is required")
+		}
+		e.targets = cumulate(config.Volume, profileWeights(config.Profile, config.Start, config.Duration, config.Slices))
+	case Iceberg:
+		if !config.VisibleVolume.IsPositive() {
+			return nil, fmt.Errorf("VisibleVolume is required")
+		}
+		if !config.LimitPrice.IsPositive() {
+			return nil, fmt.Errorf("LimitPrice is required")
+		}
+		e.config.OrderType = kraken.Limit
+	default:
+		return nil, fmt.Errorf("Algorithm is required")
+	}
+	return e, nil
+}
+
+// UpdateTicker feeds the best prices of the pair, used to price the children
+func (e *Execution) UpdateTicker(ticker kraken.AssetTickerInfo) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	e.bid = ticker.Bid.Price
+	e.ask = ticker.Ask.Price
+}
+
+// UpdateTrades feeds the trades of the market, used to cap the participation
+func (e *Execution) UpdateTrades(trades []kraken.TradeData) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	for _, trade := range trades {
+		if !trade.Time.Before(e.config.Start) {
+			e.marketVolume = e.marketVolume.Add(trade.Volume)
+		}
+	}
+}
+
+// Pause cancels the open child and stops sending new ones until Resume
+func (e *Execution) Pause() error {
+	e.mu.Lock()
+	defer e.unlock()
+
+	if e.status != Running {
+		return nil
+	}
+	if err := e.cancelChildren(); err != nil {
+		return err
+	}
+	e.setStatus(Paused)
+	return nil
+}
+
+// Resume resumes a paused execution
+func (e *Execution) Resume() {
+	e.mu.Lock()
+	defer e.unlock()
+
+	if e.status == Paused {
+		e.setStatus(Running)
+	}
+}
+
+// Cancel cancels the open child and stops the execution
+func (e *Execution) Cancel() error {
+	e.mu.Lock()
+	defer e.unlock()
+
+	if e.status == Completed || e.status == Canceled {
+		return nil
+	}
+	if err := e.cancelChildren(); err != nil {
+		return err
+	}
+	e.setStatus(Canceled)
+	return nil
+}
+
+// Progress returns the progress of the execution
+func (e *Execution) Progress() Progress {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	return e.progress()
+}
+
+// Run steps the execution every Interval until it is completed or canceled, or the context is done
+func (e *Execution) Run(ctx context.Context) error {
+	ticker := time.NewTicker(e.config.Interval)
+	defer ticker.Stop()
+
+	for {
+		if err := e.Step(); err != nil {
+			return err
+		}
+		if status := e.Progress().Status; status == Completed || status == Canceled {
+			return nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+// Step refreshes the children and sends the next one when the schedule requires it
+func (e *Execution) Step() error {
+	e.mu.Lock()
+	defer e.unlock()
+
+	if err := e.refresh(); err != nil {
+		return err
+	}
+	if e.status != Running {
+		return nil
+	}
+
+	now := e.config.Now()
+	slice := e.slice(now)
+
+	// The child of a previous slice is canceled, its remaining volume rolls into the next ones
+	for _, child := range e.children {
+		if child.isDone() {
+			continue
+		}
+		if e.config.Algorithm == Iceberg || child.Slice >= slice {
+			return nil
+		}
+		if err := e.cancelChildren(); err != nil {
+			return err
+		}
+		if err := e.refresh(); err != nil {
+			return err
+		}
+		break
+	}
+	progress := e.progress()
+	for _, child := range progress.Children {
+		if !child.isDone() {
+			return nil
+		}
+	}
+
+	remaining := e.config.Info.RoundVolume(progress.Remaining())
+	if !remaining.IsPositive() || remaining.LessThan(e.config.Info.OrderMin) {
+		e.setStatus(Completed)
+		return nil
+	}
+
+	volume := e.target(now).Sub(progress.Executed)
+	if e.config.Algorithm == Iceberg {
+		volume = decimal.Min(e.config.VisibleVolume, remaining)
+	}
+	if e.config.MaxParticipation.IsPositive() {
+		volume = decimal.Min(volume, e.marketVolume.Mul(e.config.MaxParticipation).Sub(progress.Executed))
+	}
+	volume = e.config.Info.RoundVolume(decimal.Min(volume, remaining))
+	// A remainder below the minimum is sent with the previous slice
+	if rest := remaining.Sub(volume); rest.IsPositive() && rest.LessThan(e.config.Info.OrderMin) && e.target(now).Equal(e.config.Volume) {
+		volume = remaining
+	}
+	if !volume.IsPositive() || volume.LessThan(e.config.Info.OrderMin) {
+		return nil
+	}
+
+	price, ok := e.price()
+	if !ok {
+		return nil
+	}
+	return e.send(slice, volume, price, now)
+}
+
+// slice returns the index of the slice at the given time, or the number of slices after the end
+func (e *Execution) slice(now time.Time) int {
+	if e.config.Algorithm == Iceberg || now.Before(e.config.Start) {
+		return 0
+	}
+	elapsed := now.Sub(e.config.Start)
+	if elapsed >= e.config.Duration {
+		return e.config.Slices
+	}
+	return int(elapsed * time.Duration(e.config.Slices) / e.config.Duration)
+}
+
+// target returns the volume which should be executed by the given time
+func (e *Execution) target(now time.Time) decimal.Decimal {
+	if e.config.Algorithm == Iceberg {
+		return e.config.Volume
+	}
+	if now.Before(e.config.Start) {
+		return decimal.Zero
+	}
+	slice := e.slice(now)
+	if slice >= len(e.targets) {
+		return e.config.Volume
+	}
+	return e.targets[slice]
+}
+
+// price returns the price of the next child (zero for market orders), or false when the
+// market is beyond the limit price
+func (e *Execution) price() (decimal.Decimal, bool) {
+	if e.config.Algorithm == Iceberg {
+		return e.config.LimitPrice, true
+	}
+
+	touch := e.ask
+	if e.config.Type == kraken.Sell {
+		touch = e.bid
+	}
+	limit := e.config.LimitPrice
+	if touch.IsPositive() && limit.IsPositive() {
+		if e.config.Type == kraken.Buy && touch.GreaterThan(limit) {
+			return decimal.Zero, false
+		}
+		if e.config.Type == kraken.Sell && touch.LessThan(limit) {
+			return decimal.Zero, false
+		}
+	}
+
+	if e.config.OrderType == kraken.Market {
+		if !touch.IsPositive() && limit.IsPositive() {
+			// The limit price cannot be checked without market data
+			return decimal.Zero, false
+		}
+		return decimal.Zero, true
+	}
+
+	price := touch
+	if !price.IsPositive() {
+		price = limit
+	}
+	if !price.IsPositive() {
+		return decimal.Zero, false
+	}
+	if e.config.Type == kraken.Buy {
+		return e.config.Info.FloorPrice(price), true
+	}
+	return e.config.Info.CeilPrice(price), true
+}
+
+func (e *Execution) send(slice int, volume, price decimal.Decimal, now time.Time) error {
+	result, err := e.trader.AddOrder(kraken.AddOrderConfig{
+		AssetPair: e.config.AssetPair,
+		Type:      e.config.Type,
+		OrderType: e.config.OrderType,
+		Volume:    volume,
+		Price:     price,
+	})
+	if err != nil {
+		return err
+	}
+	if len(result.TransactionIDs) == 0 {
+		return fmt.Errorf("no transaction ID returned for the child order")
+	}
+
+	e.children = append(e.children, Child{
+		TransactionID: result.TransactionIDs[0],
+		Slice:         slice,
+		Volume:        volume,
+		Price:         price,
+		Status:        kraken.Open,
+		PlacedAt:      now,
+	})
+	e.changed = true
+	return nil
+}
+
+// refresh updates the children not done yet from OpenOrders and ClosedOrders, paged through
+// with Offset until all the children are found
+func (e *Execution) refresh() error {
+	pending := false
+	for _, child := range e.children {
+		if !child.isDone() {
+			pending = true
+		}
+	}
+	if !pending {
+		return nil
+	}
+
+	open, err := e.trader.OpenOrders(kraken.OpenOrdersConfig{})
+	if err != nil {
+		return err
+	}
+
+	// The closed orders are paged through until all the children are found
+	closed := make(map[string]kraken.Order)
+	for {
+		page, err := e.trader.ClosedOrders(kraken.ClosedOrdersConfig{Start: e.children[0].PlacedAt.Add(-time.Minute), Offset: int64(len(closed))})
+		if err != nil {
+			return err
+		}
+		added := 0
+		for txid, order := range page {
+			if _, ok := closed[txid]; !ok {
+				closed[txid] = order
+				added++
+			}
+		}
+
+		if len(page) < closedOrdersPage || added == 0 || !e.missing(open, closed) {
+			break
+		}
+	}
+
+	for i := range e.children {
+		child := &e.children[i]
+		if child.isDone() {
+			continue
+		}
+		order, ok := open[child.TransactionID]
+		if !ok {
+			order, ok = closed[child.TransactionID]
+		}
+		if !ok {
+			continue
+		}
+		if order.Status != child.Status || !order.VolumeExecuted.Equal(child.VolumeExecuted) {
+			e.changed = true
+		}
+		child.Status = order.Status
+		child.VolumeExecuted = order.VolumeExecuted
+		child.Cost = order.Cost
+		child.Fee = order.Fee
+	}
+	return nil
+}
+
+// missing returns true if a child not done yet is neither open nor closed
+func (e *Execution) missing(open, closed map[string]kraken.Order) bool {
+	for _, child := range e.children {
+		if child.isDone() {
+			continue
+		}
+		if _, ok := open[child.TransactionID]; ok {
+			continue
+		}
+		if _, ok := closed[child.TransactionID]; !ok {
+			return true
+		}
+	}
+	return false
+}
+
+// cancelChildren cancels the children not done yet, their fills are known at the next refresh
+func (e *Execution) cancelChildren() error {
+	errs := []error{}
+	for _, child := range e.children {
+		if child.isDone() {
+			continue
+		}
+		_, err := e.trader.CancelOrder(kraken.CancelOrderConfig{TransactionID: child.TransactionID})
+		if err != nil && !kraken.IsUnknownOrder(err) {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func (e *Execution) setStatus(status Status) {
+	e.status = status
+	e.changed = true
+}
+
+func (e *Execution) progress() Progress {
+	progress := Progress{
+		Status:    e.status,
+		Volume:    e.config.Volume,
+		Target:    e.target(e.config.Now()),
+		Children:  append([]Child{}, e.children...),
+		UpdatedAt: e.config.Now(),
+	}
+	for _, child := range e.children {
+		progress.Executed = progress.Executed.Add(child.VolumeExecuted)
+		progress.Cost = progress.Cost.Add(child.Cost)
+		progress.Fee = progress.Fee.Add(child.Fee)
+	}
+	if progress.Executed.IsPositive() {
+		progress.AveragePrice = progress.Cost.Div(progress.Executed)
+	}
+	return progress
+}
+
+// unlock releases the lock, then reports the progress if it changed
+func (e *Execution) unlock() {
+	changed := e.changed
+	e.changed = false
+	progress := e.progress()
+	e.mu.Unlock()
+
+	if changed && e.config.OnProgress != nil {
+		e.config.OnProgress(progress)
+	}
+}
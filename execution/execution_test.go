@@ -0,0 +1,541 @@
+package execution
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	kraken "github.com/astaluego/golang-kraken"
+	"github.com/shopspring/decimal"
+)
+
+var d = decimal.RequireFromString
+
+var start = time.Date(2023, 7, 6, 22, 0, 0, 0, time.UTC)
+
+var xbtUSD = kraken.AssetPairsInfo{
+	PairDecimals: 1,
+	LotDecimals:  8,
+	OrderMin:     d("0.0001"),
+	TickSize:     d("0.1"),
+	Status:       kraken.AssetPairOnline,
+}
+
+// fakeTrader is a Trader whose children are executed by the test. ClosedOrders returns the
+// orders by pages of 50, the most recently closed first, like Kraken.
+type fakeTrader struct {
+	mu       sync.Mutex
+	sequence int
+	orders   map[string]kraken.Order
+	sent     []kraken.AddOrderConfig
+	canceled []string
+	offsets  []int64
+	closedAt time.Time
+}
+
+func newFakeTrader() *fakeTrader {
+	return &fakeTrader{orders: make(map[string]kraken.Order), closedAt: start}
+}
+
+func (f *fakeTrader) AddOrder(config kraken.AddOrderConfig) (*kraken.AddOrderResult, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.sent = append(f.sent, config)
+	f.sequence++
+	txid := fmt.Sprintf("O%d", f.sequence)
+	f.orders[txid] = kraken.Order{Status: kraken.Open, Volume: config.Volume}
+
+	result := &kraken.AddOrderResult{}
+	result.TransactionIDs = []string{txid}
+	return result, nil
+}
+
+func (f *fakeTrader) CancelOrder(config kraken.CancelOrderConfig) (*kraken.CancelOrderResult, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	order, ok := f.orders[config.TransactionID]
+	if !ok || order.Status != kraken.Open {
+		return nil, &kraken.APIError{Errors: []string{"EOrder:Unknown order"}}
+	}
+	f.close(config.TransactionID, order, kraken.Canceled)
+	f.canceled = append(f.canceled, config.TransactionID)
+	return &kraken.CancelOrderResult{Count: 1}, nil
+}
+
+func (f *fakeTrader) OpenOrders(config kraken.OpenOrdersConfig) (map[string]kraken.Order, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	orders := make(map[string]kraken.Order)
+	for txid, order := range f.orders {
+		if order.Status == kraken.Open {
+			orders[txid] = order
+		}
+	}
+	return orders, nil
+}
+
+func (f *fakeTrader) ClosedOrders(config kraken.ClosedOrdersConfig) (map[string]kraken.Order, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.offsets = append(f.offsets, config.Offset)
+	txids := []string{}
+	for txid, order := range f.orders {
+		if order.Status != kraken.Open {
+			txids = append(txids, txid)
+		}
+	}
+	sort.Slice(txids, func(i, j int) bool {
+		return f.orders[txids[i]].ClosedAt.After(f.orders[txids[j]].ClosedAt)
+	})
+
+	orders := make(map[string]kraken.Order)
+	for i := int(config.Offset); i < len(txids) && i < int(config.Offset)+50; i++ {
+		orders[txids[i]] = f.orders[txids[i]]
+	}
+	return orders, nil
+}
+
+func (f *fakeTrader) Balance() (kraken.Balances, error) {
+	return kraken.Balances{}, nil
+}
+
+// execute sets the volume executed of an order at a price with a fee of 0.1%, and closes it
+// when it is fully executed
+func (f *fakeTrader) execute(txid, volume, price string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	order := f.orders[txid]
+	order.VolumeExecuted = d(volume)
+	order.Cost = order.VolumeExecuted.Mul(d(price))
+	order.Fee = order.Cost.Mul(d("0.001"))
+	if order.VolumeExecuted.Equal(order.Volume) {
+		f.close(txid, order, kraken.Closed)
+		return
+	}
+	f.orders[txid] = order
+}
+
+func (f *fakeTrader) close(txid string, order kraken.Order, status kraken.OrderStatus) {
+	f.closedAt = f.closedAt.Add(time.Second)
+	order.Status, order.ClosedAt = status, f.closedAt
+	f.orders[txid] = order
+}
+
+// volumes returns the volumes of the children sent
+func (f *fakeTrader) volumes() string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	volumes := []string{}
+	for _, order := range f.sent {
+		volumes = append(volumes, order.Volume.String())
+	}
+	return strings.Join(volumes, " ")
+}
+
+type clock struct {
+	now time.Time
+}
+
+func (c *clock) Now() time.Time {
+	return c.now
+}
+
+func newExecution(t *testing.T, trader *fakeTrader, config Config) (*Execution, *clock) {
+	t.Helper()
+
+	c := &clock{now: start}
+	config.AssetPair = kraken.XBT_USD
+	config.Info = xbtUSD
+	config.Now = c.Now
+	if config.Type == "" {
+		config.Type = kraken.Buy
+	}
+	e, err := New(trader, config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	e.UpdateTicker(ticker("29990", "30000"))
+	return e, c
+}
+
+func ticker(bid, ask string) kraken.AssetTickerInfo {
+	var ticker kraken.AssetTickerInfo
+	ticker.Bid.Price = d(bid)
+	ticker.Ask.Price = d(ask)
+	return ticker
+}
+
+func step(t *testing.T, e *Execution) {
+	t.Helper()
+
+	if err := e.Step(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestCumulate(t *testing.T) {
+	tests := []struct {
+		name     string
+		weights  []string
+		expected string
+	}{
+		{"uniform", []string{"1", "1", "1", "1"}, "0.25 0.5 0.75 1"},
+		{"weighted", []string{"1", "1", "2"}, "0.25 0.5 1"},
+		// The last target is the whole volume, whatever the rounding
+		{"rounded", []string{"1", "1", "1"}, "0.3333333333333333 0.6666666666666667 1"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			weights := []decimal.Decimal{}
+			for _, weight := range tt.weights {
+				weights = append(weights, d(weight))
+			}
+			if targets := join(cumulate(d("1"), weights)); targets != tt.expected {
+				t.Errorf("expected %s, got %s", tt.expected, targets)
+			}
+		})
+	}
+}
+
+func TestProfileWeights(t *testing.T) {
+	// Hourly candles of the day before, the volume of the hour h being h+1
+	hourly := []kraken.OHLCData{}
+	for hour := 0; hour < 24; hour++ {
+		hourly = append(hourly, kraken.OHLCData{
+			Time:   time.Date(2023, 7, 5, hour, 0, 0, 0, time.UTC),
+			Volume: decimal.NewFromInt(int64(hour + 1)),
+		})
+	}
+
+	tests := []struct {
+		name     string
+		profile  []kraken.OHLCData
+		start    time.Time
+		duration time.Duration
+		slices   int
+		expected string
+	}{
+		{"by hour", hourly, time.Date(2023, 7, 6, 9, 0, 0, 0, time.UTC), 3 * time.Hour, 3, "10 11 12"},
+		{"wrap at midnight", hourly, start, 4 * time.Hour, 4, "23 24 1 2"},
+		{"slices shorter than the candles", hourly, time.Date(2023, 7, 6, 9, 0, 0, 0, time.UTC), time.Hour, 2, "5 5"},
+		{"slices longer than the candles", hourly, time.Date(2023, 7, 6, 9, 0, 0, 0, time.UTC), 4 * time.Hour, 2, "21 25"},
+		{"no volume", []kraken.OHLCData{{Time: start}, {Time: start.Add(time.Hour)}}, start, time.Hour, 2, "1 1"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if weights := join(profileWeights(tt.profile, tt.start, tt.duration, tt.slices)); weights != tt.expected {
+				t.Errorf("expected %s, got %s", tt.expected, weights)
+			}
+		})
+	}
+}
+
+func TestTWAP(t *testing.T) {
+	trader := newFakeTrader()
+	progresses := 0
+	e, clock := newExecution(t, trader, Config{
+		Algorithm:  TWAP,
+		Volume:     d("1"),
+		Duration:   10 * time.Minute,
+		Slices:     5,
+		OnProgress: func(Progress) { progresses++ },
+	})
+
+	step(t, e)
+	step(t, e)
+	if volumes := trader.volumes(); volumes != "0.2" || !trader.sent[0].Price.Equal(d("30000")) || trader.sent[0].OrderType != kraken.Limit {
+		t.Fatalf("expected a single child of 0.2 at 30000, got %+v", trader.sent)
+	}
+
+	// The child of the previous slice is canceled, the volume left rolls into the next one
+	trader.execute("O1", "0.1", "30000")
+	clock.now = start.Add(2 * time.Minute)
+	step(t, e)
+	if volumes := trader.volumes(); volumes != "0.2 0.3" || len(trader.canceled) != 1 {
+		t.Fatalf("expected a child of 0.3, got %s", volumes)
+	}
+	if progress := e.Progress(); !progress.Target.Equal(d("0.4")) || !progress.Executed.Equal(d("0.1")) {
+		t.Errorf("unexpected progress: %+v", progress)
+	}
+
+	// Nothing is sent ahead of the schedule
+	trader.execute("O2", "0.3", "30000")
+	clock.now = start.Add(3 * time.Minute)
+	step(t, e)
+	if volumes := trader.volumes(); volumes != "0.2 0.3" {
+		t.Fatalf("expected no child, got %s", volumes)
+	}
+
+	// Once the duration is over, the rest is sent at once
+	e.UpdateTicker(ticker("30000", "30010.05"))
+	clock.now = start.Add(10 * time.Minute)
+	step(t, e)
+	if volumes := trader.volumes(); volumes != "0.2 0.3 0.6" || !trader.sent[2].Price.Equal(d("30010")) {
+		t.Fatalf("expected a child of 0.6 at 30010, got %+v", trader.sent[2:])
+	}
+	trader.execute("O3", "0.6", "30010")
+	step(t, e)
+
+	progress := e.Progress()
+	if progress.Status != Completed || !progress.Executed.Equal(d("1")) || !progress.Remaining().IsZero() {
+		t.Errorf("expected a completed execution, got %+v", progress)
+	}
+	if !progress.Cost.Equal(d("30006")) || !progress.AveragePrice.Equal(d("30006")) || !progress.Fee.Equal(d("30.006")) || len(progress.Children) != 3 {
+		t.Errorf("unexpected aggregation: %+v", progress)
+	}
+	if progresses == 0 {
+		t.Error("expected the progress to be reported")
+	}
+}
+
+func TestVWAP(t *testing.T) {
+	trader := newFakeTrader()
+	e, clock := newExecution(t, trader, Config{
+		Algorithm: VWAP,
+		Type:      kraken.Sell,
+		OrderType: kraken.Market,
+		Volume:    d("1"),
+		Duration:  2 * time.Hour,
+		Slices:    2,
+		// Three times more volume traded after midnight
+		Profile: []kraken.OHLCData{
+			{Time: time.Date(2023, 7, 5, 22, 0, 0, 0, time.UTC), Volume: d("10")},
+			{Time: time.Date(2023, 7, 5, 23, 0, 0, 0, time.UTC), Volume: d("10")},
+			{Time: time.Date(2023, 7, 6, 0, 0, 0, 0, time.UTC), Volume: d("60")},
+		},
+		Start: start.Add(time.Hour),
+	})
+
+	// Nothing is sent before the start
+	step(t, e)
+	if len(trader.sent) != 0 {
+		t.Fatalf("expected no child, got %+v", trader.sent)
+	}
+
+	clock.now = start.Add(time.Hour)
+	step(t, e)
+	if volumes := trader.volumes(); volumes != "0.14285714" || trader.sent[0].OrderType != kraken.Market || !trader.sent[0].Price.IsZero() {
+		t.Fatalf("expected a market child of 1/7, got %+v", trader.sent)
+	}
+	trader.execute("O1", "0.14285714", "29990")
+
+	clock.now = start.Add(2 * time.Hour)
+	step(t, e)
+	if volumes := trader.volumes(); volumes != "0.14285714 0.85714286" {
+		t.Errorf("expected a child of the rest, got %s", volumes)
+	}
+}
+
+func TestIceberg(t *testing.T) {
+	trader := newFakeTrader()
+	e, clock := newExecution(t, trader, Config{
+		Algorithm:     Iceberg,
+		Volume:        d("1"),
+		VisibleVolume: d("0.4"),
+		LimitPrice:    d("29950"),
+	})
+
+	// The children rest at the limit price, whatever the market and the time
+	step(t, e)
+	clock.now = start.Add(time.Hour)
+	step(t, e)
+	if volumes := trader.volumes(); volumes != "0.4" || !trader.sent[0].Price.Equal(d("29950")) || len(trader.canceled) != 0 {
+		t.Fatalf("expected a single child of 0.4 at 29950, got %+v", trader.sent)
+	}
+
+	// A child is refilled once executed, the last one is what is left
+	trader.execute("O1", "0.4", "29950")
+	step(t, e)
+	trader.execute("O2", "0.4", "29950")
+	step(t, e)
+	if volumes := trader.volumes(); volumes != "0.4 0.4 0.2" {
+		t.Fatalf("unexpected children: %s", volumes)
+	}
+	trader.execute("O3", "0.2", "29950")
+	step(t, e)
+	if progress := e.Progress(); progress.Status != Completed || !progress.AveragePrice.Equal(d("29950")) {
+		t.Errorf("expected a completed execution, got %+v", progress)
+	}
+}
+
+func TestMaxParticipation(t *testing.T) {
+	trader := newFakeTrader()
+	e, clock := newExecution(t, trader, Config{
+		Algorithm:        TWAP,
+		Volume:           d("1"),
+		Duration:         2 * time.Minute,
+		Slices:           2,
+		MaxParticipation: d("0.1"),
+	})
+
+	// The trades before the start are not counted
+	e.UpdateTrades([]kraken.TradeData{
+		{Time: start.Add(-time.Second), Volume: d("100")},
+		{Time: start, Volume: d("2")},
+	})
+	step(t, e)
+	if volumes := trader.volumes(); volumes != "0.2" {
+		t.Fatalf("expected a child capped at 0.2, got %s", volumes)
+	}
+	trader.execute("O1", "0.2", "30000")
+
+	// Capped again until the market trades more
+	clock.now = start.Add(time.Minute)
+	step(t, e)
+	if volumes := trader.volumes(); volumes != "0.2" {
+		t.Fatalf("expected no child, got %s", volumes)
+	}
+	e.UpdateTrades([]kraken.TradeData{{Time: start.Add(time.Minute), Volume: d("1.5")}})
+	step(t, e)
+	if volumes := trader.volumes(); volumes != "0.2 0.15" {
+		t.Errorf("expected a child of 0.15, got %s", volumes)
+	}
+}
+
+func TestLimitPrice(t *testing.T) {
+	tests := []struct {
+		name      string
+		typ       kraken.Type
+		orderType kraken.OrderType
+		ticker    *kraken.AssetTickerInfo
+		price     string
+		sent      bool
+	}{
+		{"buy below the limit", kraken.Buy, kraken.Limit, nil, "30000", true},
+		{"buy beyond the limit", kraken.Buy, kraken.Limit, tickerOf("30050", "30100.5"), "", false},
+		{"sell above the limit", kraken.Sell, kraken.Limit, tickerOf("30050.55", "30100"), "30050.6", true},
+		{"sell beyond the limit", kraken.Sell, kraken.Limit, tickerOf("29000", "29010"), "", false},
+		{"limit without market data", kraken.Buy, kraken.Limit, &kraken.AssetTickerInfo{}, "30050", true},
+		{"market buy below the limit", kraken.Buy, kraken.Market, nil, "0", true},
+		{"market buy without market data", kraken.Buy, kraken.Market, &kraken.AssetTickerInfo{}, "", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			trader := newFakeTrader()
+			e, _ := newExecution(t, trader, Config{
+				Algorithm:  TWAP,
+				Type:       tt.typ,
+				OrderType:  tt.orderType,
+				Volume:     d("1"),
+				Duration:   time.Minute,
+				Slices:     1,
+				LimitPrice: d("30050"),
+			})
+			if tt.ticker != nil {
+				e.UpdateTicker(*tt.ticker)
+			}
+
+			step(t, e)
+			if len(trader.sent) == 1 != tt.sent {
+				t.Fatalf("expected a child sent: %t, got %+v", tt.sent, trader.sent)
+			}
+			if tt.sent && !trader.sent[0].Price.Equal(d(tt.price)) {
+				t.Errorf("expected a child at %s, got %s", tt.price, trader.sent[0].Price)
+			}
+		})
+	}
+}
+
+func TestPauseResumeCancel(t *testing.T) {
+	trader := newFakeTrader()
+	statuses := []Status{}
+	e, clock := newExecution(t, trader, Config{
+		Algorithm:  TWAP,
+		Volume:     d("1"),
+		Duration:   10 * time.Minute,
+		Slices:     5,
+		OnProgress: func(progress Progress) { statuses = append(statuses, progress.Status) },
+	})
+
+	step(t, e)
+	trader.execute("O1", "0.05", "30000")
+	if err := e.Pause(); err != nil {
+		t.Fatal(err)
+	}
+	if len(trader.canceled) != 1 || e.Progress().Status != Paused {
+		t.Fatalf("expected the child to be canceled, got %v", trader.canceled)
+	}
+
+	// Nothing is sent while paused, the fills of the canceled child are still counted
+	clock.now = start.Add(4 * time.Minute)
+	step(t, e)
+	if len(trader.sent) != 1 || !e.Progress().Executed.Equal(d("0.05")) {
+		t.Fatalf("unexpected children: %+v", e.Progress().Children)
+	}
+
+	e.Resume()
+	step(t, e)
+	if volumes := trader.volumes(); volumes != "0.2 0.55" {
+		t.Fatalf("expected a child catching up with the schedule, got %s", volumes)
+	}
+
+	// The child is already closed when canceled: the unknown order is not an error
+	trader.execute("O2", "0.55", "30000")
+	if err := e.Cancel(); err != nil {
+		t.Fatal(err)
+	}
+	if err := e.Cancel(); err != nil {
+		t.Fatal(err)
+	}
+	clock.now = start.Add(10 * time.Minute)
+	step(t, e)
+	if progress := e.Progress(); progress.Status != Canceled || len(trader.sent) != 2 || !progress.Executed.Equal(d("0.6")) {
+		t.Errorf("expected a canceled execution, got %+v", progress)
+	}
+	paused := false
+	for _, status := range statuses {
+		paused = paused || status == Paused
+	}
+	if !paused || statuses[len(statuses)-1] != Canceled {
+		t.Errorf("expected the pause and the cancelation to be reported, got %v", statuses)
+	}
+}
+
+func TestRefreshPaginates(t *testing.T) {
+	trader := newFakeTrader()
+	e, _ := newExecution(t, trader, Config{
+		Algorithm:     Iceberg,
+		Volume:        d("1"),
+		VisibleVolume: d("0.5"),
+		LimitPrice:    d("29950"),
+	})
+	step(t, e)
+	trader.execute("O1", "0.5", "29950")
+
+	// 120 orders closed since then push the child to the third page
+	for i := 0; i < 120; i++ {
+		trader.close(fmt.Sprintf("X%d", i), kraken.Order{}, kraken.Closed)
+	}
+	trader.offsets = nil
+
+	step(t, e)
+	if len(trader.offsets) != 3 || trader.offsets[1] != 50 || trader.offsets[2] != 100 {
+		t.Errorf("expected 3 pages, got the offsets %v", trader.offsets)
+	}
+	if volumes := trader.volumes(); volumes != "0.5 0.5" || !e.Progress().Executed.Equal(d("0.5")) {
+		t.Errorf("expected the child to be refilled, got %s", volumes)
+	}
+}
+
+func tickerOf(bid, ask string) *kraken.AssetTickerInfo {
+	t := ticker(bid, ask)
+	return &t
+}
+
+func join(values []decimal.Decimal) string {
+	parts := []string{}
+	for _, value := range values {
+		parts = append(parts, value.String())
+	}
+	return strings.Join(parts, " ")
+}
@@ -0,0 +1,100 @@
+package execution
+
+import (
+	"sort"
+	"time"
+
+	kraken "github.com/astaluego/golang-kraken"
+	"github.com/shopspring/decimal"
+)
+
+const day = 24 * time.Hour
+
+func uniformWeights(slices int) []decimal.Decimal {
+	weights := make([]decimal.Decimal, slices)
+	for i := range weights {
+		weights[i] = decimal.NewFromInt(1)
+	}
+	return weights
+}
+
+// profileWeights returns the weight of each slice: the historical volume traded at the same
+// time of day, each candle volume being spread evenly over its interval
+func profileWeights(profile []kraken.OHLCData, start time.Time, duration time.Duration, slices int) []decimal.Decimal {
+	interval := candleInterval(profile)
+	length := duration / time.Duration(slices)
+
+	weights := make([]decimal.Decimal, slices)
+	total := decimal.Zero
+	for i := range weights {
+		from := timeOfDay(start.Add(time.Duration(i) * length))
+		for _, candle := range profile {
+			overlap := circularOverlap(from, length, timeOfDay(candle.Time), interval)
+			if overlap > 0 {
+				share := decimal.NewFromInt(int64(overlap)).Div(decimal.NewFromInt(int64(interval)))
+				weights[i] = weights[i].Add(candle.Volume.Mul(share))
+			}
+		}
+		total = total.Add(weights[i])
+	}
+
+	if !total.IsPositive() {
+		return uniformWeights(slices)
+	}
+	return weights
+}
+
+// cumulate returns the cumulative volume to execute by the end of each slice
+func cumulate(volume decimal.Decimal, weights []decimal.Decimal) []decimal.Decimal {
+	total := decimal.Zero
+	for _, weight := range weights {
+		total = total.Add(weight)
+	}
+
+	targets := make([]decimal.Decimal, len(weights))
+	sum := decimal.Zero
+	for i, weight := range weights {
+		sum = sum.Add(weight)
+		targets[i] = volume.Mul(sum).Div(total)
+	}
+	targets[len(targets)-1] = volume
+	return targets
+}
+
+// candleInterval returns the median interval between the candles
+func candleInterval(candles []kraken.OHLCData) time.Duration {
+	intervals := []time.Duration{}
+	for i := 1; i < len(candles); i++ {
+		if interval := candles[i].Time.Sub(candles[i-1].Time); interval > 0 {
+			intervals = append(intervals, interval)
+		}
+	}
+	if len(intervals) == 0 {
+		return time.Minute
+	}
+
+	sort.Slice(intervals, func(i, j int) bool { return intervals[i] < intervals[j] })
+	return intervals[len(intervals)/2]
+}
+
+func timeOfDay(t time.Time) time.Duration {
+	return time.Duration(t.UTC().Unix()%int64(day/time.Second)) * time.Second
+}
+
+// circularOverlap returns the overlap of two ranges of the time of day, which wrap at midnight
+func circularOverlap(from, length, otherFrom, otherLength time.Duration) time.Duration {
+	overlap := time.Duration(0)
+	for shift := -day; shift <= length+day; shift += day {
+		start, end := otherFrom+shift, otherFrom+shift+otherLength
+		if start < from {
+			start = from
+		}
+		if end > from+length {
+			end = from + length
+		}
+		if end > start {
+			overlap += end - start
+		}
+	}
+	return overlap
+}
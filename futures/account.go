@@ -0,0 +1,94 @@
+package futures
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+// Accounts
+// Returns the cash, margin and multi-collateral accounts, keyed by name (e.g. cash, fi_xbtusd, flex)
+// https://docs.futures.kraken.com/#http-api-trading-v3-api-account-information-get-wallets
+func (c *Client) Accounts() (map[string]Account, error) {
+	var response struct {
+		Accounts map[string]Account `json:"accounts"`
+	}
+	err := c.doRequest(http.MethodGet, "accounts", true, nil, &response)
+	return response.Accounts, err
+}
+
+// OpenPositions
+// Returns the open positions
+// https://docs.futures.kraken.com/#http-api-trading-v3-api-account-information-get-open-positions
+func (c *Client) OpenPositions() ([]OpenPosition, error) {
+	var response struct {
+		OpenPositions []OpenPosition `json:"openPositions"`
+	}
+	err := c.doRequest(http.MethodGet, "openpositions", true, nil, &response)
+	return response.OpenPositions, err
+}
+
+type FillsConfig struct {
+	// LastFillTime is optional
+	// Returns the fills before this time
+	// Default: now
+	LastFillTime time.Time
+}
+
+// Fills
+// Returns the last 100 fills
+// https://docs.futures.kraken.com/#http-api-trading-v3-api-historical-data-get-your-fills
+func (c *Client) Fills(config FillsConfig) ([]Fill, error) {
+	data := url.Values{}
+	if !config.LastFillTime.IsZero() {
+		data.Set("lastFillTime", formatTime(config.LastFillTime))
+	}
+
+	var response struct {
+		Fills []Fill `json:"fills"`
+	}
+	err := c.doRequest(http.MethodGet, "fills", true, data, &response)
+	return response.Fills, err
+}
+
+type TransferConfig struct {
+	// FromAccount is required
+	// e.g. cash, fi_xbtusd, flex
+	FromAccount string
+	// ToAccount is required
+	ToAccount string
+	// Unit is required
+	// Currency (e.g. xbt, usd)
+	Unit string
+	// Amount is required
+	Amount decimal.Decimal
+}
+
+// Transfer
+// Transfers funds between two futures accounts
+// https://docs.futures.kraken.com/#http-api-trading-v3-api-transfers-initiate-wallet-transfer
+func (c *Client) Transfer(config TransferConfig) error {
+	if config.FromAccount == "" {
+		return fmt.Errorf("FromAccount is required")
+	}
+	if config.ToAccount == "" {
+		return fmt.Errorf("ToAccount is required")
+	}
+	if config.Unit == "" {
+		return fmt.Errorf("Unit is required")
+	}
+	if !config.Amount.IsPositive() {
+		return fmt.Errorf("Amount is required")
+	}
+
+	data := url.Values{}
+	data.Set("fromAccount", config.FromAccount)
+	data.Set("toAccount", config.ToAccount)
+	data.Set("unit", config.Unit)
+	data.Set("amount", config.Amount.String())
+
+	return c.doRequest(http.MethodPost, "transfer", true, data, nil)
+}
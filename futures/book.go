@@ -0,0 +1,90 @@
+package futures
+
+import (
+	"sort"
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+// book is an order book maintained from a snapshot and its updates
+type book struct {
+	productID string
+	bids      map[string]OrderBookLevel
+	asks      map[string]OrderBookLevel
+	seq       int64
+	timestamp time.Time
+}
+
+type streamLevel struct {
+	Price decimal.Decimal `json:"price"`
+	Qty   decimal.Decimal `json:"qty"`
+}
+
+func newBook(productID string, seq int64, timestamp time.Time, bids, asks []streamLevel) *book {
+	b := &book{
+		productID: productID,
+		bids:      make(map[string]OrderBookLevel),
+		asks:      make(map[string]OrderBookLevel),
+		seq:       seq,
+		timestamp: timestamp,
+	}
+	for _, level := range bids {
+		b.set(b.bids, level)
+	}
+	for _, level := range asks {
+		b.set(b.asks, level)
+	}
+	return b
+}
+
+// update applies an update, and returns false if updates were missed
+func (b *book) update(seq int64, timestamp time.Time, side Side, level streamLevel) bool {
+	if seq != b.seq+1 {
+		return false
+	}
+	b.seq = seq
+	b.timestamp = timestamp
+
+	if side == Sell {
+		b.set(b.asks, level)
+	} else {
+		b.set(b.bids, level)
+	}
+	return true
+}
+
+// set replaces the size of a level, a zero size removes it
+func (b *book) set(levels map[string]OrderBookLevel, level streamLevel) {
+	key := level.Price.String()
+	if level.Qty.IsZero() {
+		delete(levels, key)
+		return
+	}
+	levels[key] = OrderBookLevel{Price: level.Price, Size: level.Qty}
+}
+
+// snapshot returns a copy of the book, best levels first
+func (b *book) snapshot() Book {
+	snapshot := Book{
+		ProductID: b.productID,
+		Bids:      make([]OrderBookLevel, 0, len(b.bids)),
+		Asks:      make([]OrderBookLevel, 0, len(b.asks)),
+		Seq:       b.seq,
+		Timestamp: b.timestamp,
+	}
+	for _, level := range b.bids {
+		snapshot.Bids = append(snapshot.Bids, level)
+	}
+	for _, level := range b.asks {
+		snapshot.Asks = append(snapshot.Asks, level)
+	}
+
+	sort.Slice(snapshot.Bids, func(i, j int) bool {
+		return snapshot.Bids[i].Price.GreaterThan(snapshot.Bids[j].Price)
+	})
+	sort.Slice(snapshot.Asks, func(i, j int) bool {
+		return snapshot.Asks[i].Price.LessThan(snapshot.Asks[j].Price)
+	})
+	return snapshot
+}
@@ -0,0 +1,275 @@
+// Package futures is a client of the Kraken Futures REST API (v3).
+// https://docs.futures.kraken.com/#http-api
+package futures
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	kraken "github.com/astaluego/golang-kraken"
+)
+
+const (
+	// apiURL is the Kraken Futures API Endpoint
+	apiURL = "https://futures.kraken.com"
+
+	// apiPath is the path of the v3 endpoints, the part signed is the one after /derivatives
+	apiPath = "/derivatives"
+)
+
+// Client is safe for concurrent use by multiple goroutines
+type Client struct {
+	httpClient *http.Client
+	baseURL    string
+	userAgent  string
+	apiKey     string
+	apiSecret  string
+
+	lastNonce int64
+
+	middlewares []kraken.Middleware
+}
+
+// Option configures a Client built with New
+type Option func(c *Client)
+
+// WithBaseURL overrides the Kraken Futures API endpoint (e.g. https://demo-futures.kraken.com or a local mock)
+// Default: https://futures.kraken.com
+func WithBaseURL(baseURL string) Option {
+	return func(c *Client) {
+		c.baseURL = strings.TrimRight(baseURL, "/")
+	}
+}
+
+// WithHTTPClient allows to use a custom http.Client (timeouts, proxies, ...)
+// Default: http.DefaultClient
+func WithHTTPClient(httpClient *http.Client) Option {
+	return func(c *Client) {
+		if httpClient == nil {
+			return
+		}
+		c.httpClient = httpClient
+	}
+}
+
+// WithUserAgent sets the User-Agent header sent with every request
+func WithUserAgent(userAgent string) Option {
+	return func(c *Client) {
+		c.userAgent = userAgent
+	}
+}
+
+// WithCredentials sets the API key and secret used to execute private requests
+func WithCredentials(key, secret string) Option {
+	return func(c *Client) {
+		c.apiKey = key
+		c.apiSecret = secret
+	}
+}
+
+// WithMiddlewares adds middlewares around every call made by the Client, the same ones as kraken.Client
+// (e.g. kraken.Logger, kraken.Metrics). The first middleware is the outermost one.
+func WithMiddlewares(middlewares ...kraken.Middleware) Option {
+	return func(c *Client) {
+		c.middlewares = append(c.middlewares, middlewares...)
+	}
+}
+
+// New inits a new Client configured with the given options
+func New(options ...Option) *Client {
+	c := &Client{
+		httpClient: http.DefaultClient,
+		baseURL:    apiURL,
+	}
+
+	for _, option := range options {
+		option(c)
+	}
+
+	return c
+}
+
+// response is the envelope of every response
+type response struct {
+	Result     string          `json:"result"`
+	Error      string          `json:"error"`
+	Errors     []responseError `json:"errors"`
+	ServerTime string          `json:"serverTime"`
+}
+
+type responseError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+// nonce returns a strictly increasing nonce, even when requests are sent concurrently
+// within the same millisecond
+func (c *Client) nonce(now time.Time) int64 {
+	for {
+		last := atomic.LoadInt64(&c.lastNonce)
+		next := now.UnixMilli()
+		if next <= last {
+			next = last + 1
+		}
+		if atomic.CompareAndSwapInt64(&c.lastNonce, last, next) {
+			return next
+		}
+	}
+}
+
+func (c *Client) doRequest(method string, endpoint string, isPrivate bool, data url.Values, respType interface{}) error {
+	if data == nil {
+		data = url.Values{}
+	}
+
+	req, err := c.buildRequest(method, endpoint, isPrivate, data)
+	if err != nil {
+		return err
+	}
+
+	call := &kraken.Call{
+		Endpoint: endpoint,
+		Private:  isPrivate,
+		Request:  req,
+		Form:     data,
+	}
+
+	handler := func(call *kraken.Call) error {
+		resp, err := c.httpClient.Do(call.Request)
+		if err != nil {
+			return fmt.Errorf("failed to make http request: %w", err)
+		}
+		defer resp.Body.Close()
+
+		call.StatusCode = resp.StatusCode
+
+		err = parseResponse(resp, respType)
+		var apiErr *kraken.APIError
+		if errors.As(err, &apiErr) {
+			call.Errors = apiErr.Errors
+		}
+		return err
+	}
+
+	for i := len(c.middlewares) - 1; i >= 0; i-- {
+		handler = c.middlewares[i](handler)
+	}
+
+	return handler(call)
+}
+
+func (c *Client) buildRequest(method string, endpoint string, isPrivate bool, data url.Values) (*http.Request, error) {
+	path := "/api/v3/" + endpoint
+	encoded := data.Encode()
+
+	var (
+		req *http.Request
+		err error
+	)
+	if method == http.MethodGet {
+		URL := c.baseURL + apiPath + path
+		if encoded != "" {
+			URL += "?" + encoded
+		}
+		req, err = http.NewRequest(method, URL, nil)
+	} else {
+		req, err = http.NewRequest(method, c.baseURL+apiPath+path, strings.NewReader(encoded))
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %s", err.Error())
+	}
+	if method != http.MethodGet {
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	}
+	req.Header.Set("Accept", "application/json")
+	if c.userAgent != "" {
+		req.Header.Set("User-Agent", c.userAgent)
+	}
+
+	if !isPrivate {
+		return req, nil
+	}
+
+	if c.apiKey == "" || c.apiSecret == "" {
+		return nil, fmt.Errorf("failed to create private request: key or secret is empty")
+	}
+
+	nonce := fmt.Sprintf("%d", c.nonce(time.Now()))
+	signature, err := Sign(c.apiSecret, path, nonce, encoded)
+	if err != nil {
+		return nil, fmt.Errorf("failed to sign private request: %s", err.Error())
+	}
+	req.Header.Set("APIKey", c.apiKey)
+	req.Header.Set("Nonce", nonce)
+	req.Header.Set("Authent", signature)
+
+	return req, nil
+}
+
+// Sign returns the Authent header of a private request: HMAC-SHA512, keyed with the base64-decoded
+// secret, of the SHA-256 of postData + nonce + path, where path is the endpoint path after
+// /derivatives (e.g. /api/v3/sendorder) and postData the url-encoded query or body
+func Sign(secret string, path string, nonce string, postData string) (string, error) {
+	sha := sha256.Sum256([]byte(postData + nonce + path))
+
+	key, err := base64.StdEncoding.DecodeString(secret)
+	if err != nil {
+		return "", err
+	}
+
+	mac := hmac.New(sha512.New, key)
+	if _, err := mac.Write(sha[:]); err != nil {
+		return "", err
+	}
+	return base64.StdEncoding.EncodeToString(mac.Sum(nil)), nil
+}
+
+func parseResponse(resp *http.Response, respType interface{}) error {
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("failed to read response body: %+v", err)
+	}
+
+	var envelope response
+	if err := json.Unmarshal(body, &envelope); err != nil {
+		if resp.StatusCode != http.StatusOK {
+			return fmt.Errorf("failed to get a successful response. status %d", resp.StatusCode)
+		}
+		return fmt.Errorf("failed to unmarshal response: %w", err)
+	}
+
+	if envelope.Result != "success" {
+		errs := []string{}
+		if envelope.Error != "" {
+			errs = append(errs, envelope.Error)
+		}
+		for _, e := range envelope.Errors {
+			errs = append(errs, fmt.Sprintf("%d:%s", e.Code, e.Message))
+		}
+		if len(errs) > 0 {
+			return &kraken.APIError{Errors: errs}
+		}
+	}
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("failed to get a successful response. status %d", resp.StatusCode)
+	}
+
+	if respType == nil {
+		return nil
+	}
+	if err := json.Unmarshal(body, respType); err != nil {
+		return fmt.Errorf("failed to unmarshal response: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,350 @@
+package futures
+
+import (
+	"bytes"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	kraken "github.com/astaluego/golang-kraken"
+	"github.com/shopspring/decimal"
+)
+
+func TestBuildRequestInvalidURL(t *testing.T) {
+	client := New(WithBaseURL("http://invalid\x7f"))
+
+	for _, method := range []string{http.MethodGet, http.MethodPost} {
+		if _, err := client.buildRequest(method, "sendorder", false, nil); err == nil {
+			t.Errorf("%s: expected an error for an invalid URL", method)
+		}
+	}
+}
+
+func TestBuildRequestContentType(t *testing.T) {
+	client := New()
+
+	req, err := client.buildRequest(http.MethodPost, "sendorder", false, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if req.Header.Get("Content-Type") != "application/x-www-form-urlencoded" {
+		t.Errorf("unexpected Content-Type: %q", req.Header.Get("Content-Type"))
+	}
+
+	req, err = client.buildRequest(http.MethodGet, "tickers", false, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if req.Header.Get("Content-Type") != "" {
+		t.Errorf("unexpected Content-Type on GET: %q", req.Header.Get("Content-Type"))
+	}
+}
+
+func TestSign(t *testing.T) {
+	// Computed independently with Python's hmac and hashlib, the secret is the bytes 0 to 63
+
+	tests := []struct {
+		name     string
+		path     string
+		nonce    string
+		postData string
+		expected string
+	}{
+		{
+			name:     "sendorder",
+			path:     "/api/v3/sendorder",
+			nonce:    "1415957147987",
+			postData: "orderType=lmt&symbol=PI_XBTUSD&side=buy&size=10000&limitPrice=9400",
+			expected: "+coISJqiaL64mS4zGUPbLOLClWrCV3VMC4x4ZStUwSx182y2zMiSvYZ9uvXrLxTkAz0T5Xgr/ZSYxfyNZCA6gQ==",
+		},
+		{
+			name:     "without post data",
+			path:     "/api/v3/accounts",
+			nonce:    "1",
+			expected: "pqgLT4K5p/hAqgylyrGzdhc1ZOfOoKy0pVW5zS2pxiqsWmB9ozHTpCo33PNRfMxV7uVsoxrP41WJJ5ll5Wm/Hg==",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			signature, err := Sign(testSecret, tt.path, tt.nonce, tt.postData)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if signature != tt.expected {
+				t.Errorf("expected %s, got %s", tt.expected, signature)
+			}
+		})
+	}
+
+	if _, err := Sign("not base64!", "/api/v3/accounts", "1", ""); err == nil {
+		t.Error("expected an error for a secret which is not base64")
+	}
+}
+
+const (
+	testKey    = "key"
+	testSecret = "AAECAwQFBgcICQoLDA0ODxAREhMUFRYXGBkaGxwdHh8gISIjJCUmJygpKissLS4vMDEyMzQ1Njc4OTo7PD0+Pw=="
+)
+
+// newTestServer answers the endpoints of responses (e.g. /api/v3/tickers) with their JSON body,
+// and rejects the private requests with an invalid signature the way Kraken Futures does
+func newTestServer(t *testing.T, responses map[string]string) (*Client, *[]*http.Request) {
+	t.Helper()
+	requests := []*http.Request{}
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, err := io.ReadAll(r.Body)
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		r.Body = io.NopCloser(bytes.NewReader(body))
+		requests = append(requests, r)
+
+		path := strings.TrimPrefix(r.URL.Path, apiPath)
+		response, ok := responses[path]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+
+		if r.Header.Get("APIKey") != "" {
+			postData := string(body)
+			if r.Method == http.MethodGet {
+				postData = r.URL.RawQuery
+			}
+			expected, err := Sign(testSecret, path, r.Header.Get("Nonce"), postData)
+			if err != nil || r.Header.Get("APIKey") != testKey || r.Header.Get("Authent") != expected {
+				fmt.Fprint(w, `{"result": "error", "error": "authenticationError", "serverTime": "2023-07-06T10:00:00.000Z"}`)
+				return
+			}
+		}
+		fmt.Fprint(w, response)
+	}))
+	t.Cleanup(server.Close)
+
+	return New(WithBaseURL(server.URL), WithCredentials(testKey, testSecret)), &requests
+}
+
+func TestAuthenticatedRoundTrip(t *testing.T) {
+	client, requests := newTestServer(t, map[string]string{
+		"/api/v3/sendorder": `{
+			"result": "success",
+			"sendStatus": {
+				"order_id": "179f9af8-e45e-469d-b3e9-2fd4675cb7d0",
+				"status": "placed",
+				"receivedTime": "2023-07-06T10:00:00.123Z",
+				"cliOrdId": "my-order",
+				"orderEvents": [{
+					"type": "PLACE",
+					"order": {
+						"orderId": "179f9af8-e45e-469d-b3e9-2fd4675cb7d0",
+						"cliOrdId": "my-order",
+						"type": "lmt",
+						"symbol": "PF_XBTUSD",
+						"side": "buy",
+						"quantity": 0.01,
+						"filled": 0,
+						"limitPrice": 30000.5,
+						"reduceOnly": false,
+						"timestamp": "2023-07-06T10:00:00.123Z",
+						"lastUpdateTimestamp": "2023-07-06T10:00:00.123Z"
+					}
+				}]
+			},
+			"serverTime": "2023-07-06T10:00:00.124Z"
+		}`,
+		"/api/v3/fills": `{
+			"result": "success",
+			"fills": [{
+				"fill_id": "3d57ed09-fbd6-44f1-8e8b-b10e551c5e73",
+				"symbol": "PF_XBTUSD",
+				"side": "buy",
+				"order_id": "179f9af8-e45e-469d-b3e9-2fd4675cb7d0",
+				"cliOrdId": "my-order",
+				"size": 0.01,
+				"price": 30000.5,
+				"fillTime": "2023-07-06T10:00:01.000Z",
+				"fillType": "maker"
+			}],
+			"serverTime": "2023-07-06T10:00:02.000Z"
+		}`,
+	})
+
+	status, err := client.SendOrder(SendOrderConfig{
+		OrderType:     Limit,
+		Symbol:        "PF_XBTUSD",
+		Side:          Buy,
+		Size:          decimal.RequireFromString("0.01"),
+		LimitPrice:    decimal.RequireFromString("30000.5"),
+		ClientOrderID: "my-order",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if status.Status != "placed" || status.ClientOrderID != "my-order" || len(status.OrderEvents) != 1 {
+		t.Fatalf("unexpected status: %+v", status)
+	}
+	order := status.OrderEvents[0].Order
+	if order == nil || order.Side != Buy || order.LimitPrice.String() != "30000.5" || !order.Timestamp.Equal(time.Date(2023, 7, 6, 10, 0, 0, 123e6, time.UTC)) {
+		t.Errorf("unexpected order: %+v", order)
+	}
+
+	// Signed over the query of a GET request
+	fills, err := client.Fills(FillsConfig{LastFillTime: time.Date(2023, 7, 6, 12, 0, 0, 0, time.UTC)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(fills) != 1 || fills[0].Size.String() != "0.01" || fills[0].FillType != "maker" {
+		t.Errorf("unexpected fills: %+v", fills)
+	}
+
+	if len(*requests) != 2 {
+		t.Fatalf("expected 2 requests, got %d", len(*requests))
+	}
+	sent := (*requests)[0]
+	if sent.Method != http.MethodPost || sent.URL.Path != "/derivatives/api/v3/sendorder" {
+		t.Errorf("unexpected request: %s %s", sent.Method, sent.URL.Path)
+	}
+	if err := sent.ParseForm(); err != nil {
+		t.Fatal(err)
+	}
+	if sent.PostForm.Get("limitPrice") != "30000.5" || sent.PostForm.Get("cliOrdId") != "my-order" || sent.PostForm.Get("orderType") != "lmt" {
+		t.Errorf("unexpected form: %v", sent.PostForm)
+	}
+	if query := (*requests)[1].URL.Query().Get("lastFillTime"); query != "2023-07-06T12:00:00.000Z" {
+		t.Errorf("unexpected lastFillTime: %q", query)
+	}
+
+	// The nonces are strictly increasing
+	first, _ := strconv.ParseInt((*requests)[0].Header.Get("Nonce"), 10, 64)
+	second, _ := strconv.ParseInt((*requests)[1].Header.Get("Nonce"), 10, 64)
+	if second <= first {
+		t.Errorf("expected increasing nonces, got %d then %d", first, second)
+	}
+}
+
+func TestAuthenticationError(t *testing.T) {
+	client, _ := newTestServer(t, map[string]string{"/api/v3/accounts": `{"result": "success", "accounts": {}}`})
+	client.apiSecret = base64.StdEncoding.EncodeToString([]byte("another secret"))
+
+	_, err := client.Accounts()
+	var apiErr *kraken.APIError
+	if !errors.As(err, &apiErr) || len(apiErr.Errors) != 1 || apiErr.Errors[0] != "authenticationError" {
+		t.Errorf("expected an authenticationError, got %v", err)
+	}
+
+	if _, err := New().Accounts(); err == nil || !strings.Contains(err.Error(), "key or secret is empty") {
+		t.Errorf("expected an error without credentials, got %v", err)
+	}
+}
+
+func TestDecodeResponses(t *testing.T) {
+	client, requests := newTestServer(t, map[string]string{
+		"/api/v3/instruments": `{"result": "success", "instruments": [{
+			"symbol": "PF_XBTUSD", "type": "flexible_futures", "tickSize": 0.5, "contractSize": 1, "tradeable": true,
+			"impactMidSize": 1, "maxPositionSize": 1000000, "openingDate": "2022-01-01T00:00:00.000Z",
+			"marginLevels": [{"numNonContractUnits": 0, "initialMargin": 0.02, "maintenanceMargin": 0.01}],
+			"fundingRateCoefficient": 8, "maxRelativeFundingRate": 0.001, "contractValueTradePrecision": 4,
+			"postOnly": false, "category": "Layer 1", "tags": ["perpetual"]
+		}]}`,
+		"/api/v3/tickers": `{"result": "success", "tickers": [{
+			"symbol": "PF_XBTUSD", "last": 30000.5, "lastTime": "2023-07-06T10:00:00.000Z", "lastSize": 0.1,
+			"tag": "perpetual", "pair": "XBT:USD", "markPrice": 30001, "bid": 30000, "bidSize": 1.5,
+			"ask": 30001, "askSize": 2, "vol24h": 1234.5, "fundingRate": -0.00012, "suspended": false
+		}]}`,
+		"/api/v3/orderbook": `{"result": "success", "orderBook": {
+			"bids": [[30000, 1.5], [29999.5, 2]],
+			"asks": [[30001, 2]]
+		}}`,
+		"/api/v3/accounts": `{"result": "success", "accounts": {
+			"cash": {"type": "cashAccount", "balances": {"xbt": 0.5, "usd": 1000}},
+			"flex": {
+				"type": "multiCollateralMarginAccount",
+				"currencies": {"USD": {"quantity": 1000, "value": 1000, "collateral": 1000, "available": 900}},
+				"initialMargin": 100, "maintenanceMargin": 50, "portfolioValue": 1000, "availableMargin": 900
+			}
+		}}`,
+		"/api/v3/batchorder": `{"result": "success", "batchStatus": [
+			{"order_tag": "1", "order_id": "a", "status": "placed", "dateTimeReceived": "2023-07-06T10:00:00.000Z"},
+			{"order_id": "b", "status": "cancelled"}
+		]}`,
+		"/api/v3/cancelorder": `{"result": "error", "error": "apiLimitExceeded"}`,
+		"/api/v3/editorder":   `{"result": "error", "errors": [{"code": 11, "message": "invalid argument"}]}`,
+	})
+
+	instruments, err := client.Instruments()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(instruments) != 1 || instruments[0].TickSize.String() != "0.5" || instruments[0].MarginLevels[0].InitialMargin.String() != "0.02" ||
+		instruments[0].ContractValueTradePrecision != 4 || !instruments[0].OpeningDate.Equal(time.Date(2022, 1, 1, 0, 0, 0, 0, time.UTC)) {
+		t.Errorf("unexpected instruments: %+v", instruments)
+	}
+
+	tickers, err := client.Tickers()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(tickers) != 1 || tickers[0].FundingRate.String() != "-0.00012" || tickers[0].BidSize.String() != "1.5" || tickers[0].Pair != "XBT:USD" {
+		t.Errorf("unexpected tickers: %+v", tickers)
+	}
+
+	book, err := client.OrderBook(OrderBookConfig{Symbol: "PF_XBTUSD"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(book.Bids) != 2 || book.Bids[1].Price.String() != "29999.5" || book.Bids[1].Size.String() != "2" || len(book.Asks) != 1 {
+		t.Errorf("unexpected order book: %+v", book)
+	}
+	if symbol := (*requests)[len(*requests)-1].URL.Query().Get("symbol"); symbol != "PF_XBTUSD" {
+		t.Errorf("unexpected symbol: %q", symbol)
+	}
+	if _, err := client.OrderBook(OrderBookConfig{}); err == nil {
+		t.Error("expected an error without Symbol")
+	}
+
+	accounts, err := client.Accounts()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if accounts["cash"].Balances["xbt"].String() != "0.5" || accounts["flex"].Currencies["USD"].Available.String() != "900" || accounts["flex"].AvailableMargin.String() != "900" {
+		t.Errorf("unexpected accounts: %+v", accounts)
+	}
+
+	statuses, err := client.BatchOrder(BatchOrderConfig{
+		Send: []BatchSend{{Tag: "1", Order: SendOrderConfig{
+			OrderType: Limit, Symbol: "PF_XBTUSD", Side: Sell, Size: decimal.NewFromInt(1), LimitPrice: decimal.NewFromInt(31000),
+		}}},
+		Cancel: []CancelOrderConfig{{OrderID: "b"}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(statuses) != 2 || statuses[0].OrderTag != "1" || statuses[1].Status != "cancelled" {
+		t.Errorf("unexpected statuses: %+v", statuses)
+	}
+	batch := (*requests)[len(*requests)-1]
+	if err := batch.ParseForm(); err != nil {
+		t.Fatal(err)
+	}
+	expectedBatch := `{"batchOrder":[{"limitPrice":31000,"order":"send","orderType":"lmt","order_tag":"1","side":"sell","size":1,"symbol":"PF_XBTUSD"},{"order":"cancel","order_id":"b"}]}`
+	if batch.PostForm.Get("json") != expectedBatch {
+		t.Errorf("unexpected batch: %s", batch.PostForm.Get("json"))
+	}
+
+	var apiErr *kraken.APIError
+	if _, err := client.CancelOrder(CancelOrderConfig{OrderID: "a"}); !errors.As(err, &apiErr) || apiErr.Errors[0] != "apiLimitExceeded" {
+		t.Errorf("expected apiLimitExceeded, got %v", err)
+	}
+	if _, err := client.EditOrder(EditOrderConfig{OrderID: "a", Size: decimal.NewFromInt(2)}); !errors.As(err, &apiErr) || apiErr.Errors[0] != "11:invalid argument" {
+		t.Errorf("expected the error code and message, got %v", err)
+	}
+}
@@ -0,0 +1,219 @@
+// Package futurestest provides an in-process fake Kraken Futures WebSocket server, so code using
+// futures.Stream can be tested without connecting to futures.kraken.com.
+package futurestest
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+
+	"github.com/astaluego/golang-kraken/futures"
+	"github.com/gorilla/websocket"
+)
+
+// Key is the API key accepted by the server
+const Key = "futurestest-key"
+
+// Request is a request received by the server
+type Request struct {
+	Event             string   `json:"event"`
+	Feed              string   `json:"feed"`
+	ProductIDs        []string `json:"product_ids"`
+	APIKey            string   `json:"api_key"`
+	OriginalChallenge string   `json:"original_challenge"`
+	SignedChallenge   string   `json:"signed_challenge"`
+}
+
+// SubscribeFunc returns the messages sent after a subscription (e.g. a book snapshot),
+// for each product id, or once with an empty product id for the private feeds
+type SubscribeFunc func(productID string) []interface{}
+
+// StreamServer is a fake Kraken Futures WebSocket server. The private feeds are authenticated
+// exactly like Kraken does, with a challenge signed with the secret of the server.
+type StreamServer struct {
+	server   *httptest.Server
+	secret   string
+	upgrader websocket.Upgrader
+
+	mu         sync.Mutex
+	conns      map[*websocket.Conn]bool
+	challenges map[string]bool
+	handlers   map[futures.Feed]SubscribeFunc
+	requests   []Request
+}
+
+// NewStreamServer starts a new fake server
+func NewStreamServer() *StreamServer {
+	s := &StreamServer{
+		secret:     base64.StdEncoding.EncodeToString(random(64)),
+		conns:      make(map[*websocket.Conn]bool),
+		challenges: make(map[string]bool),
+		handlers:   make(map[futures.Feed]SubscribeFunc),
+	}
+	s.server = httptest.NewServer(http.HandlerFunc(s.serveHTTP))
+	return s
+}
+
+// URL returns the WebSocket URL of the server
+func (s *StreamServer) URL() string {
+	return "ws" + strings.TrimPrefix(s.server.URL, "http")
+}
+
+// Secret returns the API secret matching Key
+func (s *StreamServer) Secret() string {
+	return s.secret
+}
+
+// Close closes the connections and stops the server
+func (s *StreamServer) Close() {
+	s.Disconnect()
+	s.server.Close()
+}
+
+// NewStream returns a futures.Stream connected to this server, with valid credentials
+func (s *StreamServer) NewStream(config futures.StreamConfig) *futures.Stream {
+	config.URL = s.URL()
+	config.APIKey = Key
+	config.APISecret = s.secret
+	return futures.NewStream(config)
+}
+
+// HandleSubscribe scripts the messages sent after each subscription to a feed
+func (s *StreamServer) HandleSubscribe(feed futures.Feed, handler SubscribeFunc) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.handlers[feed] = handler
+}
+
+// Send sends a message to every connected client
+func (s *StreamServer) Send(message interface{}) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for conn := range s.conns {
+		if err := conn.WriteJSON(message); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Disconnect closes the connections, e.g. to test the reconnection of a client
+func (s *StreamServer) Disconnect() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for conn := range s.conns {
+		conn.Close()
+		delete(s.conns, conn)
+	}
+}
+
+// Connections returns the number of connected clients
+func (s *StreamServer) Connections() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return len(s.conns)
+}
+
+// Requests returns the requests received so far
+func (s *StreamServer) Requests() []Request {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return append([]Request{}, s.requests...)
+}
+
+func (s *StreamServer) serveHTTP(w http.ResponseWriter, r *http.Request) {
+	conn, err := s.upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		return
+	}
+
+	s.mu.Lock()
+	s.conns[conn] = true
+	conn.WriteJSON(map[string]interface{}{"event": "info", "version": 1})
+	s.mu.Unlock()
+
+	defer func() {
+		s.mu.Lock()
+		delete(s.conns, conn)
+		s.mu.Unlock()
+		conn.Close()
+	}()
+
+	for {
+		var request Request
+		if err := conn.ReadJSON(&request); err != nil {
+			return
+		}
+
+		s.mu.Lock()
+		s.requests = append(s.requests, request)
+		replies := s.reply(request)
+		for _, reply := range replies {
+			conn.WriteJSON(reply)
+		}
+		s.mu.Unlock()
+	}
+}
+
+// reply returns the messages answering a request. s.mu must be held.
+func (s *StreamServer) reply(request Request) []interface{} {
+	feed := futures.Feed(request.Feed)
+
+	switch request.Event {
+	case "challenge":
+		if request.APIKey != Key {
+			return []interface{}{alert("Invalid API key")}
+		}
+		challenge := hex.EncodeToString(random(16))
+		s.challenges[challenge] = true
+		return []interface{}{map[string]interface{}{"event": "challenge", "message": challenge}}
+
+	case "subscribe":
+		if feed.IsPrivate() {
+			expected, err := futures.SignChallenge(s.secret, request.OriginalChallenge)
+			if request.APIKey != Key || !s.challenges[request.OriginalChallenge] || err != nil || expected != request.SignedChallenge {
+				return []interface{}{alert("Failed to subscribe to authenticated feed")}
+			}
+		}
+
+		replies := []interface{}{map[string]interface{}{"event": "subscribed", "feed": request.Feed, "product_ids": request.ProductIDs}}
+		handler, ok := s.handlers[feed]
+		if !ok {
+			return replies
+		}
+		if feed.IsPrivate() {
+			return append(replies, handler("")...)
+		}
+		for _, productID := range request.ProductIDs {
+			replies = append(replies, handler(productID)...)
+		}
+		return replies
+
+	case "unsubscribe":
+		return []interface{}{map[string]interface{}{"event": "unsubscribed", "feed": request.Feed, "product_ids": request.ProductIDs}}
+	}
+
+	return []interface{}{map[string]interface{}{"event": "error", "message": fmt.Sprintf("Unknown event %q", request.Event)}}
+}
+
+func alert(message string) map[string]interface{} {
+	return map[string]interface{}{"event": "alert", "message": message}
+}
+
+func random(n int) []byte {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		panic(fmt.Sprintf("futurestest: failed to generate random bytes: %s", err.Error()))
+	}
+	return b
+}
@@ -0,0 +1,91 @@
+package futures
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+// Instruments
+// Returns the specifications of all the instruments
+// https://docs.futures.kraken.com/#http-api-trading-v3-api-instrument-details-get-instruments
+func (c *Client) Instruments() ([]Instrument, error) {
+	var response struct {
+		Instruments []Instrument `json:"instruments"`
+	}
+	err := c.doRequest(http.MethodGet, "instruments", false, nil, &response)
+	return response.Instruments, err
+}
+
+// Tickers
+// Returns the market data of all the instruments
+// https://docs.futures.kraken.com/#http-api-trading-v3-api-market-data-get-tickers
+func (c *Client) Tickers() ([]Ticker, error) {
+	var response struct {
+		Tickers []Ticker `json:"tickers"`
+	}
+	err := c.doRequest(http.MethodGet, "tickers", false, nil, &response)
+	return response.Tickers, err
+}
+
+type OrderBookConfig struct {
+	// Symbol is required
+	Symbol string
+}
+
+// OrderBook
+// Returns the full non-cumulative order book of an instrument
+// https://docs.futures.kraken.com/#http-api-trading-v3-api-market-data-get-orderbook
+func (c *Client) OrderBook(config OrderBookConfig) (*OrderBook, error) {
+	if config.Symbol == "" {
+		return nil, fmt.Errorf("Symbol is required")
+	}
+
+	data := url.Values{}
+	data.Set("symbol", config.Symbol)
+
+	var response struct {
+		OrderBook OrderBook `json:"orderBook"`
+	}
+	err := c.doRequest(http.MethodGet, "orderbook", false, data, &response)
+	if err != nil {
+		return nil, err
+	}
+	return &response.OrderBook, nil
+}
+
+type HistoryConfig struct {
+	// Symbol is required
+	Symbol string
+	// LastTime is optional
+	// Returns the trades before this time
+	// Default: now
+	LastTime time.Time
+}
+
+// History
+// Returns the last 100 trades of an instrument
+// https://docs.futures.kraken.com/#http-api-trading-v3-api-market-data-get-trade-history
+func (c *Client) History(config HistoryConfig) ([]Trade, error) {
+	if config.Symbol == "" {
+		return nil, fmt.Errorf("Symbol is required")
+	}
+
+	data := url.Values{}
+	data.Set("symbol", config.Symbol)
+	if !config.LastTime.IsZero() {
+		data.Set("lastTime", formatTime(config.LastTime))
+	}
+
+	var response struct {
+		History []Trade `json:"history"`
+	}
+	err := c.doRequest(http.MethodGet, "history", false, data, &response)
+	return response.History, err
+}
+
+// formatTime formats a time the way the API expects it (ISO 8601, UTC, milliseconds)
+func formatTime(t time.Time) string {
+	return t.UTC().Format("2006-01-02T15:04:05.000Z")
+}
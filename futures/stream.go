@@ -0,0 +1,504 @@
+package futures
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+const streamURL = "wss://futures.kraken.com/ws/v1"
+
+type StreamConfig struct {
+	// URL is optional
+	// Default: wss://futures.kraken.com/ws/v1
+	URL string
+
+	// APIKey is optional
+	// Required for the private feeds
+	APIKey string
+	// APISecret is optional
+	// Required for the private feeds
+	APISecret string
+
+	// ReconnectDelay is optional
+	// Pause before reconnecting, doubled after each failure up to 30s
+	// Default: 1s
+	ReconnectDelay time.Duration
+
+	// PingInterval is optional
+	// The server closes the connections idle for 60s
+	// Default: 30s
+	PingInterval time.Duration
+
+	// Dialer is optional
+	// Default: websocket.DefaultDialer
+	Dialer *websocket.Dialer
+
+	// Buffer is optional
+	// Size of the events channel
+	// Default: 0 (unbuffered)
+	Buffer int
+}
+
+// Stream is a client of the Kraken Futures WebSocket API. It subscribes again to the feeds
+// after each reconnection, and maintains the order books of the book feed.
+// https://docs.futures.kraken.com/#websocket-api
+type Stream struct {
+	config StreamConfig
+	events chan Event
+
+	mu            sync.Mutex
+	subscriptions map[Feed]map[string]bool
+	conn          *websocket.Conn
+	// challenge and its signature, once received for the current connection
+	challenge          string
+	signedChallenge    string
+	challengeRequested bool
+	books              map[string]*book
+}
+
+// NewStream inits a new Stream, started with Run
+func NewStream(config StreamConfig) *Stream {
+	if config.URL == "" {
+		config.URL = streamURL
+	}
+	if config.ReconnectDelay == 0 {
+		config.ReconnectDelay = time.Second
+	}
+	if config.PingInterval == 0 {
+		config.PingInterval = 30 * time.Second
+	}
+	if config.Dialer == nil {
+		config.Dialer = websocket.DefaultDialer
+	}
+
+	return &Stream{
+		config:        config,
+		events:        make(chan Event, config.Buffer),
+		subscriptions: make(map[Feed]map[string]bool),
+		books:         make(map[string]*book),
+	}
+}
+
+// Events returns the channel of the events, closed when Run returns
+func (s *Stream) Events() <-chan Event {
+	return s.events
+}
+
+// Subscribe subscribes to a feed, now if the stream is connected, and after each reconnection.
+// The public feeds require product ids, the private ones none.
+func (s *Stream) Subscribe(feed Feed, productIDs ...string) error {
+	if feed.IsPrivate() && (s.config.APIKey == "" || s.config.APISecret == "") {
+		return fmt.Errorf("APIKey and APISecret are required for the %s feed", feed)
+	}
+	if !feed.IsPrivate() && len(productIDs) == 0 {
+		return fmt.Errorf("productIDs are required for the %s feed", feed)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.subscriptions[feed] == nil {
+		s.subscriptions[feed] = make(map[string]bool)
+	}
+	for _, productID := range productIDs {
+		s.subscriptions[feed][productID] = true
+	}
+
+	if s.conn == nil {
+		return nil
+	}
+	if feed.IsPrivate() && s.challenge == "" {
+		// Sent once the challenge is received
+		return s.requestChallenge()
+	}
+	return s.writeSubscription("subscribe", feed, productIDs)
+}
+
+// Unsubscribe unsubscribes from a feed
+func (s *Stream) Unsubscribe(feed Feed, productIDs ...string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if feed.IsPrivate() || len(productIDs) == 0 {
+		delete(s.subscriptions, feed)
+	} else {
+		for _, productID := range productIDs {
+			delete(s.subscriptions[feed], productID)
+		}
+	}
+	if feed == FeedBook {
+		for _, productID := range productIDs {
+			delete(s.books, productID)
+		}
+	}
+
+	if s.conn == nil || (feed.IsPrivate() && s.challenge == "") {
+		return nil
+	}
+	return s.writeSubscription("unsubscribe", feed, productIDs)
+}
+
+// Book returns a copy of the order book of a product, best levels first
+func (s *Stream) Book(productID string) (Book, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	b, ok := s.books[productID]
+	if !ok {
+		return Book{}, false
+	}
+	return b.snapshot(), true
+}
+
+// Run connects to the server and reconnects until ctx is done, and returns ctx.Err()
+func (s *Stream) Run(ctx context.Context) error {
+	defer close(s.events)
+
+	delay := s.config.ReconnectDelay
+	for {
+		conn, _, err := s.config.Dialer.DialContext(ctx, s.config.URL, nil)
+		if err == nil {
+			delay = s.config.ReconnectDelay
+			if !s.emit(ctx, &ConnectionEvent{Connected: true}) {
+				conn.Close()
+				return ctx.Err()
+			}
+			err = s.session(ctx, conn)
+		}
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		if !s.emit(ctx, &ConnectionEvent{Connected: false, Err: err}) {
+			return ctx.Err()
+		}
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		case <-timer.C:
+		}
+		if delay *= 2; delay > 30*time.Second {
+			delay = 30 * time.Second
+		}
+	}
+}
+
+// session reads the messages of a connection until it fails or ctx is done
+func (s *Stream) session(ctx context.Context, conn *websocket.Conn) error {
+	s.mu.Lock()
+	s.conn = conn
+	s.challenge, s.signedChallenge, s.challengeRequested = "", "", false
+	s.books = make(map[string]*book)
+	err := s.writeSubscriptions(false)
+	if err == nil && s.hasPrivateSubscriptions() {
+		err = s.requestChallenge()
+	}
+	s.mu.Unlock()
+
+	defer func() {
+		s.mu.Lock()
+		s.conn = nil
+		s.mu.Unlock()
+		conn.Close()
+	}()
+	if err != nil {
+		return err
+	}
+
+	// Closing the connection stops ReadMessage
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		ticker := time.NewTicker(s.config.PingInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-done:
+				return
+			case <-ctx.Done():
+				conn.Close()
+				return
+			case <-ticker.C:
+				s.mu.Lock()
+				err := conn.WriteControl(websocket.PingMessage, nil, time.Now().Add(10*time.Second))
+				s.mu.Unlock()
+				if err != nil {
+					conn.Close()
+					return
+				}
+			}
+		}
+	}()
+
+	for {
+		_, message, err := conn.ReadMessage()
+		if err != nil {
+			return err
+		}
+		if !s.handle(ctx, message) {
+			return ctx.Err()
+		}
+	}
+}
+
+// message contains the fields of every message needed to decode it
+type message struct {
+	Event     string        `json:"event"`
+	Feed      string        `json:"feed"`
+	Message   string        `json:"message"`
+	ProductID string        `json:"product_id"`
+	Seq       int64         `json:"seq"`
+	Timestamp int64         `json:"timestamp"`
+	Side      Side          `json:"side"`
+	Bids      []streamLevel `json:"bids"`
+	Asks      []streamLevel `json:"asks"`
+	Trades    []StreamTrade `json:"trades"`
+	Fills     []StreamFill  `json:"fills"`
+	Orders    []StreamOrder `json:"orders"`
+	Order     *StreamOrder  `json:"order"`
+	OrderID   string        `json:"order_id"`
+	IsCancel  bool          `json:"is_cancel"`
+	Reason    string        `json:"reason"`
+}
+
+// handle decodes a message and emits its event, it returns false when ctx is done
+func (s *Stream) handle(ctx context.Context, data []byte) bool {
+	var msg message
+	if err := json.Unmarshal(data, &msg); err != nil {
+		return s.emit(ctx, &ErrorEvent{Message: fmt.Sprintf("failed to decode message %s: %s", string(data), err.Error())})
+	}
+
+	switch msg.Event {
+	case "challenge":
+		if err := s.authenticate(msg.Message); err != nil {
+			return s.emit(ctx, &ErrorEvent{Message: err.Error()})
+		}
+		return true
+	case "error", "alert":
+		return s.emit(ctx, &ErrorEvent{Message: msg.Message})
+	case "":
+	default:
+		// info, subscribed, unsubscribed
+		return true
+	}
+
+	switch msg.Feed {
+	case "book_snapshot":
+		s.mu.Lock()
+		b := newBook(msg.ProductID, msg.Seq, fromMillis(msg.Timestamp), msg.Bids, msg.Asks)
+		s.books[msg.ProductID] = b
+		snapshot := b.snapshot()
+		s.mu.Unlock()
+		return s.emit(ctx, &BookEvent{ProductID: msg.ProductID, Snapshot: true, Book: snapshot})
+
+	case "book":
+		var level streamLevel
+		if err := json.Unmarshal(data, &level); err != nil {
+			return s.emit(ctx, &ErrorEvent{Message: fmt.Sprintf("failed to decode book update: %s", err.Error())})
+		}
+
+		s.mu.Lock()
+		b, ok := s.books[msg.ProductID]
+		if ok && !b.update(msg.Seq, fromMillis(msg.Timestamp), msg.Side, level) {
+			// Updates were missed: a new subscription sends a new snapshot
+			delete(s.books, msg.ProductID)
+			s.resubscribeBook(msg.ProductID)
+			ok = false
+		}
+		var snapshot Book
+		if ok {
+			snapshot = b.snapshot()
+		}
+		s.mu.Unlock()
+
+		if !ok {
+			return true
+		}
+		return s.emit(ctx, &BookEvent{ProductID: msg.ProductID, Book: snapshot})
+
+	case "ticker", "ticker_lite":
+		var event TickerEvent
+		if err := json.Unmarshal(data, &event); err != nil {
+			return s.emit(ctx, &ErrorEvent{Message: fmt.Sprintf("failed to decode ticker: %s", err.Error())})
+		}
+		return s.emit(ctx, &event)
+
+	case "trade_snapshot":
+		return s.emit(ctx, &TradeEvent{ProductID: msg.ProductID, Snapshot: true, Trades: msg.Trades})
+
+	case "trade":
+		var trade StreamTrade
+		if err := json.Unmarshal(data, &trade); err != nil {
+			return s.emit(ctx, &ErrorEvent{Message: fmt.Sprintf("failed to decode trade: %s", err.Error())})
+		}
+		return s.emit(ctx, &TradeEvent{ProductID: msg.ProductID, Trades: []StreamTrade{trade}})
+
+	case "fills_snapshot", "fills":
+		return s.emit(ctx, &FillsEvent{Snapshot: msg.Feed == "fills_snapshot", Fills: msg.Fills})
+
+	case "open_orders_snapshot", "open_orders_verbose_snapshot":
+		return s.emit(ctx, &OpenOrdersEvent{Snapshot: true, Orders: msg.Orders})
+
+	case "open_orders", "open_orders_verbose":
+		event := &OpenOrdersEvent{IsCancel: msg.IsCancel, OrderID: msg.OrderID, Reason: msg.Reason}
+		if msg.Order != nil {
+			event.Orders = []StreamOrder{*msg.Order}
+			if event.OrderID == "" {
+				event.OrderID = msg.Order.OrderID
+			}
+		}
+		return s.emit(ctx, event)
+
+	case "balances_snapshot", "balances":
+		var event BalancesEvent
+		if err := json.Unmarshal(data, &event); err != nil {
+			return s.emit(ctx, &ErrorEvent{Message: fmt.Sprintf("failed to decode balances: %s", err.Error())})
+		}
+		event.Snapshot = msg.Feed == "balances_snapshot"
+		return s.emit(ctx, &event)
+	}
+
+	// heartbeat and unknown feeds
+	return true
+}
+
+// authenticate signs the challenge and subscribes to the private feeds
+func (s *Stream) authenticate(challenge string) error {
+	signed, err := SignChallenge(s.config.APISecret, challenge)
+	if err != nil {
+		return fmt.Errorf("failed to sign challenge: %s", err.Error())
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.challenge, s.signedChallenge = challenge, signed
+	return s.writeSubscriptions(true)
+}
+
+// SignChallenge returns the signature of the challenge sent by the server: HMAC-SHA512, keyed with
+// the base64-decoded secret, of the SHA-256 of the challenge
+func SignChallenge(secret string, challenge string) (string, error) {
+	sha := sha256.Sum256([]byte(challenge))
+
+	key, err := base64.StdEncoding.DecodeString(secret)
+	if err != nil {
+		return "", err
+	}
+
+	mac := hmac.New(sha512.New, key)
+	if _, err := mac.Write(sha[:]); err != nil {
+		return "", err
+	}
+	return base64.StdEncoding.EncodeToString(mac.Sum(nil)), nil
+}
+
+// writeSubscriptions sends the public or private subscriptions. s.mu must be held.
+func (s *Stream) writeSubscriptions(private bool) error {
+	feeds := make([]string, 0, len(s.subscriptions))
+	for feed := range s.subscriptions {
+		feeds = append(feeds, string(feed))
+	}
+	sort.Strings(feeds)
+
+	for _, name := range feeds {
+		feed := Feed(name)
+		if feed.IsPrivate() != private {
+			continue
+		}
+
+		productIDs := make([]string, 0, len(s.subscriptions[feed]))
+		for productID := range s.subscriptions[feed] {
+			productIDs = append(productIDs, productID)
+		}
+		sort.Strings(productIDs)
+		if !private && len(productIDs) == 0 {
+			continue
+		}
+
+		if err := s.writeSubscription("subscribe", feed, productIDs); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// resubscribeBook subscribes again to the book of a product, to get a new snapshot. s.mu must be held.
+func (s *Stream) resubscribeBook(productID string) {
+	if s.conn == nil {
+		return
+	}
+	if err := s.writeSubscription("unsubscribe", FeedBook, []string{productID}); err != nil {
+		s.conn.Close()
+		return
+	}
+	if err := s.writeSubscription("subscribe", FeedBook, []string{productID}); err != nil {
+		s.conn.Close()
+	}
+}
+
+// writeSubscription sends a subscribe or unsubscribe request. s.mu must be held.
+func (s *Stream) writeSubscription(event string, feed Feed, productIDs []string) error {
+	request := map[string]interface{}{
+		"event": event,
+		"feed":  feed,
+	}
+	if feed.IsPrivate() {
+		request["api_key"] = s.config.APIKey
+		request["original_challenge"] = s.challenge
+		request["signed_challenge"] = s.signedChallenge
+	} else {
+		request["product_ids"] = productIDs
+	}
+	return s.write(request)
+}
+
+// write sends a request. s.mu must be held.
+func (s *Stream) write(request interface{}) error {
+	if s.conn == nil {
+		return fmt.Errorf("not connected")
+	}
+	s.conn.SetWriteDeadline(time.Now().Add(10 * time.Second))
+	return s.conn.WriteJSON(request)
+}
+
+// requestChallenge asks for the challenge authenticating the private feeds, once per connection.
+// s.mu must be held.
+func (s *Stream) requestChallenge() error {
+	if s.challengeRequested {
+		return nil
+	}
+	s.challengeRequested = true
+	return s.write(map[string]interface{}{"event": "challenge", "api_key": s.config.APIKey})
+}
+
+func (s *Stream) hasPrivateSubscriptions() bool {
+	for feed := range s.subscriptions {
+		if feed.IsPrivate() {
+			return true
+		}
+	}
+	return false
+}
+
+// emit sends the event unless ctx is done, and returns false in this case
+func (s *Stream) emit(ctx context.Context, event Event) bool {
+	select {
+	case s.events <- event:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
@@ -0,0 +1,295 @@
+package futures_test
+
+import (
+	"context"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/astaluego/golang-kraken/futures"
+	"github.com/astaluego/golang-kraken/futures/futurestest"
+)
+
+// next returns the next event of the stream, which must be a T
+func next[T futures.Event](t *testing.T, stream *futures.Stream) T {
+	t.Helper()
+
+	select {
+	case event, ok := <-stream.Events():
+		if !ok {
+			t.Fatal("the events channel is closed")
+		}
+		typed, ok := event.(T)
+		if !ok {
+			t.Fatalf("expected a %T, got %#v", *new(T), event)
+		}
+		return typed
+	case <-time.After(2 * time.Second):
+		t.Fatalf("expected a %T, got nothing", *new(T))
+	}
+	panic("unreachable")
+}
+
+// run starts the stream, and stops it at the end of the test
+func run(t *testing.T, stream *futures.Stream) {
+	t.Helper()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() { done <- stream.Run(ctx) }()
+	t.Cleanup(func() {
+		cancel()
+		for range stream.Events() {
+		}
+		<-done
+	})
+}
+
+func level(price, qty float64) map[string]float64 {
+	return map[string]float64{"price": price, "qty": qty}
+}
+
+func levels(book []futures.OrderBookLevel) string {
+	parts := []string{}
+	for _, level := range book {
+		parts = append(parts, level.Price.String()+":"+level.Size.String())
+	}
+	return strings.Join(parts, " ")
+}
+
+func TestStreamChallengeAuthentication(t *testing.T) {
+	server := futurestest.NewStreamServer()
+	defer server.Close()
+	server.HandleSubscribe(futures.FeedFills, func(string) []interface{} {
+		return []interface{}{map[string]interface{}{
+			"feed": "fills_snapshot",
+			"fills": []interface{}{map[string]interface{}{
+				"instrument": "PF_XBTUSD", "time": 1688637600000, "seq": 1, "buy": true,
+				"qty": 0.01, "price": 30000.5, "order_id": "a", "fill_id": "b", "fill_type": "maker",
+			}},
+		}}
+	})
+
+	stream := server.NewStream(futures.StreamConfig{})
+	if err := stream.Subscribe(futures.FeedFills); err != nil {
+		t.Fatal(err)
+	}
+	run(t, stream)
+
+	if event := next[*futures.ConnectionEvent](t, stream); !event.Connected {
+		t.Fatalf("unexpected event: %+v", event)
+	}
+	fills := next[*futures.FillsEvent](t, stream)
+	if !fills.Snapshot || len(fills.Fills) != 1 || fills.Fills[0].Price.String() != "30000.5" ||
+		!fills.Fills[0].Time.Equal(time.Date(2023, 7, 6, 10, 0, 0, 0, time.UTC)) {
+		t.Errorf("unexpected fills: %+v", fills)
+	}
+
+	// The challenge is requested first, then signed in the subscription
+	requests := server.Requests()
+	if len(requests) != 2 || requests[0].Event != "challenge" || requests[0].APIKey != futurestest.Key {
+		t.Fatalf("unexpected requests: %+v", requests)
+	}
+	subscription := requests[1]
+	signed, err := futures.SignChallenge(server.Secret(), subscription.OriginalChallenge)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if subscription.Event != "subscribe" || subscription.Feed != "fills" || subscription.OriginalChallenge == "" || subscription.SignedChallenge != signed {
+		t.Errorf("unexpected subscription: %+v", subscription)
+	}
+}
+
+func TestStreamChallengeInvalidSecret(t *testing.T) {
+	server := futurestest.NewStreamServer()
+	defer server.Close()
+
+	stream := futures.NewStream(futures.StreamConfig{
+		URL:       server.URL(),
+		APIKey:    futurestest.Key,
+		APISecret: "AAECAwQFBgcICQoLDA0ODxAREhMUFRYXGBkaGxwdHh8gISIjJCUmJygpKissLS4vMDEyMzQ1Njc4OTo7PD0+Pw==",
+	})
+	if err := stream.Subscribe(futures.FeedBalances); err != nil {
+		t.Fatal(err)
+	}
+	run(t, stream)
+
+	next[*futures.ConnectionEvent](t, stream)
+	if event := next[*futures.ErrorEvent](t, stream); event.Message != "Failed to subscribe to authenticated feed" {
+		t.Errorf("unexpected error: %+v", event)
+	}
+
+	if err := futures.NewStream(futures.StreamConfig{}).Subscribe(futures.FeedFills); err == nil {
+		t.Error("expected an error without credentials")
+	}
+}
+
+func TestStreamBook(t *testing.T) {
+	server := futurestest.NewStreamServer()
+	defer server.Close()
+
+	// The first snapshot, then the one sent again after a gap
+	var snapshots int32
+	server.HandleSubscribe(futures.FeedBook, func(productID string) []interface{} {
+		if atomic.AddInt32(&snapshots, 1) == 1 {
+			return []interface{}{map[string]interface{}{
+				"feed": "book_snapshot", "product_id": productID, "timestamp": 1688637600000, "seq": 1,
+				"bids": []interface{}{level(30000, 1), level(29999.5, 2)},
+				"asks": []interface{}{level(30001, 1.5), level(30002, 3)},
+			}}
+		}
+		return []interface{}{map[string]interface{}{
+			"feed": "book_snapshot", "product_id": productID, "timestamp": 1688637660000, "seq": 10,
+			"bids": []interface{}{level(30100, 1)},
+			"asks": []interface{}{level(30101, 1)},
+		}}
+	})
+
+	stream := server.NewStream(futures.StreamConfig{})
+	if err := stream.Subscribe(futures.FeedBook, "PF_XBTUSD"); err != nil {
+		t.Fatal(err)
+	}
+	run(t, stream)
+	next[*futures.ConnectionEvent](t, stream)
+
+	snapshot := next[*futures.BookEvent](t, stream)
+	if !snapshot.Snapshot || snapshot.ProductID != "PF_XBTUSD" || snapshot.Book.Seq != 1 ||
+		levels(snapshot.Book.Bids) != "30000:1 29999.5:2" || levels(snapshot.Book.Asks) != "30001:1.5 30002:3" {
+		t.Fatalf("unexpected snapshot: %+v", snapshot)
+	}
+
+	// A new best bid, then the best ask is removed
+	update := func(seq int64, side futures.Side, price, qty float64) {
+		t.Helper()
+		err := server.Send(map[string]interface{}{
+			"feed": "book", "product_id": "PF_XBTUSD", "side": side, "seq": seq,
+			"price": price, "qty": qty, "timestamp": 1688637600000 + seq,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	update(2, futures.Buy, 30000.5, 0.5)
+	if event := next[*futures.BookEvent](t, stream); event.Snapshot || levels(event.Book.Bids) != "30000.5:0.5 30000:1 29999.5:2" {
+		t.Fatalf("unexpected update: %+v", event)
+	}
+	update(3, futures.Sell, 30001, 0)
+	if event := next[*futures.BookEvent](t, stream); event.Book.Seq != 3 || levels(event.Book.Asks) != "30002:3" {
+		t.Fatalf("unexpected update: %+v", event)
+	}
+
+	book, ok := stream.Book("PF_XBTUSD")
+	if !ok || levels(book.Bids) != "30000.5:0.5 30000:1 29999.5:2" || !book.Timestamp.Equal(time.UnixMilli(1688637600003)) {
+		t.Errorf("unexpected book: %+v", book)
+	}
+	if _, ok := stream.Book("PF_ETHUSD"); ok {
+		t.Error("expected no book for a product not subscribed")
+	}
+
+	// The update 4 was missed: the book is dropped, and a new snapshot requested
+	update(5, futures.Buy, 29000, 1)
+	resync := next[*futures.BookEvent](t, stream)
+	if !resync.Snapshot || resync.Book.Seq != 10 || levels(resync.Book.Bids) != "30100:1" {
+		t.Fatalf("expected a new snapshot, got %+v", resync)
+	}
+
+	requests := server.Requests()
+	events := []string{}
+	for _, request := range requests {
+		events = append(events, request.Event+":"+request.Feed+":"+strings.Join(request.ProductIDs, ","))
+	}
+	expected := "subscribe:book:PF_XBTUSD unsubscribe:book:PF_XBTUSD subscribe:book:PF_XBTUSD"
+	if strings.Join(events, " ") != expected {
+		t.Errorf("expected %s, got %s", expected, strings.Join(events, " "))
+	}
+
+	update(11, futures.Sell, 30101, 2)
+	if event := next[*futures.BookEvent](t, stream); levels(event.Book.Asks) != "30101:2" {
+		t.Errorf("unexpected update: %+v", event)
+	}
+}
+
+func TestStreamReconnect(t *testing.T) {
+	server := futurestest.NewStreamServer()
+	defer server.Close()
+	server.HandleSubscribe(futures.FeedBook, func(productID string) []interface{} {
+		return []interface{}{map[string]interface{}{
+			"feed": "book_snapshot", "product_id": productID, "timestamp": 1688637600000, "seq": 1,
+			"bids": []interface{}{level(30000, 1)},
+		}}
+	})
+	server.HandleSubscribe(futures.FeedBalances, func(string) []interface{} {
+		return []interface{}{map[string]interface{}{
+			"feed": "balances_snapshot", "seq": 1, "timestamp": 1688637600000,
+		}}
+	})
+
+	stream := server.NewStream(futures.StreamConfig{ReconnectDelay: 10 * time.Millisecond})
+	if err := stream.Subscribe(futures.FeedBook, "PF_XBTUSD"); err != nil {
+		t.Fatal(err)
+	}
+	if err := stream.Subscribe(futures.FeedBalances); err != nil {
+		t.Fatal(err)
+	}
+	run(t, stream)
+
+	for connection := 0; connection < 2; connection++ {
+		if event := next[*futures.ConnectionEvent](t, stream); !event.Connected {
+			t.Fatalf("connection %d: unexpected event %+v", connection, event)
+		}
+		// The public feeds are subscribed at once, the private ones after the challenge
+		if event := next[*futures.BookEvent](t, stream); !event.Snapshot {
+			t.Fatalf("connection %d: unexpected event %+v", connection, event)
+		}
+		if event := next[*futures.BalancesEvent](t, stream); !event.Snapshot {
+			t.Fatalf("connection %d: unexpected event %+v", connection, event)
+		}
+
+		if connection == 0 {
+			server.Disconnect()
+			if event := next[*futures.ConnectionEvent](t, stream); event.Connected || event.Err == nil {
+				t.Fatalf("expected a disconnection, got %+v", event)
+			}
+		}
+	}
+
+	subscriptions := map[string]int{}
+	challenges := map[string]bool{}
+	for _, request := range server.Requests() {
+		if request.Event == "subscribe" {
+			subscriptions[request.Feed]++
+		}
+		if request.OriginalChallenge != "" {
+			challenges[request.OriginalChallenge] = true
+		}
+	}
+	if subscriptions["book"] != 2 || subscriptions["balances"] != 2 {
+		t.Errorf("expected each feed subscribed again, got %v", subscriptions)
+	}
+	// Each connection is authenticated with its own challenge
+	if len(challenges) != 2 {
+		t.Errorf("expected 2 challenges, got %d", len(challenges))
+	}
+
+	// Unsubscribed feeds are not subscribed again
+	if err := stream.Unsubscribe(futures.FeedBook, "PF_XBTUSD"); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := stream.Book("PF_XBTUSD"); ok {
+		t.Error("expected the book to be dropped")
+	}
+	server.Disconnect()
+	next[*futures.ConnectionEvent](t, stream)
+	next[*futures.ConnectionEvent](t, stream)
+	next[*futures.BalancesEvent](t, stream)
+
+	for _, request := range server.Requests() {
+		if request.Event == "subscribe" && request.Feed == "book" {
+			subscriptions["book"]--
+		}
+	}
+	if subscriptions["book"] != 0 {
+		t.Errorf("expected no subscription to the book after Unsubscribe, got %d more", -subscriptions["book"])
+	}
+}
@@ -0,0 +1,311 @@
+package futures
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+// Feed is a feed of the WebSocket API
+type Feed string
+
+const (
+	// Public feeds
+	FeedBook   Feed = "book"
+	FeedTicker Feed = "ticker"
+	FeedTrade  Feed = "trade"
+
+	// Private feeds, authenticated with a challenge
+	FeedFills      Feed = "fills"
+	FeedOpenOrders Feed = "open_orders"
+	FeedBalances   Feed = "balances"
+)
+
+// IsPrivate returns true if the feed requires the challenge authentication
+func (feed Feed) IsPrivate() bool {
+	switch feed {
+	case FeedFills, FeedOpenOrders, FeedBalances:
+		return true
+	}
+	return false
+}
+
+// Event is a message received on a Stream: *BookEvent, *TickerEvent, *TradeEvent, *FillsEvent,
+// *OpenOrdersEvent, *BalancesEvent, *ErrorEvent or *ConnectionEvent
+type Event interface {
+	// Feed of the event, empty for the errors and connection events
+	Feed() Feed
+}
+
+// BookEvent is emitted after each snapshot or update of an order book
+type BookEvent struct {
+	ProductID string
+	// Snapshot is true when the whole book was received
+	Snapshot bool
+	// Book after the update
+	Book Book
+}
+
+func (*BookEvent) Feed() Feed { return FeedBook }
+
+// Book is an order book maintained from a snapshot and its updates
+type Book struct {
+	ProductID string
+	// Bids, best first
+	Bids []OrderBookLevel
+	// Asks, best first
+	Asks []OrderBookLevel
+	// Sequence number of the last update
+	Seq int64
+	// Time of the last update
+	Timestamp time.Time
+}
+
+type TickerEvent struct {
+	ProductID     string          `json:"product_id"`
+	Bid           decimal.Decimal `json:"bid"`
+	Ask           decimal.Decimal `json:"ask"`
+	BidSize       decimal.Decimal `json:"bid_size"`
+	AskSize       decimal.Decimal `json:"ask_size"`
+	Volume        decimal.Decimal `json:"volume"`
+	VolumeQuote   decimal.Decimal `json:"volumeQuote"`
+	Last          decimal.Decimal `json:"last"`
+	Change        decimal.Decimal `json:"change"`
+	Index         decimal.Decimal `json:"index"`
+	MarkPrice     decimal.Decimal `json:"markPrice"`
+	OpenInterest  decimal.Decimal `json:"openInterest"`
+	FundingRate   decimal.Decimal `json:"funding_rate"`
+	Premium       decimal.Decimal `json:"premium"`
+	Suspended     bool            `json:"suspended"`
+	PostOnly      bool            `json:"post_only"`
+	Time          time.Time       `json:"-"`
+	NextFundingAt time.Time       `json:"-"`
+}
+
+func (*TickerEvent) Feed() Feed { return FeedTicker }
+
+func (e *TickerEvent) UnmarshalJSON(data []byte) error {
+	type alias TickerEvent
+	var raw struct {
+		alias
+		Time                int64 `json:"time"`
+		NextFundingRateTime int64 `json:"next_funding_rate_time"`
+	}
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+	*e = TickerEvent(raw.alias)
+	e.Time = fromMillis(raw.Time)
+	e.NextFundingAt = fromMillis(raw.NextFundingRateTime)
+	return nil
+}
+
+// TradeEvent is emitted with the last trades on subscription, then with each trade
+type TradeEvent struct {
+	ProductID string
+	// Snapshot is true for the trades sent on subscription
+	Snapshot bool
+	Trades   []StreamTrade
+}
+
+func (*TradeEvent) Feed() Feed { return FeedTrade }
+
+type StreamTrade struct {
+	ProductID string          `json:"product_id"`
+	UID       string          `json:"uid"`
+	Side      Side            `json:"side"`
+	Type      string          `json:"type"`
+	Seq       int64           `json:"seq"`
+	Qty       decimal.Decimal `json:"qty"`
+	Price     decimal.Decimal `json:"price"`
+	Time      time.Time       `json:"-"`
+}
+
+func (t *StreamTrade) UnmarshalJSON(data []byte) error {
+	type alias StreamTrade
+	var raw struct {
+		alias
+		Time int64 `json:"time"`
+	}
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+	*t = StreamTrade(raw.alias)
+	t.Time = fromMillis(raw.Time)
+	return nil
+}
+
+// FillsEvent is emitted with the last fills on subscription, then with each new fill
+type FillsEvent struct {
+	// Snapshot is true for the fills sent on subscription
+	Snapshot bool
+	Fills    []StreamFill
+}
+
+func (*FillsEvent) Feed() Feed { return FeedFills }
+
+type StreamFill struct {
+	Instrument     string          `json:"instrument"`
+	Seq            int64           `json:"seq"`
+	Buy            bool            `json:"buy"`
+	Qty            decimal.Decimal `json:"qty"`
+	Price          decimal.Decimal `json:"price"`
+	OrderID        string          `json:"order_id"`
+	ClientOrderID  string          `json:"cli_ord_id"`
+	FillID         string          `json:"fill_id"`
+	FillType       string          `json:"fill_type"`
+	FeePaid        decimal.Decimal `json:"fee_paid"`
+	FeeCurrency    string          `json:"fee_currency"`
+	OrderType      string          `json:"order_type"`
+	TakerOrderType string          `json:"taker_order_type"`
+	Time           time.Time       `json:"-"`
+}
+
+func (f *StreamFill) UnmarshalJSON(data []byte) error {
+	type alias StreamFill
+	var raw struct {
+		alias
+		Time int64 `json:"time"`
+	}
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+	*f = StreamFill(raw.alias)
+	f.Time = fromMillis(raw.Time)
+	return nil
+}
+
+// OpenOrdersEvent is emitted with the open orders on subscription, then with each change of an order
+type OpenOrdersEvent struct {
+	// Snapshot is true for the orders sent on subscription
+	Snapshot bool
+	// Orders open on subscription, or the order placed or updated
+	Orders []StreamOrder
+	// IsCancel is true when the order is not open anymore (cancelled or filled)
+	IsCancel bool
+	// OrderID of the order not open anymore
+	OrderID string
+	// Reason of the change (e.g. new_placed_order_by_user, cancelled_by_user, full_fill)
+	Reason string
+}
+
+func (*OpenOrdersEvent) Feed() Feed { return FeedOpenOrders }
+
+type StreamOrder struct {
+	Instrument    string          `json:"instrument"`
+	Qty           decimal.Decimal `json:"qty"`
+	Filled        decimal.Decimal `json:"filled"`
+	LimitPrice    decimal.Decimal `json:"limit_price"`
+	StopPrice     decimal.Decimal `json:"stop_price"`
+	Type          string          `json:"type"`
+	OrderID       string          `json:"order_id"`
+	ClientOrderID string          `json:"cli_ord_id"`
+	Side          Side            `json:"-"`
+	ReduceOnly    bool            `json:"reduce_only"`
+	TriggerSignal string          `json:"triggerSignal"`
+	Time          time.Time       `json:"-"`
+	LastUpdatedAt time.Time       `json:"-"`
+}
+
+func (o *StreamOrder) UnmarshalJSON(data []byte) error {
+	type alias StreamOrder
+	var raw struct {
+		alias
+		Time           int64 `json:"time"`
+		LastUpdateTime int64 `json:"last_update_time"`
+		// 0 for buy, 1 for sell
+		Direction int `json:"direction"`
+	}
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+	*o = StreamOrder(raw.alias)
+	o.Time = fromMillis(raw.Time)
+	o.LastUpdatedAt = fromMillis(raw.LastUpdateTime)
+	o.Side = Buy
+	if raw.Direction == 1 {
+		o.Side = Sell
+	}
+	return nil
+}
+
+// BalancesEvent is emitted with the balances on subscription, then with each change
+type BalancesEvent struct {
+	// Snapshot is true for the balances sent on subscription
+	Snapshot bool  `json:"-"`
+	Seq      int64 `json:"-"`
+	// Holding wallets by currency
+	Holding map[string]decimal.Decimal `json:"holding"`
+	// Single-collateral margin accounts by name
+	Futures map[string]FuturesBalance `json:"futures"`
+	// Multi-collateral margin account
+	FlexFutures *FlexFuturesBalance `json:"flex_futures"`
+	Timestamp   time.Time           `json:"-"`
+}
+
+func (*BalancesEvent) Feed() Feed { return FeedBalances }
+
+type FuturesBalance struct {
+	Name              string          `json:"name"`
+	PairName          string          `json:"pair_name"`
+	Unit              string          `json:"unit"`
+	PortfolioValue    decimal.Decimal `json:"portfolio_value"`
+	Balance           decimal.Decimal `json:"balance"`
+	MaintenanceMargin decimal.Decimal `json:"maintenance_margin"`
+	InitialMargin     decimal.Decimal `json:"initial_margin"`
+	Available         decimal.Decimal `json:"available"`
+	UnrealizedFunding decimal.Decimal `json:"unrealized_funding"`
+	PNL               decimal.Decimal `json:"pnl"`
+}
+
+type FlexFuturesBalance struct {
+	Currencies        map[string]FlexCurrency `json:"currencies"`
+	BalanceValue      decimal.Decimal         `json:"balance_value"`
+	PortfolioValue    decimal.Decimal         `json:"portfolio_value"`
+	CollateralValue   decimal.Decimal         `json:"collateral_value"`
+	InitialMargin     decimal.Decimal         `json:"initial_margin"`
+	MaintenanceMargin decimal.Decimal         `json:"maintenance_margin"`
+	PNL               decimal.Decimal         `json:"pnl"`
+	UnrealizedFunding decimal.Decimal         `json:"unrealized_funding"`
+	AvailableMargin   decimal.Decimal         `json:"available_margin"`
+}
+
+// ErrorEvent is an error or alert sent by the server (e.g. an invalid product id)
+type ErrorEvent struct {
+	Message string
+}
+
+func (*ErrorEvent) Feed() Feed { return "" }
+
+// ConnectionEvent is emitted when the connection is established or lost
+type ConnectionEvent struct {
+	Connected bool
+	// Err is the reason of the disconnection
+	Err error
+}
+
+func (*ConnectionEvent) Feed() Feed { return "" }
+
+func fromMillis(ms int64) time.Time {
+	if ms == 0 {
+		return time.Time{}
+	}
+	return time.UnixMilli(ms).UTC()
+}
+
+func (e *BalancesEvent) UnmarshalJSON(data []byte) error {
+	type alias BalancesEvent
+	var raw struct {
+		alias
+		Seq       int64 `json:"seq"`
+		Timestamp int64 `json:"timestamp"`
+	}
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+	*e = BalancesEvent(raw.alias)
+	e.Seq = raw.Seq
+	e.Timestamp = fromMillis(raw.Timestamp)
+	return nil
+}
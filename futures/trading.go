@@ -0,0 +1,304 @@
+package futures
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+
+	"github.com/shopspring/decimal"
+)
+
+type SendOrderConfig struct {
+	// OrderType is required
+	OrderType OrderType
+	// Symbol is required
+	Symbol string
+	// Side is required
+	Side Side
+	// Size is required
+	Size decimal.Decimal
+
+	// LimitPrice is optional
+	// Required for limit, post-only, immediate-or-cancel orders, and stop or take profit orders with a limit
+	LimitPrice decimal.Decimal
+	// StopPrice is optional
+	// Required for stop and take profit orders
+	StopPrice decimal.Decimal
+	// ClientOrderID is optional
+	ClientOrderID string
+	// TriggerSignal is optional
+	// Default: mark
+	TriggerSignal TriggerSignal
+	// ReduceOnly is optional
+	ReduceOnly bool
+	// TrailingStopMaxDeviation is optional
+	// Required for trailing stop orders
+	TrailingStopMaxDeviation decimal.Decimal
+	// TrailingStopDeviationUnit is optional
+	// Required for trailing stop orders
+	TrailingStopDeviationUnit DeviationUnit
+}
+
+func (config SendOrderConfig) validate() error {
+	if config.OrderType == "" {
+		return fmt.Errorf("OrderType is required")
+	}
+	if config.Symbol == "" {
+		return fmt.Errorf("Symbol is required")
+	}
+	if config.Side == "" {
+		return fmt.Errorf("Side is required")
+	}
+	if !config.Size.IsPositive() {
+		return fmt.Errorf("Size is required")
+	}
+
+	switch config.OrderType {
+	case Limit, PostOnly, ImmediateOrCancel:
+		if config.LimitPrice.IsZero() {
+			return fmt.Errorf("LimitPrice is required for %s orders", config.OrderType)
+		}
+	case Stop, TakeProfit:
+		if config.StopPrice.IsZero() {
+			return fmt.Errorf("StopPrice is required for %s orders", config.OrderType)
+		}
+	case TrailingStop:
+		if config.TrailingStopMaxDeviation.IsZero() || config.TrailingStopDeviationUnit == "" {
+			return fmt.Errorf("TrailingStopMaxDeviation and TrailingStopDeviationUnit are required for %s orders", config.OrderType)
+		}
+	}
+	return nil
+}
+
+// instruction returns the fields of the order, as sent to sendorder and batchorder
+func (config SendOrderConfig) instruction() map[string]interface{} {
+	instruction := map[string]interface{}{
+		"orderType": config.OrderType,
+		"symbol":    config.Symbol,
+		"side":      config.Side,
+		"size":      number(config.Size),
+	}
+	if !config.LimitPrice.IsZero() {
+		instruction["limitPrice"] = number(config.LimitPrice)
+	}
+	if !config.StopPrice.IsZero() {
+		instruction["stopPrice"] = number(config.StopPrice)
+	}
+	if config.ClientOrderID != "" {
+		instruction["cliOrdId"] = config.ClientOrderID
+	}
+	if config.TriggerSignal != "" {
+		instruction["triggerSignal"] = config.TriggerSignal
+	}
+	if config.ReduceOnly {
+		instruction["reduceOnly"] = true
+	}
+	if !config.TrailingStopMaxDeviation.IsZero() {
+		instruction["trailingStopMaxDeviation"] = number(config.TrailingStopMaxDeviation)
+	}
+	if config.TrailingStopDeviationUnit != "" {
+		instruction["trailingStopDeviationUnit"] = config.TrailingStopDeviationUnit
+	}
+	return instruction
+}
+
+// SendOrder
+// Sends a new order. The status of the response tells if it was placed (e.g. placed) or not
+// (e.g. insufficientAvailableFunds).
+// https://docs.futures.kraken.com/#http-api-trading-v3-api-order-management-send-order
+func (c *Client) SendOrder(config SendOrderConfig) (*SendStatus, error) {
+	if err := config.validate(); err != nil {
+		return nil, err
+	}
+
+	var response struct {
+		SendStatus SendStatus `json:"sendStatus"`
+	}
+	err := c.doRequest(http.MethodPost, "sendorder", true, form(config.instruction()), &response)
+	if err != nil {
+		return nil, err
+	}
+	return &response.SendStatus, nil
+}
+
+type EditOrderConfig struct {
+	// OrderID is optional
+	// Either OrderID or ClientOrderID is required
+	OrderID string
+	// ClientOrderID is optional
+	ClientOrderID string
+
+	// Size is optional
+	Size decimal.Decimal
+	// LimitPrice is optional
+	LimitPrice decimal.Decimal
+	// StopPrice is optional
+	StopPrice decimal.Decimal
+}
+
+func (config EditOrderConfig) instruction() (map[string]interface{}, error) {
+	instruction := map[string]interface{}{}
+	switch {
+	case config.OrderID != "":
+		instruction["orderId"] = config.OrderID
+	case config.ClientOrderID != "":
+		instruction["cliOrdId"] = config.ClientOrderID
+	default:
+		return nil, fmt.Errorf("OrderID or ClientOrderID is required")
+	}
+
+	if !config.Size.IsZero() {
+		instruction["size"] = number(config.Size)
+	}
+	if !config.LimitPrice.IsZero() {
+		instruction["limitPrice"] = number(config.LimitPrice)
+	}
+	if !config.StopPrice.IsZero() {
+		instruction["stopPrice"] = number(config.StopPrice)
+	}
+	return instruction, nil
+}
+
+// EditOrder
+// Edits the size or the prices of an open order
+// https://docs.futures.kraken.com/#http-api-trading-v3-api-order-management-edit-order
+func (c *Client) EditOrder(config EditOrderConfig) (*EditStatus, error) {
+	instruction, err := config.instruction()
+	if err != nil {
+		return nil, err
+	}
+
+	var response struct {
+		EditStatus EditStatus `json:"editStatus"`
+	}
+	err = c.doRequest(http.MethodPost, "editorder", true, form(instruction), &response)
+	if err != nil {
+		return nil, err
+	}
+	return &response.EditStatus, nil
+}
+
+type CancelOrderConfig struct {
+	// OrderID is optional
+	// Either OrderID or ClientOrderID is required
+	OrderID string
+	// ClientOrderID is optional
+	ClientOrderID string
+}
+
+// CancelOrder
+// Cancels an open order
+// https://docs.futures.kraken.com/#http-api-trading-v3-api-order-management-cancel-order
+func (c *Client) CancelOrder(config CancelOrderConfig) (*CancelStatus, error) {
+	data := url.Values{}
+	switch {
+	case config.OrderID != "":
+		data.Set("order_id", config.OrderID)
+	case config.ClientOrderID != "":
+		data.Set("cliOrdId", config.ClientOrderID)
+	default:
+		return nil, fmt.Errorf("OrderID or ClientOrderID is required")
+	}
+
+	var response struct {
+		CancelStatus CancelStatus `json:"cancelStatus"`
+	}
+	err := c.doRequest(http.MethodPost, "cancelorder", true, data, &response)
+	if err != nil {
+		return nil, err
+	}
+	return &response.CancelStatus, nil
+}
+
+// BatchSend is a new order of a batch
+type BatchSend struct {
+	// Tag is required
+	// Identifies the order in the statuses of the batch
+	Tag string
+	// Order is required
+	Order SendOrderConfig
+}
+
+type BatchOrderConfig struct {
+	// Send is optional
+	Send []BatchSend
+	// Edit is optional
+	Edit []EditOrderConfig
+	// Cancel is optional
+	Cancel []CancelOrderConfig
+}
+
+// BatchOrder
+// Sends, edits and cancels several orders in a single request
+// https://docs.futures.kraken.com/#http-api-trading-v3-api-order-management-batch-order-management
+func (c *Client) BatchOrder(config BatchOrderConfig) ([]BatchStatus, error) {
+	instructions := []map[string]interface{}{}
+
+	for _, send := range config.Send {
+		if send.Tag == "" {
+			return nil, fmt.Errorf("Tag is required")
+		}
+		if err := send.Order.validate(); err != nil {
+			return nil, err
+		}
+		instruction := send.Order.instruction()
+		instruction["order"] = "send"
+		instruction["order_tag"] = send.Tag
+		instructions = append(instructions, instruction)
+	}
+	for _, edit := range config.Edit {
+		instruction, err := edit.instruction()
+		if err != nil {
+			return nil, err
+		}
+		instruction["order"] = "edit"
+		if id, ok := instruction["orderId"]; ok {
+			delete(instruction, "orderId")
+			instruction["order_id"] = id
+		}
+		instructions = append(instructions, instruction)
+	}
+	for _, cancel := range config.Cancel {
+		instruction := map[string]interface{}{"order": "cancel"}
+		switch {
+		case cancel.OrderID != "":
+			instruction["order_id"] = cancel.OrderID
+		case cancel.ClientOrderID != "":
+			instruction["cliOrdId"] = cancel.ClientOrderID
+		default:
+			return nil, fmt.Errorf("OrderID or ClientOrderID is required")
+		}
+		instructions = append(instructions, instruction)
+	}
+	if len(instructions) == 0 {
+		return nil, fmt.Errorf("Send, Edit or Cancel is required")
+	}
+
+	batch, err := json.Marshal(map[string]interface{}{"batchOrder": instructions})
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode batch: %s", err.Error())
+	}
+	data := url.Values{}
+	data.Set("json", string(batch))
+
+	var response struct {
+		BatchStatus []BatchStatus `json:"batchStatus"`
+	}
+	err = c.doRequest(http.MethodPost, "batchorder", true, data, &response)
+	return response.BatchStatus, err
+}
+
+// number sends a decimal as a JSON number, instead of the quoted string of decimal.Decimal
+func number(d decimal.Decimal) json.Number {
+	return json.Number(d.String())
+}
+
+// form encodes the fields of an instruction as form values
+func form(instruction map[string]interface{}) url.Values {
+	data := url.Values{}
+	for key, value := range instruction {
+		data.Set(key, fmt.Sprint(value))
+	}
+	return data
+}
@@ -0,0 +1,381 @@
+package futures
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+type Side string
+
+const (
+	Buy  Side = "buy"
+	Sell Side = "sell"
+)
+
+type OrderType string
+
+const (
+	// Limit order
+	Limit OrderType = "lmt"
+	// Post-only limit order
+	PostOnly OrderType = "post"
+	// Immediate-or-cancel order
+	ImmediateOrCancel OrderType = "ioc"
+	// Market order
+	Market OrderType = "mkt"
+	// Stop order
+	Stop OrderType = "stp"
+	// Take profit order
+	TakeProfit OrderType = "take_profit"
+	// Trailing stop order
+	TrailingStop OrderType = "trailing_stop"
+)
+
+type TriggerSignal string
+
+const (
+	MarkPrice  TriggerSignal = "mark"
+	IndexPrice TriggerSignal = "index"
+	LastPrice  TriggerSignal = "last"
+)
+
+type DeviationUnit string
+
+const (
+	Percent    DeviationUnit = "PERCENT"
+	QuoteValue DeviationUnit = "QUOTE_CURRENCY"
+)
+
+type Instrument struct {
+	// Symbol (e.g. PF_XBTUSD)
+	Symbol string `json:"symbol"`
+	// Type (e.g. flexible_futures, futures_inverse, futures_vanilla)
+	Type string `json:"type"`
+	// Underlying index (for inverse and vanilla futures)
+	Underlying string `json:"underlying"`
+	// Tick size
+	TickSize decimal.Decimal `json:"tickSize"`
+	// Contract size
+	ContractSize decimal.Decimal `json:"contractSize"`
+	// True if the instrument can be traded
+	Tradeable bool `json:"tradeable"`
+	// Impact mid size
+	ImpactMidSize decimal.Decimal `json:"impactMidSize"`
+	// Maximum position size
+	MaxPositionSize decimal.Decimal `json:"maxPositionSize"`
+	// Opening date
+	OpeningDate time.Time `json:"openingDate"`
+	// Last trading time (for fixed maturity futures)
+	LastTradingTime time.Time `json:"lastTradingTime"`
+	// Margin levels
+	MarginLevels []MarginLevel `json:"marginLevels"`
+	// Funding rate coefficient (for perpetuals)
+	FundingRateCoefficient decimal.Decimal `json:"fundingRateCoefficient"`
+	// Maximum relative funding rate (for perpetuals)
+	MaxRelativeFundingRate decimal.Decimal `json:"maxRelativeFundingRate"`
+	// Number of decimals of the size
+	ContractValueTradePrecision int64 `json:"contractValueTradePrecision"`
+	// True if only post-only orders are accepted
+	PostOnly bool `json:"postOnly"`
+	// Category (e.g. Layer 1)
+	Category string `json:"category"`
+	// Tags
+	Tags []string `json:"tags"`
+}
+
+type MarginLevel struct {
+	// Lower limit of the position size, in contracts
+	Contracts decimal.Decimal `json:"contracts"`
+	// Lower limit of the position size, in base currency (for flexible futures)
+	NumNonContractUnits decimal.Decimal `json:"numNonContractUnits"`
+	// Initial margin
+	InitialMargin decimal.Decimal `json:"initialMargin"`
+	// Maintenance margin
+	MaintenanceMargin decimal.Decimal `json:"maintenanceMargin"`
+}
+
+type Ticker struct {
+	// Symbol (e.g. PF_XBTUSD)
+	Symbol string `json:"symbol"`
+	// Last fill price
+	Last decimal.Decimal `json:"last"`
+	// Time of the last fill
+	LastTime time.Time `json:"lastTime"`
+	// Size of the last fill
+	LastSize decimal.Decimal `json:"lastSize"`
+	// Tag (e.g. perpetual, month, quarter)
+	Tag string `json:"tag"`
+	// Pair (e.g. XBT:USD)
+	Pair string `json:"pair"`
+	// Mark price
+	MarkPrice decimal.Decimal `json:"markPrice"`
+	// Best bid
+	Bid decimal.Decimal `json:"bid"`
+	// Size of the best bid
+	BidSize decimal.Decimal `json:"bidSize"`
+	// Best ask
+	Ask decimal.Decimal `json:"ask"`
+	// Size of the best ask
+	AskSize decimal.Decimal `json:"askSize"`
+	// Volume over the last 24h
+	Vol24h decimal.Decimal `json:"vol24h"`
+	// Volume over the last 24h, in quote currency
+	VolumeQuote decimal.Decimal `json:"volumeQuote"`
+	// Open interest
+	OpenInterest decimal.Decimal `json:"openInterest"`
+	// Price 24h ago
+	Open24h decimal.Decimal `json:"open24h"`
+	// Highest price over the last 24h
+	High24h decimal.Decimal `json:"high24h"`
+	// Lowest price over the last 24h
+	Low24h decimal.Decimal `json:"low24h"`
+	// Change over the last 24h, in percent
+	Change24h decimal.Decimal `json:"change24h"`
+	// Funding rate (for perpetuals)
+	FundingRate decimal.Decimal `json:"fundingRate"`
+	// Predicted funding rate (for perpetuals)
+	FundingRatePrediction decimal.Decimal `json:"fundingRatePrediction"`
+	// Index price
+	IndexPrice decimal.Decimal `json:"indexPrice"`
+	// True if the market is suspended
+	Suspended bool `json:"suspended"`
+	// True if only post-only orders are accepted
+	PostOnly bool `json:"postOnly"`
+}
+
+type OrderBook struct {
+	// Bids, best first
+	Bids []OrderBookLevel `json:"bids"`
+	// Asks, best first
+	Asks []OrderBookLevel `json:"asks"`
+}
+
+type OrderBookLevel struct {
+	Price decimal.Decimal
+	Size  decimal.Decimal
+}
+
+// UnmarshalJSON decodes a level sent as [price, size]
+func (level *OrderBookLevel) UnmarshalJSON(data []byte) error {
+	var values []decimal.Decimal
+	if err := json.Unmarshal(data, &values); err != nil {
+		return err
+	}
+	if len(values) != 2 {
+		return fmt.Errorf("failed to decode order book level %s: expected [price, size]", string(data))
+	}
+	level.Price = values[0]
+	level.Size = values[1]
+	return nil
+}
+
+type Trade struct {
+	// Time of the trade
+	Time time.Time `json:"time"`
+	// Trade ID, increasing for each symbol
+	TradeID int64 `json:"trade_id"`
+	// Price
+	Price decimal.Decimal `json:"price"`
+	// Size
+	Size decimal.Decimal `json:"size"`
+	// Side of the taker
+	Side Side `json:"side"`
+	// Type (fill, liquidation, assignment, termination, block)
+	Type string `json:"type"`
+	// Unique identifier
+	UID string `json:"uid"`
+}
+
+type Account struct {
+	// Type (cashAccount, marginAccount, multiCollateralMarginAccount)
+	Type string `json:"type"`
+	// Currency of the margin account
+	Currency string `json:"currency"`
+	// Balances by currency
+	Balances map[string]decimal.Decimal `json:"balances"`
+	// Auxiliary values of the margin account (usd, pv, pnl, af, funding)
+	Auxiliary map[string]decimal.Decimal `json:"auxiliary"`
+	// Margin requirements of the margin account
+	MarginRequirements *MarginRequirements `json:"marginRequirements"`
+	// Estimates of the price at which the margin requirements are reached
+	TriggerEstimates *MarginRequirements `json:"triggerEstimates"`
+
+	// Currencies of the multi-collateral account
+	Currencies map[string]FlexCurrency `json:"currencies"`
+	// Initial margin of the multi-collateral account
+	InitialMargin decimal.Decimal `json:"initialMargin"`
+	// Maintenance margin of the multi-collateral account
+	MaintenanceMargin decimal.Decimal `json:"maintenanceMargin"`
+	// Balance value of the multi-collateral account, in USD
+	BalanceValue decimal.Decimal `json:"balanceValue"`
+	// Portfolio value of the multi-collateral account, in USD
+	PortfolioValue decimal.Decimal `json:"portfolioValue"`
+	// Collateral value of the multi-collateral account, in USD
+	CollateralValue decimal.Decimal `json:"collateralValue"`
+	// Unrealized profit and loss of the multi-collateral account, in USD
+	PNL decimal.Decimal `json:"pnl"`
+	// Available margin of the multi-collateral account, in USD
+	AvailableMargin decimal.Decimal `json:"availableMargin"`
+}
+
+type MarginRequirements struct {
+	// Initial margin
+	IM decimal.Decimal `json:"im"`
+	// Maintenance margin
+	MM decimal.Decimal `json:"mm"`
+	// Liquidation threshold
+	LT decimal.Decimal `json:"lt"`
+	// Termination threshold
+	TT decimal.Decimal `json:"tt"`
+}
+
+type FlexCurrency struct {
+	Quantity   decimal.Decimal `json:"quantity"`
+	Value      decimal.Decimal `json:"value"`
+	Collateral decimal.Decimal `json:"collateral"`
+	Available  decimal.Decimal `json:"available"`
+}
+
+type OpenPosition struct {
+	// Symbol
+	Symbol string `json:"symbol"`
+	// Side (long or short)
+	Side string `json:"side"`
+	// Average entry price
+	Price decimal.Decimal `json:"price"`
+	// Time of the last fill
+	FillTime time.Time `json:"fillTime"`
+	// Size
+	Size decimal.Decimal `json:"size"`
+	// Unrealized funding (for perpetuals)
+	UnrealizedFunding decimal.Decimal `json:"unrealizedFunding"`
+	// Currency of the profit and loss (for flexible futures)
+	PNLCurrency string `json:"pnlCurrency"`
+}
+
+type Fill struct {
+	// Fill ID
+	FillID string `json:"fill_id"`
+	// Symbol
+	Symbol string `json:"symbol"`
+	// Side
+	Side Side `json:"side"`
+	// Order ID
+	OrderID string `json:"order_id"`
+	// Client order ID
+	ClientOrderID string `json:"cliOrdId"`
+	// Size
+	Size decimal.Decimal `json:"size"`
+	// Price
+	Price decimal.Decimal `json:"price"`
+	// Time of the fill
+	FillTime time.Time `json:"fillTime"`
+	// Type (maker, taker, liquidation, assignee, assignor, ...)
+	FillType string `json:"fillType"`
+}
+
+type Order struct {
+	// Order ID
+	OrderID string `json:"orderId"`
+	// Client order ID
+	ClientOrderID string `json:"cliOrdId"`
+	// Type (lmt, ioc, post, stp, take_profit, ...)
+	Type string `json:"type"`
+	// Symbol
+	Symbol string `json:"symbol"`
+	// Side
+	Side Side `json:"side"`
+	// Quantity
+	Quantity decimal.Decimal `json:"quantity"`
+	// Quantity filled
+	Filled decimal.Decimal `json:"filled"`
+	// Limit price
+	LimitPrice decimal.Decimal `json:"limitPrice"`
+	// Stop price
+	StopPrice decimal.Decimal `json:"stopPrice"`
+	// Reduce only
+	ReduceOnly bool `json:"reduceOnly"`
+	// Time the order was placed
+	Timestamp time.Time `json:"timestamp"`
+	// Time of the last update
+	LastUpdateTimestamp time.Time `json:"lastUpdateTimestamp"`
+}
+
+// OrderEvent is a change of an order caused by a request (e.g. PLACE, EXECUTION, EDIT, CANCEL, REJECT)
+type OrderEvent struct {
+	// Type of the event
+	Type string `json:"type"`
+	// Order after the event
+	Order *Order `json:"order"`
+	// Order before the event, for EDIT events
+	OrderPriorEdit *Order `json:"old"`
+	// Order before the event, for EXECUTION events
+	OrderPriorExecution *Order `json:"orderPriorExecution"`
+	// New order, for EDIT events
+	New *Order `json:"new"`
+	// Price of an EXECUTION event
+	Price decimal.Decimal `json:"price"`
+	// Amount of an EXECUTION event
+	Amount decimal.Decimal `json:"amount"`
+	// Execution ID of an EXECUTION event
+	ExecutionID string `json:"executionId"`
+	// Reason of a REJECT or CANCEL event
+	Reason string `json:"reason"`
+	// UID of the order, for CANCEL events
+	UID string `json:"uid"`
+}
+
+type SendStatus struct {
+	// Order ID
+	OrderID string `json:"order_id"`
+	// Status (placed, partiallyFilled, filled, cancelled, edited, marketSuspended, insufficientAvailableFunds, ...)
+	Status string `json:"status"`
+	// Time the order was received
+	ReceivedTime time.Time `json:"receivedTime"`
+	// Events caused by the order
+	OrderEvents []OrderEvent `json:"orderEvents"`
+	// Client order ID
+	ClientOrderID string `json:"cliOrdId"`
+}
+
+type EditStatus struct {
+	// Order ID
+	OrderID string `json:"orderId"`
+	// Status (edited, invalidSize, invalidPrice, insufficientAvailableFunds, orderForEditNotFound, ...)
+	Status string `json:"status"`
+	// Time the edit was received
+	ReceivedTime time.Time `json:"receivedTime"`
+	// Events caused by the edit
+	OrderEvents []OrderEvent `json:"orderEvents"`
+}
+
+type CancelStatus struct {
+	// Order ID
+	OrderID string `json:"order_id"`
+	// Status (cancelled, filled, notFound)
+	Status string `json:"status"`
+	// Time the cancellation was received
+	ReceivedTime time.Time `json:"receivedTime"`
+	// Events caused by the cancellation
+	OrderEvents []OrderEvent `json:"orderEvents"`
+	// Client order ID
+	ClientOrderID string `json:"cliOrdId"`
+}
+
+type BatchStatus struct {
+	// Tag of the instruction, for send instructions
+	OrderTag string `json:"order_tag"`
+	// Order ID
+	OrderID string `json:"order_id"`
+	// Status (placed, edited, cancelled, ...)
+	Status string `json:"status"`
+	// Time the instruction was received
+	DateTimeReceived time.Time `json:"dateTimeReceived"`
+	// Events caused by the instruction
+	OrderEvents []OrderEvent `json:"orderEvents"`
+	// Client order ID
+	ClientOrderID string `json:"cliOrdId"`
+}
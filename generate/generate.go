@@ -1,39 +1,50 @@
-//go:build tools
-// +build tools
-
 package main
 
 import (
 	"bytes"
 	"encoding/json"
 	"errors"
+	"flag"
 	"fmt"
 	"go/format"
 	"io/ioutil"
 	"net/http"
 	"os"
+	"path/filepath"
 	"strings"
+	"text/template"
 
 	"github.com/rs/zerolog"
 	"github.com/rs/zerolog/log"
 )
 
 var (
-	urlAsset           = "https://api.kraken.com/0/public/Assets"
-	urlAssetPairs      = "https://api.kraken.com/0/public/AssetPairs"
-	pathAssetFile      = "/"
-	pathAssetPairsFile = "/"
-	filenameAsset      = "asset.go"
-	filenameAssetPairs = "asset_pairs.go"
+	urlAsset               = "https://api.kraken.com/0/public/Assets"
+	urlAssetPairs          = "https://api.kraken.com/0/public/AssetPairs"
+	filenameAsset          = "asset.go"
+	filenameAssetPairs     = "asset_pairs.go"
+	filenameAssetPairsInfo = "asset_pairs_metadata.go"
 )
 
+// Fixture is a snapshot of the responses of Assets and AssetPairs,
+// used to generate the files without network access
+type Fixture struct {
+	Assets     json.RawMessage `json:"assets"`
+	AssetPairs json.RawMessage `json:"asset_pairs"`
+}
+
 type ResponseAsset struct {
 	Error  []string             `json:"error"`
 	Result map[string]AssetInfo `json:"result"`
 }
 
 type AssetInfo struct {
-	Altname string `json:"altname"`
+	Altname         string `json:"altname"`
+	Decimals        int64  `json:"decimals"`
+	DisplayDecimals int64  `json:"display_decimals"`
+
+	// Name of the Golang constant
+	ConstName string `json:"-"`
 }
 
 type ResponseAssetPairs struct {
@@ -42,65 +53,185 @@ type ResponseAssetPairs struct {
 }
 
 type AssetPairsInfo struct {
-	Altname string `json:"altname"`
-	WSname  string `json:"wsname"`
+	Altname      string `json:"altname"`
+	WSname       string `json:"wsname"`
+	Base         string `json:"base"`
+	Quote        string `json:"quote"`
+	PairDecimals int64  `json:"pair_decimals"`
+	CostDecimals int64  `json:"cost_decimals"`
+	LotDecimals  int64  `json:"lot_decimals"`
+
+	// Name of the Golang constant
+	ConstName string `json:"-"`
 }
 
 func main() {
 	zerolog.TimeFieldFormat = zerolog.TimeFormatUnix
 
+	fixturePath := flag.String("fixture", "", "generate from this JSON fixture instead of the Kraken API")
+	recordPath := flag.String("record", "", "save the responses of the Kraken API into this JSON fixture")
+	outputPath := flag.String("output", "", "directory of the generated files (default: current directory)")
+	flag.Parse()
+
 	// Get data
-	assets, err := getAssets()
+	var (
+		fixture *Fixture
+		err     error
+	)
+	if *fixturePath != "" {
+		fixture, err = readFixture(*fixturePath)
+		if err != nil {
+			log.Fatal().Err(err).Msg("Failed to read the fixture")
+		}
+	} else {
+		fixture, err = fetchFixture()
+		if err != nil {
+			log.Fatal().Err(err).Msg("Failed to get data from Kraken")
+		}
+		if *recordPath != "" {
+			if err := writeFixture(*recordPath, fixture); err != nil {
+				log.Fatal().Err(err).Msg("Failed to record the fixture")
+			}
+		}
+	}
+
+	assets, err := parseAssets(fixture.Assets)
 	if err != nil {
-		log.Fatal().Err(err)
+		log.Fatal().Err(err).Msg("Failed to parse assets")
 	}
-	assetPairs, err := getAssetPairs()
+	assetPairs, err := parseAssetPairs(fixture.AssetPairs)
 	if err != nil {
-		log.Fatal().Err(err)
+		log.Fatal().Err(err).Msg("Failed to parse asset pairs")
 	}
 
-	// Get the current path
-	currentPath, err := os.Getwd()
-	if err != nil {
-		log.Fatal().Err(err)
+	// Get the output path
+	currentPath := *outputPath
+	if currentPath == "" {
+		currentPath, err = os.Getwd()
+		if err != nil {
+			log.Fatal().Err(err).Msg("Failed to get the current directory")
+		}
+	}
+
+	// Generate the files, and exit with an error status if any of them failed
+	if err := generateFiles(currentPath, assets, assetPairs); err != nil {
+		log.Fatal().Err(err).Msg("Failed to generate the files")
 	}
+}
+
+// generateFiles generates asset.go, asset_pairs.go and asset_pairs_metadata.go into currentPath.
+// All the files are attempted, and the errors of the ones which failed are joined.
+func generateFiles(currentPath string, assets *ResponseAsset, assetPairs *ResponseAssetPairs) error {
+	var errs []error
 
 	// Generate asset.go file
-	if err := generateAssetfile(currentPath, assets); err != nil {
-		log.Error().Err(err).Msg("Failed to generate Asset file:")
+	if err := generateFile(currentPath, filenameAsset, "Asset", packageTemplateAsset, struct {
+		URL    string
+		Assets map[string]AssetInfo
+	}{
+		URL:    urlAsset,
+		Assets: assets.Result,
+	}); err != nil {
+		errs = append(errs, fmt.Errorf("failed to generate Asset file: %w", err))
 	}
 
 	// Generate asset_pairs.go file
-	if err := generateAssetPairsfile(currentPath, assetPairs); err != nil {
-		log.Error().Err(err).Msg("Failed to generate Asset Pairs file:")
+	if err := generateFile(currentPath, filenameAssetPairs, "Asset Pairs", packageTemplateAssetPairs, struct {
+		URL        string
+		AssetPairs map[string]AssetPairsInfo
+	}{
+		URL:        urlAssetPairs,
+		AssetPairs: assetPairs.Result,
+	}); err != nil {
+		errs = append(errs, fmt.Errorf("failed to generate Asset Pairs file: %w", err))
+	}
+
+	// Generate asset_pairs_metadata.go file
+	if err := generateFile(currentPath, filenameAssetPairsInfo, "Asset Pairs metadata", packageTemplateAssetPairsMetadata, struct {
+		URL        string
+		Assets     map[string]AssetInfo
+		AssetPairs map[string]AssetPairsInfo
+	}{
+		URL:        urlAssetPairs,
+		Assets:     assets.Result,
+		AssetPairs: assetPairs.Result,
+	}); err != nil {
+		errs = append(errs, fmt.Errorf("failed to generate Asset Pairs metadata file: %w", err))
 	}
+
+	return errors.Join(errs...)
 }
 
-func getAssets() (*ResponseAsset, error) {
-	// Get asset list from Kraken
-	rsp, err := http.Get(urlAsset)
+func fetchFixture() (*Fixture, error) {
+	assets, err := fetch(urlAsset)
+	if err != nil {
+		return nil, err
+	}
+	assetPairs, err := fetch(urlAssetPairs)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Fixture{
+		Assets:     assets,
+		AssetPairs: assetPairs,
+	}, nil
+}
+
+func fetch(url string) ([]byte, error) {
+	rsp, err := http.Get(url)
 	if err != nil {
 		return nil, err
 	}
 	defer rsp.Body.Close()
 
+	if rsp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed to get %s: status %d", url, rsp.StatusCode)
+	}
+
 	// Read the json response
-	body, err := ioutil.ReadAll(rsp.Body)
+	return ioutil.ReadAll(rsp.Body)
+}
+
+func readFixture(path string) (*Fixture, error) {
+	data, err := ioutil.ReadFile(path)
 	if err != nil {
 		return nil, err
 	}
 
+	var fixture Fixture
+	if err := json.Unmarshal(data, &fixture); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal %s: %s", path, err.Error())
+	}
+	if len(fixture.Assets) == 0 || len(fixture.AssetPairs) == 0 {
+		return nil, fmt.Errorf("%s must contain assets and asset_pairs", path)
+	}
+	return &fixture, nil
+}
+
+func writeFixture(path string, fixture *Fixture) error {
+	data, err := json.MarshalIndent(fixture, "", "  ")
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(path, append(data, '\n'), 0644)
+}
+
+func parseAssets(body []byte) (*ResponseAsset, error) {
 	var responseAsset ResponseAsset
 
 	// Unmarshal the response into a go structure
-	err = json.Unmarshal(body, &responseAsset)
+	err := json.Unmarshal(body, &responseAsset)
 	if err != nil {
 		return nil, err
 	}
+	if len(responseAsset.Error) > 0 {
+		return nil, fmt.Errorf("got server errors: %+v", responseAsset.Error)
+	}
 
 	// Clean data
 	for key, value := range responseAsset.Result {
-		value.Altname, err = rewriteAsset(value.Altname)
+		value.ConstName, err = rewriteAsset(value.Altname)
 		if err != nil {
 			log.Info().Msgf("Asset: key removed %s (%s: %s)", key, value.Altname, err.Error())
 			delete(responseAsset.Result, key)
@@ -111,38 +242,27 @@ func getAssets() (*ResponseAsset, error) {
 	return &responseAsset, nil
 }
 
-func getAssetPairs() (*ResponseAssetPairs, error) {
-	// Get asset pairs list from Kraken
-	rsp, err := http.Get(urlAssetPairs)
-	if err != nil {
-		return nil, err
-	}
-	defer rsp.Body.Close()
-
-	// Read the json response
-	body, err := ioutil.ReadAll(rsp.Body)
-	if err != nil {
-		return nil, err
-	}
-
+func parseAssetPairs(body []byte) (*ResponseAssetPairs, error) {
 	var responseAssetPairs ResponseAssetPairs
 
 	// Unmarshal the response into a go structure
-	err = json.Unmarshal(body, &responseAssetPairs)
+	err := json.Unmarshal(body, &responseAssetPairs)
 	if err != nil {
 		return nil, err
 	}
+	if len(responseAssetPairs.Error) > 0 {
+		return nil, fmt.Errorf("got server errors: %+v", responseAssetPairs.Error)
+	}
 
 	// Clean data
 	for key, value := range responseAssetPairs.Result {
 		if value.WSname == "" {
-			log.Info().Msgf("AssetPair: key removed %s (%s: %s)", key, value.Altname, err.Error())
+			log.Info().Msgf("AssetPair: key removed %s (%s: no wsname)", key, value.Altname)
 			delete(responseAssetPairs.Result, key)
 			continue
 		}
 
-		value.WSname = strings.ReplaceAll(value.WSname, "/", "_")
-		value.WSname, err = rewriteAsset(value.WSname)
+		value.ConstName, err = rewriteAsset(strings.ReplaceAll(value.WSname, "/", "_"))
 		if err != nil {
 			log.Info().Msgf("AssetPair: key removed %s (%s: %s)", key, value.WSname, err.Error())
 			delete(responseAssetPairs.Result, key)
@@ -154,144 +274,50 @@ func getAssetPairs() (*ResponseAssetPairs, error) {
 	return &responseAssetPairs, nil
 }
 
-func generateAssetfile(currentPath string, assets *ResponseAsset) error {
-	// Create a temporary file
-	f, err := os.Create(filenameAsset + ".tmp")
-	if err != nil {
-		return err
-	}
-	defer f.Close()
-
+func generateFile(currentPath string, filename string, label string, tmpl *template.Template, data interface{}) error {
 	var buf bytes.Buffer
 
-	// Generate the file with the template below the main function
-	err = packageTemplateAsset.Execute(&buf, struct {
-		URL    string
-		Assets map[string]AssetInfo
-	}{
-		URL:    urlAsset,
-		Assets: assets.Result,
-	})
-	if err != nil {
+	// Generate the file with the templates of template_asset.go
+	if err := tmpl.Execute(&buf, data); err != nil {
 		return err
 	}
 
-	p, err := format.Source(buf.Bytes())
+	generated, err := format.Source(buf.Bytes())
 	if err != nil {
 		return err
 	}
-	f.Write(p)
 
-	// Open the file generated
-	tmp, err := ioutil.ReadFile(filenameAsset + ".tmp")
-	if err != nil {
-		return err
-	}
+	path := filepath.Join(currentPath, filename)
 
 	// Check if there is a file
-	if _, err := os.Stat(currentPath + pathAssetFile + filenameAsset); os.IsNotExist(err) {
-		err := os.Rename(filenameAsset+".tmp", currentPath+pathAssetFile+filenameAsset)
-		if err != nil {
+	current, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		if err := writeGenerated(path, generated); err != nil {
 			return err
 		}
-		fmt.Print("Asset file has been generated successfully <3\n")
+		fmt.Printf("%s file has been generated successfully <3\n", label)
 		return nil
 	}
-
-	// Open the current file
-	current, err := ioutil.ReadFile(currentPath + pathAssetFile + filenameAsset)
 	if err != nil {
 		return err
 	}
 
-	// Make a diff between the 2 files, and exit if they are differents
-	if !bytes.Equal(tmp, current) {
-		// If the 2 files are different
-		fmt.Print("/!\\ The Asset structure has been updated\n")
-		err := os.Rename(filenameAsset+".tmp", currentPath+pathAssetFile+filenameAsset)
-		if err != nil {
-			return err
-		}
-	} else {
-		// If the 2 files contains the same content, remove the temporary file
-		fmt.Print("\\o/ Same Asset list\n")
-		err = os.Remove(filenameAsset + ".tmp")
-		if err != nil {
-			return err
-		}
+	// Make a diff between the 2 files
+	if bytes.Equal(generated, current) {
+		fmt.Printf("\\o/ Same %s list\n", label)
+		return nil
 	}
 
-	return nil
+	fmt.Printf("/!\\ The %s structure has been updated\n", label)
+	return writeGenerated(path, generated)
 }
 
-func generateAssetPairsfile(currentPath string, assetPairs *ResponseAssetPairs) error {
-	// Create a temporary file
-	f, err := os.Create(filenameAssetPairs + ".tmp")
-	if err != nil {
-		return err
-	}
-	defer f.Close()
-
-	var buf bytes.Buffer
-
-	// Generate the file with the template below the main function
-	err = packageTemplateAssetPairs.Execute(&buf, struct {
-		URL        string
-		AssetPairs map[string]AssetPairsInfo
-	}{
-		URL:        urlAssetPairs,
-		AssetPairs: assetPairs.Result,
-	})
-	if err != nil {
-		return err
-	}
-
-	p, err := format.Source(buf.Bytes())
-	if err != nil {
-		return err
-	}
-	f.Write(p)
-
-	// Open the file generated
-	tmp, err := ioutil.ReadFile(filenameAssetPairs + ".tmp")
-	if err != nil {
-		return err
-	}
-
-	// Check if there is a file
-	if _, err := os.Stat(currentPath + pathAssetPairsFile + filenameAssetPairs); os.IsNotExist(err) {
-		err := os.Rename(filenameAssetPairs+".tmp", currentPath+pathAssetPairsFile+filenameAssetPairs)
-		if err != nil {
-			return err
-		}
-		fmt.Print("Asset Pairs file has been generated successfully <3\n")
-		return nil
-	}
-
-	// Open the current file
-	current, err := ioutil.ReadFile(currentPath + pathAssetPairsFile + filenameAssetPairs)
-	if err != nil {
+// writeGenerated replaces the file through a temporary file, so it is never left half-written
+func writeGenerated(path string, data []byte) error {
+	if err := ioutil.WriteFile(path+".tmp", data, 0644); err != nil {
 		return err
 	}
-
-	// Make a diff between the 2 files, and exit if they are differents
-	if !bytes.Equal(tmp, current) {
-		// If the 2 files are different
-		fmt.Print("/!\\ The Asset Pairs structure has been updated\n")
-		err := os.Rename(filenameAssetPairs+".tmp", currentPath+pathAssetPairsFile+filenameAssetPairs)
-		if err != nil {
-			return err
-		}
-	} else {
-		// If the 2 files contains the same content, remove the temporary file
-		fmt.Print("\\o/ Same Asset Pairs list\n")
-		err = os.Remove(filenameAssetPairs + ".tmp")
-		if err != nil {
-			return err
-		}
-	}
-
-	return nil
+	return os.Rename(path+".tmp", path)
 }
 
 func rewriteAsset(asset string) (string, error) {
@@ -313,7 +339,7 @@ func rewriteAsset(asset string) (string, error) {
 
 func hasDigitPrefix(str string) bool {
 	for _, c := range str {
-		if c > '0' && c < '9' {
+		if c >= '0' && c <= '9' {
 			return true
 		}
 		break
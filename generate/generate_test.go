@@ -0,0 +1,116 @@
+package main
+
+import (
+	"go/parser"
+	"go/token"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestGenerateFiles(t *testing.T) {
+	fixture, err := readFixture(filepath.Join("testdata", "fixture.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	assets, err := parseAssets(fixture.Assets)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assetPairs, err := parseAssetPairs(fixture.AssetPairs)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The pairs without wsname are removed
+	if _, ok := assetPairs.Result["XXBTZUSD.d"]; ok {
+		t.Error("XXBTZUSD.d should have been removed")
+	}
+
+	output := t.TempDir()
+	if err := generateFiles(output, assets, assetPairs); err != nil {
+		t.Fatal(err)
+	}
+
+	expected := map[string][]string{
+		filenameAsset: {
+			`XBT Asset = "XXBT"`,
+			`DOTs Asset = "DOT.S"`,
+		},
+		filenameAssetPairs: {
+			`XBT_USD AssetPair = "XXBTZUSD"`,
+			`USDT_USD AssetPair = "USDTZUSD"`,
+		},
+		filenameAssetPairsInfo: {
+			`"XXBTZUSD": {`,
+			`WSname: "XBT/USD",`,
+			`Base: "XXBT",`,
+			`PairDecimals: 1,`,
+			`"XXBT": "XBT",`,
+			`"USD": "ZUSD",`,
+		},
+	}
+	for filename, lines := range expected {
+		path := filepath.Join(output, filename)
+		data, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := parser.ParseFile(token.NewFileSet(), path, data, 0); err != nil {
+			t.Errorf("%s: %v", filename, err)
+		}
+		// The alignment of gofmt is ignored
+		generated := strings.Join(strings.Fields(string(data)), " ")
+		for _, line := range lines {
+			if !strings.Contains(generated, line) {
+				t.Errorf("%s: missing %s", filename, line)
+			}
+		}
+	}
+	if strings.Contains(readFile(t, filepath.Join(output, filenameAssetPairsInfo)), "XBTUSD.d") {
+		t.Error("XBTUSD.d should not be generated")
+	}
+
+	// Generating again keeps the same files
+	if err := generateFiles(output, assets, assetPairs); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestGenerateFilesError(t *testing.T) {
+	fixture, err := readFixture(filepath.Join("testdata", "fixture.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	assets, err := parseAssets(fixture.Assets)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assetPairs, err := parseAssetPairs(fixture.AssetPairs)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = generateFiles(filepath.Join(t.TempDir(), "missing"), assets, assetPairs)
+	if err == nil {
+		t.Fatal("expected an error for a missing output directory")
+	}
+	for _, label := range []string{"Asset file", "Asset Pairs file", "Asset Pairs metadata file"} {
+		if !strings.Contains(err.Error(), label) {
+			t.Errorf("expected the error of the %s, got %v", label, err)
+		}
+	}
+
+	if _, err := readFixture(filepath.Join(t.TempDir(), "fixture.json")); err == nil {
+		t.Error("expected an error for a missing fixture")
+	}
+}
+
+func readFile(t *testing.T, path string) string {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(data)
+}
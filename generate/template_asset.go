@@ -1,6 +1,3 @@
-//go:build tools
-// +build tools
-
 package main
 
 import "text/template"
@@ -15,13 +12,13 @@ type Asset string
 
 const (
 {{- range $key, $value := .Assets }}
-	{{ $value.Altname }} Asset = "{{ $key }}"
+	{{ $value.ConstName }} Asset = "{{ $key }}"
 {{- end }}
 )
 
 type Assets struct {
 {{- range $key, $value := .Assets }}
-	{{ $value.Altname }} float64 ` + "`" + `json:"{{ $key }},string,omitempty"` + "`" + `
+	{{ $value.ConstName }} float64 ` + "`" + `json:"{{ $key }},string,omitempty"` + "`" + `
 {{- end }}
 }
 `))
@@ -35,7 +32,68 @@ type AssetPair string
 
 const (
 {{- range $key, $value := .AssetPairs }}
-	{{ $value.WSname }} AssetPair = "{{ $key }}"
+	{{ $value.ConstName }} AssetPair = "{{ $key }}"
 {{- end }}
 )
 `))
+
+var packageTemplateAssetPairsMetadata = template.Must(template.New("").Parse(`// Code generated by go generate; DO NOT EDIT.
+// This file was generated using data from {{ .URL }}
+
+package kraken
+
+// AssetPairMetadata describes an asset pair as it was at the time of the generation.
+// Use a Registry for up-to-date data.
+type AssetPairMetadata struct {
+	// Legacy name, the key of the pair in AssetPairs (e.g. XXBTZUSD)
+	Name AssetPair
+	// Alternate name (e.g. XBTUSD)
+	Altname string
+	// WebSocket name (e.g. XBT/USD)
+	WSname string
+	// Base asset (e.g. XXBT)
+	Base Asset
+	// Quote asset (e.g. ZUSD)
+	Quote Asset
+	// Scaling decimal places for pair
+	PairDecimals int64
+	// Scaling decimal places for cost
+	CostDecimals int64
+	// Scaling decimal places for volume
+	LotDecimals int64
+}
+
+// AssetPairsMetadata lists the asset pairs by legacy name
+var AssetPairsMetadata = map[AssetPair]AssetPairMetadata{
+{{- range $key, $value := .AssetPairs }}
+	{{ printf "%q" $key }}: {
+		Name:         {{ printf "%q" $key }},
+		Altname:      {{ printf "%q" $value.Altname }},
+		WSname:       {{ printf "%q" $value.WSname }},
+		Base:         {{ printf "%q" $value.Base }},
+		Quote:        {{ printf "%q" $value.Quote }},
+		PairDecimals: {{ $value.PairDecimals }},
+		CostDecimals: {{ $value.CostDecimals }},
+		LotDecimals:  {{ $value.LotDecimals }},
+	},
+{{- end }}
+}
+
+// AssetAltnames maps the legacy name of the assets to their alternate name (e.g. XXBT to XBT)
+var AssetAltnames = map[Asset]string{
+{{- range $key, $value := .Assets }}
+{{- if ne $key $value.Altname }}
+	{{ printf "%q" $key }}: {{ printf "%q" $value.Altname }},
+{{- end }}
+{{- end }}
+}
+
+// AssetLegacyNames maps the alternate name of the assets to their legacy name (e.g. XBT to XXBT)
+var AssetLegacyNames = map[string]Asset{
+{{- range $key, $value := .Assets }}
+{{- if ne $key $value.Altname }}
+	{{ printf "%q" $value.Altname }}: {{ printf "%q" $key }},
+{{- end }}
+{{- end }}
+}
+`))
@@ -1,8 +1,9 @@
 module github.com/astaluego/golang-kraken
 
-go 1.17
+go 1.21
 
 require (
-	github.com/rs/zerolog v1.26.1 // indirect
-	github.com/shopspring/decimal v1.3.1 // indirect
+	github.com/gorilla/websocket v1.5.3
+	github.com/rs/zerolog v1.26.1
+	github.com/shopspring/decimal v1.3.1
 )
@@ -0,0 +1,137 @@
+// Package indicators computes technical indicators over kraken.OHLCData with decimal precision.
+//
+// Each indicator can be used in batch form over a slice of candles (e.g. SMASeries), or as a
+// streaming indicator updated with each new candle (e.g. NewSMA then Update). A candle with the
+// same time as the last one is a revision of the live candle returned by OHLC, and replaces it.
+package indicators
+
+import (
+	"fmt"
+	"time"
+
+	kraken "github.com/astaluego/golang-kraken"
+	"github.com/shopspring/decimal"
+)
+
+var (
+	hundred = decimal.NewFromInt(100)
+	two     = decimal.NewFromInt(2)
+)
+
+// Point is the value of an indicator at the time of a candle
+type Point struct {
+	Time  time.Time
+	Value decimal.Decimal
+}
+
+func checkPeriod(name string, period int) error {
+	if period < 1 {
+		return fmt.Errorf("%s must be at least 1, got %d", name, period)
+	}
+	return nil
+}
+
+// window is a fixed-size FIFO of values
+type window struct {
+	size   int
+	values []decimal.Decimal
+}
+
+func newWindow(size int) *window {
+	return &window{size: size, values: make([]decimal.Decimal, 0, size)}
+}
+
+// push adds a value and returns the value evicted, if any
+func (w *window) push(value decimal.Decimal) (decimal.Decimal, bool) {
+	if len(w.values) < w.size {
+		w.values = append(w.values, value)
+		return decimal.Decimal{}, false
+	}
+
+	evicted := w.values[0]
+	copy(w.values, w.values[1:])
+	w.values[len(w.values)-1] = value
+	return evicted, true
+}
+
+func (w *window) full() bool {
+	return len(w.values) == w.size
+}
+
+func (w *window) clone() *window {
+	values := make([]decimal.Decimal, len(w.values), w.size)
+	copy(values, w.values)
+	return &window{size: w.size, values: values}
+}
+
+type cloner[T any] interface {
+	clone() T
+}
+
+// live keeps a copy of a streaming indicator from before its last candle. A candle with the
+// same time as the last one is a revision of a live, not yet committed, candle: it replaces
+// the last candle instead of being added after it.
+type live[T cloner[T]] struct {
+	time   time.Time
+	before T
+	saved  bool
+}
+
+// revise is called before an indicator is updated with a candle. For a revision, it returns
+// the indicator to restore. Otherwise, the current indicator is kept for the next revision.
+func (l *live[T]) revise(t time.Time, current T) (T, bool) {
+	if l.saved && t.Equal(l.time) {
+		return l.before.clone(), true
+	}
+	l.time, l.before, l.saved = t, current.clone(), true
+
+	var none T
+	return none, false
+}
+
+// sqrt computes the square root of a positive decimal with Newton's method
+func sqrt(d decimal.Decimal) decimal.Decimal {
+	if d.Sign() <= 0 {
+		return decimal.Zero
+	}
+
+	precision := decimal.New(1, -int32(decimal.DivisionPrecision))
+	x := d
+	if d.GreaterThan(decimal.NewFromInt(1)) {
+		x = d.Div(two)
+	}
+	for i := 0; i < 100; i++ {
+		next := x.Add(d.Div(x)).Div(two)
+		if next.Sub(x).Abs().LessThanOrEqual(precision) {
+			return next
+		}
+		x = next
+	}
+	return x
+}
+
+// series runs a streaming indicator over candles and keeps the ready values
+func series(data []kraken.OHLCData, update func(candle kraken.OHLCData) (decimal.Decimal, bool)) []Point {
+	points := []Point{}
+	for i, candle := range data {
+		if value, ok := update(candle); ok {
+			points = appendValue(points, Point{Time: candle.Time, Value: value}, revises(data, i))
+		}
+	}
+	return points
+}
+
+// revises returns true if the candle i has the same time as the previous one
+func revises(data []kraken.OHLCData, i int) bool {
+	return i > 0 && data[i].Time.Equal(data[i-1].Time)
+}
+
+// appendValue appends the value of a candle, or replaces the last value when the candle is a
+// revision of the previous one
+func appendValue[T any](values []T, value T, revision bool) []T {
+	if revision && len(values) > 0 {
+		values[len(values)-1] = value
+		return values
+	}
+	return append(values, value)
+}
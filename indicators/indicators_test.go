@@ -0,0 +1,387 @@
+package indicators
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	kraken "github.com/astaluego/golang-kraken"
+	"github.com/shopspring/decimal"
+)
+
+// Close prices of the 10-day moving average example of StockCharts ChartSchool
+var movingAverageCloses = []string{
+	"22.27", "22.19", "22.08", "22.17", "22.18", "22.13", "22.23", "22.43", "22.24", "22.29",
+	"22.15", "22.39", "22.38", "22.61", "23.36", "24.05", "23.75", "23.83", "23.95", "23.63",
+	"23.82", "23.87", "23.65", "23.19", "23.10", "23.33", "22.68", "23.10", "22.40", "22.17",
+}
+
+// Close prices of the 14-day RSI example of StockCharts ChartSchool
+var rsiCloses = []string{
+	"44.3389", "44.0902", "44.1497", "43.6124", "44.3278", "44.8264", "45.0955", "45.4245", "45.8433", "46.0826",
+	"45.8931", "46.0328", "45.6140", "46.2820", "46.2820", "46.0028", "46.0328", "46.4116", "46.2222", "45.6439",
+	"46.2122", "46.2521", "45.7137", "46.4515", "45.7835", "45.3548", "44.0288", "44.1783", "44.2181", "44.5672",
+	"43.4205", "42.6628", "43.1314",
+}
+
+// candles builds one candle per minute from close prices. The high, low and volume follow a
+// fixed pattern, and the VWAP of each candle is its typical price (high + low + close) / 3.
+func candles(closes []string) []kraken.OHLCData {
+	start := time.Unix(1688671200, 0)
+	data := make([]kraken.OHLCData, 0, len(closes))
+	for i, value := range closes {
+		close := decimal.RequireFromString(value)
+		high := close.Add(decimal.New(int64(i%3+1), -1))
+		low := close.Sub(decimal.New(int64((i+1)%3+1), -1))
+		data = append(data, kraken.OHLCData{
+			Time:   start.Add(time.Duration(i) * time.Minute),
+			Open:   close,
+			High:   high,
+			Low:    low,
+			Close:  close,
+			VWAP:   high.Add(low).Add(close).Div(decimal.NewFromInt(3)),
+			Volume: decimal.NewFromInt(int64(100 + 10*(i%5))),
+		})
+	}
+	return data
+}
+
+// indicator runs an indicator over candles, in batch or streaming form, and formats its values
+type indicator struct {
+	batch  func(data []kraken.OHLCData) ([]string, error)
+	stream func() (func(candle kraken.OHLCData) (string, bool), error)
+}
+
+func points(places int32, compute func() ([]Point, error)) ([]string, error) {
+	series, err := compute()
+	if err != nil {
+		return nil, err
+	}
+	values := []string{}
+	for _, point := range series {
+		values = append(values, point.Value.StringFixed(places))
+	}
+	return values, nil
+}
+
+func streamValue(places int32, update func(candle kraken.OHLCData) (decimal.Decimal, bool)) func(candle kraken.OHLCData) (string, bool) {
+	return func(candle kraken.OHLCData) (string, bool) {
+		value, ok := update(candle)
+		return value.StringFixed(places), ok
+	}
+}
+
+func formatMACD(value MACDValue) string {
+	return fmt.Sprintf("%s/%s/%s", value.MACD.StringFixed(4), value.Signal.StringFixed(4), value.Histogram.StringFixed(4))
+}
+
+func formatBands(value BandsValue) string {
+	return fmt.Sprintf("%s/%s/%s", value.Upper.StringFixed(4), value.Middle.StringFixed(4), value.Lower.StringFixed(4))
+}
+
+func formatStochastic(value StochasticValue) string {
+	return fmt.Sprintf("%s/%s", value.K.StringFixed(4), value.D.StringFixed(4))
+}
+
+func indicators() map[string]indicator {
+	k := decimal.NewFromInt(2)
+
+	return map[string]indicator{
+		"SMA": {
+			batch: func(data []kraken.OHLCData) ([]string, error) {
+				return points(2, func() ([]Point, error) { return SMASeries(data, 10) })
+			},
+			stream: func() (func(candle kraken.OHLCData) (string, bool), error) {
+				sma, err := NewSMA(10)
+				if err != nil {
+					return nil, err
+				}
+				return streamValue(2, sma.Update), nil
+			},
+		},
+		"EMA": {
+			batch: func(data []kraken.OHLCData) ([]string, error) {
+				return points(2, func() ([]Point, error) { return EMASeries(data, 10) })
+			},
+			stream: func() (func(candle kraken.OHLCData) (string, bool), error) {
+				ema, err := NewEMA(10)
+				if err != nil {
+					return nil, err
+				}
+				return streamValue(2, ema.Update), nil
+			},
+		},
+		"RSI": {
+			batch: func(data []kraken.OHLCData) ([]string, error) {
+				return points(2, func() ([]Point, error) { return RSISeries(data, 14) })
+			},
+			stream: func() (func(candle kraken.OHLCData) (string, bool), error) {
+				rsi, err := NewRSI(14)
+				if err != nil {
+					return nil, err
+				}
+				return streamValue(2, rsi.Update), nil
+			},
+		},
+		"WMA": {
+			batch: func(data []kraken.OHLCData) ([]string, error) {
+				return points(4, func() ([]Point, error) { return WMASeries(data, 10) })
+			},
+			stream: func() (func(candle kraken.OHLCData) (string, bool), error) {
+				wma, err := NewWMA(10)
+				if err != nil {
+					return nil, err
+				}
+				return streamValue(4, wma.Update), nil
+			},
+		},
+		"MACD": {
+			batch: func(data []kraken.OHLCData) ([]string, error) {
+				series, err := MACDSeries(data, 5, 10, 4)
+				values := []string{}
+				for _, value := range series {
+					values = append(values, formatMACD(value))
+				}
+				return values, err
+			},
+			stream: func() (func(candle kraken.OHLCData) (string, bool), error) {
+				macd, err := NewMACD(5, 10, 4)
+				if err != nil {
+					return nil, err
+				}
+				return func(candle kraken.OHLCData) (string, bool) {
+					value, ok := macd.Update(candle)
+					return formatMACD(value), ok
+				}, nil
+			},
+		},
+		"BollingerBands": {
+			batch: func(data []kraken.OHLCData) ([]string, error) {
+				series, err := BollingerBandsSeries(data, 10, k)
+				values := []string{}
+				for _, value := range series {
+					values = append(values, formatBands(value))
+				}
+				return values, err
+			},
+			stream: func() (func(candle kraken.OHLCData) (string, bool), error) {
+				bands, err := NewBollingerBands(10, k)
+				if err != nil {
+					return nil, err
+				}
+				return func(candle kraken.OHLCData) (string, bool) {
+					value, ok := bands.Update(candle)
+					return formatBands(value), ok
+				}, nil
+			},
+		},
+		"ATR": {
+			batch: func(data []kraken.OHLCData) ([]string, error) {
+				return points(4, func() ([]Point, error) { return ATRSeries(data, 5) })
+			},
+			stream: func() (func(candle kraken.OHLCData) (string, bool), error) {
+				atr, err := NewATR(5)
+				if err != nil {
+					return nil, err
+				}
+				return streamValue(4, atr.Update), nil
+			},
+		},
+		"Stochastic": {
+			batch: func(data []kraken.OHLCData) ([]string, error) {
+				series, err := StochasticSeries(data, 5, 3)
+				values := []string{}
+				for _, value := range series {
+					values = append(values, formatStochastic(value))
+				}
+				return values, err
+			},
+			stream: func() (func(candle kraken.OHLCData) (string, bool), error) {
+				stochastic, err := NewStochastic(5, 3)
+				if err != nil {
+					return nil, err
+				}
+				return func(candle kraken.OHLCData) (string, bool) {
+					value, ok := stochastic.Update(candle)
+					return formatStochastic(value), ok
+				}, nil
+			},
+		},
+		"OBV": {
+			batch: func(data []kraken.OHLCData) ([]string, error) {
+				return points(0, func() ([]Point, error) { return OBVSeries(data), nil })
+			},
+			stream: func() (func(candle kraken.OHLCData) (string, bool), error) {
+				return streamValue(0, NewOBV().Update), nil
+			},
+		},
+		"VWAP": {
+			batch: func(data []kraken.OHLCData) ([]string, error) {
+				return points(4, func() ([]Point, error) { return VWAPSeries(data, 5) })
+			},
+			stream: func() (func(candle kraken.OHLCData) (string, bool), error) {
+				vwap, err := NewVWAP(5)
+				if err != nil {
+					return nil, err
+				}
+				return streamValue(4, vwap.Update), nil
+			},
+		},
+	}
+}
+
+// Expected values: SMA, EMA and RSI are the values published by StockCharts ChartSchool for
+// their examples, rounded half up to 2 decimals. The other values were computed independently
+// from the definitions of the indicators, with 40 significant digits, and rounded to 4 decimals.
+var golden = map[string]struct {
+	closes   []string
+	expected []string
+}{
+	"SMA": {movingAverageCloses, []string{
+		"22.22", "22.21", "22.23", "22.26", "22.30", "22.42", "22.61", "22.77", "22.91", "23.08", "23.21", "23.38", "23.53", "23.65", "23.71", "23.68", "23.61", "23.51", "23.43", "23.28", "23.13",
+	}},
+	"EMA": {movingAverageCloses, []string{
+		"22.22", "22.21", "22.24", "22.27", "22.33", "22.52", "22.80", "22.97", "23.13", "23.28", "23.34", "23.43", "23.51", "23.53", "23.47", "23.40", "23.39", "23.26", "23.23", "23.08", "22.92",
+	}},
+	"RSI": {rsiCloses, []string{
+		"70.53", "66.32", "66.55", "69.41", "66.36", "57.97", "62.93", "63.26", "56.06", "62.38", "54.71", "50.42", "39.99", "41.46", "41.87", "45.46", "37.30", "33.08", "37.77",
+	}},
+	"WMA": {movingAverageCloses, []string{
+		"22.2429", "22.2300", "22.2629", "22.2904", "22.3542", "22.5464",
+		"22.8425", "23.0493", "23.2429", "23.4329", "23.5336", "23.6445",
+		"23.7342", "23.7569", "23.6729", "23.5620", "23.4976", "23.3282",
+		"23.2545", "23.0669", "22.8656",
+	}},
+	// 5, 10, 4: MACD/Signal/Histogram
+	"MACD": {movingAverageCloses, []string{
+		"0.0487/0.0396/0.0091", "0.0845/0.0576/0.0269", "0.2126/0.1196/0.0930", "0.3741/0.2214/0.1527", "0.3941/0.2904/0.1036", "0.3932/0.3315/0.0616",
+		"0.3871/0.3538/0.0333", "0.3118/0.3370/-0.0252", "0.2806/0.3144/-0.0338", "0.2542/0.2903/-0.0361", "0.1910/0.2506/-0.0596", "0.0753/0.1805/-0.1052",
+		"-0.0060/0.1059/-0.1119", "-0.0152/0.0575/-0.0726", "-0.1177/-0.0126/-0.1051", "-0.1029/-0.0487/-0.0542", "-0.1946/-0.1071/-0.0875", "-0.2677/-0.1713/-0.0964",
+	}},
+	// 10, 2: Upper/Middle/Lower
+	"BollingerBands": {movingAverageCloses, []string{
+		"22.4051/22.2210/22.0369", "22.3944/22.2090/22.0236", "22.4428/22.2290/22.0152", "22.4648/22.2590/22.0532", "22.5871/22.3030/22.0189", "23.1036/22.4210/21.7384",
+		"23.7732/22.6130/21.4528", "24.0734/22.7650/21.4566", "24.3341/22.9050/21.4759", "24.5543/23.0760/21.5977", "24.6204/23.2100/21.7996", "24.6328/23.3770/22.1212",
+		"24.6191/23.5250/22.4309", "24.4358/23.6520/22.8682", "24.2119/23.7100/23.2081", "24.2748/23.6840/23.0932", "24.1820/23.6120/23.0420", "24.2917/23.5050/22.7183",
+		"24.2200/23.4320/22.6440", "24.1954/23.2770/22.3586", "24.2258/23.1310/22.0362",
+	}},
+	"ATR": {movingAverageCloses, []string{
+		"0.4000", "0.4000", "0.3800", "0.4040", "0.4032", "0.3826",
+		"0.4060", "0.4328", "0.4063", "0.4250", "0.5500", "0.5980",
+		"0.5984", "0.5587", "0.5070", "0.5296", "0.5217", "0.4773",
+		"0.4859", "0.5007", "0.4606", "0.4684", "0.5248", "0.5238",
+		"0.6190", "0.5752",
+	}},
+	// 5, 3: K/D
+	"Stochastic": {movingAverageCloses, []string{
+		"63.6364/55.9715", "73.3333/60.8081", "48.0000/61.6566", "43.3333/54.8889", "38.4615/43.2650", "64.2857/48.6935",
+		"63.0952/55.2808", "79.1667/68.8492", "83.4254/75.2291", "94.9239/85.8386", "79.6954/86.0149", "82.6087/85.7427",
+		"77.5281/79.9441", "36.5854/65.5741", "61.2500/58.4545", "67.5000/55.1118", "40.5063/56.4188", "9.7087/39.2384",
+		"16.3934/22.2028", "40.1869/22.0964", "7.8740/21.4848", "54.7368/34.2659", "20.9790/27.8633", "6.8493/27.5217",
+	}},
+	"OBV": {movingAverageCloses, []string{
+		"0", "-110", "-230", "-100", "40", "-60",
+		"50", "170", "40", "180", "80", "190",
+		"70", "200", "340", "440", "330", "450",
+		"580", "440", "540", "650", "530", "400",
+		"260", "360", "250", "370", "240", "100",
+	}},
+	"VWAP": {movingAverageCloses, []string{
+		"22.1613", "22.1547", "22.1620", "22.2120", "22.2488", "22.2745",
+		"22.2612", "22.3088", "22.2988", "22.3573", "22.6303", "22.9470",
+		"23.1780", "23.4880", "23.7783", "23.8180", "23.7963", "23.8183",
+		"23.7623", "23.6193", "23.4957", "23.3973", "23.1975", "23.0875",
+		"22.8947", "22.7010",
+	}},
+}
+
+func TestGolden(t *testing.T) {
+	for name, indicator := range indicators() {
+		t.Run(name, func(t *testing.T) {
+			values, err := indicator.batch(candles(golden[name].closes))
+			if err != nil {
+				t.Fatal(err)
+			}
+			expected := golden[name].expected
+			if strings.Join(values, ",") != strings.Join(expected, ",") {
+				t.Errorf("expected\n%v\ngot\n%v", expected, values)
+			}
+		})
+	}
+}
+
+func TestStreamingMatchesBatch(t *testing.T) {
+	for name, indicator := range indicators() {
+		t.Run(name, func(t *testing.T) {
+			data := candles(golden[name].closes)
+			batch, err := indicator.batch(data)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			update, err := indicator.stream()
+			if err != nil {
+				t.Fatal(err)
+			}
+			values := []string{}
+			for _, candle := range data {
+				if value, ok := update(candle); ok {
+					values = append(values, value)
+				}
+			}
+			if strings.Join(values, ",") != strings.Join(batch, ",") {
+				t.Errorf("batch\n%v\nstreaming\n%v", batch, values)
+			}
+		})
+	}
+}
+
+// TestLiveCandles feeds a live version of each candle before its final version, with the same
+// time: the values must be the ones of the final candles only
+func TestLiveCandles(t *testing.T) {
+	for name, indicator := range indicators() {
+		t.Run(name, func(t *testing.T) {
+			data := candles(golden[name].closes)
+			batch, err := indicator.batch(data)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			revised := []kraken.OHLCData{}
+			for _, candle := range data {
+				live := candle
+				live.High = candle.High.Add(decimal.NewFromInt(1))
+				live.Low = candle.Low.Sub(decimal.NewFromInt(1))
+				live.Close = candle.Close.Add(decimal.RequireFromString("0.5"))
+				live.VWAP = candle.VWAP.Add(decimal.RequireFromString("0.25"))
+				live.Volume = candle.Volume.Div(decimal.NewFromInt(2))
+				revised = append(revised, live, live, candle)
+			}
+
+			values, err := indicator.batch(revised)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if strings.Join(values, ",") != strings.Join(batch, ",") {
+				t.Errorf("batch\n%v\nwith live candles\n%v", batch, values)
+			}
+
+			update, err := indicator.stream()
+			if err != nil {
+				t.Fatal(err)
+			}
+			streamed := []string{}
+			for i, candle := range revised {
+				value, ok := update(candle)
+				// Only the final version of each candle is kept
+				if ok && i%3 == 2 {
+					streamed = append(streamed, value)
+				}
+			}
+			if strings.Join(streamed, ",") != strings.Join(batch, ",") {
+				t.Errorf("batch\n%v\nstreaming with live candles\n%v", batch, streamed)
+			}
+		})
+	}
+}
@@ -0,0 +1,175 @@
+package indicators
+
+import (
+	kraken "github.com/astaluego/golang-kraken"
+	"github.com/shopspring/decimal"
+)
+
+// SMA is the simple moving average of the close prices
+type SMA struct {
+	period int
+	window *window
+	sum    decimal.Decimal
+	live   live[*SMA]
+}
+
+// NewSMA inits a streaming simple moving average
+func NewSMA(period int) (*SMA, error) {
+	if err := checkPeriod("period", period); err != nil {
+		return nil, err
+	}
+	return &SMA{period: period, window: newWindow(period)}, nil
+}
+
+// Update adds a candle and returns the average, once period candles were added.
+// A candle with the same time as the last one replaces it.
+func (s *SMA) Update(candle kraken.OHLCData) (decimal.Decimal, bool) {
+	if before, ok := s.live.revise(candle.Time, s); ok {
+		before.live = s.live
+		*s = *before
+	}
+	return s.Add(candle.Close)
+}
+
+func (s *SMA) clone() *SMA {
+	return &SMA{period: s.period, window: s.window.clone(), sum: s.sum}
+}
+
+// Add adds a value and returns the average, once period values were added
+func (s *SMA) Add(value decimal.Decimal) (decimal.Decimal, bool) {
+	s.sum = s.sum.Add(value)
+	if evicted, ok := s.window.push(value); ok {
+		s.sum = s.sum.Sub(evicted)
+	}
+
+	if !s.window.full() {
+		return decimal.Zero, false
+	}
+	return s.sum.Div(decimal.NewFromInt(int64(s.period))), true
+}
+
+// SMASeries computes the simple moving average of the close prices
+func SMASeries(data []kraken.OHLCData, period int) ([]Point, error) {
+	sma, err := NewSMA(period)
+	if err != nil {
+		return nil, err
+	}
+	return series(data, sma.Update), nil
+}
+
+// EMA is the exponential moving average of the close prices, seeded with the SMA of the
+// first period values
+type EMA struct {
+	period int
+	alpha  decimal.Decimal
+	seed   *SMA
+	value  decimal.Decimal
+	ready  bool
+	live   live[*EMA]
+}
+
+// NewEMA inits a streaming exponential moving average
+func NewEMA(period int) (*EMA, error) {
+	seed, err := NewSMA(period)
+	if err != nil {
+		return nil, err
+	}
+	return &EMA{
+		period: period,
+		alpha:  two.Div(decimal.NewFromInt(int64(period + 1))),
+		seed:   seed,
+	}, nil
+}
+
+// Update adds a candle and returns the average, once period candles were added.
+// A candle with the same time as the last one replaces it.
+func (e *EMA) Update(candle kraken.OHLCData) (decimal.Decimal, bool) {
+	if before, ok := e.live.revise(candle.Time, e); ok {
+		before.live = e.live
+		*e = *before
+	}
+	return e.Add(candle.Close)
+}
+
+func (e *EMA) clone() *EMA {
+	return &EMA{period: e.period, alpha: e.alpha, seed: e.seed.clone(), value: e.value, ready: e.ready}
+}
+
+// Add adds a value and returns the average, once period values were added
+func (e *EMA) Add(value decimal.Decimal) (decimal.Decimal, bool) {
+	if !e.ready {
+		e.value, e.ready = e.seed.Add(value)
+		return e.value, e.ready
+	}
+
+	e.value = value.Sub(e.value).Mul(e.alpha).Add(e.value)
+	return e.value, true
+}
+
+// EMASeries computes the exponential moving average of the close prices
+func EMASeries(data []kraken.OHLCData, period int) ([]Point, error) {
+	ema, err := NewEMA(period)
+	if err != nil {
+		return nil, err
+	}
+	return series(data, ema.Update), nil
+}
+
+// WMA is the linearly weighted moving average of the close prices, the most recent value
+// having the weight period
+type WMA struct {
+	period int
+	window *window
+	// divisor is period * (period + 1) / 2
+	divisor decimal.Decimal
+	live    live[*WMA]
+}
+
+// NewWMA inits a streaming weighted moving average
+func NewWMA(period int) (*WMA, error) {
+	if err := checkPeriod("period", period); err != nil {
+		return nil, err
+	}
+	return &WMA{
+		period:  period,
+		window:  newWindow(period),
+		divisor: decimal.NewFromInt(int64(period * (period + 1) / 2)),
+	}, nil
+}
+
+// Update adds a candle and returns the average, once period candles were added.
+// A candle with the same time as the last one replaces it.
+func (w *WMA) Update(candle kraken.OHLCData) (decimal.Decimal, bool) {
+	if before, ok := w.live.revise(candle.Time, w); ok {
+		before.live = w.live
+		*w = *before
+	}
+	return w.Add(candle.Close)
+}
+
+func (w *WMA) clone() *WMA {
+	return &WMA{period: w.period, window: w.window.clone(), divisor: w.divisor}
+}
+
+// Add adds a value and returns the average, once period values were added
+func (w *WMA) Add(value decimal.Decimal) (decimal.Decimal, bool) {
+	w.window.push(value)
+	if !w.window.full() {
+		return decimal.Zero, false
+	}
+
+	sum := decimal.Zero
+	for i, v := range w.window.values {
+		sum = sum.Add(v.Mul(decimal.NewFromInt(int64(i + 1))))
+	}
+	return sum.Div(w.divisor), true
+}
+
+// WMASeries computes the weighted moving average of the close prices
+func WMASeries(data []kraken.OHLCData, period int) ([]Point, error) {
+	wma, err := NewWMA(period)
+	if err != nil {
+		return nil, err
+	}
+	return series(data, wma.Update), nil
+}
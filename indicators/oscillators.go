@@ -0,0 +1,262 @@
+package indicators
+
+import (
+	"time"
+
+	kraken "github.com/astaluego/golang-kraken"
+	"github.com/shopspring/decimal"
+)
+
+// RSI is the relative strength index of the close prices, with Wilder's smoothing
+type RSI struct {
+	period    int
+	previous  decimal.Decimal
+	count     int
+	avgGain   decimal.Decimal
+	avgLoss   decimal.Decimal
+	periodDec decimal.Decimal
+	live      live[*RSI]
+}
+
+// NewRSI inits a streaming relative strength index
+func NewRSI(period int) (*RSI, error) {
+	if err := checkPeriod("period", period); err != nil {
+		return nil, err
+	}
+	return &RSI{period: period, periodDec: decimal.NewFromInt(int64(period))}, nil
+}
+
+// Update adds a candle and returns the index, once period + 1 candles were added.
+// A candle with the same time as the last one replaces it.
+func (r *RSI) Update(candle kraken.OHLCData) (decimal.Decimal, bool) {
+	if before, ok := r.live.revise(candle.Time, r); ok {
+		before.live = r.live
+		*r = *before
+	}
+	return r.Add(candle.Close)
+}
+
+func (r *RSI) clone() *RSI {
+	clone := *r
+	clone.live = live[*RSI]{}
+	return &clone
+}
+
+// Add adds a value and returns the index, once period + 1 values were added
+func (r *RSI) Add(value decimal.Decimal) (decimal.Decimal, bool) {
+	r.count++
+	if r.count == 1 {
+		r.previous = value
+		return decimal.Zero, false
+	}
+
+	change := value.Sub(r.previous)
+	r.previous = value
+
+	gain, loss := decimal.Zero, decimal.Zero
+	if change.IsPositive() {
+		gain = change
+	} else {
+		loss = change.Neg()
+	}
+
+	changes := r.count - 1
+	switch {
+	case changes < r.period:
+		r.avgGain = r.avgGain.Add(gain)
+		r.avgLoss = r.avgLoss.Add(loss)
+		return decimal.Zero, false
+	case changes == r.period:
+		// Seed with the simple average of the first changes
+		r.avgGain = r.avgGain.Add(gain).Div(r.periodDec)
+		r.avgLoss = r.avgLoss.Add(loss).Div(r.periodDec)
+	default:
+		previous := r.periodDec.Sub(decimal.NewFromInt(1))
+		r.avgGain = r.avgGain.Mul(previous).Add(gain).Div(r.periodDec)
+		r.avgLoss = r.avgLoss.Mul(previous).Add(loss).Div(r.periodDec)
+	}
+
+	if r.avgLoss.IsZero() {
+		if r.avgGain.IsZero() {
+			return decimal.NewFromInt(50), true
+		}
+		return hundred, true
+	}
+
+	rs := r.avgGain.Div(r.avgLoss)
+	return hundred.Sub(hundred.Div(rs.Add(decimal.NewFromInt(1)))), true
+}
+
+// RSISeries computes the relative strength index of the close prices
+func RSISeries(data []kraken.OHLCData, period int) ([]Point, error) {
+	rsi, err := NewRSI(period)
+	if err != nil {
+		return nil, err
+	}
+	return series(data, rsi.Update), nil
+}
+
+// MACDValue is a value of the moving average convergence divergence
+type MACDValue struct {
+	Time time.Time
+	// MACD is the fast EMA minus the slow EMA
+	MACD decimal.Decimal
+	// Signal is the EMA of the MACD
+	Signal decimal.Decimal
+	// Histogram is the MACD minus the signal
+	Histogram decimal.Decimal
+}
+
+// MACD is the moving average convergence divergence of the close prices
+type MACD struct {
+	fast   *EMA
+	slow   *EMA
+	signal *EMA
+	live   live[*MACD]
+}
+
+// NewMACD inits a streaming moving average convergence divergence (usually 12, 26, 9)
+func NewMACD(fast, slow, signal int) (*MACD, error) {
+	fastEMA, err := NewEMA(fast)
+	if err != nil {
+		return nil, err
+	}
+	slowEMA, err := NewEMA(slow)
+	if err != nil {
+		return nil, err
+	}
+	signalEMA, err := NewEMA(signal)
+	if err != nil {
+		return nil, err
+	}
+	return &MACD{fast: fastEMA, slow: slowEMA, signal: signalEMA}, nil
+}
+
+// Update adds a candle and returns the MACD, once the slow and the signal EMAs are ready.
+// A candle with the same time as the last one replaces it.
+func (m *MACD) Update(candle kraken.OHLCData) (MACDValue, bool) {
+	if before, ok := m.live.revise(candle.Time, m); ok {
+		before.live = m.live
+		*m = *before
+	}
+
+	fast, fastReady := m.fast.Add(candle.Close)
+	slow, slowReady := m.slow.Add(candle.Close)
+	if !fastReady || !slowReady {
+		return MACDValue{}, false
+	}
+
+	macd := fast.Sub(slow)
+	signal, ok := m.signal.Add(macd)
+	if !ok {
+		return MACDValue{}, false
+	}
+
+	return MACDValue{
+		Time:      candle.Time,
+		MACD:      macd,
+		Signal:    signal,
+		Histogram: macd.Sub(signal),
+	}, true
+}
+
+func (m *MACD) clone() *MACD {
+	return &MACD{fast: m.fast.clone(), slow: m.slow.clone(), signal: m.signal.clone()}
+}
+
+// MACDSeries computes the moving average convergence divergence of the close prices
+func MACDSeries(data []kraken.OHLCData, fast, slow, signal int) ([]MACDValue, error) {
+	macd, err := NewMACD(fast, slow, signal)
+	if err != nil {
+		return nil, err
+	}
+
+	values := []MACDValue{}
+	for i, candle := range data {
+		if value, ok := macd.Update(candle); ok {
+			values = appendValue(values, value, revises(data, i))
+		}
+	}
+	return values, nil
+}
+
+// StochasticValue is a value of the stochastic oscillator
+type StochasticValue struct {
+	Time time.Time
+	// K is the position of the close within the high-low range of the last kPeriod candles
+	K decimal.Decimal
+	// D is the simple moving average of K
+	D decimal.Decimal
+}
+
+// Stochastic is the stochastic oscillator
+type Stochastic struct {
+	highs *window
+	lows  *window
+	d     *SMA
+	live  live[*Stochastic]
+}
+
+// NewStochastic inits a streaming stochastic oscillator (usually 14, 3)
+func NewStochastic(kPeriod, dPeriod int) (*Stochastic, error) {
+	if err := checkPeriod("kPeriod", kPeriod); err != nil {
+		return nil, err
+	}
+	d, err := NewSMA(dPeriod)
+	if err != nil {
+		return nil, err
+	}
+	return &Stochastic{highs: newWindow(kPeriod), lows: newWindow(kPeriod), d: d}, nil
+}
+
+// Update adds a candle and returns the oscillator, once kPeriod + dPeriod - 1 candles were added.
+// A candle with the same time as the last one replaces it.
+func (s *Stochastic) Update(candle kraken.OHLCData) (StochasticValue, bool) {
+	if before, ok := s.live.revise(candle.Time, s); ok {
+		before.live = s.live
+		*s = *before
+	}
+
+	s.highs.push(candle.High)
+	s.lows.push(candle.Low)
+	if !s.highs.full() {
+		return StochasticValue{}, false
+	}
+
+	highest, lowest := s.highs.values[0], s.lows.values[0]
+	for i := range s.highs.values {
+		highest = decimal.Max(highest, s.highs.values[i])
+		lowest = decimal.Min(lowest, s.lows.values[i])
+	}
+
+	k := decimal.NewFromInt(50)
+	if spread := highest.Sub(lowest); !spread.IsZero() {
+		k = candle.Close.Sub(lowest).Div(spread).Mul(hundred)
+	}
+
+	d, ok := s.d.Add(k)
+	if !ok {
+		return StochasticValue{}, false
+	}
+	return StochasticValue{Time: candle.Time, K: k, D: d}, true
+}
+
+func (s *Stochastic) clone() *Stochastic {
+	return &Stochastic{highs: s.highs.clone(), lows: s.lows.clone(), d: s.d.clone()}
+}
+
+// StochasticSeries computes the stochastic oscillator
+func StochasticSeries(data []kraken.OHLCData, kPeriod, dPeriod int) ([]StochasticValue, error) {
+	stochastic, err := NewStochastic(kPeriod, dPeriod)
+	if err != nil {
+		return nil, err
+	}
+
+	values := []StochasticValue{}
+	for i, candle := range data {
+		if value, ok := stochastic.Update(candle); ok {
+			values = appendValue(values, value, revises(data, i))
+		}
+	}
+	return values, nil
+}
@@ -0,0 +1,149 @@
+package indicators
+
+import (
+	"time"
+
+	kraken "github.com/astaluego/golang-kraken"
+	"github.com/shopspring/decimal"
+)
+
+// BandsValue is a value of the Bollinger Bands
+type BandsValue struct {
+	Time time.Time
+	// Upper is the middle band plus k standard deviations
+	Upper decimal.Decimal
+	// Middle is the simple moving average
+	Middle decimal.Decimal
+	// Lower is the middle band minus k standard deviations
+	Lower decimal.Decimal
+}
+
+// BollingerBands are the bands at k (population) standard deviations around the simple moving
+// average of the close prices
+type BollingerBands struct {
+	period int
+	k      decimal.Decimal
+	sma    *SMA
+	live   live[*BollingerBands]
+}
+
+// NewBollingerBands inits streaming Bollinger Bands (usually 20, 2)
+func NewBollingerBands(period int, k decimal.Decimal) (*BollingerBands, error) {
+	sma, err := NewSMA(period)
+	if err != nil {
+		return nil, err
+	}
+	return &BollingerBands{period: period, k: k, sma: sma}, nil
+}
+
+// Update adds a candle and returns the bands, once period candles were added.
+// A candle with the same time as the last one replaces it.
+func (b *BollingerBands) Update(candle kraken.OHLCData) (BandsValue, bool) {
+	if before, ok := b.live.revise(candle.Time, b); ok {
+		before.live = b.live
+		*b = *before
+	}
+
+	middle, ok := b.sma.Add(candle.Close)
+	if !ok {
+		return BandsValue{}, false
+	}
+
+	variance := decimal.Zero
+	for _, value := range b.sma.window.values {
+		deviation := value.Sub(middle)
+		variance = variance.Add(deviation.Mul(deviation))
+	}
+	variance = variance.Div(decimal.NewFromInt(int64(b.period)))
+	width := sqrt(variance).Mul(b.k)
+
+	return BandsValue{
+		Time:   candle.Time,
+		Upper:  middle.Add(width),
+		Middle: middle,
+		Lower:  middle.Sub(width),
+	}, true
+}
+
+func (b *BollingerBands) clone() *BollingerBands {
+	return &BollingerBands{period: b.period, k: b.k, sma: b.sma.clone()}
+}
+
+// BollingerBandsSeries computes the Bollinger Bands of the close prices
+func BollingerBandsSeries(data []kraken.OHLCData, period int, k decimal.Decimal) ([]BandsValue, error) {
+	bands, err := NewBollingerBands(period, k)
+	if err != nil {
+		return nil, err
+	}
+
+	values := []BandsValue{}
+	for i, candle := range data {
+		if value, ok := bands.Update(candle); ok {
+			values = appendValue(values, value, revises(data, i))
+		}
+	}
+	return values, nil
+}
+
+// ATR is the average true range, with Wilder's smoothing
+type ATR struct {
+	period    int
+	periodDec decimal.Decimal
+	count     int
+	previous  decimal.Decimal
+	value     decimal.Decimal
+	live      live[*ATR]
+}
+
+// NewATR inits a streaming average true range (usually 14)
+func NewATR(period int) (*ATR, error) {
+	if err := checkPeriod("period", period); err != nil {
+		return nil, err
+	}
+	return &ATR{period: period, periodDec: decimal.NewFromInt(int64(period))}, nil
+}
+
+// Update adds a candle and returns the average true range, once period candles were added.
+// A candle with the same time as the last one replaces it.
+func (a *ATR) Update(candle kraken.OHLCData) (decimal.Decimal, bool) {
+	if before, ok := a.live.revise(candle.Time, a); ok {
+		before.live = a.live
+		*a = *before
+	}
+
+	trueRange := candle.High.Sub(candle.Low)
+	if a.count > 0 {
+		trueRange = decimal.Max(trueRange,
+			candle.High.Sub(a.previous).Abs(),
+			candle.Low.Sub(a.previous).Abs())
+	}
+	a.previous = candle.Close
+	a.count++
+
+	switch {
+	case a.count < a.period:
+		a.value = a.value.Add(trueRange)
+		return decimal.Zero, false
+	case a.count == a.period:
+		// Seed with the simple average of the first true ranges
+		a.value = a.value.Add(trueRange).Div(a.periodDec)
+	default:
+		a.value = a.value.Mul(a.periodDec.Sub(decimal.NewFromInt(1))).Add(trueRange).Div(a.periodDec)
+	}
+	return a.value, true
+}
+
+func (a *ATR) clone() *ATR {
+	clone := *a
+	clone.live = live[*ATR]{}
+	return &clone
+}
+
+// ATRSeries computes the average true range
+func ATRSeries(data []kraken.OHLCData, period int) ([]Point, error) {
+	atr, err := NewATR(period)
+	if err != nil {
+		return nil, err
+	}
+	return series(data, atr.Update), nil
+}
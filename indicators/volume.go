@@ -0,0 +1,112 @@
+package indicators
+
+import (
+	kraken "github.com/astaluego/golang-kraken"
+	"github.com/shopspring/decimal"
+)
+
+// OBV is the on-balance volume: the volume is added when the close rises, and subtracted
+// when it falls
+type OBV struct {
+	started  bool
+	previous decimal.Decimal
+	value    decimal.Decimal
+	live     live[*OBV]
+}
+
+// NewOBV inits a streaming on-balance volume, starting at zero
+func NewOBV() *OBV {
+	return &OBV{}
+}
+
+// Update adds a candle and returns the on-balance volume.
+// A candle with the same time as the last one replaces it.
+func (o *OBV) Update(candle kraken.OHLCData) (decimal.Decimal, bool) {
+	if before, ok := o.live.revise(candle.Time, o); ok {
+		before.live = o.live
+		*o = *before
+	}
+
+	if o.started {
+		switch candle.Close.Cmp(o.previous) {
+		case 1:
+			o.value = o.value.Add(candle.Volume)
+		case -1:
+			o.value = o.value.Sub(candle.Volume)
+		}
+	}
+	o.started = true
+	o.previous = candle.Close
+	return o.value, true
+}
+
+func (o *OBV) clone() *OBV {
+	clone := *o
+	clone.live = live[*OBV]{}
+	return &clone
+}
+
+// OBVSeries computes the on-balance volume
+func OBVSeries(data []kraken.OHLCData) []Point {
+	return series(data, NewOBV().Update)
+}
+
+// VWAP is the rolling volume weighted average price over the last period candles,
+// computed from the VWAP and the volume of each candle
+type VWAP struct {
+	notionals *window
+	volumes   *window
+	notional  decimal.Decimal
+	volume    decimal.Decimal
+	live      live[*VWAP]
+}
+
+// NewVWAP inits a streaming rolling volume weighted average price
+func NewVWAP(period int) (*VWAP, error) {
+	if err := checkPeriod("period", period); err != nil {
+		return nil, err
+	}
+	return &VWAP{notionals: newWindow(period), volumes: newWindow(period)}, nil
+}
+
+// Update adds a candle and returns the average price, once period candles were added.
+// Without any volume over the period, the close of the candle is returned.
+// A candle with the same time as the last one replaces it.
+func (v *VWAP) Update(candle kraken.OHLCData) (decimal.Decimal, bool) {
+	if before, ok := v.live.revise(candle.Time, v); ok {
+		before.live = v.live
+		*v = *before
+	}
+
+	notional := candle.VWAP.Mul(candle.Volume)
+
+	v.notional = v.notional.Add(notional)
+	if evicted, ok := v.notionals.push(notional); ok {
+		v.notional = v.notional.Sub(evicted)
+	}
+	v.volume = v.volume.Add(candle.Volume)
+	if evicted, ok := v.volumes.push(candle.Volume); ok {
+		v.volume = v.volume.Sub(evicted)
+	}
+
+	if !v.volumes.full() {
+		return decimal.Zero, false
+	}
+	if v.volume.IsZero() {
+		return candle.Close, true
+	}
+	return v.notional.Div(v.volume), true
+}
+
+func (v *VWAP) clone() *VWAP {
+	return &VWAP{notionals: v.notionals.clone(), volumes: v.volumes.clone(), notional: v.notional, volume: v.volume}
+}
+
+// VWAPSeries computes the rolling volume weighted average price
+func VWAPSeries(data []kraken.OHLCData, period int) ([]Point, error) {
+	vwap, err := NewVWAP(period)
+	if err != nil {
+		return nil, err
+	}
+	return series(data, vwap.Update), nil
+}
@@ -2,6 +2,7 @@ package kraken
 
 import (
 	"encoding/json"
+	"fmt"
 	"strings"
 	"time"
 
@@ -18,7 +19,7 @@ func (a *AssetPairsInfo) UnmarshalJSON(data []byte) error {
 	}{
 		Alias: (*Alias)(a),
 	}
-	if err := json.Unmarshal(data, &aux); err != nil {
+	if err := json.Unmarshal(data, aux); err != nil {
 		return err
 	}
 
@@ -67,10 +68,36 @@ func (t *AssetTickerInfo) UnmarshalJSON(data []byte) error {
 	}{
 		Alias: (*Alias)(t),
 	}
-	if err := json.Unmarshal(data, &aux); err != nil {
+	if err := json.Unmarshal(data, aux); err != nil {
 		return err
 	}
 
+	const typeName = "AssetTickerInfo"
+	checks := []struct {
+		field  string
+		length int
+		actual int
+	}{
+		{"Ask", 3, len(aux.Ask)},
+		{"Bid", 3, len(aux.Bid)},
+		{"LastTradeClosed", 2, len(aux.LastTradeClosed)},
+		{"Volume", 2, len(aux.Volume)},
+		{"VWAP", 2, len(aux.VWAP)},
+		{"CountTrades", 2, len(aux.CountTrades)},
+		{"Low", 2, len(aux.Low)},
+		{"High", 2, len(aux.High)},
+	}
+	for _, check := range checks {
+		if check.actual < check.length {
+			return &ParseError{
+				Type:  typeName,
+				Field: check.field,
+				Value: string(data),
+				Err:   fmt.Errorf("expected %d elements, got %d", check.length, check.actual),
+			}
+		}
+	}
+
 	// Parse a
 	t.Ask.Price = aux.Ask[0]
 	t.Ask.WholeLotVolume = aux.Ask[1]
@@ -121,7 +148,7 @@ func (o *Order) UnmarshalJSON(data []byte) error {
 	}{
 		Alias: (*Alias)(o),
 	}
-	if err := json.Unmarshal(data, &aux); err != nil {
+	if err := json.Unmarshal(data, aux); err != nil {
 		return err
 	}
 
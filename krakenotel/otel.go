@@ -0,0 +1,53 @@
+// Package krakenotel traces the calls made by a kraken.Client with OpenTelemetry
+package krakenotel
+
+import (
+	"fmt"
+	"strings"
+
+	kraken "github.com/astaluego/golang-kraken"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const instrumentationName = "github.com/astaluego/golang-kraken"
+
+// Tracing starts a span for every call made by the Client.
+// When tracer is nil, the global TracerProvider is used.
+// Credentials are never recorded.
+func Tracing(tracer trace.Tracer) kraken.Middleware {
+	if tracer == nil {
+		tracer = otel.GetTracerProvider().Tracer(instrumentationName)
+	}
+
+	return func(next kraken.Handler) kraken.Handler {
+		return func(call *kraken.Call) error {
+			ctx, span := tracer.Start(call.Request.Context(), fmt.Sprintf("kraken.%s", call.Endpoint),
+				trace.WithSpanKind(trace.SpanKindClient),
+				trace.WithAttributes(
+					attribute.String("kraken.endpoint", call.Endpoint),
+					attribute.Bool("kraken.private", call.Private),
+					attribute.Int("kraken.cost", call.Cost),
+					attribute.String("http.request.method", call.Request.Method),
+					attribute.String("url.full", call.Request.URL.String()),
+				),
+			)
+			defer span.End()
+
+			call.Request = call.Request.WithContext(ctx)
+			err := next(call)
+
+			span.SetAttributes(attribute.Int("http.response.status_code", call.StatusCode))
+			if len(call.Errors) > 0 {
+				span.SetAttributes(attribute.String("kraken.errors", strings.Join(call.Errors, ",")))
+			}
+			if err != nil {
+				span.RecordError(err)
+				span.SetStatus(codes.Error, err.Error())
+			}
+			return err
+		}
+	}
+}
@@ -0,0 +1,81 @@
+package krakenotel_test
+
+import (
+	"encoding/json"
+	"testing"
+
+	kraken "github.com/astaluego/golang-kraken"
+	"github.com/astaluego/golang-kraken/krakenotel"
+	"github.com/astaluego/golang-kraken/krakentest"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+)
+
+func TestTracing(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+
+	server := krakentest.NewServer()
+	defer server.Close()
+	client := server.NewClient(kraken.WithMiddlewares(krakenotel.Tracing(provider.Tracer("test"))))
+
+	if _, err := client.ServerTime(); err != nil {
+		t.Fatal(err)
+	}
+	server.Enqueue("Balance", krakentest.Response{Result: json.RawMessage(`{}`), Errors: []string{"EAPI:Invalid nonce"}})
+	if _, err := client.Balance(); err == nil {
+		t.Fatal("expected an error")
+	}
+
+	spans := recorder.Ended()
+	if len(spans) != 2 {
+		t.Fatalf("expected 2 spans, got %d", len(spans))
+	}
+
+	public := spans[0]
+	if public.Name() != "kraken.Time" || public.SpanKind() != trace.SpanKindClient || public.Status().Code == codes.Error {
+		t.Errorf("unexpected span: %s %s %v", public.Name(), public.SpanKind(), public.Status())
+	}
+	checkAttributes(t, public.Attributes(), map[attribute.Key]attribute.Value{
+		"kraken.endpoint":           attribute.StringValue("Time"),
+		"kraken.private":            attribute.BoolValue(false),
+		"http.request.method":       attribute.StringValue("POST"),
+		"http.response.status_code": attribute.IntValue(200),
+	})
+
+	private := spans[1]
+	if private.Name() != "kraken.Balance" || private.Status().Code != codes.Error || len(private.Events()) == 0 {
+		t.Errorf("unexpected span: %s %v %v", private.Name(), private.Status(), private.Events())
+	}
+	checkAttributes(t, private.Attributes(), map[attribute.Key]attribute.Value{
+		"kraken.private": attribute.BoolValue(true),
+		"kraken.errors":  attribute.StringValue("EAPI:Invalid nonce"),
+	})
+
+	// The credentials are never recorded
+	for _, span := range spans {
+		for _, kv := range span.Attributes() {
+			value := kv.Value.Emit()
+			if value == server.Secret() || value == krakentest.Key {
+				t.Errorf("credentials recorded in %s", kv.Key)
+			}
+		}
+	}
+}
+
+func checkAttributes(t *testing.T, attributes []attribute.KeyValue, expected map[attribute.Key]attribute.Value) {
+	t.Helper()
+
+	found := make(map[attribute.Key]attribute.Value)
+	for _, kv := range attributes {
+		found[kv.Key] = kv.Value
+	}
+	for key, value := range expected {
+		if found[key] != value {
+			t.Errorf("%s: expected %s, got %s", key, value.Emit(), found[key].Emit())
+		}
+	}
+}
@@ -0,0 +1,158 @@
+package krakentest
+
+import (
+	"encoding/json"
+	"net/url"
+	"time"
+)
+
+// defaultResponses are the fixtures answered by a new Server
+func defaultResponses() map[string]HandlerFunc {
+	handlers := map[string]HandlerFunc{
+		"Time": func(url.Values) Response {
+			now := time.Now().UTC()
+			return Response{Result: map[string]interface{}{
+				"unixtime": now.Unix(),
+				"rfc1123":  now.Format(time.RFC1123),
+			}}
+		},
+		"SystemStatus": func(url.Values) Response {
+			return Response{Result: map[string]interface{}{
+				"status":    "online",
+				"timestamp": time.Now().UTC().Format(time.RFC3339),
+			}}
+		},
+	}
+
+	for endpoint, fixture := range fixtures {
+		handlers[endpoint] = staticHandler(Response{Result: json.RawMessage(fixture)})
+	}
+
+	return handlers
+}
+
+var fixtures = map[string]string{
+	"Assets": `{
+		"XXBT": {"aclass": "currency", "altname": "XBT", "decimals": 10, "display_decimals": 5, "collateral_value": 1, "status": "enabled"},
+		"XETH": {"aclass": "currency", "altname": "ETH", "decimals": 10, "display_decimals": 5, "collateral_value": 1, "status": "enabled"},
+		"ZUSD": {"aclass": "currency", "altname": "USD", "decimals": 4, "display_decimals": 2, "collateral_value": 1, "status": "enabled"},
+		"ZEUR": {"aclass": "currency", "altname": "EUR", "decimals": 4, "display_decimals": 2, "collateral_value": 1, "status": "enabled"}
+	}`,
+	"AssetPairs": `{
+		"XXBTZUSD": {
+			"altname": "XBTUSD", "wsname": "XBT/USD", "aclass_base": "currency", "base": "XXBT", "aclass_quote": "currency", "quote": "ZUSD",
+			"pair_decimals": 1, "cost_decimals": 5, "lot_decimals": 8, "lot_multiplier": 1,
+			"leverage_buy": [2, 3, 4, 5], "leverage_sell": [2, 3, 4, 5],
+			"fees": [[0, 0.26], [50000, 0.24], [100000, 0.22]], "fees_maker": [[0, 0.16], [50000, 0.14], [100000, 0.12]],
+			"fee_volume_currency": "ZUSD", "margin_call": 80, "margin_stop": 40,
+			"ordermin": "0.0001", "costmin": "0.5", "tick_size": "0.1", "status": "online",
+			"long_position_limit": 270, "short_position_limit": 180
+		},
+		"XETHZEUR": {
+			"altname": "ETHEUR", "wsname": "ETH/EUR", "aclass_base": "currency", "base": "XETH", "aclass_quote": "currency", "quote": "ZEUR",
+			"pair_decimals": 2, "cost_decimals": 5, "lot_decimals": 8, "lot_multiplier": 1,
+			"leverage_buy": [2, 3, 4, 5], "leverage_sell": [2, 3, 4, 5],
+			"fees": [[0, 0.26], [50000, 0.24], [100000, 0.22]], "fees_maker": [[0, 0.16], [50000, 0.14], [100000, 0.12]],
+			"fee_volume_currency": "ZUSD", "margin_call": 80, "margin_stop": 40,
+			"ordermin": "0.01", "costmin": "0.5", "tick_size": "0.01", "status": "online",
+			"long_position_limit": 3000, "short_position_limit": 2000
+		}
+	}`,
+	"Ticker": `{
+		"XXBTZUSD": {
+			"a": ["30300.10000", "1", "1.000"], "b": ["30300.00000", "1", "1.000"], "c": ["30303.20000", "0.00067643"],
+			"v": ["4083.67001100", "4412.73601799"], "p": ["30706.77771", "30689.13205"], "t": [34619, 38907],
+			"l": ["29868.30000", "29868.30000"], "h": ["31631.00000", "31631.00000"], "o": "30502.80000"
+		}
+	}`,
+	"OHLC": `{
+		"XXBTZUSD": [
+			[1688671200, "30306.1", "30306.2", "30305.7", "30305.7", "30306.1", "3.39243896", 23],
+			[1688671260, "30304.5", "30304.5", "30300.0", "30300.0", "30300.7", "4.42996871", 18]
+		],
+		"last": 1688671200
+	}`,
+	"Depth": `{
+		"XXBTZUSD": {
+			"asks": [["30384.10000", "2.059", 1688671659], ["30387.90000", "1.500", 1688671380]],
+			"bids": [["30297.00000", "1.115", 1688671636], ["30296.70000", "2.002", 1688671674]]
+		}
+	}`,
+	"Trades": `{
+		"XXBTZUSD": [
+			["30243.40000", "0.34507674", 1688669597.8277369, "b", "m", "", 61044952],
+			["30243.30000", "0.00376960", 1688669598.2804112, "s", "l", "", 61044953]
+		],
+		"last": "1688669598280411228"
+	}`,
+	"Spread": `{
+		"XXBTZUSD": [
+			[1688671834, "30292.10000", "30297.50000"],
+			[1688671834, "30292.10000", "30296.70000"]
+		],
+		"last": 1688671834
+	}`,
+	"Balance": `{
+		"ZUSD": "171288.6158",
+		"ZEUR": "504861.8946",
+		"XXBT": "1011.1908877900",
+		"XETH": "818.5500000000"
+	}`,
+	"BalanceEx": `{
+		"ZUSD": {"balance": "171288.6158", "hold_trade": "8249.7600"},
+		"ZEUR": {"balance": "504861.8946", "hold_trade": "0.0000"},
+		"XXBT": {"balance": "1011.1908877900", "hold_trade": "0.5000000000"},
+		"XETH": {"balance": "818.5500000000", "hold_trade": "0.0000000000"}
+	}`,
+	"TradeBalance": `{
+		"eb": "1101.3425", "tb": "392.2264", "m": "7.0354", "n": "-10.0232", "c": "21.1063",
+		"v": "31.1297", "e": "382.2032", "mf": "375.1678", "ml": "5432.57", "uv": "0"
+	}`,
+	"OpenOrders": `{
+		"open": {
+			"OQCLML-BW3P3-BUCMWZ": {
+				"refid": null, "userref": 0, "status": "open", "opentm": 1688666559.8974, "starttm": 0, "expiretm": 0,
+				"descr": {
+					"pair": "XBTUSD", "type": "buy", "ordertype": "limit", "price": "30010.0", "price2": "0",
+					"leverage": "none", "order": "buy 1.25000000 XBTUSD @ limit 30010.0", "close": ""
+				},
+				"vol": "1.25000000", "vol_exec": "0.37500000", "cost": "11253.7", "fee": "0.00000", "price": "30010.0",
+				"stopprice": "0.00000", "limitprice": "0.00000", "misc": "", "oflags": "fciq"
+			}
+		}
+	}`,
+	"ClosedOrders": `{
+		"closed": {
+			"O37652-RJWRT-IMO74O": {
+				"refid": null, "userref": 1, "status": "canceled", "reason": "User requested",
+				"opentm": 1688148493.7708, "closetm": 1688148610.0482, "starttm": 0, "expiretm": 0,
+				"descr": {
+					"pair": "XBTGBP", "type": "buy", "ordertype": "stop-loss-limit", "price": "23667.0", "price2": "0",
+					"leverage": "none", "order": "buy 0.00100000 XBTGBP @ limit 23667.0", "close": ""
+				},
+				"vol": "0.00100000", "vol_exec": "0.00000000", "cost": "0.00000", "fee": "0.00000", "price": "0.00000",
+				"stopprice": "0.00000", "limitprice": "0.00000", "misc": "", "oflags": "fciq"
+			}
+		},
+		"count": 1
+	}`,
+	"QueryOrders": `{
+		"OBCMZD-JIEE7-77TH3F": {
+			"refid": null, "userref": 0, "status": "closed", "reason": null,
+			"opentm": 1688665496.7808, "closetm": 1688665499.1922, "starttm": 0, "expiretm": 0,
+			"descr": {
+				"pair": "XBTUSD", "type": "buy", "ordertype": "stop-loss-limit", "price": "27500.0", "price2": "0",
+				"leverage": "none", "order": "buy 1.25000000 XBTUSD @ limit 27500.0", "close": ""
+			},
+			"vol": "1.25000000", "vol_exec": "1.25000000", "cost": "27526.2", "fee": "26.2", "price": "27500.0",
+			"stopprice": "0.00000", "limitprice": "0.00000", "misc": "", "oflags": "fciq"
+		}
+	}`,
+	"AddOrder": `{
+		"descr": {"order": "buy 1.25000000 XBTUSD @ limit 27500.0"},
+		"txid": ["OU22CG-KLAF2-FWUDD7"]
+	}`,
+	"CancelOrder": `{
+		"count": 1
+	}`,
+}
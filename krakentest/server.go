@@ -0,0 +1,281 @@
+// Package krakentest provides an in-process fake Kraken server, so code using this library
+// can be tested without hitting api.kraken.com.
+package krakentest
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	kraken "github.com/astaluego/golang-kraken"
+)
+
+// Key is the API key accepted by the server
+const Key = "krakentest-key"
+
+// Response is a scripted response of the server
+type Response struct {
+	// Result is marshalled as the "result" field (use json.RawMessage to send raw JSON)
+	Result interface{}
+	// Errors are sent in the "error" field (e.g. EAPI:Rate limit exceeded)
+	Errors []string
+	// Status is the HTTP status code
+	// Default: 200
+	Status int
+	// Latency is waited before answering
+	Latency time.Duration
+}
+
+// HandlerFunc builds the response of an endpoint from the form sent by the client
+type HandlerFunc func(form url.Values) Response
+
+// Server is a fake Kraken server implementing the public and private REST endpoints.
+// Private requests are authenticated exactly like Kraken does: API-Key, API-Sign over the raw body and nonce.
+type Server struct {
+	server *httptest.Server
+	secret string
+
+	mu       sync.Mutex
+	handlers map[string]HandlerFunc
+	queues   map[string][]Response
+	calls    map[string][]url.Values
+	nonce    int64
+}
+
+// NewServer starts a new fake Kraken server, answering with default fixtures
+// until the endpoints are scripted with Handle, HandleFunc or Enqueue.
+func NewServer() *Server {
+	secret := make([]byte, 64)
+	if _, err := rand.Read(secret); err != nil {
+		panic(fmt.Sprintf("krakentest: failed to generate secret: %s", err.Error()))
+	}
+
+	s := &Server{
+		secret:   base64.StdEncoding.EncodeToString(secret),
+		handlers: make(map[string]HandlerFunc),
+		queues:   make(map[string][]Response),
+		calls:    make(map[string][]url.Values),
+	}
+	for endpoint, handler := range defaultResponses() {
+		s.handlers[endpoint] = handler
+	}
+
+	s.server = httptest.NewServer(http.HandlerFunc(s.serveHTTP))
+	return s
+}
+
+// URL returns the base URL of the server
+func (s *Server) URL() string {
+	return s.server.URL
+}
+
+// Secret returns the API secret accepted by the server
+func (s *Server) Secret() string {
+	return s.secret
+}
+
+// Close shuts down the server
+func (s *Server) Close() {
+	s.server.Close()
+}
+
+// NewClient returns a Client wired to the server, authenticated with its key and secret
+func (s *Server) NewClient(options ...kraken.Option) *kraken.Client {
+	options = append([]kraken.Option{
+		kraken.WithBaseURL(s.server.URL),
+		kraken.WithHTTPClient(s.server.Client()),
+		kraken.WithCredentials(Key, s.secret),
+	}, options...)
+
+	return kraken.NewWithOptions(options...)
+}
+
+// Handle sets the response of an endpoint (e.g. Ticker, Balance)
+func (s *Server) Handle(endpoint string, response Response) {
+	s.HandleFunc(endpoint, staticHandler(response))
+}
+
+// HandleFunc sets the function building the response of an endpoint
+func (s *Server) HandleFunc(endpoint string, handler HandlerFunc) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.handlers[endpoint] = handler
+}
+
+// Enqueue queues responses returned once each, in order, before the endpoint falls back
+// to its handler
+func (s *Server) Enqueue(endpoint string, responses ...Response) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.queues[endpoint] = append(s.queues[endpoint], responses...)
+}
+
+// Calls returns the forms received by an endpoint, in order
+func (s *Server) Calls(endpoint string) []url.Values {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return append([]url.Values{}, s.calls[endpoint]...)
+}
+
+func (s *Server) serveHTTP(w http.ResponseWriter, r *http.Request) {
+	// Path: /<version>/<public|private>/<endpoint>
+	parts := strings.Split(strings.Trim(r.URL.Path, "/"), "/")
+	if len(parts) != 3 || (parts[1] != "public" && parts[1] != "private") {
+		http.NotFound(w, r)
+		return
+	}
+	endpoint := parts[2]
+
+	body, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		writeResponse(w, Response{Status: http.StatusBadRequest})
+		return
+	}
+	form, err := url.ParseQuery(string(body))
+	if err != nil {
+		writeResponse(w, Response{Errors: []string{"EGeneral:Invalid arguments"}})
+		return
+	}
+
+	if parts[1] == "private" {
+		if errorCode := s.authenticate(r, form, body); errorCode != "" {
+			writeResponse(w, Response{Errors: []string{errorCode}})
+			return
+		}
+	}
+
+	response, ok := s.response(endpoint, form)
+	if !ok {
+		writeResponse(w, Response{Errors: []string{"EGeneral:Unknown method"}})
+		return
+	}
+
+	if response.Latency > 0 {
+		select {
+		case <-time.After(response.Latency):
+		case <-r.Context().Done():
+			return
+		}
+	}
+	writeResponse(w, response)
+}
+
+// authenticate verifies a private request and returns the Kraken error code on failure.
+// The signature covers the raw body, as sent by the client.
+func (s *Server) authenticate(r *http.Request, form url.Values, body []byte) string {
+	if r.Header.Get("API-Key") != Key {
+		return "EAPI:Invalid key"
+	}
+
+	nonce, err := strconv.ParseInt(form.Get("nonce"), 10, 64)
+	if err != nil {
+		return "EAPI:Invalid nonce"
+	}
+
+	expected, err := SignBody(s.secret, r.URL.Path, form.Get("nonce"), body)
+	if err != nil || !hmac.Equal([]byte(expected), []byte(r.Header.Get("API-Sign"))) {
+		return "EAPI:Invalid signature"
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if nonce <= s.nonce {
+		return "EAPI:Invalid nonce"
+	}
+	s.nonce = nonce
+
+	return ""
+}
+
+func (s *Server) response(endpoint string, form url.Values) (Response, bool) {
+	s.mu.Lock()
+	s.calls[endpoint] = append(s.calls[endpoint], form)
+
+	if queue := s.queues[endpoint]; len(queue) > 0 {
+		s.queues[endpoint] = queue[1:]
+		s.mu.Unlock()
+		return queue[0], true
+	}
+
+	handler, ok := s.handlers[endpoint]
+	s.mu.Unlock()
+	if !ok {
+		return Response{}, false
+	}
+
+	return handler(form), true
+}
+
+// Sign computes the API-Sign header of a private request whose body is the encoded form
+func Sign(secret string, path string, form url.Values) (string, error) {
+	return SignBody(secret, path, form.Get("nonce"), []byte(form.Encode()))
+}
+
+// SignBody computes the API-Sign header of a private request:
+// HMAC-SHA512 of (URI path + SHA256(nonce + POST data)) with the base64-decoded secret
+func SignBody(secret string, path string, nonce string, body []byte) (string, error) {
+	sha := sha256.Sum256(append([]byte(nonce), body...))
+
+	key, err := base64.StdEncoding.DecodeString(secret)
+	if err != nil {
+		return "", err
+	}
+
+	mac := hmac.New(sha512.New, key)
+	_, err = mac.Write(append([]byte(path), sha[:]...))
+	if err != nil {
+		return "", err
+	}
+
+	return base64.StdEncoding.EncodeToString(mac.Sum(nil)), nil
+}
+
+func staticHandler(response Response) HandlerFunc {
+	return func(url.Values) Response {
+		return response
+	}
+}
+
+func writeResponse(w http.ResponseWriter, response Response) {
+	status := response.Status
+	if status == 0 {
+		status = http.StatusOK
+	}
+
+	errors := response.Errors
+	if errors == nil {
+		errors = []string{}
+	}
+
+	body, err := json.Marshal(struct {
+		Error  []string    `json:"error"`
+		Result interface{} `json:"result,omitempty"`
+	}{
+		Error:  errors,
+		Result: response.Result,
+	})
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	w.Write(body)
+}
@@ -0,0 +1,153 @@
+package krakentest_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	kraken "github.com/astaluego/golang-kraken"
+	"github.com/astaluego/golang-kraken/krakentest"
+)
+
+// post sends a private request to Balance with a raw body, signed over signedBody
+func post(t *testing.T, server *krakentest.Server, key, nonce, signedBody, body string) []string {
+	t.Helper()
+
+	path := "/0/private/Balance"
+	signature, err := krakentest.SignBody(server.Secret(), path, nonce, []byte(signedBody))
+	if err != nil {
+		t.Fatal(err)
+	}
+	req, err := http.NewRequest(http.MethodPost, server.URL()+path, strings.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("API-Key", key)
+	req.Header.Set("API-Sign", signature)
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+
+	var response struct {
+		Error []string `json:"error"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&response); err != nil {
+		t.Fatal(err)
+	}
+	return response.Error
+}
+
+func TestAuthenticate(t *testing.T) {
+	server := krakentest.NewServer()
+	defer server.Close()
+
+	tests := []struct {
+		name       string
+		key        string
+		nonce      string
+		signedBody string
+		body       string
+		expected   string
+	}{
+		{"valid", krakentest.Key, "10", "nonce=10&asset=XBT", "nonce=10&asset=XBT", ""},
+		// Same form once parsed, but not the body which was signed
+		{"reordered body", krakentest.Key, "11", "asset=XBT&nonce=11", "nonce=11&asset=XBT", "EAPI:Invalid signature"},
+		{"other body", krakentest.Key, "12", "nonce=12&asset=XBT", "nonce=12&asset=ETH", "EAPI:Invalid signature"},
+		{"invalid key", "other", "13", "nonce=13", "nonce=13", "EAPI:Invalid key"},
+		{"missing nonce", krakentest.Key, "", "asset=XBT", "asset=XBT", "EAPI:Invalid nonce"},
+		{"replayed nonce", krakentest.Key, "10", "nonce=10&asset=XBT", "nonce=10&asset=XBT", "EAPI:Invalid nonce"},
+		{"lower nonce", krakentest.Key, "9", "nonce=9", "nonce=9", "EAPI:Invalid nonce"},
+		{"next nonce", krakentest.Key, "14", "nonce=14", "nonce=14", ""},
+	}
+
+	for _, test := range tests {
+		errors := post(t, server, test.key, test.nonce, test.signedBody, test.body)
+		if test.expected == "" && len(errors) > 0 {
+			t.Errorf("%s: unexpected errors %v", test.name, errors)
+		}
+		if test.expected != "" && (len(errors) != 1 || errors[0] != test.expected) {
+			t.Errorf("%s: expected %s, got %v", test.name, test.expected, errors)
+		}
+	}
+
+	// Sign is SignBody over the encoded form
+	form := url.Values{"nonce": {"20"}, "asset": {"XBT"}}
+	signature, err := krakentest.Sign(server.Secret(), "/0/private/Balance", form)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected, _ := krakentest.SignBody(server.Secret(), "/0/private/Balance", "20", []byte(form.Encode()))
+	if signature != expected {
+		t.Errorf("Sign and SignBody differ: %s, %s", signature, expected)
+	}
+}
+
+func TestClientAuthenticated(t *testing.T) {
+	server := krakentest.NewServer()
+	defer server.Close()
+
+	// A client signing with another secret is rejected
+	other := kraken.NewWithOptions(
+		kraken.WithBaseURL(server.URL()),
+		kraken.WithCredentials(krakentest.Key, "c2VjcmV0"),
+	)
+	if _, err := other.Balance(); err == nil || !strings.Contains(err.Error(), "EAPI:Invalid signature") {
+		t.Errorf("expected an invalid signature, got %v", err)
+	}
+
+	client := server.NewClient()
+	for i := 0; i < 3; i++ {
+		if _, err := client.Balance(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if len(server.Calls("Balance")) != 3 {
+		t.Errorf("expected 3 calls, got %d", len(server.Calls("Balance")))
+	}
+}
+
+func TestScriptedResponses(t *testing.T) {
+	server := krakentest.NewServer()
+	defer server.Close()
+	client := server.NewClient()
+
+	server.Enqueue("Time",
+		krakentest.Response{Errors: []string{"EService:Unavailable"}},
+		krakentest.Response{Result: json.RawMessage(`{"unixtime": 1688669448, "rfc1123": "Thu, 06 Jul 23 18:50:48 +0000"}`)},
+	)
+	if _, err := client.ServerTime(); err == nil || !strings.Contains(err.Error(), "EService:Unavailable") {
+		t.Errorf("expected the queued error, got %v", err)
+	}
+	first, err := client.ServerTime()
+	if err != nil || first.Unixtime != 1688669448 {
+		t.Errorf("expected the queued time, got %+v, %v", first, err)
+	}
+
+	// The queue is empty, the handler answers
+	server.Handle("Time", krakentest.Response{Result: json.RawMessage(`{"unixtime": 1700000000, "rfc1123": ""}`)})
+	second, err := client.ServerTime()
+	if err != nil || second.Unixtime != 1700000000 {
+		t.Errorf("expected the handler time, got %+v, %v", second, err)
+	}
+
+	// The latency is waited
+	server.Enqueue("Time", krakentest.Response{Result: json.RawMessage(`{"unixtime": 1, "rfc1123": ""}`), Latency: 50 * time.Millisecond})
+	start := time.Now()
+	if _, err := client.ServerTime(); err != nil {
+		t.Fatal(err)
+	}
+	if time.Since(start) < 50*time.Millisecond {
+		t.Error("expected the latency to be waited")
+	}
+
+	if len(server.Calls("Time")) != 4 {
+		t.Errorf("expected 4 calls, got %d", len(server.Calls("Time")))
+	}
+}
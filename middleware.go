@@ -0,0 +1,154 @@
+package kraken
+
+import (
+	"log/slog"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+const redacted = "REDACTED"
+
+// Call describes a request made to the Kraken API, as seen by the middlewares
+type Call struct {
+	// Endpoint called (e.g. Ticker, Balance)
+	Endpoint string
+	// Private is true for authenticated endpoints
+	Private bool
+	// Request sent to Kraken, already signed
+	Request *http.Request
+	// Form body of the request
+	Form url.Values
+	// Cost of the call on the API rate-limit counter
+	Cost int
+
+	// StatusCode of the HTTP response (0 if no response was received)
+	StatusCode int
+	// Errors returned by Kraken (e.g. EAPI:Rate limit exceeded)
+	Errors []string
+}
+
+// RedactedHeader returns the request headers with API keys and signatures removed
+func (call *Call) RedactedHeader() http.Header {
+	header := call.Request.Header.Clone()
+	// API-Key and API-Sign for Kraken, APIKey and Authent for Kraken Futures
+	for _, key := range []string{"API-Key", "API-Sign", "APIKey", "Authent"} {
+		if header.Get(key) != "" {
+			header.Set(key, redacted)
+		}
+	}
+	return header
+}
+
+// RedactedForm returns the form body with the one-time password removed
+func (call *Call) RedactedForm() url.Values {
+	form := url.Values{}
+	for key, values := range call.Form {
+		if key == "otp" {
+			form.Set(key, redacted)
+			continue
+		}
+		form[key] = append([]string{}, values...)
+	}
+	return form
+}
+
+// Handler executes a Call
+type Handler func(call *Call) error
+
+// Middleware wraps the Handler executing every call made by the Client,
+// e.g. to log, measure or trace it.
+type Middleware func(next Handler) Handler
+
+// WithMiddlewares adds middlewares around every call made by the Client.
+// The first middleware is the outermost one.
+func WithMiddlewares(middlewares ...Middleware) Option {
+	return func(c *Client) {
+		c.middlewares = append(c.middlewares, middlewares...)
+	}
+}
+
+// cost returns the cost of an endpoint on the API rate-limit counter
+// https://docs.kraken.com/rest/#section/Rate-Limits
+func cost(endpoint string, isPrivate bool) int {
+	if !isPrivate {
+		return 0
+	}
+
+	switch endpoint {
+	case "Ledgers", "QueryLedgers", "TradesHistory":
+		return 2
+	case "AddOrder", "AddOrderBatch", "EditOrder", "CancelOrder", "CancelAll", "CancelAllOrdersAfter", "CancelOrderBatch":
+		// Trading endpoints are limited by the matching engine instead
+		return 0
+	}
+	return 1
+}
+
+// Logger logs every call with log/slog: endpoint, latency, status, Kraken errors and cost.
+// Credentials are never logged.
+func Logger(logger *slog.Logger) Middleware {
+	return func(next Handler) Handler {
+		return func(call *Call) error {
+			start := time.Now()
+			err := next(call)
+
+			attrs := []slog.Attr{
+				slog.String("endpoint", call.Endpoint),
+				slog.Bool("private", call.Private),
+				slog.Int("status", call.StatusCode),
+				slog.Duration("latency", time.Since(start)),
+				slog.Int("cost", call.Cost),
+			}
+			if len(call.Errors) > 0 {
+				attrs = append(attrs, slog.Any("errors", call.Errors))
+			}
+
+			ctx := call.Request.Context()
+			if err != nil {
+				attrs = append(attrs, slog.String("error", err.Error()))
+				logger.LogAttrs(ctx, slog.LevelError, "kraken request failed", attrs...)
+				return err
+			}
+
+			if logger.Enabled(ctx, slog.LevelDebug) {
+				attrs = append(attrs, slog.String("form", call.RedactedForm().Encode()))
+			}
+			logger.LogAttrs(ctx, slog.LevelDebug, "kraken request", attrs...)
+			return nil
+		}
+	}
+}
+
+// MetricsCollector receives the measures of every call.
+// It is designed to be backed by Prometheus counters and histograms, or any other metrics system.
+type MetricsCollector interface {
+	// ObserveRequest is called once per call
+	ObserveRequest(endpoint string, status int, latency time.Duration)
+	// IncError is called for each error returned by Kraken, or with "transport" when no response was received
+	IncError(endpoint string, code string)
+	// AddCost is called with the cost of the call on the API rate-limit counter
+	AddCost(endpoint string, cost int)
+}
+
+// Metrics reports every call to the given MetricsCollector
+func Metrics(collector MetricsCollector) Middleware {
+	return func(next Handler) Handler {
+		return func(call *Call) error {
+			start := time.Now()
+			err := next(call)
+
+			collector.ObserveRequest(call.Endpoint, call.StatusCode, time.Since(start))
+			for _, code := range call.Errors {
+				collector.IncError(call.Endpoint, code)
+			}
+			if err != nil && call.StatusCode == 0 {
+				collector.IncError(call.Endpoint, "transport")
+			}
+			if call.Cost > 0 {
+				collector.AddCost(call.Endpoint, call.Cost)
+			}
+			return err
+		}
+	}
+}
@@ -5,6 +5,8 @@ import (
 	"strconv"
 	"strings"
 	"time"
+
+	"github.com/shopspring/decimal"
 )
 
 type Payload url.Values
@@ -77,6 +79,14 @@ func (payload Payload) OptOffset(offset int64) {
 	payload["ofs"] = []string{strconv.FormatInt(offset, 10)}
 }
 
+func (payload Payload) OptOTP(otp string) {
+	if otp == "" {
+		return
+	}
+
+	payload["otp"] = []string{otp}
+}
+
 func (payload Payload) OptSince(time time.Time) {
 	if time.IsZero() {
 		return
@@ -85,6 +95,14 @@ func (payload Payload) OptSince(time time.Time) {
 	payload["since"] = []string{strconv.FormatInt(time.Unix(), 10)}
 }
 
+func (payload Payload) OptSinceNano(time time.Time) {
+	if time.IsZero() {
+		return
+	}
+
+	payload["since"] = []string{strconv.FormatInt(time.UnixNano(), 10)}
+}
+
 func (payload Payload) OptStart(time time.Time) {
 	if time.IsZero() {
 		return
@@ -116,3 +134,87 @@ func (payload Payload) OptUserReferenceID(userReferenceID int64) {
 
 	payload["userref"] = []string{strconv.FormatInt(userReferenceID, 10)}
 }
+
+func (payload Payload) OptPrice(price decimal.Decimal) {
+	if price.IsZero() {
+		return
+	}
+
+	payload["price"] = []string{price.String()}
+}
+
+func (payload Payload) OptPrice2(price decimal.Decimal) {
+	if price.IsZero() {
+		return
+	}
+
+	payload["price2"] = []string{price.String()}
+}
+
+func (payload Payload) OptTrigger(trigger TriggerType) {
+	if trigger == "" {
+		return
+	}
+
+	payload["trigger"] = []string{string(trigger)}
+}
+
+func (payload Payload) OptLeverage(leverage int64) {
+	if leverage == 0 {
+		return
+	}
+
+	payload["leverage"] = []string{strconv.FormatInt(leverage, 10)}
+}
+
+func (payload Payload) OptReduceOnly(reduceOnly bool) {
+	if !reduceOnly {
+		return
+	}
+
+	payload["reduce_only"] = []string{"true"}
+}
+
+func (payload Payload) OptOrderFlags(flags []OrderFlag) {
+	if len(flags) == 0 {
+		return
+	}
+
+	list := []string{}
+	for _, flag := range flags {
+		list = append(list, string(flag))
+	}
+	payload["oflags"] = []string{strings.Join(list, ",")}
+}
+
+func (payload Payload) OptStartTime(time time.Time) {
+	if time.IsZero() {
+		return
+	}
+
+	payload["starttm"] = []string{strconv.FormatInt(time.Unix(), 10)}
+}
+
+func (payload Payload) OptExpireTime(time time.Time) {
+	if time.IsZero() {
+		return
+	}
+
+	payload["expiretm"] = []string{strconv.FormatInt(time.Unix(), 10)}
+}
+
+func (payload Payload) OptValidate(validate bool) {
+	if !validate {
+		return
+	}
+
+	payload["validate"] = []string{"true"}
+}
+
+func (payload Payload) OptClientOrderID(clientOrderID string) {
+	if clientOrderID == "" {
+		return
+	}
+
+	payload["cl_ord_id"] = []string{clientOrderID}
+}
@@ -0,0 +1,1016 @@
+package kraken
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+// closedOrdersPage is the number of orders returned by a call to ClosedOrders
+const closedOrdersPage = 50
+
+// GroupKind is the kind of orders managed together by an OrderManager
+type GroupKind string
+
+const (
+	// OCO is a group of orders where the execution of one cancels the others
+	OCO GroupKind = "oco"
+	// Bracket is an entry order followed by a take-profit and a stop-loss exit (one-cancels-other)
+	Bracket GroupKind = "bracket"
+	// TrailingStop is a stop order following the best price reached
+	TrailingStop GroupKind = "trailing_stop"
+)
+
+// GroupStatus is the status of a group of managed orders
+type GroupStatus string
+
+const (
+	GroupActive GroupStatus = "active"
+	// GroupFilled is a group done with at least one order executed
+	GroupFilled   GroupStatus = "filled"
+	GroupCanceled GroupStatus = "canceled"
+	GroupExpired  GroupStatus = "expired"
+	// GroupFailed is a group whose triggered order was rejected, see ManagedGroup.Error
+	GroupFailed GroupStatus = "failed"
+)
+
+// LegStatus is the status of an order of a group
+type LegStatus string
+
+const (
+	// LegWaiting is an exit of a bracket waiting for the entry to be filled
+	LegWaiting LegStatus = "waiting"
+	// LegWatching is a client-side order waiting for the price to reach its trigger
+	LegWatching LegStatus = "watching"
+	// LegPlacing is an order being sent to Kraken
+	LegPlacing LegStatus = "placing"
+	// LegOpen is an order open on Kraken
+	LegOpen     LegStatus = "open"
+	LegFilled   LegStatus = "filled"
+	LegCanceled LegStatus = "canceled"
+)
+
+// LegRole is the role of an order in a bracket
+type LegRole string
+
+const (
+	RoleEntry      LegRole = "entry"
+	RoleTakeProfit LegRole = "take_profit"
+	RoleStopLoss   LegRole = "stop_loss"
+)
+
+// Trail is the distance of a trailing stop from the best price reached: Offset in quote
+// currency, or Percent of the best price
+type Trail struct {
+	Offset  decimal.Decimal `json:"offset"`
+	Percent decimal.Decimal `json:"percent"`
+}
+
+// IsZero returns true if the stop does not trail
+func (t Trail) IsZero() bool {
+	return !t.Offset.IsPositive() && !t.Percent.IsPositive()
+}
+
+// ManagedLeg is an order of a group
+type ManagedLeg struct {
+	Role  LegRole        `json:"role,omitempty"`
+	Order AddOrderConfig `json:"order"`
+	// ClientSide is set for the stop-loss and take-profit orders triggered by the manager,
+	// they are sent to Kraken as market orders (or limit orders at Price2) once triggered
+	ClientSide bool `json:"client_side"`
+	// Trail of a trailing stop
+	Trail Trail `json:"trail"`
+	// BestPrice is the best price reached by a trailing stop
+	BestPrice decimal.Decimal `json:"best_price"`
+	// StopPrice is the trigger price of a client-side order
+	StopPrice decimal.Decimal `json:"stop_price"`
+	// Triggered is set once a client-side order is triggered
+	Triggered      bool            `json:"triggered"`
+	Status         LegStatus       `json:"status"`
+	TransactionID  string          `json:"txid"`
+	VolumeExecuted decimal.Decimal `json:"vol_exec"`
+	PlacedAt       time.Time       `json:"placed_at"`
+}
+
+// ManagedGroup is a group of orders managed together
+type ManagedGroup struct {
+	ID     string       `json:"id"`
+	Kind   GroupKind    `json:"kind"`
+	Status GroupStatus  `json:"status"`
+	Legs   []ManagedLeg `json:"legs"`
+	// ExpireAt is the time the orders not executed yet are canceled at
+	ExpireAt time.Time `json:"expire_at"`
+	// Error is the last error met while managing the group
+	Error     string    `json:"error,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// OrderManagerState is the state of an OrderManager, to persist and resume from
+type OrderManagerState struct {
+	Sequence int64          `json:"sequence"`
+	Groups   []ManagedGroup `json:"groups"`
+}
+
+type OrderManagerConfig struct {
+	// PollInterval is optional
+	// Pause between two reconciliations with OpenOrders and ClosedOrders done by Run
+	// Default: 5s
+	PollInterval time.Duration
+
+	// State is optional
+	// Resumes the groups of a previous manager
+	State *OrderManagerState
+
+	// OnState is optional
+	// Called after each change, and before each order is sent, so the state can be persisted.
+	// It must not call the manager.
+	OnState func(state OrderManagerState) error
+
+	// OnChange is optional
+	// Called after each change of a group
+	OnChange func(group ManagedGroup)
+
+	// OnError is optional
+	// Called when a reconciliation done by Run fails
+	OnError func(err error)
+
+	// Now is optional
+	// Default: time.Now
+	Now func() time.Time
+}
+
+type OCOConfig struct {
+	// Orders is required
+	// Two or more orders: limit and market orders are sent to Kraken, stop-loss and take-profit
+	// orders are triggered client-side, so they do not lock funds needed by the others
+	Orders []AddOrderConfig
+
+	// ExpireAt is optional
+	// The orders not executed yet are canceled at this time
+	ExpireAt time.Time
+}
+
+type BracketConfig struct {
+	// Entry is required
+	Entry AddOrderConfig
+
+	// TakeProfit is required
+	// Limit price of the exit in profit
+	TakeProfit decimal.Decimal
+
+	// StopLoss is required unless Trail is set
+	// Trigger price of the exit in loss
+	StopLoss decimal.Decimal
+
+	// Trail is optional
+	// Turns the exit in loss into a trailing stop
+	Trail Trail
+
+	// ExpireAt is optional
+	// The orders not executed yet are canceled at this time
+	ExpireAt time.Time
+}
+
+type TrailingStopConfig struct {
+	// AssetPair is required
+	AssetPair AssetPair
+
+	// Type is required
+	// sell to protect a long position, buy to protect a short one
+	Type Type
+
+	// Volume is required
+	Volume decimal.Decimal
+
+	// Trail is required
+	Trail Trail
+
+	// ExpireAt is optional
+	ExpireAt time.Time
+}
+
+// OrderManager manages client-side OCO, bracket and trailing stop orders on top of a Trader.
+// It is fed with live prices by UpdatePrice, and reconciles its orders with Kraken by Poll.
+// It is safe for concurrent use by multiple goroutines.
+type OrderManager struct {
+	trader Trader
+	config OrderManagerConfig
+
+	mu       sync.Mutex
+	sequence int64
+	groups   []*ManagedGroup
+	changed  map[*ManagedGroup]bool
+}
+
+// NewOrderManager inits a new OrderManager, resuming config.State if set
+func NewOrderManager(trader Trader, config OrderManagerConfig) (*OrderManager, error) {
+	if trader == nil {
+		return nil, fmt.Errorf("trader is required")
+	}
+	if config.PollInterval == 0 {
+		config.PollInterval = 5 * time.Second
+	}
+	if config.Now == nil {
+		config.Now = time.Now
+	}
+
+	m := &OrderManager{
+		trader:  trader,
+		config:  config,
+		changed: make(map[*ManagedGroup]bool),
+	}
+	if config.State != nil {
+		m.sequence = config.State.Sequence
+		for _, group := range config.State.Groups {
+			group := group
+			group.Legs = append([]ManagedLeg{}, group.Legs...)
+			m.groups = append(m.groups, &group)
+		}
+	}
+	return m, nil
+}
+
+// AddOCO places a one-cancels-other group and returns its ID
+func (m *OrderManager) AddOCO(config OCOConfig) (string, error) {
+	if len(config.Orders) < 2 {
+		return "", fmt.Errorf("Orders is required (at least 2)")
+	}
+
+	legs := []ManagedLeg{}
+	for _, order := range config.Orders {
+		if err := checkManagedOrder(order); err != nil {
+			return "", err
+		}
+		legs = append(legs, newLeg("", order))
+	}
+	return m.add(OCO, legs, config.ExpireAt)
+}
+
+// AddBracket places an entry order, followed by a take-profit limit order and a client-side
+// stop-loss once the entry is executed, and returns the ID of the group. The exits are resized
+// as the entry is partially executed.
+func (m *OrderManager) AddBracket(config BracketConfig) (string, error) {
+	if err := checkManagedOrder(config.Entry); err != nil {
+		return "", err
+	}
+	if !config.TakeProfit.IsPositive() {
+		return "", fmt.Errorf("TakeProfit is required")
+	}
+	if !config.StopLoss.IsPositive() && config.Trail.IsZero() {
+		return "", fmt.Errorf("StopLoss is required")
+	}
+
+	exit := Sell
+	if config.Entry.Type == Sell {
+		exit = Buy
+	}
+	takeProfit := newLeg(RoleTakeProfit, AddOrderConfig{
+		AssetPair: config.Entry.AssetPair,
+		Type:      exit,
+		OrderType: Limit,
+		Price:     config.TakeProfit,
+	})
+	stopLoss := newLeg(RoleStopLoss, AddOrderConfig{
+		AssetPair: config.Entry.AssetPair,
+		Type:      exit,
+		OrderType: StopLoss,
+		Price:     config.StopLoss,
+	})
+	stopLoss.Trail = config.Trail
+	stopLoss.StopPrice = config.StopLoss
+	takeProfit.Status, stopLoss.Status = LegWaiting, LegWaiting
+
+	return m.add(Bracket, []ManagedLeg{newLeg(RoleEntry, config.Entry), takeProfit, stopLoss}, config.ExpireAt)
+}
+
+// AddTrailingStop starts a client-side trailing stop and returns its ID.
+// The stop starts at the distance of the trail from the first price received.
+func (m *OrderManager) AddTrailingStop(config TrailingStopConfig) (string, error) {
+	if config.Trail.IsZero() {
+		return "", fmt.Errorf("Trail is required")
+	}
+
+	leg := newLeg(RoleStopLoss, AddOrderConfig{
+		AssetPair: config.AssetPair,
+		Type:      config.Type,
+		OrderType: StopLoss,
+		Volume:    config.Volume,
+	})
+	leg.Trail = config.Trail
+	if err := checkManagedOrder(leg.Order); err != nil {
+		return "", err
+	}
+	return m.add(TrailingStop, []ManagedLeg{leg}, config.ExpireAt)
+}
+
+// Cancel cancels the orders of a group not executed yet
+func (m *OrderManager) Cancel(id string) error {
+	m.mu.Lock()
+	defer m.unlock()
+
+	g := m.group(id)
+	if g == nil {
+		return fmt.Errorf("unknown group %s", id)
+	}
+	if g.Status != GroupActive {
+		return nil
+	}
+
+	if err := m.cancelLegs(g, -1); err != nil {
+		return err
+	}
+	m.setStatus(g, GroupCanceled)
+	return m.save()
+}
+
+// Group returns a group by ID
+func (m *OrderManager) Group(id string) (ManagedGroup, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	g := m.group(id)
+	if g == nil {
+		return ManagedGroup{}, false
+	}
+	return g.copy(), true
+}
+
+// Groups returns all the groups, in creation order
+func (m *OrderManager) Groups() []ManagedGroup {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	groups := make([]ManagedGroup, 0, len(m.groups))
+	for _, g := range m.groups {
+		groups = append(groups, g.copy())
+	}
+	return groups
+}
+
+// State returns the state of the manager, to persist and resume from
+func (m *OrderManager) State() OrderManagerState {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return m.state()
+}
+
+// UpdatePrice feeds the last price of a pair: the trailing stops follow it, and the
+// client-side orders whose trigger is reached are sent to Kraken
+func (m *OrderManager) UpdatePrice(pair AssetPair, price decimal.Decimal) error {
+	m.mu.Lock()
+	defer m.unlock()
+
+	m.expire()
+
+	errs := []error{}
+	for _, g := range m.groups {
+		if g.Status != GroupActive {
+			continue
+		}
+		for i := range g.Legs {
+			leg := &g.Legs[i]
+			if leg.Status != LegWatching || leg.Order.AssetPair != pair {
+				continue
+			}
+			if leg.follow(price) {
+				m.touch(g)
+			}
+			if !leg.reached(price) {
+				continue
+			}
+			if err := m.trigger(g, i); err != nil {
+				errs = append(errs, fmt.Errorf("group %s: %w", g.ID, err))
+			}
+		}
+	}
+
+	if err := m.save(); err != nil {
+		errs = append(errs, err)
+	}
+	return errors.Join(errs...)
+}
+
+// Poll reconciles the orders of the active groups with OpenOrders and ClosedOrders, paged
+// through with Offset until all the orders are found, and executes the follow-up actions (e.g. cancel the other orders of an OCO once one is filled)
+func (m *OrderManager) Poll() error {
+	m.mu.Lock()
+	defer m.unlock()
+
+	m.expire()
+
+	since := time.Time{}
+	for _, g := range m.groups {
+		if g.Status == GroupActive && (since.IsZero() || g.CreatedAt.Before(since)) {
+			since = g.CreatedAt
+		}
+	}
+	if since.IsZero() {
+		return nil
+	}
+
+	open, err := m.trader.OpenOrders(OpenOrdersConfig{})
+	if err != nil {
+		return err
+	}
+
+	// Orders sent just before a crash are found by their client order ID
+	byClientID := make(map[string]string)
+	index := func(orders map[string]Order) {
+		for txid, order := range orders {
+			if order.ClientOrderID != "" {
+				byClientID[order.ClientOrderID] = txid
+			}
+		}
+	}
+	index(open)
+
+	// The closed orders are paged through until all the orders of the groups are found
+	closed, err := m.closedOrders(since, func(closed map[string]Order) bool {
+		index(closed)
+		return m.missing(open, closed, byClientID)
+	})
+	if err != nil {
+		return err
+	}
+	index(closed)
+
+	errs := []error{}
+	for _, g := range m.groups {
+		if g.Status != GroupActive {
+			continue
+		}
+
+		for i := range g.Legs {
+			leg := &g.Legs[i]
+			if leg.TransactionID == "" && leg.Status == LegPlacing {
+				leg.TransactionID = byClientID[leg.Order.ClientOrderID]
+				if leg.TransactionID == "" {
+					// The order never reached Kraken
+					leg.Status = LegCanceled
+					m.touch(g)
+					continue
+				}
+			}
+			if leg.TransactionID == "" {
+				continue
+			}
+
+			order, ok := open[leg.TransactionID]
+			if !ok {
+				order, ok = closed[leg.TransactionID]
+			}
+			if ok && leg.apply(order) {
+				m.touch(g)
+			}
+		}
+
+		if err := m.advance(g); err != nil {
+			errs = append(errs, fmt.Errorf("group %s: %w", g.ID, err))
+		}
+	}
+
+	if err := m.save(); err != nil {
+		errs = append(errs, err)
+	}
+	return errors.Join(errs...)
+}
+
+// missing returns true if an order of the active groups is neither open nor closed
+func (m *OrderManager) missing(open, closed map[string]Order, byClientID map[string]string) bool {
+	for _, g := range m.groups {
+		if g.Status != GroupActive {
+			continue
+		}
+		for _, leg := range g.Legs {
+			txid := leg.TransactionID
+			if txid == "" && leg.Status == LegPlacing {
+				txid = byClientID[leg.Order.ClientOrderID]
+				if txid == "" {
+					return true
+				}
+			}
+			if txid == "" || leg.Status != LegOpen && leg.Status != LegPlacing {
+				continue
+			}
+			if _, ok := open[txid]; ok {
+				continue
+			}
+			if _, ok := closed[txid]; !ok {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// closedOrders pages through the orders closed since a time with Offset, until none is
+// missing or the pages are exhausted
+func (m *OrderManager) closedOrders(since time.Time, missing func(closed map[string]Order) bool) (map[string]Order, error) {
+	closed := make(map[string]Order)
+	for {
+		page, err := m.trader.ClosedOrders(ClosedOrdersConfig{Start: since.Add(-time.Minute), Offset: int64(len(closed))})
+		if err != nil {
+			return nil, err
+		}
+		added := 0
+		for txid, order := range page {
+			if _, ok := closed[txid]; !ok {
+				closed[txid] = order
+				added++
+			}
+		}
+
+		if len(page) < closedOrdersPage || added == 0 || !missing(closed) {
+			return closed, nil
+		}
+	}
+}
+
+// Run polls Kraken every PollInterval until the context is done
+func (m *OrderManager) Run(ctx context.Context) error {
+	ticker := time.NewTicker(m.config.PollInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+			if err := m.Poll(); err != nil && m.config.OnError != nil {
+				m.config.OnError(err)
+			}
+		}
+	}
+}
+
+func (m *OrderManager) add(kind GroupKind, legs []ManagedLeg, expireAt time.Time) (string, error) {
+	m.mu.Lock()
+	defer m.unlock()
+
+	m.sequence++
+	now := m.config.Now()
+	g := &ManagedGroup{
+		ID:        fmt.Sprintf("%s-%d", kind, m.sequence),
+		Kind:      kind,
+		Status:    GroupActive,
+		Legs:      legs,
+		ExpireAt:  expireAt,
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+	m.groups = append(m.groups, g)
+	m.touch(g)
+
+	for i := range g.Legs {
+		if g.Legs[i].Status != LegPlacing {
+			continue
+		}
+		if err := m.place(g, i, g.Legs[i].Order); err != nil {
+			// The orders already sent are canceled, the group is kept as failed
+			cancelErr := m.cancelLegs(g, -1)
+			m.setStatus(g, GroupFailed)
+			return g.ID, errors.Join(err, cancelErr, m.save())
+		}
+	}
+	return g.ID, m.save()
+}
+
+// advance executes the follow-up actions of a group after its orders changed
+func (m *OrderManager) advance(g *ManagedGroup) error {
+	legs := g.Legs
+	if g.Kind == Bracket {
+		entry := &g.Legs[0]
+		if !entry.VolumeExecuted.IsPositive() {
+			if entry.isActive() {
+				return nil
+			}
+			// The entry is done without any execution
+			if g.Legs[1].Status == LegWaiting {
+				g.Legs[1].Status, g.Legs[2].Status = LegCanceled, LegCanceled
+				m.setStatus(g, GroupCanceled)
+				return nil
+			}
+		}
+
+		// The exits protect the volume executed, as soon as the entry is partially executed
+		if err := m.protect(g); err != nil {
+			return err
+		}
+		legs = g.Legs[1:]
+	}
+
+	// One cancels the others: the first order executed or triggered wins, and an order
+	// canceled on Kraken cancels the others as well (an order rejected does not)
+	winner, canceled := -1, false
+	for i := range legs {
+		if legs[i].VolumeExecuted.IsPositive() || legs[i].Triggered {
+			winner = i
+			break
+		}
+		if legs[i].Status == LegCanceled && legs[i].TransactionID != "" {
+			canceled = true
+		}
+	}
+	if winner >= 0 || canceled {
+		offset := len(g.Legs) - len(legs)
+		skip := -1
+		if winner >= 0 {
+			skip = offset + winner
+		}
+		if err := m.cancelLegs(g, skip); err != nil {
+			return err
+		}
+	}
+
+	for _, leg := range legs {
+		if leg.isActive() {
+			return nil
+		}
+	}
+	for _, leg := range g.Legs {
+		if leg.VolumeExecuted.IsPositive() {
+			m.setStatus(g, GroupFilled)
+			return nil
+		}
+	}
+	m.setStatus(g, GroupCanceled)
+	return nil
+}
+
+// protect sizes the exits of a bracket to the volume executed by its entry: the take-profit
+// order is placed after the first execution, and replaced when the entry executes more.
+// Nothing is changed once an exit is executed or triggered.
+func (m *OrderManager) protect(g *ManagedGroup) error {
+	entry, takeProfit, stopLoss := &g.Legs[0], &g.Legs[1], &g.Legs[2]
+	for _, exit := range g.Legs[1:] {
+		if exit.VolumeExecuted.IsPositive() || exit.Triggered {
+			return nil
+		}
+	}
+	volume := entry.VolumeExecuted
+
+	if stopLoss.Status == LegWaiting || stopLoss.Status == LegWatching && !stopLoss.Order.Volume.Equal(volume) {
+		stopLoss.Order.Volume = volume
+		stopLoss.Status = LegWatching
+		m.touch(g)
+	}
+
+	switch {
+	case takeProfit.Status == LegWaiting:
+	case takeProfit.Status == LegOpen && !takeProfit.Order.Volume.Equal(volume):
+		// The order on Kraken is replaced by one of the new volume
+		_, err := m.trader.CancelOrder(CancelOrderConfig{TransactionID: takeProfit.TransactionID})
+		if IsUnknownOrder(err) {
+			// Already closed, the next Poll tells whether it was executed
+			return nil
+		}
+		if err != nil {
+			g.Error = err.Error()
+			m.touch(g)
+			return err
+		}
+	default:
+		return nil
+	}
+
+	takeProfit.Order.Volume = volume
+	takeProfit.Order.ClientOrderID = ""
+	takeProfit.TransactionID = ""
+	err := m.place(g, 1, takeProfit.Order)
+	if err != nil && entry.isActive() {
+		// Placed again with the next execution of the entry, e.g. once the volume reaches OrderMin
+		takeProfit.Status = LegWaiting
+	}
+	return err
+}
+
+// trigger sends a client-side order whose trigger is reached, after canceling the others
+func (m *OrderManager) trigger(g *ManagedGroup, index int) error {
+	leg := &g.Legs[index]
+	if err := m.cancelLegs(g, index); err != nil {
+		return err
+	}
+
+	order := leg.Order
+	switch order.OrderType {
+	case StopLossLimit, TakeProfitLimit:
+		order.OrderType = Limit
+		order.Price, order.Price2 = order.Price2, decimal.Zero
+	default:
+		order.OrderType = Market
+		order.Price = decimal.Zero
+	}
+
+	// The other exits of a bracket may have been partially executed until they were canceled
+	if g.Kind == Bracket {
+		if err := m.settle(g, index); err != nil {
+			return err
+		}
+		for i := 1; i < len(g.Legs); i++ {
+			if i != index {
+				order.Volume = order.Volume.Sub(g.Legs[i].VolumeExecuted)
+			}
+		}
+	}
+	if !order.Volume.IsPositive() {
+		leg.Status = LegCanceled
+		m.touch(g)
+		return m.advance(g)
+	}
+
+	leg.Triggered = true
+	return m.place(g, index, order)
+}
+
+// settle reads the final volume executed by the exits of a bracket canceled for the one at
+// index, so the triggered exit does not sell what they already sold
+func (m *OrderManager) settle(g *ManagedGroup, index int) error {
+	legs := make(map[string]int)
+	for i := 1; i < len(g.Legs); i++ {
+		if i != index && g.Legs[i].TransactionID != "" {
+			legs[g.Legs[i].TransactionID] = i
+		}
+	}
+	if len(legs) == 0 {
+		return nil
+	}
+
+	closed, err := m.closedOrders(g.CreatedAt, func(closed map[string]Order) bool {
+		for txid := range legs {
+			if _, ok := closed[txid]; !ok {
+				return true
+			}
+		}
+		return false
+	})
+	if err != nil {
+		g.Error = err.Error()
+		m.touch(g)
+		return err
+	}
+	for txid, i := range legs {
+		if order, ok := closed[txid]; ok && g.Legs[i].apply(order) {
+			m.touch(g)
+		}
+	}
+	return nil
+}
+
+// place sends an order of a group to Kraken
+func (m *OrderManager) place(g *ManagedGroup, index int, order AddOrderConfig) error {
+	leg := &g.Legs[index]
+	order.Validate = false
+	if order.ClientOrderID == "" {
+		order.ClientOrderID = newClientOrderID()
+	}
+
+	// The order is recorded before it is sent, so a restart finds it by its client order ID
+	leg.Order.ClientOrderID = order.ClientOrderID
+	leg.Status = LegPlacing
+	leg.PlacedAt = m.config.Now()
+	m.touch(g)
+	if err := m.save(); err != nil {
+		return err
+	}
+
+	result, err := m.trader.AddOrder(order)
+	if err != nil {
+		leg.Status = LegCanceled
+		g.Error = err.Error()
+		if leg.Triggered {
+			m.setStatus(g, GroupFailed)
+		}
+		return err
+	}
+	if len(result.TransactionIDs) > 0 {
+		leg.TransactionID = result.TransactionIDs[0]
+		leg.Status = LegOpen
+	}
+	return nil
+}
+
+// cancelLegs cancels the orders of a group not executed yet, except the one at index skip
+func (m *OrderManager) cancelLegs(g *ManagedGroup, skip int) error {
+	for i := range g.Legs {
+		leg := &g.Legs[i]
+		if i == skip || !leg.isActive() && leg.Status != LegWaiting {
+			continue
+		}
+
+		if leg.TransactionID != "" {
+			_, err := m.trader.CancelOrder(CancelOrderConfig{TransactionID: leg.TransactionID})
+			if err != nil {
+				if IsUnknownOrder(err) {
+					// Already closed, the next Poll tells whether it was executed
+					continue
+				}
+				g.Error = err.Error()
+				m.touch(g)
+				return err
+			}
+		}
+		leg.Status = LegCanceled
+		m.touch(g)
+	}
+	return nil
+}
+
+// expire cancels the groups whose expiration time is reached
+func (m *OrderManager) expire() {
+	now := m.config.Now()
+	for _, g := range m.groups {
+		if g.Status != GroupActive || g.ExpireAt.IsZero() || now.Before(g.ExpireAt) {
+			continue
+		}
+		if err := m.cancelLegs(g, -1); err != nil {
+			continue
+		}
+		m.setStatus(g, GroupExpired)
+	}
+}
+
+func (m *OrderManager) group(id string) *ManagedGroup {
+	for _, g := range m.groups {
+		if g.ID == id {
+			return g
+		}
+	}
+	return nil
+}
+
+func (m *OrderManager) setStatus(g *ManagedGroup, status GroupStatus) {
+	g.Status = status
+	m.touch(g)
+}
+
+func (m *OrderManager) touch(g *ManagedGroup) {
+	g.UpdatedAt = m.config.Now()
+	m.changed[g] = true
+}
+
+func (m *OrderManager) state() OrderManagerState {
+	state := OrderManagerState{Sequence: m.sequence}
+	for _, g := range m.groups {
+		state.Groups = append(state.Groups, g.copy())
+	}
+	return state
+}
+
+// save reports the state when it changed
+func (m *OrderManager) save() error {
+	if len(m.changed) == 0 || m.config.OnState == nil {
+		return nil
+	}
+	return m.config.OnState(m.state())
+}
+
+// unlock releases the lock, then reports the groups changed while it was held
+func (m *OrderManager) unlock() {
+	changed := []ManagedGroup{}
+	for _, g := range m.groups {
+		if m.changed[g] {
+			changed = append(changed, g.copy())
+		}
+	}
+	m.changed = make(map[*ManagedGroup]bool)
+	m.mu.Unlock()
+
+	if m.config.OnChange == nil {
+		return
+	}
+	for _, group := range changed {
+		m.config.OnChange(group)
+	}
+}
+
+func (g *ManagedGroup) copy() ManagedGroup {
+	group := *g
+	group.Legs = append([]ManagedLeg{}, g.Legs...)
+	return group
+}
+
+func newLeg(role LegRole, order AddOrderConfig) ManagedLeg {
+	// The two-factor password is not persisted with the state
+	order.OTP = ""
+
+	leg := ManagedLeg{
+		Role:   role,
+		Order:  order,
+		Status: LegPlacing,
+	}
+	switch order.OrderType {
+	case StopLoss, TakeProfit, StopLossLimit, TakeProfitLimit:
+		leg.ClientSide = true
+		leg.StopPrice = order.Price
+		leg.Status = LegWatching
+	}
+	return leg
+}
+
+func checkManagedOrder(order AddOrderConfig) error {
+	if order.AssetPair == "" {
+		return fmt.Errorf("AssetPair is required")
+	}
+	if order.Type == "" {
+		return fmt.Errorf("Type is required")
+	}
+	if order.OrderType == "" {
+		return fmt.Errorf("OrderType is required")
+	}
+	if !order.Volume.IsPositive() {
+		return fmt.Errorf("Volume is required")
+	}
+	return nil
+}
+
+func (leg *ManagedLeg) isActive() bool {
+	return leg.Status == LegWatching || leg.Status == LegPlacing || leg.Status == LegOpen
+}
+
+// follow moves the stop of a trailing stop with the best price, it returns true if it moved
+func (leg *ManagedLeg) follow(price decimal.Decimal) bool {
+	if leg.Trail.IsZero() {
+		return false
+	}
+	if !leg.BestPrice.IsZero() {
+		if leg.Order.Type == Sell && !price.GreaterThan(leg.BestPrice) {
+			return false
+		}
+		if leg.Order.Type == Buy && !price.LessThan(leg.BestPrice) {
+			return false
+		}
+	}
+
+	offset := leg.Trail.Offset
+	if leg.Trail.Percent.IsPositive() {
+		offset = price.Mul(leg.Trail.Percent).Div(decimal.NewFromInt(100))
+	}
+	stop := price.Sub(offset)
+	if leg.Order.Type == Buy {
+		stop = price.Add(offset)
+	}
+
+	leg.BestPrice = price
+	// The initial stop loss of a bracket is only raised by the trail
+	if !leg.StopPrice.IsZero() && (leg.Order.Type == Sell && stop.LessThan(leg.StopPrice) || leg.Order.Type == Buy && stop.GreaterThan(leg.StopPrice)) {
+		return true
+	}
+	leg.StopPrice = stop
+	return true
+}
+
+// reached returns true if the price reaches the trigger of a client-side order
+func (leg *ManagedLeg) reached(price decimal.Decimal) bool {
+	if leg.StopPrice.IsZero() {
+		return false
+	}
+
+	switch leg.Order.OrderType {
+	case StopLoss, StopLossLimit:
+		if leg.Order.Type == Sell {
+			return price.LessThanOrEqual(leg.StopPrice)
+		}
+		return price.GreaterThanOrEqual(leg.StopPrice)
+	case TakeProfit, TakeProfitLimit:
+		if leg.Order.Type == Sell {
+			return price.GreaterThanOrEqual(leg.StopPrice)
+		}
+		return price.LessThanOrEqual(leg.StopPrice)
+	}
+	return false
+}
+
+// apply updates a leg from its order on Kraken, it returns true if the leg changed
+func (leg *ManagedLeg) apply(order Order) bool {
+	status := leg.Status
+	switch order.Status {
+	case Pending, Open:
+		status = LegOpen
+	case Closed:
+		status = LegFilled
+	case Canceled, Expired:
+		status = LegCanceled
+	}
+
+	changed := status != leg.Status || !order.VolumeExecuted.Equal(leg.VolumeExecuted)
+	leg.Status = status
+	leg.VolumeExecuted = order.VolumeExecuted
+	return changed
+}
+
+// newClientOrderID returns a random client order ID of 18 characters, the maximum for free text
+func newClientOrderID() string {
+	b := make([]byte, 8)
+	if _, err := rand.Read(b); err != nil {
+		return fmt.Sprintf("om%016x", time.Now().UnixNano())
+	}
+	return "om" + hex.EncodeToString(b)
+}
@@ -0,0 +1,636 @@
+package kraken_test
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+
+	kraken "github.com/astaluego/golang-kraken"
+	"github.com/shopspring/decimal"
+)
+
+// fakeTrader is a Trader whose orders are executed by the test. ClosedOrders returns the
+// orders by pages of 50, the most recently closed first, like Kraken.
+type fakeTrader struct {
+	mu        sync.Mutex
+	sequence  int
+	orders    map[string]kraken.Order
+	sent      []kraken.AddOrderConfig
+	canceled  []string
+	offsets   []int64
+	addErr    map[int]error
+	cancelErr error
+}
+
+func newFakeTrader() *fakeTrader {
+	return &fakeTrader{orders: make(map[string]kraken.Order), addErr: make(map[int]error)}
+}
+
+func (f *fakeTrader) AddOrder(config kraken.AddOrderConfig) (*kraken.AddOrderResult, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.sent = append(f.sent, config)
+	if err := f.addErr[len(f.sent)]; err != nil {
+		return nil, err
+	}
+	f.sequence++
+	txid := fmt.Sprintf("O%d", f.sequence)
+	order := kraken.Order{ClientOrderID: config.ClientOrderID, Status: kraken.Open, Volume: config.Volume, OpenedAt: time.Now()}
+	f.orders[txid] = order
+
+	result := &kraken.AddOrderResult{}
+	result.TransactionIDs = []string{txid}
+	return result, nil
+}
+
+func (f *fakeTrader) CancelOrder(config kraken.CancelOrderConfig) (*kraken.CancelOrderResult, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.cancelErr != nil {
+		return nil, f.cancelErr
+	}
+	order, ok := f.orders[config.TransactionID]
+	if !ok || order.Status != kraken.Open {
+		return nil, &kraken.APIError{Errors: []string{"EOrder:Unknown order"}}
+	}
+	order.Status, order.ClosedAt = kraken.Canceled, time.Now()
+	f.orders[config.TransactionID] = order
+	f.canceled = append(f.canceled, config.TransactionID)
+	return &kraken.CancelOrderResult{Count: 1}, nil
+}
+
+func (f *fakeTrader) OpenOrders(config kraken.OpenOrdersConfig) (map[string]kraken.Order, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	orders := make(map[string]kraken.Order)
+	for txid, order := range f.orders {
+		if order.Status == kraken.Open {
+			orders[txid] = order
+		}
+	}
+	return orders, nil
+}
+
+func (f *fakeTrader) ClosedOrders(config kraken.ClosedOrdersConfig) (map[string]kraken.Order, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.offsets = append(f.offsets, config.Offset)
+	txids := []string{}
+	for txid, order := range f.orders {
+		if order.Status != kraken.Open {
+			txids = append(txids, txid)
+		}
+	}
+	sort.Slice(txids, func(i, j int) bool {
+		return f.orders[txids[i]].ClosedAt.After(f.orders[txids[j]].ClosedAt)
+	})
+
+	orders := make(map[string]kraken.Order)
+	for i := int(config.Offset); i < len(txids) && i < int(config.Offset)+50; i++ {
+		orders[txids[i]] = f.orders[txids[i]]
+	}
+	return orders, nil
+}
+
+func (f *fakeTrader) Balance() (kraken.Balances, error) {
+	return kraken.Balances{}, nil
+}
+
+// execute sets the volume executed of an order, and closes it when it is fully executed
+func (f *fakeTrader) execute(txid string, volume string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	order := f.orders[txid]
+	order.VolumeExecuted = decimal.RequireFromString(volume)
+	if order.VolumeExecuted.Equal(order.Volume) {
+		order.Status, order.ClosedAt = kraken.Closed, time.Now()
+	}
+	f.orders[txid] = order
+}
+
+func limitOrder(t kraken.Type, price, volume string) kraken.AddOrderConfig {
+	return kraken.AddOrderConfig{
+		AssetPair: kraken.XBT_USD,
+		Type:      t,
+		OrderType: kraken.Limit,
+		Price:     decimal.RequireFromString(price),
+		Volume:    decimal.RequireFromString(volume),
+	}
+}
+
+func TestOrderManagerAddJoinsErrors(t *testing.T) {
+	trader := newFakeTrader()
+	errAdd := errors.New("add failed")
+	errCancel := errors.New("cancel failed")
+	errState := errors.New("state failed")
+	trader.addErr[2] = errAdd
+	trader.cancelErr = errCancel
+
+	saves := 0
+	manager, err := kraken.NewOrderManager(trader, kraken.OrderManagerConfig{
+		OnState: func(state kraken.OrderManagerState) error {
+			// The first save records the first order before it is sent
+			saves++
+			if saves > 2 {
+				return errState
+			}
+			return nil
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	id, err := manager.AddOCO(kraken.OCOConfig{Orders: []kraken.AddOrderConfig{
+		limitOrder(kraken.Sell, "40000", "1"),
+		limitOrder(kraken.Sell, "41000", "1"),
+	}})
+	for _, expected := range []error{errAdd, errCancel, errState} {
+		if !errors.Is(err, expected) {
+			t.Errorf("expected %v in %v", expected, err)
+		}
+	}
+	if group, _ := manager.Group(id); group.Status != kraken.GroupFailed {
+		t.Errorf("expected a failed group, got %s", group.Status)
+	}
+}
+
+func TestOrderManagerPollPaginates(t *testing.T) {
+	trader := newFakeTrader()
+	manager, err := kraken.NewOrderManager(trader, kraken.OrderManagerConfig{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	id, err := manager.AddOCO(kraken.OCOConfig{Orders: []kraken.AddOrderConfig{
+		limitOrder(kraken.Sell, "40000", "1"),
+		limitOrder(kraken.Sell, "41000", "1"),
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	trader.execute("O1", "1")
+
+	// 120 orders closed since then push the order to the third page
+	closedAt := trader.orders["O1"].ClosedAt
+	for i := 0; i < 120; i++ {
+		trader.orders[fmt.Sprintf("X%d", i)] = kraken.Order{Status: kraken.Closed, ClosedAt: closedAt.Add(time.Duration(i+1) * time.Second)}
+	}
+
+	if err := manager.Poll(); err != nil {
+		t.Fatal(err)
+	}
+	if len(trader.offsets) != 3 || trader.offsets[1] != 50 || trader.offsets[2] != 100 {
+		t.Errorf("expected 3 pages, got the offsets %v", trader.offsets)
+	}
+	group, _ := manager.Group(id)
+	if group.Status != kraken.GroupFilled || group.Legs[0].Status != kraken.LegFilled || group.Legs[1].Status != kraken.LegCanceled {
+		t.Errorf("unexpected group: %+v", group)
+	}
+	if len(trader.canceled) != 1 || trader.canceled[0] != "O2" {
+		t.Errorf("expected O2 to be canceled, got %v", trader.canceled)
+	}
+
+	// Nothing is left to look for
+	trader.offsets = nil
+	if err := manager.Poll(); err != nil {
+		t.Fatal(err)
+	}
+	if len(trader.offsets) != 0 {
+		t.Errorf("unexpected calls to ClosedOrders: %v", trader.offsets)
+	}
+}
+
+func TestOrderManagerBracketPartialFills(t *testing.T) {
+	trader := newFakeTrader()
+	manager, err := kraken.NewOrderManager(trader, kraken.OrderManagerConfig{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	id, err := manager.AddBracket(kraken.BracketConfig{
+		Entry:      limitOrder(kraken.Buy, "30000", "1"),
+		TakeProfit: decimal.NewFromInt(33000),
+		StopLoss:   decimal.NewFromInt(29000),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The first execution of the entry is protected right away
+	trader.execute("O1", "0.4")
+	if err := manager.Poll(); err != nil {
+		t.Fatal(err)
+	}
+	group, _ := manager.Group(id)
+	if group.Legs[1].TransactionID != "O2" || group.Legs[1].Order.Volume.String() != "0.4" {
+		t.Errorf("expected a take-profit of 0.4, got %+v", group.Legs[1])
+	}
+	if group.Legs[2].Status != kraken.LegWatching || group.Legs[2].Order.Volume.String() != "0.4" {
+		t.Errorf("expected a stop-loss of 0.4, got %+v", group.Legs[2])
+	}
+
+	// The take-profit is replaced when the entry executes more
+	trader.execute("O1", "1")
+	if err := manager.Poll(); err != nil {
+		t.Fatal(err)
+	}
+	group, _ = manager.Group(id)
+	if len(trader.canceled) != 1 || trader.canceled[0] != "O2" {
+		t.Errorf("expected O2 to be canceled, got %v", trader.canceled)
+	}
+	if group.Legs[1].TransactionID != "O3" || group.Legs[1].Order.Volume.String() != "1" || group.Legs[2].Order.Volume.String() != "1" {
+		t.Errorf("expected exits of 1, got %+v", group.Legs[1:])
+	}
+
+	// Polling again changes nothing
+	if err := manager.Poll(); err != nil {
+		t.Fatal(err)
+	}
+	if len(trader.sent) != 3 {
+		t.Errorf("expected 3 orders, got %d", len(trader.sent))
+	}
+
+	// The stop-loss sells the whole volume and cancels the take-profit
+	if err := manager.UpdatePrice(kraken.XBT_USD, decimal.NewFromInt(28900)); err != nil {
+		t.Fatal(err)
+	}
+	if len(trader.sent) != 4 || trader.sent[3].OrderType != kraken.Market || trader.sent[3].Volume.String() != "1" {
+		t.Errorf("unexpected stop-loss: %+v", trader.sent[3:])
+	}
+	if len(trader.canceled) != 2 || trader.canceled[1] != "O3" {
+		t.Errorf("expected O3 to be canceled, got %v", trader.canceled)
+	}
+}
+
+func TestOrderManagerTriggerReadsFinalVolume(t *testing.T) {
+	trader := newFakeTrader()
+	manager, err := kraken.NewOrderManager(trader, kraken.OrderManagerConfig{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	id, err := manager.AddBracket(kraken.BracketConfig{
+		Entry:      limitOrder(kraken.Buy, "30000", "1"),
+		TakeProfit: decimal.NewFromInt(33000),
+		StopLoss:   decimal.NewFromInt(29000),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	trader.execute("O1", "1")
+	if err := manager.Poll(); err != nil {
+		t.Fatal(err)
+	}
+
+	// The take-profit executes 0.3 after the last Poll, before the stop-loss is triggered
+	trader.execute("O2", "0.3")
+	if err := manager.UpdatePrice(kraken.XBT_USD, decimal.NewFromInt(28900)); err != nil {
+		t.Fatal(err)
+	}
+	if len(trader.sent) != 3 || trader.sent[2].OrderType != kraken.Market || trader.sent[2].Volume.String() != "0.7" {
+		t.Fatalf("expected a stop-loss of 0.7, got %+v", trader.sent[2:])
+	}
+	group, _ := manager.Group(id)
+	if group.Legs[1].Status != kraken.LegCanceled || group.Legs[1].VolumeExecuted.String() != "0.3" || !group.Legs[2].Triggered {
+		t.Errorf("unexpected exits: %+v", group.Legs[1:])
+	}
+
+	trader.execute("O3", "0.7")
+	if err := manager.Poll(); err != nil {
+		t.Fatal(err)
+	}
+	if group, _ := manager.Group(id); group.Status != kraken.GroupFilled || group.Legs[2].Status != kraken.LegFilled {
+		t.Errorf("unexpected group: %+v", group)
+	}
+
+	// The take-profit was fully executed: nothing is left to sell
+	id, err = manager.AddBracket(kraken.BracketConfig{
+		Entry:      limitOrder(kraken.Buy, "30000", "1"),
+		TakeProfit: decimal.NewFromInt(33000),
+		StopLoss:   decimal.NewFromInt(29000),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	trader.execute("O4", "1")
+	if err := manager.Poll(); err != nil {
+		t.Fatal(err)
+	}
+	trader.execute("O5", "1")
+	if err := manager.UpdatePrice(kraken.XBT_USD, decimal.NewFromInt(28900)); err != nil {
+		t.Fatal(err)
+	}
+	if len(trader.sent) != 5 {
+		t.Errorf("expected no stop-loss, got %+v", trader.sent[5:])
+	}
+	if group, _ := manager.Group(id); group.Status != kraken.GroupFilled || group.Legs[1].Status != kraken.LegFilled || group.Legs[2].Status != kraken.LegCanceled {
+		t.Errorf("unexpected group: %+v", group)
+	}
+}
+
+func TestOrderManagerOCOClientSideStop(t *testing.T) {
+	stopLoss := kraken.AddOrderConfig{
+		AssetPair: kraken.XBT_USD,
+		Type:      kraken.Sell,
+		OrderType: kraken.StopLoss,
+		Price:     decimal.NewFromInt(29000),
+		Volume:    decimal.NewFromInt(1),
+	}
+
+	t.Run("stop-loss triggered", func(t *testing.T) {
+		trader := newFakeTrader()
+		manager, err := kraken.NewOrderManager(trader, kraken.OrderManagerConfig{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		id, err := manager.AddOCO(kraken.OCOConfig{Orders: []kraken.AddOrderConfig{limitOrder(kraken.Sell, "33000", "1"), stopLoss}})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		// Only the limit order is sent to Kraken, the stop-loss does not lock the funds
+		group, _ := manager.Group(id)
+		if len(trader.sent) != 1 || group.Legs[1].Status != kraken.LegWatching || !group.Legs[1].ClientSide {
+			t.Fatalf("unexpected group: %+v", group)
+		}
+
+		if err := manager.UpdatePrice(kraken.XBT_USD, decimal.NewFromInt(29500)); err != nil {
+			t.Fatal(err)
+		}
+		if len(trader.sent) != 1 {
+			t.Fatalf("expected the stop-loss not to trigger, got %+v", trader.sent)
+		}
+		if err := manager.UpdatePrice(kraken.XBT_USD, decimal.NewFromInt(29000)); err != nil {
+			t.Fatal(err)
+		}
+		if len(trader.sent) != 2 || trader.sent[1].OrderType != kraken.Market || trader.sent[1].Volume.String() != "1" {
+			t.Fatalf("expected a market order, got %+v", trader.sent)
+		}
+		if len(trader.canceled) != 1 || trader.canceled[0] != "O1" {
+			t.Errorf("expected O1 to be canceled, got %v", trader.canceled)
+		}
+
+		trader.execute("O2", "1")
+		if err := manager.Poll(); err != nil {
+			t.Fatal(err)
+		}
+		if group, _ := manager.Group(id); group.Status != kraken.GroupFilled || group.Legs[0].Status != kraken.LegCanceled || group.Legs[1].TransactionID != "O2" {
+			t.Errorf("unexpected group: %+v", group)
+		}
+	})
+
+	t.Run("limit order executed", func(t *testing.T) {
+		trader := newFakeTrader()
+		manager, err := kraken.NewOrderManager(trader, kraken.OrderManagerConfig{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		id, err := manager.AddOCO(kraken.OCOConfig{Orders: []kraken.AddOrderConfig{limitOrder(kraken.Sell, "33000", "1"), stopLoss}})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		trader.execute("O1", "0.5")
+		if err := manager.Poll(); err != nil {
+			t.Fatal(err)
+		}
+		// The stop-loss is dropped without any call to Kraken
+		group, _ := manager.Group(id)
+		if group.Status != kraken.GroupActive || group.Legs[1].Status != kraken.LegCanceled || len(trader.canceled) != 0 {
+			t.Errorf("unexpected group: %+v", group)
+		}
+		if err := manager.UpdatePrice(kraken.XBT_USD, decimal.NewFromInt(28000)); err != nil {
+			t.Fatal(err)
+		}
+		if len(trader.sent) != 1 {
+			t.Errorf("expected the stop-loss not to trigger, got %+v", trader.sent)
+		}
+	})
+}
+
+func TestOrderManagerTrailingStop(t *testing.T) {
+	type step struct {
+		price     string
+		stop      string
+		triggered bool
+	}
+	tests := []struct {
+		name  string
+		typ   kraken.Type
+		trail kraken.Trail
+		steps []step
+	}{
+		{
+			name:  "sell by percent",
+			typ:   kraken.Sell,
+			trail: kraken.Trail{Percent: decimal.NewFromInt(1)},
+			steps: []step{{"100", "99", false}, {"105", "103.95", false}, {"104", "103.95", false}, {"103.95", "103.95", true}},
+		},
+		{
+			name:  "sell by offset",
+			typ:   kraken.Sell,
+			trail: kraken.Trail{Offset: decimal.NewFromInt(2)},
+			steps: []step{{"100", "98", false}, {"110", "108", false}, {"108.5", "108", false}, {"107.5", "108", true}},
+		},
+		{
+			name:  "buy by percent",
+			typ:   kraken.Buy,
+			trail: kraken.Trail{Percent: decimal.NewFromInt(1)},
+			steps: []step{{"100", "101", false}, {"95", "95.95", false}, {"95.5", "95.95", false}, {"96", "95.95", true}},
+		},
+		{
+			name:  "buy by offset",
+			typ:   kraken.Buy,
+			trail: kraken.Trail{Offset: decimal.NewFromInt(2)},
+			steps: []step{{"100", "102", false}, {"90", "92", false}, {"91", "92", false}, {"92", "92", true}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			trader := newFakeTrader()
+			manager, err := kraken.NewOrderManager(trader, kraken.OrderManagerConfig{})
+			if err != nil {
+				t.Fatal(err)
+			}
+			id, err := manager.AddTrailingStop(kraken.TrailingStopConfig{
+				AssetPair: kraken.XBT_USD,
+				Type:      tt.typ,
+				Volume:    decimal.NewFromInt(1),
+				Trail:     tt.trail,
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			for _, step := range tt.steps {
+				if err := manager.UpdatePrice(kraken.XBT_USD, decimal.RequireFromString(step.price)); err != nil {
+					t.Fatal(err)
+				}
+				group, _ := manager.Group(id)
+				leg := group.Legs[0]
+				if leg.StopPrice.String() != step.stop || leg.Triggered != step.triggered || len(trader.sent) == 0 == step.triggered {
+					t.Fatalf("at %s: expected a stop of %s (triggered: %t), got %+v", step.price, step.stop, step.triggered, leg)
+				}
+			}
+			if sent := trader.sent[0]; sent.Type != tt.typ || sent.OrderType != kraken.Market || sent.Volume.String() != "1" {
+				t.Errorf("unexpected order: %+v", sent)
+			}
+		})
+	}
+}
+
+func TestOrderManagerExpireAt(t *testing.T) {
+	now := time.Date(2023, 7, 6, 10, 0, 0, 0, time.UTC)
+	trader := newFakeTrader()
+	changes := []kraken.GroupStatus{}
+	manager, err := kraken.NewOrderManager(trader, kraken.OrderManagerConfig{
+		Now:      func() time.Time { return now },
+		OnChange: func(group kraken.ManagedGroup) { changes = append(changes, group.Status) },
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	id, err := manager.AddOCO(kraken.OCOConfig{
+		Orders: []kraken.AddOrderConfig{
+			limitOrder(kraken.Sell, "33000", "1"),
+			limitOrder(kraken.Sell, "34000", "1"),
+		},
+		ExpireAt: now.Add(time.Hour),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	now = now.Add(59 * time.Minute)
+	if err := manager.Poll(); err != nil {
+		t.Fatal(err)
+	}
+	if group, _ := manager.Group(id); group.Status != kraken.GroupActive || len(trader.canceled) != 0 {
+		t.Fatalf("expected an active group, got %+v", group)
+	}
+
+	now = now.Add(time.Minute)
+	if err := manager.Poll(); err != nil {
+		t.Fatal(err)
+	}
+	group, _ := manager.Group(id)
+	if group.Status != kraken.GroupExpired || group.Legs[0].Status != kraken.LegCanceled || group.Legs[1].Status != kraken.LegCanceled {
+		t.Errorf("expected an expired group, got %+v", group)
+	}
+	if len(trader.canceled) != 2 {
+		t.Errorf("expected the orders to be canceled, got %v", trader.canceled)
+	}
+	if changes[len(changes)-1] != kraken.GroupExpired {
+		t.Errorf("expected the expiration to be reported, got %v", changes)
+	}
+}
+
+func TestOrderManagerResume(t *testing.T) {
+	trader := newFakeTrader()
+
+	// The state saved before the second order is sent, as if the process crashed right after
+	var saved []byte
+	saves := 0
+	manager, err := kraken.NewOrderManager(trader, kraken.OrderManagerConfig{
+		OnState: func(state kraken.OrderManagerState) error {
+			saves++
+			if saves == 2 {
+				var err error
+				saved, err = json.Marshal(state)
+				return err
+			}
+			return nil
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	id, err := manager.AddOCO(kraken.OCOConfig{Orders: []kraken.AddOrderConfig{
+		limitOrder(kraken.Sell, "33000", "1"),
+		limitOrder(kraken.Sell, "34000", "1"),
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	resume := func(t *testing.T, modify func(state *kraken.OrderManagerState)) *kraken.OrderManager {
+		t.Helper()
+
+		state := kraken.OrderManagerState{}
+		if err := json.Unmarshal(saved, &state); err != nil {
+			t.Fatal(err)
+		}
+		if leg := state.Groups[0].Legs[1]; leg.Status != kraken.LegPlacing || leg.TransactionID != "" || leg.Order.ClientOrderID == "" {
+			t.Fatalf("expected the second order being placed, got %+v", leg)
+		}
+		modify(&state)
+		manager, err := kraken.NewOrderManager(trader, kraken.OrderManagerConfig{State: &state})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return manager
+	}
+
+	t.Run("order sent", func(t *testing.T) {
+		manager := resume(t, func(*kraken.OrderManagerState) {})
+
+		// The order is recovered by its client order ID
+		if err := manager.Poll(); err != nil {
+			t.Fatal(err)
+		}
+		group, _ := manager.Group(id)
+		if group.Legs[0].TransactionID != "O1" || group.Legs[1].TransactionID != "O2" || group.Legs[1].Status != kraken.LegOpen {
+			t.Fatalf("unexpected group: %+v", group)
+		}
+
+		trader.execute("O2", "1")
+		if err := manager.Poll(); err != nil {
+			t.Fatal(err)
+		}
+		if group, _ := manager.Group(id); group.Status != kraken.GroupFilled || group.Legs[0].Status != kraken.LegCanceled {
+			t.Errorf("unexpected group: %+v", group)
+		}
+		if len(trader.canceled) != 1 || trader.canceled[0] != "O1" {
+			t.Errorf("expected O1 to be canceled, got %v", trader.canceled)
+		}
+
+		// The sequence of the IDs goes on
+		next, err := manager.AddOCO(kraken.OCOConfig{Orders: []kraken.AddOrderConfig{
+			limitOrder(kraken.Sell, "33000", "1"),
+			limitOrder(kraken.Sell, "34000", "1"),
+		}})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if next == id {
+			t.Errorf("expected a new ID, got %s again", next)
+		}
+	})
+
+	t.Run("order lost", func(t *testing.T) {
+		trader.orders["O1"] = kraken.Order{Status: kraken.Open, Volume: decimal.NewFromInt(1)}
+		manager := resume(t, func(state *kraken.OrderManagerState) {
+			state.Groups[0].Legs[1].Order.ClientOrderID = "om-never-sent"
+		})
+
+		if err := manager.Poll(); err != nil {
+			t.Fatal(err)
+		}
+		group, _ := manager.Group(id)
+		if group.Status != kraken.GroupActive || group.Legs[0].Status != kraken.LegOpen || group.Legs[1].Status != kraken.LegCanceled {
+			t.Errorf("unexpected group: %+v", group)
+		}
+	})
+}
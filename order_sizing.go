@@ -0,0 +1,238 @@
+package kraken
+
+import (
+	"fmt"
+
+	"github.com/shopspring/decimal"
+)
+
+var hundred = decimal.NewFromInt(100)
+
+type SizingConfig struct {
+	// AssetPair is required
+	AssetPair AssetPair
+
+	// Type is required
+	Type Type
+
+	// OrderType is optional
+	// market or limit
+	// Default: market
+	OrderType OrderType
+
+	// Price is required for limit orders
+	// Market orders are sized at the best ask (buys) or bid (sells)
+	Price decimal.Decimal
+
+	// Notional is optional
+	// Amount of quote currency to spend, fee included (buys) or to sell for (sells)
+	// Either Notional or Percent is required
+	Notional decimal.Decimal
+
+	// Percent is optional
+	// Percent of the balance to use: quote currency for buys, base currency for sells
+	Percent decimal.Decimal
+
+	// Balance is optional
+	// Balance Percent applies to
+	// Default: the available balance returned by ExtendedBalance, i.e. not held by open orders
+	Balance decimal.Decimal
+
+	// FeeVolume is optional
+	// 30-day trade volume used to pick the fee tier
+	FeeVolume decimal.Decimal
+
+	// Viqc is optional
+	// Market buys are sent in quote currency with the Viqc flag, so Kraken computes the volume
+	// at the execution price. Used as a fallback when no ask price is known.
+	Viqc bool
+}
+
+type SizedOrder struct {
+	// Order ready to be sent with AddOrder
+	Order AddOrderConfig
+	// Price the volume is computed at
+	Price decimal.Decimal
+	// Cost expected, in quote currency
+	Cost decimal.Decimal
+	// Fee expected, in quote currency
+	Fee decimal.Decimal
+}
+
+// Total returns the quote currency expected to be spent (buys) or received (sells), fee included
+func (o SizedOrder) Total() decimal.Decimal {
+	if o.Order.Type == Sell {
+		return o.Cost.Sub(o.Fee)
+	}
+	return o.Cost.Add(o.Fee)
+}
+
+// SizeOrder
+// Converts a notional or a percent of the balance into an order, with the trading rules and
+// prices of the pair fetched from AssetPairs, TickerInformation and ExtendedBalance.
+func (c *Client) SizeOrder(config SizingConfig) (*SizedOrder, error) {
+	if config.AssetPair == "" {
+		return nil, fmt.Errorf("AssetPair is required")
+	}
+
+	pairs, err := c.AssetPairs(AssetPairsConfig{AssetPairs: []AssetPair{config.AssetPair}})
+	if err != nil {
+		return nil, err
+	}
+	info, ok := pairs[config.AssetPair]
+	if !ok && len(pairs) == 1 {
+		for _, pairInfo := range pairs {
+			info, ok = pairInfo, true
+		}
+	}
+	if !ok {
+		return nil, fmt.Errorf("unknown asset pair %s", config.AssetPair)
+	}
+
+	tickers, err := c.TickerInformation(TickerInformationConfig{AssetPairs: []AssetPair{config.AssetPair}})
+	if err != nil {
+		return nil, err
+	}
+	ticker := tickers[config.AssetPair]
+	if len(tickers) == 1 {
+		for _, pairTicker := range tickers {
+			ticker = pairTicker
+		}
+	}
+
+	if config.Percent.IsPositive() && config.Balance.IsZero() {
+		balances, err := c.ExtendedBalance()
+		if err != nil {
+			return nil, err
+		}
+		asset := info.QuoteAsset
+		if config.Type == Sell {
+			asset = info.BaseAsset
+		}
+		balance, ok := balances[asset]
+		if !ok {
+			return nil, fmt.Errorf("no balance of %s", asset)
+		}
+		config.Balance = balance.Available()
+	}
+
+	return SizeOrder(info, ticker, config)
+}
+
+// SizeOrder converts a notional or a percent of the balance into an order of the pair:
+// the volume is rounded down to the lot decimals and the order is checked against OrderMin
+// and CostMin, a *ValidationError is returned otherwise
+func SizeOrder(info AssetPairsInfo, ticker AssetTickerInfo, config SizingConfig) (*SizedOrder, error) {
+	if config.AssetPair == "" {
+		return nil, fmt.Errorf("AssetPair is required")
+	}
+	if config.Type != Buy && config.Type != Sell {
+		return nil, fmt.Errorf("Type is required")
+	}
+	if config.OrderType == "" {
+		config.OrderType = Market
+	}
+	if config.OrderType != Market && config.OrderType != Limit {
+		return nil, fmt.Errorf("OrderType must be market or limit")
+	}
+	if config.OrderType == Limit && !config.Price.IsPositive() {
+		return nil, fmt.Errorf("Price is required for limit orders")
+	}
+	if !config.Notional.IsPositive() && !config.Percent.IsPositive() {
+		return nil, fmt.Errorf("Notional or Percent is required")
+	}
+
+	price := config.Price
+	if config.OrderType == Market {
+		price = ticker.Ask.Price
+		if config.Type == Sell {
+			price = ticker.Bid.Price
+		}
+	}
+
+	// Market orders are charged the taker fee, limit orders may be too
+	fee := info.FeePercent(config.FeeVolume, false).Div(hundred)
+	one := decimal.NewFromInt(1)
+
+	sized := &SizedOrder{
+		Order: AddOrderConfig{
+			AssetPair: config.AssetPair,
+			Type:      config.Type,
+			OrderType: config.OrderType,
+		},
+		Price: price,
+	}
+	if config.OrderType == Limit {
+		sized.Order.Price = price
+	}
+
+	switch {
+	case config.Type == Sell && config.Percent.IsPositive():
+		sized.Order.Volume = config.Balance.Mul(config.Percent).Div(hundred)
+	case config.Type == Sell:
+		if !price.IsPositive() {
+			return nil, fmt.Errorf("no bid price for %s", config.AssetPair)
+		}
+		sized.Order.Volume = config.Notional.Div(price)
+	default:
+		notional := config.Notional
+		if config.Percent.IsPositive() {
+			notional = config.Balance.Mul(config.Percent).Div(hundred)
+		}
+
+		if config.OrderType == Market && (config.Viqc || !price.IsPositive()) {
+			if !config.Viqc {
+				return nil, fmt.Errorf("no ask price for %s", config.AssetPair)
+			}
+			return sizeViqc(info, sized, notional, fee)
+		}
+		if !price.IsPositive() {
+			return nil, fmt.Errorf("no ask price for %s", config.AssetPair)
+		}
+		sized.Order.Volume = notional.Div(price.Mul(one.Add(fee)))
+	}
+
+	sized.Order.Volume = info.RoundVolume(sized.Order.Volume)
+	sized.Cost = sized.Order.Volume.Mul(price)
+	sized.Fee = sized.Cost.Mul(fee)
+
+	if err := ValidateOrder(info, sized.Order, price); err != nil {
+		return nil, err
+	}
+	return sized, nil
+}
+
+// sizeViqc sizes a market buy in quote currency, the fee being charged on top of the volume
+func sizeViqc(info AssetPairsInfo, sized *SizedOrder, notional, fee decimal.Decimal) (*SizedOrder, error) {
+	decimals := int32(info.CostDecimals)
+	if decimals == 0 {
+		decimals = int32(info.PairDecimals)
+	}
+
+	sized.Order.Flags = []OrderFlag{Viqc}
+	sized.Order.Volume = notional.Div(decimal.NewFromInt(1).Add(fee)).Truncate(decimals)
+	sized.Cost = sized.Order.Volume
+	sized.Fee = sized.Cost.Mul(fee)
+
+	if err := ValidateOrder(info, sized.Order, sized.Price); err != nil {
+		return nil, err
+	}
+	return sized, nil
+}
+
+// FeePercent returns the fee of the tier reached by a 30-day trade volume, from Fees for
+// the taker orders and FeesMaker (if any) for the maker orders
+func (info AssetPairsInfo) FeePercent(volume decimal.Decimal, maker bool) decimal.Decimal {
+	tiers := info.Fees
+	if maker && len(info.FeesMaker) > 0 {
+		tiers = info.FeesMaker
+	}
+
+	percent := decimal.Zero
+	for _, tier := range tiers {
+		if decimal.NewFromInt(tier.Volume).LessThanOrEqual(volume) {
+			percent = tier.Percent
+		}
+	}
+	return percent
+}
@@ -0,0 +1,264 @@
+package kraken_test
+
+import (
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+
+	kraken "github.com/astaluego/golang-kraken"
+	"github.com/astaluego/golang-kraken/krakentest"
+	"github.com/shopspring/decimal"
+)
+
+func TestSizeOrderAvailableBalance(t *testing.T) {
+	server := krakentest.NewServer()
+	defer server.Close()
+	client := server.NewClient()
+
+	// 1000 of the 9000 ZUSD are held by open orders
+	server.Enqueue("BalanceEx", krakentest.Response{Result: json.RawMessage(`{
+		"ZUSD": {"balance": "9000.0000", "hold_trade": "1000.0000"}
+	}`)})
+
+	sized, err := client.SizeOrder(kraken.SizingConfig{
+		AssetPair: kraken.XBT_USD,
+		Type:      kraken.Buy,
+		OrderType: kraken.Limit,
+		Price:     decimal.NewFromInt(20000),
+		Percent:   decimal.NewFromInt(50),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 50% of the 8000 ZUSD available, taker fee of 0.26% included
+	if !sized.Total().LessThanOrEqual(decimal.NewFromInt(4000)) || sized.Total().LessThan(decimal.NewFromInt(3999)) {
+		t.Errorf("unexpected total: %s", sized.Total())
+	}
+	if sized.Order.Volume.String() != "0.19948134" {
+		t.Errorf("unexpected volume: %s", sized.Order.Volume)
+	}
+}
+
+func TestSizeOrderMissingBalance(t *testing.T) {
+	server := krakentest.NewServer()
+	defer server.Close()
+
+	server.Enqueue("BalanceEx", krakentest.Response{Result: json.RawMessage(`{
+		"ZUSD": {"balance": "9000.0000", "hold_trade": "0.0000"}
+	}`)})
+
+	_, err := server.NewClient().SizeOrder(kraken.SizingConfig{
+		AssetPair: kraken.XBT_USD,
+		Type:      kraken.Sell,
+		Percent:   decimal.NewFromInt(50),
+	})
+	if err == nil || !strings.Contains(err.Error(), "XXBT") {
+		t.Errorf("expected an error for the missing XXBT balance, got %v", err)
+	}
+}
+
+func TestSizeOrder(t *testing.T) {
+	d := decimal.RequireFromString
+	info := withInfo(xbtUSD, func(info *kraken.AssetPairsInfo) {
+		info.CostDecimals = 5
+		info.Fees = []kraken.Fee{{Volume: 0, Percent: d("0.26")}, {Volume: 50000, Percent: d("0.24")}}
+	})
+	var ticker kraken.AssetTickerInfo
+	ticker.Bid.Price = d("29999.9")
+	ticker.Ask.Price = d("30000")
+
+	tests := []struct {
+		name   string
+		info   kraken.AssetPairsInfo
+		ticker kraken.AssetTickerInfo
+		config kraken.SizingConfig
+		volume string
+		cost   string
+		fee    string
+		viqc   bool
+	}{
+		{
+			// 1000 / (30000 * 1.0026) truncated to the lot decimals
+			name:   "notional buy",
+			info:   info,
+			ticker: ticker,
+			config: kraken.SizingConfig{Type: kraken.Buy, Notional: d("1000")},
+			volume: "0.03324689",
+			cost:   "997.4067",
+			fee:    "2.59325742",
+		},
+		{
+			name:   "notional buy of the second fee tier",
+			info:   info,
+			ticker: ticker,
+			config: kraken.SizingConfig{Type: kraken.Buy, Notional: d("1000"), FeeVolume: d("60000")},
+			volume: "0.03325352",
+			cost:   "997.6056",
+			fee:    "2.39425344",
+		},
+		{
+			// 1000 / 29999.9 = 0.0333334444, rounded down rather than to the nearest lot
+			name:   "notional sell at the bid",
+			info:   info,
+			ticker: ticker,
+			config: kraken.SizingConfig{Type: kraken.Sell, Notional: d("1000")},
+			volume: "0.03333344",
+			cost:   "999.999866656",
+			fee:    "2.5999996533056",
+		},
+		{
+			name:   "percent of the balance of a limit buy",
+			info:   info,
+			ticker: ticker,
+			config: kraken.SizingConfig{Type: kraken.Buy, OrderType: kraken.Limit, Price: d("25000"), Percent: d("10"), Balance: d("10000")},
+			volume: "0.03989626",
+			cost:   "997.4065",
+			fee:    "2.5932569",
+		},
+		{
+			name:   "percent of the balance of a sell",
+			info:   info,
+			ticker: ticker,
+			config: kraken.SizingConfig{Type: kraken.Sell, Percent: d("50"), Balance: d("0.123456789")},
+			volume: "0.06172839",
+			cost:   "1851.845527161",
+			fee:    "4.8147983706186",
+		},
+		{
+			// 1000 / 1.0026 truncated to the cost decimals
+			name:   "viqc",
+			info:   info,
+			ticker: ticker,
+			config: kraken.SizingConfig{Type: kraken.Buy, Notional: d("1000"), Viqc: true},
+			volume: "997.40674",
+			cost:   "997.40674",
+			fee:    "2.593257524",
+			viqc:   true,
+		},
+		{
+			name:   "viqc without ask price",
+			info:   info,
+			ticker: kraken.AssetTickerInfo{},
+			config: kraken.SizingConfig{Type: kraken.Buy, Notional: d("100.123456789"), Viqc: true},
+			volume: "99.86381",
+			cost:   "99.86381",
+			fee:    "0.259645906",
+			viqc:   true,
+		},
+		{
+			name:   "viqc truncated to the pair decimals",
+			info:   withInfo(info, func(info *kraken.AssetPairsInfo) { info.CostDecimals = 0 }),
+			ticker: ticker,
+			config: kraken.SizingConfig{Type: kraken.Buy, Notional: d("1000"), Viqc: true},
+			volume: "997.4",
+			cost:   "997.4",
+			fee:    "2.59324",
+			viqc:   true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.config.AssetPair = kraken.XBT_USD
+			sized, err := kraken.SizeOrder(tt.info, tt.ticker, tt.config)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !sized.Order.Volume.Equal(d(tt.volume)) || !sized.Cost.Equal(d(tt.cost)) || !sized.Fee.Equal(d(tt.fee)) {
+				t.Errorf("expected %s for %s and a fee of %s, got %s for %s and a fee of %s",
+					tt.volume, tt.cost, tt.fee, sized.Order.Volume, sized.Cost, sized.Fee)
+			}
+			if viqc := len(sized.Order.Flags) == 1 && sized.Order.Flags[0] == kraken.Viqc; viqc != tt.viqc {
+				t.Errorf("expected the viqc flag: %t, got %v", tt.viqc, sized.Order.Flags)
+			}
+			if sized.Order.OrderType == kraken.Limit && !sized.Order.Price.Equal(tt.config.Price) {
+				t.Errorf("expected the limit price %s, got %s", tt.config.Price, sized.Order.Price)
+			}
+		})
+	}
+}
+
+func TestSizeOrderErrors(t *testing.T) {
+	d := decimal.RequireFromString
+	var ticker kraken.AssetTickerInfo
+	ticker.Bid.Price = d("29999.9")
+	ticker.Ask.Price = d("30000")
+
+	tests := []struct {
+		name      string
+		info      kraken.AssetPairsInfo
+		ticker    kraken.AssetTickerInfo
+		config    kraken.SizingConfig
+		violation kraken.ViolationCode
+		message   string
+	}{
+		{
+			name:      "volume below OrderMin",
+			info:      xbtUSD,
+			ticker:    ticker,
+			config:    kraken.SizingConfig{Type: kraken.Buy, Notional: d("2")},
+			violation: kraken.ViolationVolumeBelowMin,
+		},
+		{
+			name:      "cost below CostMin",
+			info:      withInfo(xbtUSD, func(info *kraken.AssetPairsInfo) { info.OrderMin = decimal.Zero }),
+			ticker:    ticker,
+			config:    kraken.SizingConfig{Type: kraken.Sell, Notional: d("0.4")},
+			violation: kraken.ViolationCostBelowMin,
+		},
+		{
+			name:      "viqc below CostMin",
+			info:      withInfo(xbtUSD, func(info *kraken.AssetPairsInfo) { info.OrderMin = decimal.Zero }),
+			ticker:    ticker,
+			config:    kraken.SizingConfig{Type: kraken.Buy, Notional: d("0.4"), Viqc: true},
+			violation: kraken.ViolationCostBelowMin,
+		},
+		{
+			name:    "missing ask",
+			info:    xbtUSD,
+			config:  kraken.SizingConfig{Type: kraken.Buy, Notional: d("1000")},
+			message: "no ask price for XXBTZUSD",
+		},
+		{
+			name:    "missing bid",
+			info:    xbtUSD,
+			config:  kraken.SizingConfig{Type: kraken.Sell, Notional: d("1000")},
+			message: "no bid price for XXBTZUSD",
+		},
+		{
+			name:    "missing notional",
+			info:    xbtUSD,
+			ticker:  ticker,
+			config:  kraken.SizingConfig{Type: kraken.Buy},
+			message: "Notional or Percent is required",
+		},
+		{
+			name:    "missing limit price",
+			info:    xbtUSD,
+			ticker:  ticker,
+			config:  kraken.SizingConfig{Type: kraken.Buy, OrderType: kraken.Limit, Notional: d("1000")},
+			message: "Price is required for limit orders",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.config.AssetPair = kraken.XBT_USD
+			sized, err := kraken.SizeOrder(tt.info, tt.ticker, tt.config)
+			if sized != nil {
+				t.Errorf("expected no order, got %+v", sized)
+			}
+			if tt.message != "" {
+				if err == nil || err.Error() != tt.message {
+					t.Errorf("expected %q, got %v", tt.message, err)
+				}
+				return
+			}
+			var validationErr *kraken.ValidationError
+			if !errors.As(err, &validationErr) || !validationErr.Has(tt.violation) {
+				t.Errorf("expected the violation %s, got %v", tt.violation, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,453 @@
+package kraken
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+// IsFinal returns true if no other status can follow: closed, canceled or expired
+func (s OrderStatus) IsFinal() bool {
+	return s == Closed || s == Canceled || s == Expired
+}
+
+// CanTransitionTo returns true if an order can go from the status to the next one:
+// pending to open, and pending or open to closed, canceled or expired.
+// Staying in the same status is valid as well, e.g. for a partial fill.
+func (s OrderStatus) CanTransitionTo(next OrderStatus) bool {
+	if s == next {
+		return true
+	}
+	switch s {
+	case Pending:
+		return next == Open || next.IsFinal()
+	case Open:
+		return next.IsFinal()
+	}
+	return false
+}
+
+// TransitionError is reported when an update would move an order to a status which cannot
+// follow its current one, e.g. a stale REST snapshot reopening an order closed by the stream.
+// The update is ignored.
+type TransitionError struct {
+	TransactionID string
+	From          OrderStatus
+	To            OrderStatus
+	Source        OrderSource
+}
+
+func (e *TransitionError) Error() string {
+	return fmt.Sprintf("invalid transition of order %s from %s to %s (%s)", e.TransactionID, e.From, e.To, e.Source)
+}
+
+// OrderSource is where an update of an order comes from
+type OrderSource string
+
+const (
+	// SourceLocal is an update made by the application, e.g. an order just added
+	SourceLocal OrderSource = "local"
+	// SourceStream is an update received from a stream, e.g. a WebSocket feed
+	SourceStream OrderSource = "stream"
+	// SourceREST is an update found by a reconciliation with the REST API
+	SourceREST OrderSource = "rest"
+)
+
+// OrderEvent is a change of an order tracked by an OrderTracker
+type OrderEvent struct {
+	TransactionID string
+	// PreviousStatus is empty when the order was not tracked yet
+	PreviousStatus OrderStatus
+	// Order is the state of the order after the change
+	Order  Order
+	Source OrderSource
+}
+
+type OrderTrackerConfig struct {
+	// ReconcileInterval is optional
+	// Pause between two reconciliations with the REST API done by Run
+	// Default: 30s
+	ReconcileInterval time.Duration
+
+	// OnChange is optional
+	// Called for each order added, or whose status or executed volume changed
+	OnChange func(event OrderEvent)
+
+	// OnError is optional
+	// Called with the *TransitionError of the updates ignored, and the errors of the
+	// reconciliations done by Run
+	OnError func(err error)
+}
+
+// OrderTracker holds the state of every order, by transaction ID, user reference ID and client
+// order ID. It merges the orders added locally, the updates of a stream and the fills, and
+// repairs the updates missed by reconciling with OpenOrders and ClosedOrders.
+// It is safe for concurrent use by multiple goroutines.
+type OrderTracker struct {
+	trader Trader
+	config OrderTrackerConfig
+
+	mu       sync.RWMutex
+	orders   map[string]Order
+	byClient map[string]string
+	// trades are the transaction IDs of the orders by ID of the trades already applied by Fill
+	trades map[string]string
+	events []OrderEvent
+	errors []error
+}
+
+// NewOrderTracker inits a new OrderTracker, empty until orders are tracked or reconciled
+func NewOrderTracker(trader Trader, config OrderTrackerConfig) (*OrderTracker, error) {
+	if trader == nil {
+		return nil, fmt.Errorf("trader is required")
+	}
+	if config.ReconcileInterval == 0 {
+		config.ReconcileInterval = 30 * time.Second
+	}
+
+	return &OrderTracker{
+		trader:   trader,
+		config:   config,
+		orders:   make(map[string]Order),
+		byClient: make(map[string]string),
+		trades:   make(map[string]string),
+	}, nil
+}
+
+// TrackAdded tracks the orders just added with AddOrder, as pending until they are updated
+func (t *OrderTracker) TrackAdded(config AddOrderConfig, result *AddOrderResult) {
+	if result == nil {
+		return
+	}
+
+	order := Order{
+		UserReferenceID: config.UserReference,
+		ClientOrderID:   config.ClientOrderID,
+		Status:          Pending,
+		OpenedAt:        time.Now(),
+		StartAt:         config.StartAt,
+		ExpireAt:        config.ExpireAt,
+		Volume:          config.Volume,
+		Trigger:         config.Trigger,
+		Flags:           config.Flags,
+	}
+	order.OrderDescription.Pair = config.AssetPair
+	order.OrderDescription.Type = config.Type
+	order.OrderDescription.Ordertype = config.OrderType
+	order.OrderDescription.Price = config.Price
+	order.OrderDescription.Price2 = config.Price2
+	order.OrderDescription.Order = result.Description.Order
+	order.OrderDescription.Close = result.Description.Close
+
+	t.mu.Lock()
+	defer t.unlock()
+
+	for _, txid := range result.TransactionIDs {
+		// An update received before the result of AddOrder is kept
+		if _, ok := t.orders[txid]; ok {
+			continue
+		}
+		t.apply(txid, order, SourceLocal)
+	}
+}
+
+// Update applies the state of an order received from a stream.
+// It returns a *TransitionError when the update is ignored.
+func (t *OrderTracker) Update(txid string, order Order) error {
+	t.mu.Lock()
+	defer t.unlock()
+
+	return t.apply(txid, order, SourceStream)
+}
+
+// Fill applies a fill of an order: the executed volume, cost and fee are added, and the
+// order is closed once its volume is fully executed. A trade already applied is ignored,
+// so a fill received twice, e.g. replayed by a stream after a reconnection, is counted once.
+func (t *OrderTracker) Fill(txid string, tradeID string, volume, cost, fee decimal.Decimal) error {
+	if tradeID == "" {
+		return fmt.Errorf("tradeID is required")
+	}
+
+	t.mu.Lock()
+	defer t.unlock()
+
+	order, ok := t.orders[txid]
+	if !ok {
+		return fmt.Errorf("unknown order %s", txid)
+	}
+	if _, ok := t.trades[tradeID]; ok {
+		return nil
+	}
+	t.trades[tradeID] = txid
+
+	order.VolumeExecuted = order.VolumeExecuted.Add(volume)
+	order.Cost = order.Cost.Add(cost)
+	order.Fee = order.Fee.Add(fee)
+	if order.VolumeExecuted.IsPositive() {
+		order.Price = order.Cost.Div(order.VolumeExecuted)
+	}
+	if order.Status == Pending {
+		order.Status = Open
+	}
+	if order.Volume.IsPositive() && !order.VolumeExecuted.LessThan(order.Volume) {
+		order.Status = Closed
+		order.ClosedAt = time.Now()
+	}
+	if err := t.apply(txid, order, SourceStream); err != nil {
+		delete(t.trades, tradeID)
+		return err
+	}
+	return nil
+}
+
+// Canceled marks an order as canceled, e.g. after a successful CancelOrder
+func (t *OrderTracker) Canceled(txid string, reason string) error {
+	t.mu.Lock()
+	defer t.unlock()
+
+	order, ok := t.orders[txid]
+	if !ok {
+		return fmt.Errorf("unknown order %s", txid)
+	}
+
+	order.Status = Canceled
+	order.Reason = reason
+	order.ClosedAt = time.Now()
+	return t.apply(txid, order, SourceLocal)
+}
+
+// Reconcile repairs the state of the orders with OpenOrders and the first page of ClosedOrders.
+// The orders tracked but missing from both, e.g. closed before the 50 orders of the page, are
+// queried with Orders when the trader supports it.
+// The executed volume, cost and fee returned by Kraken replace the ones summed from the fills.
+func (t *OrderTracker) Reconcile() error {
+	t.mu.RLock()
+	since := time.Time{}
+	for _, order := range t.orders {
+		if !order.Status.IsFinal() && (since.IsZero() || order.OpenedAt.Before(since)) {
+			since = order.OpenedAt
+		}
+	}
+	t.mu.RUnlock()
+
+	// The trades of the orders are requested, so the fills they include are not applied again
+	open, err := t.trader.OpenOrders(OpenOrdersConfig{Trades: true})
+	if err != nil {
+		return err
+	}
+	config := ClosedOrdersConfig{Trades: true}
+	if !since.IsZero() {
+		config.Start = since.Add(-time.Minute)
+	}
+	closed, err := t.trader.ClosedOrders(config)
+	if err != nil {
+		return err
+	}
+
+	t.mu.Lock()
+	missing := []string{}
+	for txid, order := range t.orders {
+		if _, ok := open[txid]; ok || order.Status.IsFinal() {
+			continue
+		}
+		if _, ok := closed[txid]; !ok {
+			missing = append(missing, txid)
+		}
+	}
+	for _, orders := range []map[string]Order{open, closed} {
+		for txid, order := range orders {
+			t.apply(txid, order, SourceREST)
+		}
+	}
+	t.unlock()
+
+	querier, ok := t.trader.(interface {
+		Orders(config OrdersConfig) (map[string]Order, error)
+	})
+	if len(missing) == 0 || !ok {
+		return nil
+	}
+
+	// QueryOrders accepts up to 50 transaction IDs per call
+	for len(missing) > 0 {
+		batch := missing[:min(len(missing), 50)]
+		missing = missing[len(batch):]
+
+		orders, err := querier.Orders(OrdersConfig{Trades: true, TransactionIDs: batch})
+		if err != nil {
+			return err
+		}
+		t.mu.Lock()
+		for txid, order := range orders {
+			t.apply(txid, order, SourceREST)
+		}
+		t.unlock()
+	}
+	return nil
+}
+
+// Run reconciles every ReconcileInterval until the context is done
+func (t *OrderTracker) Run(ctx context.Context) error {
+	ticker := time.NewTicker(t.config.ReconcileInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+			if err := t.Reconcile(); err != nil && t.config.OnError != nil {
+				t.config.OnError(err)
+			}
+		}
+	}
+}
+
+// Order returns an order by transaction ID
+func (t *OrderTracker) Order(txid string) (Order, bool) {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+
+	order, ok := t.orders[txid]
+	return order, ok
+}
+
+// ByClientOrderID returns an order by client order ID, with its transaction ID
+func (t *OrderTracker) ByClientOrderID(clientOrderID string) (string, Order, bool) {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+
+	txid, ok := t.byClient[clientOrderID]
+	if !ok {
+		return "", Order{}, false
+	}
+	return txid, t.orders[txid], true
+}
+
+// ByUserReference returns the orders having a user reference ID, by transaction ID
+func (t *OrderTracker) ByUserReference(userReferenceID int64) map[string]Order {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+
+	orders := make(map[string]Order)
+	for txid, order := range t.orders {
+		if order.UserReferenceID == userReferenceID {
+			orders[txid] = order
+		}
+	}
+	return orders
+}
+
+// Orders returns all the orders tracked, by transaction ID
+func (t *OrderTracker) Orders() map[string]Order {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+
+	orders := make(map[string]Order, len(t.orders))
+	for txid, order := range t.orders {
+		orders[txid] = order
+	}
+	return orders
+}
+
+// OpenOrders returns the orders pending or open, by transaction ID
+func (t *OrderTracker) OpenOrders() map[string]Order {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+
+	orders := make(map[string]Order)
+	for txid, order := range t.orders {
+		if !order.Status.IsFinal() {
+			orders[txid] = order
+		}
+	}
+	return orders
+}
+
+// Prune forgets the orders closed, canceled or expired before a time, it returns their number
+func (t *OrderTracker) Prune(before time.Time) int {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	count := 0
+	for txid, order := range t.orders {
+		if order.Status.IsFinal() && order.ClosedAt.Before(before) {
+			delete(t.orders, txid)
+			if order.ClientOrderID != "" && t.byClient[order.ClientOrderID] == txid {
+				delete(t.byClient, order.ClientOrderID)
+			}
+			count++
+		}
+	}
+	for tradeID, txid := range t.trades {
+		if _, ok := t.orders[txid]; !ok {
+			delete(t.trades, tradeID)
+		}
+	}
+	return count
+}
+
+// apply merges an update into the state of an order, and records the event of the change
+func (t *OrderTracker) apply(txid string, order Order, source OrderSource) error {
+	previous, tracked := t.orders[txid]
+	if tracked {
+		if !previous.Status.CanTransitionTo(order.Status) {
+			err := &TransitionError{TransactionID: txid, From: previous.Status, To: order.Status, Source: source}
+			t.errors = append(t.errors, err)
+			return err
+		}
+		// A stale update of a stream never takes fills back, the totals of Kraken are authoritative
+		if source != SourceREST && order.VolumeExecuted.LessThan(previous.VolumeExecuted) {
+			order.VolumeExecuted = previous.VolumeExecuted
+			order.Cost = previous.Cost
+			order.Fee = previous.Fee
+			order.Price = previous.Price
+		}
+		// The identifiers known locally are kept when the update does not carry them
+		if order.ClientOrderID == "" {
+			order.ClientOrderID = previous.ClientOrderID
+		}
+		if order.UserReferenceID == 0 {
+			order.UserReferenceID = previous.UserReferenceID
+		}
+	}
+
+	t.orders[txid] = order
+	if order.ClientOrderID != "" {
+		t.byClient[order.ClientOrderID] = txid
+	}
+	for _, tradeID := range order.TradesIDs {
+		t.trades[tradeID] = txid
+	}
+
+	if tracked && previous.Status == order.Status && previous.VolumeExecuted.Equal(order.VolumeExecuted) {
+		return nil
+	}
+	event := OrderEvent{TransactionID: txid, Order: order, Source: source}
+	if tracked {
+		event.PreviousStatus = previous.Status
+	}
+	t.events = append(t.events, event)
+	return nil
+}
+
+// unlock releases the lock, then reports the events and errors recorded while it was held
+func (t *OrderTracker) unlock() {
+	events, errs := t.events, t.errors
+	t.events, t.errors = nil, nil
+	t.mu.Unlock()
+
+	if t.config.OnChange != nil {
+		for _, event := range events {
+			t.config.OnChange(event)
+		}
+	}
+	if t.config.OnError != nil {
+		for _, err := range errs {
+			t.config.OnError(err)
+		}
+	}
+}
@@ -0,0 +1,435 @@
+package kraken_test
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	kraken "github.com/astaluego/golang-kraken"
+	"github.com/shopspring/decimal"
+)
+
+func TestOrderTrackerFillOnce(t *testing.T) {
+	trader := newFakeTrader()
+	events := []kraken.OrderEvent{}
+	tracker, err := kraken.NewOrderTracker(trader, kraken.OrderTrackerConfig{
+		OnChange: func(event kraken.OrderEvent) {
+			events = append(events, event)
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	config := limitOrder(kraken.Buy, "30000", "2")
+	result, err := trader.AddOrder(config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tracker.TrackAdded(config, result)
+
+	half := decimal.RequireFromString("0.5")
+	cost := decimal.NewFromInt(15000)
+	fee := decimal.NewFromInt(39)
+
+	// The same trade received twice is applied once
+	for i := 0; i < 2; i++ {
+		if err := tracker.Fill("O1", "T1", half, cost, fee); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := tracker.Fill("O1", "T2", half, cost, fee); err != nil {
+		t.Fatal(err)
+	}
+	order, _ := tracker.Order("O1")
+	if order.VolumeExecuted.String() != "1" || order.Cost.String() != "30000" || order.Fee.String() != "78" || order.Status != kraken.Open {
+		t.Errorf("unexpected order: %+v", order)
+	}
+	if len(events) != 3 {
+		t.Errorf("expected 3 events, got %d", len(events))
+	}
+
+	if err := tracker.Fill("O1", "", half, cost, fee); err == nil {
+		t.Error("expected an error without trade ID")
+	}
+	if err := tracker.Fill("O2", "T3", half, cost, fee); err == nil {
+		t.Error("expected an error for an unknown order")
+	}
+}
+
+func TestOrderTrackerReconcileTotals(t *testing.T) {
+	trader := newFakeTrader()
+	tracker, err := kraken.NewOrderTracker(trader, kraken.OrderTrackerConfig{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	config := limitOrder(kraken.Buy, "30000", "2")
+	result, err := trader.AddOrder(config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tracker.TrackAdded(config, result)
+
+	// The stream overstates the executed volume
+	if err := tracker.Update("O1", kraken.Order{Status: kraken.Open, Volume: decimal.NewFromInt(2), VolumeExecuted: decimal.NewFromInt(1)}); err != nil {
+		t.Fatal(err)
+	}
+
+	// Kraken executed the trade T1 only
+	order := trader.orders["O1"]
+	order.VolumeExecuted = decimal.RequireFromString("0.5")
+	order.Cost = decimal.NewFromInt(15000)
+	order.TradesIDs = []string{"T1"}
+	trader.orders["O1"] = order
+
+	if err := tracker.Reconcile(); err != nil {
+		t.Fatal(err)
+	}
+	tracked, _ := tracker.Order("O1")
+	if tracked.VolumeExecuted.String() != "0.5" || tracked.Cost.String() != "15000" {
+		t.Errorf("expected the totals of Kraken, got %+v", tracked)
+	}
+
+	// T1 is already included in the totals of Kraken, T2 is new
+	half := decimal.RequireFromString("0.5")
+	if err := tracker.Fill("O1", "T1", half, decimal.NewFromInt(15000), decimal.Zero); err != nil {
+		t.Fatal(err)
+	}
+	if err := tracker.Fill("O1", "T2", half, decimal.NewFromInt(15000), decimal.Zero); err != nil {
+		t.Fatal(err)
+	}
+	tracked, _ = tracker.Order("O1")
+	if tracked.VolumeExecuted.String() != "1" || tracked.Cost.String() != "30000" {
+		t.Errorf("unexpected order: %+v", tracked)
+	}
+}
+
+// querier is a fakeTrader supporting Orders, like Client
+type querier struct {
+	*fakeTrader
+	queried [][]string
+}
+
+func (q *querier) Orders(config kraken.OrdersConfig) (map[string]kraken.Order, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	q.queried = append(q.queried, config.TransactionIDs)
+	orders := make(map[string]kraken.Order)
+	for _, txid := range config.TransactionIDs {
+		if order, ok := q.orders[txid]; ok {
+			orders[txid] = order
+		}
+	}
+	return orders, nil
+}
+
+func TestOrderStatusCanTransitionTo(t *testing.T) {
+	statuses := []kraken.OrderStatus{kraken.Pending, kraken.Open, kraken.Closed, kraken.Canceled, kraken.Expired}
+	valid := map[kraken.OrderStatus][]kraken.OrderStatus{
+		kraken.Pending:  {kraken.Pending, kraken.Open, kraken.Closed, kraken.Canceled, kraken.Expired},
+		kraken.Open:     {kraken.Open, kraken.Closed, kraken.Canceled, kraken.Expired},
+		kraken.Closed:   {kraken.Closed},
+		kraken.Canceled: {kraken.Canceled},
+		kraken.Expired:  {kraken.Expired},
+	}
+
+	for _, from := range statuses {
+		for _, to := range statuses {
+			expected := false
+			for _, status := range valid[from] {
+				expected = expected || status == to
+			}
+			if from.CanTransitionTo(to) != expected {
+				t.Errorf("expected %s to %s to be valid: %t", from, to, expected)
+			}
+		}
+		if from.IsFinal() != (len(valid[from]) == 1) {
+			t.Errorf("unexpected IsFinal of %s", from)
+		}
+	}
+}
+
+func TestOrderTrackerIgnoresStaleSnapshots(t *testing.T) {
+	trader := newFakeTrader()
+	errs := []error{}
+	events := []kraken.OrderEvent{}
+	tracker, err := kraken.NewOrderTracker(trader, kraken.OrderTrackerConfig{
+		OnChange: func(event kraken.OrderEvent) { events = append(events, event) },
+		OnError:  func(err error) { errs = append(errs, err) },
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	config := limitOrder(kraken.Buy, "30000", "1")
+	result, err := trader.AddOrder(config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tracker.TrackAdded(config, result)
+
+	// The stream closes the order, while Kraken still lists it as open
+	closed := kraken.Order{Status: kraken.Closed, Volume: decimal.NewFromInt(1), VolumeExecuted: decimal.NewFromInt(1)}
+	if err := tracker.Update("O1", closed); err != nil {
+		t.Fatal(err)
+	}
+	if err := tracker.Reconcile(); err != nil {
+		t.Fatal(err)
+	}
+
+	var transitionErr *kraken.TransitionError
+	if len(errs) != 1 || !errors.As(errs[0], &transitionErr) {
+		t.Fatalf("expected a *TransitionError, got %v", errs)
+	}
+	if transitionErr.TransactionID != "O1" || transitionErr.From != kraken.Closed || transitionErr.To != kraken.Open || transitionErr.Source != kraken.SourceREST {
+		t.Errorf("unexpected error: %+v", transitionErr)
+	}
+	if order, _ := tracker.Order("O1"); order.Status != kraken.Closed || !order.VolumeExecuted.Equal(decimal.NewFromInt(1)) {
+		t.Errorf("expected the order to stay closed, got %+v", order)
+	}
+
+	// The error is returned to the caller of Update as well
+	err = tracker.Update("O1", kraken.Order{Status: kraken.Open})
+	if !errors.As(err, &transitionErr) || transitionErr.Source != kraken.SourceStream || len(errs) != 2 {
+		t.Errorf("expected a *TransitionError, got %v", err)
+	}
+
+	expected := []kraken.OrderStatus{kraken.Pending, kraken.Closed}
+	if len(events) != len(expected) {
+		t.Fatalf("expected %d events, got %+v", len(expected), events)
+	}
+	for i, event := range events {
+		if event.Order.Status != expected[i] {
+			t.Errorf("event %d: expected %s, got %s", i, expected[i], event.Order.Status)
+		}
+	}
+	if events[0].PreviousStatus != "" || events[0].Source != kraken.SourceLocal || events[1].PreviousStatus != kraken.Pending || events[1].Source != kraken.SourceStream {
+		t.Errorf("unexpected events: %+v", events)
+	}
+}
+
+func TestOrderTrackerCanceled(t *testing.T) {
+	trader := newFakeTrader()
+	events := []kraken.OrderEvent{}
+	tracker, err := kraken.NewOrderTracker(trader, kraken.OrderTrackerConfig{
+		OnChange: func(event kraken.OrderEvent) { events = append(events, event) },
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, price := range []string{"30000", "31000"} {
+		config := limitOrder(kraken.Buy, price, "1")
+		result, err := trader.AddOrder(config)
+		if err != nil {
+			t.Fatal(err)
+		}
+		tracker.TrackAdded(config, result)
+	}
+
+	if err := tracker.Canceled("O1", "User requested"); err != nil {
+		t.Fatal(err)
+	}
+	order, _ := tracker.Order("O1")
+	if order.Status != kraken.Canceled || order.Reason != "User requested" || order.ClosedAt.IsZero() {
+		t.Errorf("unexpected order: %+v", order)
+	}
+	if event := events[len(events)-1]; event.TransactionID != "O1" || event.PreviousStatus != kraken.Pending || event.Source != kraken.SourceLocal {
+		t.Errorf("unexpected event: %+v", event)
+	}
+	if open := tracker.OpenOrders(); len(open) != 1 || open["O2"].Status != kraken.Pending {
+		t.Errorf("expected O2 to be the only open order, got %v", open)
+	}
+
+	// A closed order cannot be canceled
+	if err := tracker.Update("O2", kraken.Order{Status: kraken.Closed}); err != nil {
+		t.Fatal(err)
+	}
+	var transitionErr *kraken.TransitionError
+	if err := tracker.Canceled("O2", ""); !errors.As(err, &transitionErr) {
+		t.Errorf("expected a *TransitionError, got %v", err)
+	}
+	if err := tracker.Canceled("O3", ""); err == nil {
+		t.Error("expected an error for an unknown order")
+	}
+}
+
+func TestOrderTrackerLookups(t *testing.T) {
+	trader := newFakeTrader()
+	tracker, err := kraken.NewOrderTracker(trader, kraken.OrderTrackerConfig{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	orders := []struct {
+		userReference int64
+		clientOrderID string
+	}{
+		{7, "grid-1"},
+		{7, "grid-2"},
+		{8, ""},
+	}
+	for _, o := range orders {
+		config := limitOrder(kraken.Buy, "30000", "1")
+		config.UserReference = o.userReference
+		config.ClientOrderID = o.clientOrderID
+		result, err := trader.AddOrder(config)
+		if err != nil {
+			t.Fatal(err)
+		}
+		tracker.TrackAdded(config, result)
+	}
+
+	// An update not carrying the identifiers keeps them
+	if err := tracker.Update("O2", kraken.Order{Status: kraken.Open}); err != nil {
+		t.Fatal(err)
+	}
+
+	if grid := tracker.ByUserReference(7); len(grid) != 2 || grid["O1"].ClientOrderID != "grid-1" || grid["O2"].Status != kraken.Open {
+		t.Errorf("unexpected orders of the user reference 7: %v", grid)
+	}
+	if orders := tracker.ByUserReference(9); len(orders) != 0 {
+		t.Errorf("expected no order, got %v", orders)
+	}
+	if txid, order, ok := tracker.ByClientOrderID("grid-2"); !ok || txid != "O2" || order.UserReferenceID != 7 {
+		t.Errorf("unexpected order: %s %+v", txid, order)
+	}
+	if _, _, ok := tracker.ByClientOrderID("grid-3"); ok {
+		t.Error("expected no order for an unknown client order ID")
+	}
+	if all := tracker.Orders(); len(all) != 3 {
+		t.Errorf("expected 3 orders, got %d", len(all))
+	}
+}
+
+func TestOrderTrackerPrune(t *testing.T) {
+	trader := newFakeTrader()
+	tracker, err := kraken.NewOrderTracker(trader, kraken.OrderTrackerConfig{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for i := 0; i < 3; i++ {
+		config := limitOrder(kraken.Buy, "30000", "1")
+		config.ClientOrderID = fmt.Sprintf("client-%d", i+1)
+		result, err := trader.AddOrder(config)
+		if err != nil {
+			t.Fatal(err)
+		}
+		tracker.TrackAdded(config, result)
+	}
+
+	// O1 is filled and closed an hour ago, O2 canceled just now, O3 still open
+	now := time.Now()
+	one := decimal.NewFromInt(1)
+	if err := tracker.Fill("O1", "T1", one, decimal.NewFromInt(30000), decimal.Zero); err != nil {
+		t.Fatal(err)
+	}
+	if err := tracker.Update("O1", kraken.Order{Status: kraken.Closed, Volume: one, VolumeExecuted: one, ClosedAt: now.Add(-time.Hour)}); err != nil {
+		t.Fatal(err)
+	}
+	if err := tracker.Update("O2", kraken.Order{Status: kraken.Canceled, ClosedAt: now}); err != nil {
+		t.Fatal(err)
+	}
+
+	if count := tracker.Prune(now.Add(-time.Minute)); count != 1 {
+		t.Errorf("expected 1 order pruned, got %d", count)
+	}
+	if _, ok := tracker.Order("O1"); ok {
+		t.Error("expected O1 to be pruned")
+	}
+	if _, _, ok := tracker.ByClientOrderID("client-1"); ok {
+		t.Error("expected the client order ID of O1 to be forgotten")
+	}
+	if _, _, ok := tracker.ByClientOrderID("client-2"); !ok {
+		t.Error("expected O2 to be kept")
+	}
+	// The trades of the pruned orders are forgotten with them
+	if err := tracker.Fill("O1", "T1", one, decimal.NewFromInt(30000), decimal.Zero); err == nil {
+		t.Error("expected an error for a pruned order")
+	}
+
+	// Open orders are never pruned
+	if count := tracker.Prune(now.Add(time.Hour)); count != 1 || len(tracker.Orders()) != 1 {
+		t.Errorf("expected O2 only to be pruned, got %d and %v", count, tracker.Orders())
+	}
+}
+
+func TestOrderTrackerReconcileQueriesMissingOrders(t *testing.T) {
+	trader := &querier{fakeTrader: newFakeTrader()}
+	tracker, err := kraken.NewOrderTracker(trader, kraken.OrderTrackerConfig{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// 60 orders tracked, all closed, followed by 50 orders closed elsewhere
+	for i := 0; i < 60; i++ {
+		config := limitOrder(kraken.Buy, "30000", "1")
+		result, err := trader.AddOrder(config)
+		if err != nil {
+			t.Fatal(err)
+		}
+		tracker.TrackAdded(config, result)
+		trader.execute(result.TransactionIDs[0], "1")
+	}
+	closedAt := time.Now().Add(time.Hour)
+	for i := 0; i < 50; i++ {
+		trader.orders[fmt.Sprintf("X%d", i)] = kraken.Order{Status: kraken.Closed, ClosedAt: closedAt.Add(time.Duration(i) * time.Second)}
+	}
+
+	if err := tracker.Reconcile(); err != nil {
+		t.Fatal(err)
+	}
+
+	// The first page of ClosedOrders is the only one read, the orders missing from it are
+	// queried by batches of 50
+	if len(trader.offsets) != 1 {
+		t.Errorf("expected a single page, got the offsets %v", trader.offsets)
+	}
+	if len(trader.queried) != 2 || len(trader.queried[0]) != 50 || len(trader.queried[1]) != 10 {
+		t.Fatalf("expected 2 batches of 50 and 10 orders, got %v", trader.queried)
+	}
+	if open := tracker.OpenOrders(); len(open) != 0 {
+		t.Errorf("expected every order to be closed, got %d open", len(open))
+	}
+
+	// Nothing is left to query
+	trader.queried = nil
+	if err := tracker.Reconcile(); err != nil {
+		t.Fatal(err)
+	}
+	if len(trader.queried) != 0 {
+		t.Errorf("unexpected queries: %v", trader.queried)
+	}
+}
+
+func TestOrderTrackerReconcileWithoutOrders(t *testing.T) {
+	trader := newFakeTrader()
+	tracker, err := kraken.NewOrderTracker(trader, kraken.OrderTrackerConfig{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	config := limitOrder(kraken.Buy, "30000", "1")
+	result, err := trader.AddOrder(config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tracker.TrackAdded(config, result)
+	trader.execute("O1", "1")
+	closedAt := time.Now().Add(time.Hour)
+	for i := 0; i < 50; i++ {
+		trader.orders[fmt.Sprintf("X%d", i)] = kraken.Order{Status: kraken.Closed, ClosedAt: closedAt.Add(time.Duration(i) * time.Second)}
+	}
+
+	// The order is neither open nor on the page, and the trader cannot query it: it is left as is
+	if err := tracker.Reconcile(); err != nil {
+		t.Fatal(err)
+	}
+	if order, _ := tracker.Order("O1"); order.Status != kraken.Pending {
+		t.Errorf("expected the order to be left pending, got %s", order.Status)
+	}
+}
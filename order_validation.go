@@ -0,0 +1,237 @@
+package kraken
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/shopspring/decimal"
+)
+
+// ViolationCode identifies the trading rule broken by an order
+type ViolationCode string
+
+const (
+	// ViolationMissingField is a required field left empty (e.g. the price of a limit order)
+	ViolationMissingField ViolationCode = "missing_field"
+	// ViolationVolumeBelowMin is a volume lower than AssetPairsInfo.OrderMin
+	ViolationVolumeBelowMin ViolationCode = "volume_below_min"
+	// ViolationVolumePrecision is a volume with more decimals than AssetPairsInfo.LotDecimals
+	ViolationVolumePrecision ViolationCode = "volume_precision"
+	// ViolationCostBelowMin is a cost (volume * price) lower than AssetPairsInfo.CostMin
+	ViolationCostBelowMin ViolationCode = "cost_below_min"
+	// ViolationPriceOffTick is a price which is not a multiple of AssetPairsInfo.TickSize
+	ViolationPriceOffTick ViolationCode = "price_off_tick"
+	// ViolationLeverage is a leverage missing from AssetPairsInfo.LeverageBuy or LeverageSell
+	ViolationLeverage ViolationCode = "leverage"
+	// ViolationPairStatus is an order forbidden by AssetPairsInfo.Status
+	ViolationPairStatus ViolationCode = "pair_status"
+)
+
+// Violation is a trading rule broken by an order
+type Violation struct {
+	Code ViolationCode
+	// Field of AddOrderConfig at fault (e.g. Volume, Price)
+	Field string
+	// Value of the field, or computed from it (e.g. the cost)
+	Value decimal.Decimal
+	// Limit of the rule (e.g. OrderMin, TickSize), if any
+	Limit decimal.Decimal
+	// Message describes the violation
+	Message string
+}
+
+func (v Violation) Error() string {
+	return fmt.Sprintf("%s: %s", v.Field, v.Message)
+}
+
+// ValidationError is returned when an order breaks the trading rules of its pair
+type ValidationError struct {
+	AssetPair  AssetPair
+	Violations []Violation
+}
+
+func (e *ValidationError) Error() string {
+	messages := []string{}
+	for _, violation := range e.Violations {
+		messages = append(messages, violation.Error())
+	}
+	return fmt.Sprintf("invalid order on %s: %s", e.AssetPair, strings.Join(messages, ", "))
+}
+
+// Has returns true if one of the violations has the given code
+func (e *ValidationError) Has(code ViolationCode) bool {
+	for _, violation := range e.Violations {
+		if violation.Code == code {
+			return true
+		}
+	}
+	return false
+}
+
+// ValidateOrder checks an order against the trading rules of its pair, before it is submitted.
+// referencePrice is optional, it is used to check the cost of the orders without a price
+// (e.g. market orders), typically the last trade price of TickerInformation.
+// It returns a *ValidationError listing every violation, or nil.
+func ValidateOrder(info AssetPairsInfo, order AddOrderConfig, referencePrice decimal.Decimal) error {
+	violations := []Violation{}
+	add := func(code ViolationCode, field string, value, limit decimal.Decimal, format string, args ...interface{}) {
+		violations = append(violations, Violation{
+			Code:    code,
+			Field:   field,
+			Value:   value,
+			Limit:   limit,
+			Message: fmt.Sprintf(format, args...),
+		})
+	}
+
+	if order.Type != Buy && order.Type != Sell {
+		add(ViolationMissingField, "Type", decimal.Zero, decimal.Zero, "must be buy or sell")
+	}
+	if order.OrderType == "" {
+		add(ViolationMissingField, "OrderType", decimal.Zero, decimal.Zero, "is required")
+	}
+
+	// Pair status
+	switch info.Status {
+	case AssetPairCancelOnly:
+		add(ViolationPairStatus, "AssetPair", decimal.Zero, decimal.Zero, "only cancellations are accepted (%s)", info.Status)
+	case AssetPairPostOnly:
+		if order.OrderType != Limit || !order.HasFlag(Post) {
+			add(ViolationPairStatus, "OrderType", decimal.Zero, decimal.Zero, "only post-only limit orders are accepted (%s)", info.Status)
+		}
+	case AssetPairLimitOnly:
+		if order.OrderType != Limit {
+			add(ViolationPairStatus, "OrderType", decimal.Zero, decimal.Zero, "only limit orders are accepted (%s)", info.Status)
+		}
+	case AssetPairReduceOnly:
+		if !order.ReduceOnly {
+			add(ViolationPairStatus, "ReduceOnly", decimal.Zero, decimal.Zero, "only reduce-only orders are accepted (%s)", info.Status)
+		}
+	}
+
+	// Prices
+	price := order.Price
+	switch order.OrderType {
+	case Limit, StopLoss, TakeProfit, StopLossLimit, TakeProfitLimit:
+		if !order.Price.IsPositive() {
+			add(ViolationMissingField, "Price", order.Price, decimal.Zero, "is required for %s orders", order.OrderType)
+		} else if !info.onTick(order.Price) {
+			add(ViolationPriceOffTick, "Price", order.Price, info.tickSize(), "must be a multiple of %s", info.tickSize())
+		}
+	}
+	switch order.OrderType {
+	case StopLossLimit, TakeProfitLimit:
+		if !order.Price2.IsPositive() {
+			add(ViolationMissingField, "Price2", order.Price2, decimal.Zero, "is required for %s orders", order.OrderType)
+		} else if !info.onTick(order.Price2) {
+			add(ViolationPriceOffTick, "Price2", order.Price2, info.tickSize(), "must be a multiple of %s", info.tickSize())
+		}
+		// The order executes at its limit price
+		price = order.Price2
+	case Market:
+		price = referencePrice
+	}
+
+	// Volume and cost
+	volume, cost := order.Volume, decimal.Zero
+	if order.HasFlag(Viqc) {
+		cost = order.Volume
+		volume = decimal.Zero
+		if price.IsPositive() {
+			volume = order.Volume.Div(price)
+		}
+	} else if price.IsPositive() {
+		cost = order.Volume.Mul(price)
+	}
+
+	if !order.Volume.IsPositive() {
+		add(ViolationMissingField, "Volume", order.Volume, decimal.Zero, "must be positive")
+	} else {
+		if !order.HasFlag(Viqc) && !order.Volume.Equal(info.RoundVolume(order.Volume)) {
+			add(ViolationVolumePrecision, "Volume", order.Volume, decimal.New(1, -int32(info.LotDecimals)), "must have at most %d decimals", info.LotDecimals)
+		}
+		if volume.IsPositive() && volume.LessThan(info.OrderMin) {
+			add(ViolationVolumeBelowMin, "Volume", volume, info.OrderMin, "%s is below the minimum of %s", volume, info.OrderMin)
+		}
+		if cost.IsPositive() && cost.LessThan(info.CostMin) {
+			add(ViolationCostBelowMin, "Volume", cost, info.CostMin, "cost %s is below the minimum of %s", cost, info.CostMin)
+		}
+	}
+
+	// Leverage
+	if order.Leverage > 0 {
+		leverages := info.LeverageBuy
+		if order.Type == Sell {
+			leverages = info.LeverageSell
+		}
+		supported := false
+		for _, leverage := range leverages {
+			if leverage == order.Leverage {
+				supported = true
+			}
+		}
+		if !supported {
+			add(ViolationLeverage, "Leverage", decimal.NewFromInt(order.Leverage), decimal.Zero, "%d is not supported for %s orders (supported: %v)", order.Leverage, order.Type, leverages)
+		}
+	}
+
+	if len(violations) == 0 {
+		return nil
+	}
+	return &ValidationError{AssetPair: order.AssetPair, Violations: violations}
+}
+
+// RoundPrice rounds a price to the nearest multiple of the tick size
+func (info AssetPairsInfo) RoundPrice(price decimal.Decimal) decimal.Decimal {
+	tick := info.tickSize()
+	return price.DivRound(tick, 0).Mul(tick)
+}
+
+// FloorPrice rounds a price down to a multiple of the tick size, e.g. for a buy limit order
+func (info AssetPairsInfo) FloorPrice(price decimal.Decimal) decimal.Decimal {
+	tick := info.tickSize()
+	return price.Div(tick).Floor().Mul(tick)
+}
+
+// CeilPrice rounds a price up to a multiple of the tick size, e.g. for a sell limit order
+func (info AssetPairsInfo) CeilPrice(price decimal.Decimal) decimal.Decimal {
+	tick := info.tickSize()
+	return price.Div(tick).Ceil().Mul(tick)
+}
+
+// RoundVolume rounds a volume down to the lot decimals, so it never exceeds the volume available
+func (info AssetPairsInfo) RoundVolume(volume decimal.Decimal) decimal.Decimal {
+	return volume.Truncate(int32(info.LotDecimals))
+}
+
+// RoundOrder returns the order with its prices on the tick grid, in the conservative direction
+// (down for buys, up for sells), and its volume rounded down to the lot decimals
+func (info AssetPairsInfo) RoundOrder(order AddOrderConfig) AddOrderConfig {
+	round := info.FloorPrice
+	if order.Type == Sell {
+		round = info.CeilPrice
+	}
+
+	if !order.Price.IsZero() {
+		order.Price = round(order.Price)
+	}
+	if !order.Price2.IsZero() {
+		order.Price2 = round(order.Price2)
+	}
+	if !order.HasFlag(Viqc) {
+		order.Volume = info.RoundVolume(order.Volume)
+	}
+	return order
+}
+
+// tickSize returns TickSize, or the price precision when Kraken does not send it
+func (info AssetPairsInfo) tickSize() decimal.Decimal {
+	if info.TickSize.IsPositive() {
+		return info.TickSize
+	}
+	return decimal.New(1, -int32(info.PairDecimals))
+}
+
+func (info AssetPairsInfo) onTick(price decimal.Decimal) bool {
+	return price.Mod(info.tickSize()).IsZero()
+}
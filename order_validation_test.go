@@ -0,0 +1,243 @@
+package kraken_test
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	kraken "github.com/astaluego/golang-kraken"
+	"github.com/shopspring/decimal"
+)
+
+var xbtUSD = kraken.AssetPairsInfo{
+	PairDecimals: 1,
+	LotDecimals:  8,
+	LeverageBuy:  []int64{2, 3},
+	LeverageSell: []int64{2},
+	OrderMin:     decimal.RequireFromString("0.0001"),
+	CostMin:      decimal.RequireFromString("0.5"),
+	TickSize:     decimal.RequireFromString("0.1"),
+	Status:       kraken.AssetPairOnline,
+}
+
+func TestValidateOrder(t *testing.T) {
+	d := decimal.RequireFromString
+	limit := func(volume, price string) kraken.AddOrderConfig {
+		return kraken.AddOrderConfig{
+			AssetPair: kraken.XBT_USD,
+			Type:      kraken.Buy,
+			OrderType: kraken.Limit,
+			Volume:    d(volume),
+			Price:     d(price),
+		}
+	}
+	withOrder := func(order kraken.AddOrderConfig, fn func(order *kraken.AddOrderConfig)) kraken.AddOrderConfig {
+		fn(&order)
+		return order
+	}
+
+	tests := []struct {
+		name           string
+		info           kraken.AssetPairsInfo
+		order          kraken.AddOrderConfig
+		referencePrice string
+		expected       []kraken.ViolationCode
+	}{
+		{"valid", xbtUSD, limit("0.01", "30000.1"), "0", nil},
+		{"volume below minimum", xbtUSD, limit("0.00005", "30000"), "0", []kraken.ViolationCode{kraken.ViolationVolumeBelowMin}},
+		{"cost below minimum", xbtUSD, limit("0.0001", "1000"), "0", []kraken.ViolationCode{kraken.ViolationCostBelowMin}},
+		{"volume precision", xbtUSD, limit("0.010000001", "30000"), "0", []kraken.ViolationCode{kraken.ViolationVolumePrecision}},
+		{"price off tick", xbtUSD, limit("0.01", "30000.15"), "0", []kraken.ViolationCode{kraken.ViolationPriceOffTick}},
+		{
+			name:     "tick from the pair decimals",
+			info:     withInfo(xbtUSD, func(info *kraken.AssetPairsInfo) { info.TickSize = decimal.Zero; info.PairDecimals = 2 }),
+			order:    limit("0.01", "30000.15"),
+			expected: nil,
+		},
+		{"missing price", xbtUSD, limit("0.01", "0"), "0", []kraken.ViolationCode{kraken.ViolationMissingField}},
+		{
+			name: "missing limit price of a stop-loss-limit",
+			info: xbtUSD,
+			order: withOrder(limit("0.01", "29000"), func(order *kraken.AddOrderConfig) {
+				order.OrderType = kraken.StopLossLimit
+			}),
+			expected: []kraken.ViolationCode{kraken.ViolationMissingField},
+		},
+		{
+			name: "limit price of a stop-loss-limit off tick",
+			info: xbtUSD,
+			order: withOrder(limit("0.01", "29000"), func(order *kraken.AddOrderConfig) {
+				order.OrderType = kraken.StopLossLimit
+				order.Price2 = d("28999.95")
+			}),
+			expected: []kraken.ViolationCode{kraken.ViolationPriceOffTick},
+		},
+		{"missing volume", xbtUSD, limit("0", "30000"), "0", []kraken.ViolationCode{kraken.ViolationMissingField}},
+		{
+			name: "market order checked against the reference price",
+			info: xbtUSD,
+			order: withOrder(limit("0.00001", "0"), func(order *kraken.AddOrderConfig) {
+				order.OrderType = kraken.Market
+			}),
+			referencePrice: "30000",
+			expected:       []kraken.ViolationCode{kraken.ViolationVolumeBelowMin, kraken.ViolationCostBelowMin},
+		},
+		{
+			name: "market order without reference price",
+			info: xbtUSD,
+			order: withOrder(limit("0.001", "0"), func(order *kraken.AddOrderConfig) {
+				order.OrderType = kraken.Market
+			}),
+			expected: nil,
+		},
+		{
+			// 100 USD at 30000 is 0.00333 XBT, more decimals than the lot is fine in quote currency
+			name: "viqc volume in quote currency",
+			info: xbtUSD,
+			order: withOrder(limit("100.123456789", "30000"), func(order *kraken.AddOrderConfig) {
+				order.Flags = []kraken.OrderFlag{kraken.Viqc}
+			}),
+			expected: nil,
+		},
+		{
+			name: "viqc volume below minimum",
+			info: xbtUSD,
+			order: withOrder(limit("2", "30000"), func(order *kraken.AddOrderConfig) {
+				order.Flags = []kraken.OrderFlag{kraken.Viqc}
+			}),
+			expected: []kraken.ViolationCode{kraken.ViolationVolumeBelowMin},
+		},
+		{
+			name: "viqc cost below minimum",
+			info: withInfo(xbtUSD, func(info *kraken.AssetPairsInfo) { info.OrderMin = decimal.Zero }),
+			order: withOrder(limit("0.4", "30000"), func(order *kraken.AddOrderConfig) {
+				order.Flags = []kraken.OrderFlag{kraken.Viqc}
+			}),
+			expected: []kraken.ViolationCode{kraken.ViolationCostBelowMin},
+		},
+		{
+			name:     "unsupported leverage",
+			info:     xbtUSD,
+			order:    withOrder(limit("0.01", "30000"), func(order *kraken.AddOrderConfig) { order.Type = kraken.Sell; order.Leverage = 3 }),
+			expected: []kraken.ViolationCode{kraken.ViolationLeverage},
+		},
+		{
+			name:     "limit only",
+			info:     withInfo(xbtUSD, func(info *kraken.AssetPairsInfo) { info.Status = kraken.AssetPairLimitOnly }),
+			order:    withOrder(limit("0.01", "0"), func(order *kraken.AddOrderConfig) { order.OrderType = kraken.Market }),
+			expected: []kraken.ViolationCode{kraken.ViolationPairStatus},
+		},
+		{
+			name:     "post only",
+			info:     withInfo(xbtUSD, func(info *kraken.AssetPairsInfo) { info.Status = kraken.AssetPairPostOnly }),
+			order:    limit("0.01", "30000"),
+			expected: []kraken.ViolationCode{kraken.ViolationPairStatus},
+		},
+		{
+			name:     "cancel only",
+			info:     withInfo(xbtUSD, func(info *kraken.AssetPairsInfo) { info.Status = kraken.AssetPairCancelOnly }),
+			order:    limit("0.00005", "30000.15"),
+			expected: []kraken.ViolationCode{kraken.ViolationPairStatus, kraken.ViolationPriceOffTick, kraken.ViolationVolumeBelowMin},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			referencePrice := decimal.Zero
+			if tt.referencePrice != "" {
+				referencePrice = d(tt.referencePrice)
+			}
+
+			err := kraken.ValidateOrder(tt.info, tt.order, referencePrice)
+			if tt.expected == nil {
+				if err != nil {
+					t.Errorf("expected no violation, got %v", err)
+				}
+				return
+			}
+
+			var validationErr *kraken.ValidationError
+			if !errors.As(err, &validationErr) {
+				t.Fatalf("expected a *ValidationError, got %v", err)
+			}
+			codes := []kraken.ViolationCode{}
+			for _, violation := range validationErr.Violations {
+				codes = append(codes, violation.Code)
+			}
+			if !reflect.DeepEqual(codes, tt.expected) {
+				t.Errorf("expected %v, got %v (%v)", tt.expected, codes, err)
+			}
+			for _, code := range tt.expected {
+				if !validationErr.Has(code) {
+					t.Errorf("expected Has(%s) to be true", code)
+				}
+			}
+		})
+	}
+}
+
+func TestValidateOrderViolationDetails(t *testing.T) {
+	err := kraken.ValidateOrder(xbtUSD, kraken.AddOrderConfig{
+		AssetPair: kraken.XBT_USD,
+		Type:      kraken.Buy,
+		OrderType: kraken.Limit,
+		Volume:    decimal.RequireFromString("0.0001"),
+		Price:     decimal.RequireFromString("1000"),
+	}, decimal.Zero)
+
+	var validationErr *kraken.ValidationError
+	if !errors.As(err, &validationErr) || len(validationErr.Violations) != 1 {
+		t.Fatalf("expected a single violation, got %v", err)
+	}
+	violation := validationErr.Violations[0]
+	if violation.Field != "Volume" || violation.Value.String() != "0.1" || violation.Limit.String() != "0.5" {
+		t.Errorf("unexpected violation: %+v", violation)
+	}
+	if err.Error() != "invalid order on XXBTZUSD: Volume: cost 0.1 is below the minimum of 0.5" {
+		t.Errorf("unexpected message: %s", err)
+	}
+}
+
+func TestRoundingHelpers(t *testing.T) {
+	d := decimal.RequireFromString
+	info := withInfo(xbtUSD, func(info *kraken.AssetPairsInfo) { info.TickSize = d("0.5") })
+
+	tests := []struct {
+		name     string
+		round    func(decimal.Decimal) decimal.Decimal
+		value    string
+		expected string
+	}{
+		{"RoundVolume truncates", info.RoundVolume, "0.123456789", "0.12345678"},
+		{"RoundVolume keeps a rounded volume", info.RoundVolume, "0.5", "0.5"},
+		{"FloorPrice", info.FloorPrice, "30000.9", "30000.5"},
+		{"FloorPrice on tick", info.FloorPrice, "30000.5", "30000.5"},
+		{"CeilPrice", info.CeilPrice, "30000.1", "30000.5"},
+		{"CeilPrice on tick", info.CeilPrice, "30000", "30000"},
+		{"RoundPrice down", info.RoundPrice, "30000.2", "30000"},
+		{"RoundPrice up", info.RoundPrice, "30000.3", "30000.5"},
+		{"FloorPrice from the pair decimals", withInfo(xbtUSD, func(info *kraken.AssetPairsInfo) { info.TickSize = decimal.Zero }).FloorPrice, "30000.19", "30000.1"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.round(d(tt.value)); !got.Equal(d(tt.expected)) {
+				t.Errorf("expected %s, got %s", tt.expected, got)
+			}
+		})
+	}
+
+	// Buys are rounded down, sells up
+	buy := info.RoundOrder(kraken.AddOrderConfig{Type: kraken.Buy, Volume: d("0.123456789"), Price: d("30000.9"), Price2: d("29000.4")})
+	if !buy.Price.Equal(d("30000.5")) || !buy.Price2.Equal(d("29000")) || !buy.Volume.Equal(d("0.12345678")) {
+		t.Errorf("unexpected buy order: %+v", buy)
+	}
+	sell := info.RoundOrder(kraken.AddOrderConfig{Type: kraken.Sell, Volume: d("100.123456789"), Price: d("30000.1"), Flags: []kraken.OrderFlag{kraken.Viqc}})
+	if !sell.Price.Equal(d("30000.5")) || !sell.Volume.Equal(d("100.123456789")) {
+		t.Errorf("unexpected sell order: %+v", sell)
+	}
+}
+
+func withInfo(info kraken.AssetPairsInfo, fn func(info *kraken.AssetPairsInfo)) kraken.AssetPairsInfo {
+	fn(&info)
+	return info
+}
@@ -0,0 +1,99 @@
+package kraken
+
+import (
+	"crypto/hmac"
+	"crypto/sha1"
+	"encoding/base32"
+	"encoding/binary"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// OTP provides the second factor sent along with private requests when the API key
+// is protected by a password or a 2FA app.
+type OTP interface {
+	Password(t time.Time) (string, error)
+}
+
+// StaticPassword is a static password used as second factor
+type StaticPassword string
+
+// Password returns the static password, whatever the time
+func (p StaticPassword) Password(time.Time) (string, error) {
+	return string(p), nil
+}
+
+// TOTP generates RFC 6238 time-based one-time passwords
+type TOTP struct {
+	secret []byte
+
+	// Digits is optional
+	// Default: 6
+	Digits int
+
+	// Period is optional
+	// Default: 30s
+	Period time.Duration
+}
+
+// NewTOTP inits a new TOTP generator seeded from a base32 secret, as displayed by Kraken
+// when the 2FA app is set up on the API key.
+func NewTOTP(secret string) (*TOTP, error) {
+	secret = strings.ToUpper(strings.ReplaceAll(secret, " ", ""))
+	secret = strings.TrimRight(secret, "=")
+
+	key, err := base32.StdEncoding.WithPadding(base32.NoPadding).DecodeString(secret)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode TOTP secret: %s", err.Error())
+	}
+	if len(key) == 0 {
+		return nil, fmt.Errorf("failed to decode TOTP secret: secret is empty")
+	}
+
+	return &TOTP{
+		secret: key,
+		Digits: 6,
+		Period: 30 * time.Second,
+	}, nil
+}
+
+// Password returns the one-time password valid at the given time
+func (t *TOTP) Password(now time.Time) (string, error) {
+	digits := t.Digits
+	if digits == 0 {
+		digits = 6
+	}
+	if digits < 6 || digits > 8 {
+		return "", fmt.Errorf("failed to generate TOTP: invalid number of digits %d", digits)
+	}
+
+	period := t.Period
+	if period == 0 {
+		period = 30 * time.Second
+	}
+	if period < time.Second {
+		return "", fmt.Errorf("failed to generate TOTP: period must be at least 1s")
+	}
+
+	counter := make([]byte, 8)
+	binary.BigEndian.PutUint64(counter, uint64(now.Unix()/int64(period/time.Second)))
+
+	mac := hmac.New(sha1.New, t.secret)
+	_, err := mac.Write(counter)
+	if err != nil {
+		return "", err
+	}
+	sum := mac.Sum(nil)
+
+	// Dynamic truncation (RFC 4226 section 5.3)
+	offset := sum[len(sum)-1] & 0x0f
+	code := binary.BigEndian.Uint32(sum[offset:offset+4]) & 0x7fffffff
+
+	modulo := uint32(1)
+	for i := 0; i < digits; i++ {
+		modulo *= 10
+	}
+
+	return fmt.Sprintf("%0*d", digits, code%modulo), nil
+}
@@ -0,0 +1,67 @@
+package kraken_test
+
+import (
+	"testing"
+	"time"
+
+	kraken "github.com/astaluego/golang-kraken"
+)
+
+// TestTOTPVectors checks the SHA1 test vectors of RFC 6238 Appendix B
+func TestTOTPVectors(t *testing.T) {
+	// base32 of the ASCII secret "12345678901234567890"
+	totp, err := kraken.NewTOTP("GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	vectors := []struct {
+		unix     int64
+		password string
+	}{
+		{59, "94287082"},
+		{1111111109, "07081804"},
+		{1111111111, "14050471"},
+		{1234567890, "89005924"},
+		{2000000000, "69279037"},
+		{20000000000, "65353130"},
+	}
+
+	for _, vector := range vectors {
+		totp.Digits = 8
+		password, err := totp.Password(time.Unix(vector.unix, 0))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if password != vector.password {
+			t.Errorf("%d: expected %s, got %s", vector.unix, vector.password, password)
+		}
+
+		// 6 digits are the last 6 digits of the same code
+		totp.Digits = 6
+		password, err = totp.Password(time.Unix(vector.unix, 0))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if password != vector.password[2:] {
+			t.Errorf("%d: expected %s, got %s", vector.unix, vector.password[2:], password)
+		}
+	}
+}
+
+func TestTOTPInvalid(t *testing.T) {
+	for _, secret := range []string{"", "not base32!"} {
+		if _, err := kraken.NewTOTP(secret); err == nil {
+			t.Errorf("%q: expected an error", secret)
+		}
+	}
+
+	totp, err := kraken.NewTOTP("gezd gnbv gy3t qojq")
+	if err != nil {
+		t.Fatal(err)
+	}
+	totp.Digits = 9
+	if _, err := totp.Password(time.Now()); err == nil {
+		t.Error("expected an error for 9 digits")
+	}
+}
@@ -0,0 +1,43 @@
+package kraken
+
+import "strings"
+
+// resolveAssetPair maps the pair name echoed back by Kraken (e.g. XXBTZUSD) to the asset pair
+// which was requested (e.g. XBTUSD, XBT/USD or XXBTZUSD)
+func resolveAssetPair(requested []AssetPair, name string) AssetPair {
+	for _, assetPair := range requested {
+		if string(assetPair) == name {
+			return assetPair
+		}
+	}
+
+	normalized := normalizePairName(name)
+	for _, assetPair := range requested {
+		if normalizePairName(string(assetPair)) == normalized {
+			return assetPair
+		}
+	}
+
+	// A single pair was requested and a single pair was returned under another name
+	if len(requested) == 1 {
+		return requested[0]
+	}
+
+	return AssetPair(name)
+}
+
+// normalizePairName removes the separators and the X/Z prefixes of the legacy names,
+// e.g. XXBTZUSD, XBT/USD and xbtusd all become XBTUSD
+func normalizePairName(name string) string {
+	name = strings.ToUpper(name)
+	name = strings.NewReplacer("/", "", "_", "", "-", "").Replace(name)
+
+	if len(name) == 8 && isLegacyPrefix(name[0]) && isLegacyPrefix(name[4]) {
+		return name[1:4] + name[5:8]
+	}
+	return name
+}
+
+func isLegacyPrefix(c byte) bool {
+	return c == 'X' || c == 'Z'
+}
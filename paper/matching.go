@@ -0,0 +1,294 @@
+package paper
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	kraken "github.com/astaluego/golang-kraken"
+	"github.com/shopspring/decimal"
+)
+
+var hundred = decimal.NewFromInt(100)
+
+// market is the liquidity of a pair: asks by ascending price, bids by descending price
+type market struct {
+	last decimal.Decimal
+	asks []level
+	bids []level
+}
+
+type level struct {
+	price  decimal.Decimal
+	volume decimal.Decimal
+	// unbounded is set for the prices of a ticker, whose volume is not known
+	unbounded bool
+}
+
+func levels(entries []kraken.OrderBookEntry) []level {
+	list := make([]level, 0, len(entries))
+	for _, entry := range entries {
+		list = append(list, level{price: entry.Price, volume: entry.Volume})
+	}
+	return list
+}
+
+// side returns the levels an order of the given type executes against
+func (m *market) side(orderType kraken.Type) []level {
+	if m == nil {
+		return nil
+	}
+	if orderType == kraken.Sell {
+		return m.bids
+	}
+	return m.asks
+}
+
+// best returns the best price an order of the given type executes at, or zero
+func (m *market) best(orderType kraken.Type) decimal.Decimal {
+	for _, l := range m.side(orderType) {
+		if l.unbounded || l.volume.IsPositive() {
+			return l.price
+		}
+	}
+	return decimal.Zero
+}
+
+// reference returns the price used to check the cost of market orders
+func (m *market) reference(orderType kraken.Type) decimal.Decimal {
+	if price := m.best(orderType); price.IsPositive() {
+		return price
+	}
+	if m == nil {
+		return decimal.Zero
+	}
+	return m.last
+}
+
+// crosses returns true if a limit order at the given price executes immediately
+func (m *market) crosses(orderType kraken.Type, price decimal.Decimal) bool {
+	best := m.best(orderType)
+	if best.IsZero() {
+		return false
+	}
+	if orderType == kraken.Sell {
+		return best.GreaterThanOrEqual(price)
+	}
+	return best.LessThanOrEqual(price)
+}
+
+// triggerPrice returns the price compared to the trigger of stop-loss and take-profit orders:
+// the last trade price, or the middle of the spread
+func (m *market) triggerPrice() decimal.Decimal {
+	if m == nil {
+		return decimal.Zero
+	}
+	if m.last.IsPositive() {
+		return m.last
+	}
+	ask, bid := m.best(kraken.Buy), m.best(kraken.Sell)
+	if ask.IsZero() || bid.IsZero() {
+		return decimal.Zero
+	}
+	return ask.Add(bid).Div(decimal.NewFromInt(2))
+}
+
+// reservation returns the funds locked by a new order: the quote cost including the taker fee
+// for buys, the base volume for sells
+func (t *Trader) reservation(info kraken.AssetPairsInfo, o *order, m *market) (kraken.Asset, decimal.Decimal) {
+	if o.config.Type == kraken.Sell {
+		return info.BaseAsset, o.config.Volume
+	}
+
+	fee := decimal.NewFromInt(1).Add(info.FeePercent(t.config.FeeVolume, false).Div(hundred))
+	if o.config.HasFlag(kraken.Viqc) {
+		return info.QuoteAsset, o.config.Volume.Mul(fee)
+	}
+
+	price := o.config.Price
+	switch o.config.OrderType {
+	case kraken.Market:
+		price = m.reference(kraken.Buy)
+	case kraken.StopLossLimit, kraken.TakeProfitLimit:
+		price = o.config.Price2
+	}
+	return info.QuoteAsset, o.config.Volume.Mul(price).Mul(fee)
+}
+
+// match processes the open orders of a pair after its market data changed
+func (t *Trader) match(pair kraken.AssetPair) {
+	t.tick()
+
+	orders := []*order{}
+	for _, o := range t.orders {
+		if o.isOpen() && o.config.AssetPair == pair {
+			orders = append(orders, o)
+		}
+	}
+	sort.Slice(orders, func(i, j int) bool { return orders[i].sequence < orders[j].sequence })
+
+	now := t.config.Now()
+	m := t.markets[pair]
+	for _, o := range orders {
+		t.process(t.config.Pairs[pair], o, m, now)
+	}
+}
+
+// tick activates the pending orders whose start time is reached and expires the open orders
+func (t *Trader) tick() {
+	now := t.config.Now()
+	for _, o := range t.orders {
+		if o.info.Status == kraken.Pending && !now.Before(o.info.StartAt) {
+			o.info.Status = kraken.Open
+		}
+		if o.isOpen() && !o.info.ExpireAt.IsZero() && !now.Before(o.info.ExpireAt) {
+			o.close(kraken.Expired, "Order expired", now)
+		}
+	}
+}
+
+// process triggers and fills an open order against the market
+func (t *Trader) process(info kraken.AssetPairsInfo, o *order, m *market, now time.Time) {
+	if o.info.Status != kraken.Open || m == nil {
+		return
+	}
+
+	if !o.triggered {
+		price := m.triggerPrice()
+		if !o.shouldTrigger(price) {
+			return
+		}
+		o.trigger(price)
+	}
+
+	limit := decimal.Zero
+	switch o.config.OrderType {
+	case kraken.Limit:
+		limit = o.config.Price
+	case kraken.StopLossLimit, kraken.TakeProfitLimit:
+		limit = o.config.Price2
+	}
+
+	t.fill(info, o, m, limit, now)
+
+	switch {
+	case !o.isOpen():
+	case limit.IsZero():
+		// Market orders never rest in the book, the volume left is lost
+		if o.info.VolumeExecuted.IsPositive() {
+			o.close(kraken.Closed, "Insufficient liquidity", now)
+			o.addMiscellaneous("partial")
+		} else {
+			o.close(kraken.Canceled, "Insufficient liquidity", now)
+		}
+	default:
+		o.resting = true
+	}
+}
+
+// fill executes an order against the levels of the market, up to its limit price (if any)
+func (t *Trader) fill(info kraken.AssetPairsInfo, o *order, m *market, limit decimal.Decimal, now time.Time) {
+	levels := m.side(o.config.Type)
+	fee := info.FeePercent(t.config.FeeVolume, o.resting)
+
+	for i := range levels {
+		l := &levels[i]
+		if !o.isOpen() {
+			return
+		}
+		if !l.unbounded && !l.volume.IsPositive() {
+			continue
+		}
+		if limit.IsPositive() {
+			if o.config.Type == kraken.Buy && l.price.GreaterThan(limit) {
+				return
+			}
+			if o.config.Type == kraken.Sell && l.price.LessThan(limit) {
+				return
+			}
+		}
+
+		// Resting orders execute at their own price when the market moves through them
+		price := l.price
+		if o.resting {
+			price = limit
+		}
+
+		volume := o.remaining()
+		if o.config.HasFlag(kraken.Viqc) {
+			volume = info.RoundVolume(volume.Div(price))
+		}
+		if !l.unbounded && l.volume.LessThan(volume) {
+			volume = l.volume
+		}
+		volume = t.affordable(info, o, price, fee, volume)
+		if !volume.IsPositive() {
+			return
+		}
+
+		t.execute(info, o, price, volume, fee, now)
+		if !l.unbounded {
+			l.volume = l.volume.Sub(volume)
+		}
+	}
+}
+
+// affordable caps a volume to the funds available to the order
+func (t *Trader) affordable(info kraken.AssetPairsInfo, o *order, price, fee, volume decimal.Decimal) decimal.Decimal {
+	if o.config.Type == kraken.Sell {
+		available := t.available(info.BaseAsset)
+		if o.reservedAsset == info.BaseAsset {
+			available = available.Add(o.reserved)
+		}
+		return decimal.Min(volume, available)
+	}
+
+	available := t.available(info.QuoteAsset)
+	if o.reservedAsset == info.QuoteAsset {
+		available = available.Add(o.reserved)
+	}
+	unit := price.Mul(decimal.NewFromInt(1).Add(fee.Div(hundred)))
+	return decimal.Min(volume, info.RoundVolume(available.Div(unit)))
+}
+
+// execute settles a fill on the balances and the order
+func (t *Trader) execute(info kraken.AssetPairsInfo, o *order, price, volume, fee decimal.Decimal, now time.Time) {
+	cost := price.Mul(volume)
+	feeAmount := cost.Mul(fee).Div(hundred)
+	if info.CostDecimals > 0 {
+		feeAmount = feeAmount.Round(int32(info.CostDecimals))
+	}
+
+	if o.config.Type == kraken.Sell {
+		t.balances[info.BaseAsset] = t.balances[info.BaseAsset].Sub(volume)
+		t.balances[info.QuoteAsset] = t.balances[info.QuoteAsset].Add(cost).Sub(feeAmount)
+		o.release(volume)
+	} else {
+		t.balances[info.QuoteAsset] = t.balances[info.QuoteAsset].Sub(cost).Sub(feeAmount)
+		t.balances[info.BaseAsset] = t.balances[info.BaseAsset].Add(volume)
+		o.release(cost.Add(feeAmount))
+	}
+
+	o.info.VolumeExecuted = o.info.VolumeExecuted.Add(volume)
+	o.info.Cost = o.info.Cost.Add(cost)
+	o.info.Fee = o.info.Fee.Add(feeAmount)
+	o.info.Price = o.info.Cost.Div(o.info.VolumeExecuted)
+
+	t.trades++
+	t.fills = append(t.fills, Fill{
+		TransactionID: o.txid,
+		TradeID:       fmt.Sprintf("TPAPER-%06d", t.trades),
+		AssetPair:     o.config.AssetPair,
+		Type:          o.config.Type,
+		Price:         price,
+		Volume:        volume,
+		Cost:          cost,
+		Fee:           feeAmount,
+		Maker:         o.resting,
+		Time:          now,
+	})
+
+	if o.filled(info, price) {
+		o.close(kraken.Closed, "", now)
+	}
+}
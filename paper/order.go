@@ -0,0 +1,143 @@
+package paper
+
+import (
+	"strings"
+	"time"
+
+	kraken "github.com/astaluego/golang-kraken"
+	"github.com/shopspring/decimal"
+)
+
+// order is a simulated order, info is what OpenOrders and ClosedOrders return
+type order struct {
+	txid     string
+	sequence int64
+	config   kraken.AddOrderConfig
+	info     kraken.Order
+
+	// Funds locked until the order is filled or closed
+	reservedAsset kraken.Asset
+	reserved      decimal.Decimal
+
+	// triggered is set once the order executes (always set for market and limit orders)
+	triggered bool
+	// resting is set once the order rests in the book, its fills are maker fills
+	resting bool
+}
+
+func newOrder(txid string, sequence int64, info kraken.AssetPairsInfo, config kraken.AddOrderConfig, now time.Time) *order {
+	o := &order{
+		txid:     txid,
+		sequence: sequence,
+		config:   config,
+	}
+
+	o.info.UserReferenceID = config.UserReference
+	o.info.ClientOrderID = config.ClientOrderID
+	o.info.Status = kraken.Open
+	if config.StartAt.After(now) {
+		o.info.Status = kraken.Pending
+	}
+	o.info.OpenedAt = now
+	o.info.StartAt = config.StartAt
+	o.info.ExpireAt = config.ExpireAt
+	o.info.OrderDescription.Pair = config.AssetPair
+	o.info.OrderDescription.Type = config.Type
+	o.info.OrderDescription.Ordertype = config.OrderType
+	o.info.OrderDescription.Price = config.Price
+	o.info.OrderDescription.Price2 = config.Price2
+	o.info.OrderDescription.Leverage = "none"
+	o.info.OrderDescription.Order = describe(info, config)
+	o.info.Volume = config.Volume
+	o.info.Flags = config.Flags
+
+	switch config.OrderType {
+	case kraken.StopLoss, kraken.TakeProfit, kraken.StopLossLimit, kraken.TakeProfitLimit:
+		o.info.Trigger = config.Trigger
+		if o.info.Trigger == "" {
+			o.info.Trigger = kraken.Last
+		}
+	default:
+		o.triggered = true
+	}
+	return o
+}
+
+func (o *order) isOpen() bool {
+	return o.info.Status == kraken.Pending || o.info.Status == kraken.Open
+}
+
+// remaining returns the volume left to execute, in quote currency for Viqc orders
+func (o *order) remaining() decimal.Decimal {
+	if o.config.HasFlag(kraken.Viqc) {
+		return o.config.Volume.Sub(o.info.Cost)
+	}
+	return o.config.Volume.Sub(o.info.VolumeExecuted)
+}
+
+// filled returns true when the volume left cannot be executed at price: none is left, or for Viqc
+// orders the quote currency left does not buy the smallest volume of the pair
+func (o *order) filled(info kraken.AssetPairsInfo, price decimal.Decimal) bool {
+	if o.config.HasFlag(kraken.Viqc) {
+		return info.RoundVolume(o.remaining().Div(price)).IsZero()
+	}
+	return !o.remaining().IsPositive()
+}
+
+// shouldTrigger returns true if the price reaches the trigger of a stop-loss or take-profit order
+func (o *order) shouldTrigger(price decimal.Decimal) bool {
+	if price.IsZero() {
+		return false
+	}
+
+	buy := o.config.Type == kraken.Buy
+	switch o.config.OrderType {
+	case kraken.StopLoss, kraken.StopLossLimit:
+		if buy {
+			return price.GreaterThanOrEqual(o.config.Price)
+		}
+		return price.LessThanOrEqual(o.config.Price)
+	case kraken.TakeProfit, kraken.TakeProfitLimit:
+		if buy {
+			return price.LessThanOrEqual(o.config.Price)
+		}
+		return price.GreaterThanOrEqual(o.config.Price)
+	}
+	return false
+}
+
+func (o *order) trigger(price decimal.Decimal) {
+	o.triggered = true
+	o.info.StopPrice = price
+
+	switch o.config.OrderType {
+	case kraken.StopLoss, kraken.StopLossLimit:
+		o.addMiscellaneous("stopped")
+	default:
+		o.addMiscellaneous("touched")
+	}
+	switch o.config.OrderType {
+	case kraken.StopLossLimit, kraken.TakeProfitLimit:
+		o.info.LimitPrice = o.config.Price2
+	}
+}
+
+// release unlocks the funds consumed by a fill
+func (o *order) release(amount decimal.Decimal) {
+	o.reserved = decimal.Max(decimal.Zero, o.reserved.Sub(amount))
+}
+
+func (o *order) close(status kraken.OrderStatus, reason string, now time.Time) {
+	o.info.Status = status
+	o.info.Reason = reason
+	o.info.ClosedAt = now
+	o.reserved = decimal.Zero
+}
+
+func (o *order) addMiscellaneous(info string) {
+	if o.info.Miscellaneous == "" {
+		o.info.Miscellaneous = info
+		return
+	}
+	o.info.Miscellaneous = strings.Join([]string{o.info.Miscellaneous, info}, ",")
+}
@@ -0,0 +1,381 @@
+// Package paper simulates trading on Kraken against live market data, without risking funds.
+//
+// Trader implements kraken.Trader like kraken.Client, so a strategy switches between real
+// and paper trading by changing the value it is given. Orders are checked against the trading
+// rules of their pair (OrderMin, CostMin, tick size), filled against the prices fed with
+// UpdateTicker and UpdateOrderBook, charged with the fee schedule of the pair and settled
+// on simulated balances. Margin trading is not simulated.
+package paper
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	kraken "github.com/astaluego/golang-kraken"
+	"github.com/shopspring/decimal"
+)
+
+// ErrNoMarketData is returned when a market order is placed on a pair without prices yet
+var ErrNoMarketData = errors.New("paper: no market data for the asset pair")
+
+type Config struct {
+	// Pairs is required
+	// Trading rules of the tradable pairs, typically the result of Client.AssetPairs
+	Pairs map[kraken.AssetPair]kraken.AssetPairsInfo
+
+	// Balances is optional
+	// Initial balances of the account
+	Balances map[kraken.Asset]decimal.Decimal
+
+	// FeeVolume is optional
+	// 30-day trade volume used to pick the fee tier, in the FeeVolumeCurrency of the pairs
+	FeeVolume decimal.Decimal
+
+	// Now is optional
+	// Clock of the simulation, e.g. the time of the replayed market data
+	// Default: time.Now
+	Now func() time.Time
+
+	// OnFill is optional
+	// Called after each fill, outside of the lock of the Trader
+	OnFill func(fill Fill)
+}
+
+// Fill is an execution of an order
+type Fill struct {
+	TransactionID string
+	// TradeID is unique to each fill
+	TradeID   string
+	AssetPair kraken.AssetPair
+	Type      kraken.Type
+	Price     decimal.Decimal
+	Volume    decimal.Decimal
+	// Cost is Price * Volume, in quote currency
+	Cost decimal.Decimal
+	// Fee is charged in quote currency
+	Fee decimal.Decimal
+	// Maker is set when the order was resting in the book
+	Maker bool
+	Time  time.Time
+}
+
+// Trader is a simulated Kraken account
+type Trader struct {
+	config Config
+
+	mu       sync.Mutex
+	balances map[kraken.Asset]decimal.Decimal
+	orders   map[string]*order
+	markets  map[kraken.AssetPair]*market
+	sequence int64
+	trades   int64
+	fills    []Fill
+}
+
+var _ kraken.Trader = (*Trader)(nil)
+
+// New returns a paper Trader
+func New(config Config) (*Trader, error) {
+	if len(config.Pairs) == 0 {
+		return nil, fmt.Errorf("Pairs is required")
+	}
+	if config.Now == nil {
+		config.Now = time.Now
+	}
+
+	t := &Trader{
+		config:   config,
+		balances: make(map[kraken.Asset]decimal.Decimal),
+		orders:   make(map[string]*order),
+		markets:  make(map[kraken.AssetPair]*market),
+	}
+	for asset, balance := range config.Balances {
+		t.balances[asset] = balance
+	}
+	return t, nil
+}
+
+// UpdateTicker feeds the best prices of a pair, typically from Client.TickerInformation.
+// The ask and bid are considered to have unlimited volume.
+func (t *Trader) UpdateTicker(pair kraken.AssetPair, ticker kraken.AssetTickerInfo) {
+	t.mu.Lock()
+	m := t.market(pair)
+	m.last = ticker.LastTradeClosed.Price
+	m.asks = nil
+	if ticker.Ask.Price.IsPositive() {
+		m.asks = []level{{price: ticker.Ask.Price, unbounded: true}}
+	}
+	m.bids = nil
+	if ticker.Bid.Price.IsPositive() {
+		m.bids = []level{{price: ticker.Bid.Price, unbounded: true}}
+	}
+	t.match(pair)
+	t.unlock()
+}
+
+// UpdateOrderBook feeds the order book of a pair, typically from Client.OrderBook.
+// The orders consume the volume of the levels until the next update, and the stop-loss and
+// take-profit orders trigger on the middle of the spread until the next ticker.
+func (t *Trader) UpdateOrderBook(pair kraken.AssetPair, book kraken.OrderBook) {
+	t.mu.Lock()
+	m := t.market(pair)
+	m.last = decimal.Zero
+	m.asks = levels(book.Asks)
+	sort.Slice(m.asks, func(i, j int) bool { return m.asks[i].price.LessThan(m.asks[j].price) })
+	m.bids = levels(book.Bids)
+	sort.Slice(m.bids, func(i, j int) bool { return m.bids[i].price.GreaterThan(m.bids[j].price) })
+	t.match(pair)
+	t.unlock()
+}
+
+// UpdateTime processes the pending and expiring orders when the clock moves without market data
+func (t *Trader) UpdateTime() {
+	t.mu.Lock()
+	t.tick()
+	t.unlock()
+}
+
+// AddOrder places a simulated order.
+// The order is rejected with a *kraken.ValidationError when it breaks the trading rules of its
+// pair, and with a *kraken.APIError like Kraken does otherwise (e.g. EOrder:Insufficient funds).
+func (t *Trader) AddOrder(config kraken.AddOrderConfig) (*kraken.AddOrderResult, error) {
+	if config.AssetPair == "" {
+		return nil, fmt.Errorf("AssetPair is required")
+	}
+	if config.Type == "" {
+		return nil, fmt.Errorf("Type is required")
+	}
+	if config.OrderType == "" {
+		return nil, fmt.Errorf("OrderType is required")
+	}
+	if !config.Volume.IsPositive() {
+		return nil, fmt.Errorf("Volume is required")
+	}
+
+	info, ok := t.config.Pairs[config.AssetPair]
+	if !ok {
+		return nil, apiError("EQuery:Unknown asset pair")
+	}
+	switch config.OrderType {
+	case kraken.Market, kraken.Limit, kraken.StopLoss, kraken.TakeProfit, kraken.StopLossLimit, kraken.TakeProfitLimit:
+	default:
+		return nil, apiError("EGeneral:Invalid arguments:ordertype")
+	}
+	if config.Leverage > 0 || config.ReduceOnly {
+		// Margin trading is not simulated
+		return nil, apiError("EGeneral:Invalid arguments:leverage")
+	}
+	if config.HasFlag(kraken.Viqc) && (config.OrderType != kraken.Market || config.Type != kraken.Buy) {
+		return nil, apiError("EGeneral:Invalid arguments:viqc")
+	}
+
+	t.mu.Lock()
+	defer t.unlock()
+	t.tick()
+
+	m := t.markets[config.AssetPair]
+	if err := kraken.ValidateOrder(info, config, m.reference(config.Type)); err != nil {
+		return nil, err
+	}
+
+	result := &kraken.AddOrderResult{}
+	result.Description.Order = describe(info, config)
+	if config.Validate {
+		return result, nil
+	}
+
+	if config.OrderType == kraken.Market && m.best(config.Type).IsZero() {
+		return nil, ErrNoMarketData
+	}
+	if config.ClientOrderID != "" {
+		for _, o := range t.orders {
+			if o.config.ClientOrderID == config.ClientOrderID && o.isOpen() {
+				return nil, apiError("EOrder:Duplicate order")
+			}
+		}
+	}
+	if config.OrderType == kraken.Limit && config.HasFlag(kraken.Post) && m.crosses(config.Type, config.Price) {
+		return nil, apiError("EOrder:Post only order")
+	}
+
+	now := t.config.Now()
+	t.sequence++
+	o := newOrder(fmt.Sprintf("OPAPER-%06d", t.sequence), t.sequence, info, config, now)
+	o.reservedAsset, o.reserved = t.reservation(info, o, m)
+	if o.reserved.GreaterThan(t.available(o.reservedAsset)) {
+		return nil, apiError("EOrder:Insufficient funds")
+	}
+	t.orders[o.txid] = o
+
+	t.process(info, o, m, now)
+	result.TransactionIDs = []string{o.txid}
+	return result, nil
+}
+
+// CancelOrder cancels a simulated open order by transaction ID, user reference ID or client order ID
+func (t *Trader) CancelOrder(config kraken.CancelOrderConfig) (*kraken.CancelOrderResult, error) {
+	if config.TransactionID == "" && config.ClientOrderID == "" {
+		return nil, fmt.Errorf("TransactionID or ClientOrderID is required")
+	}
+
+	t.mu.Lock()
+	defer t.unlock()
+	t.tick()
+
+	now := t.config.Now()
+	result := &kraken.CancelOrderResult{}
+	for _, o := range t.orders {
+		if !o.isOpen() {
+			continue
+		}
+
+		switch {
+		case config.TransactionID != "" && config.IsUserReference():
+			if fmt.Sprint(o.info.UserReferenceID) != config.TransactionID {
+				continue
+			}
+		case config.TransactionID != "":
+			if o.txid != config.TransactionID {
+				continue
+			}
+		default:
+			if o.config.ClientOrderID != config.ClientOrderID {
+				continue
+			}
+		}
+
+		o.close(kraken.Canceled, "User requested", now)
+		result.Count++
+	}
+
+	if result.Count == 0 {
+		return nil, apiError("EOrder:Unknown order")
+	}
+	return result, nil
+}
+
+// OpenOrders returns the simulated orders which are pending or open
+func (t *Trader) OpenOrders(config kraken.OpenOrdersConfig) (map[string]kraken.Order, error) {
+	t.mu.Lock()
+	defer t.unlock()
+	t.tick()
+
+	orders := make(map[string]kraken.Order)
+	for txid, o := range t.orders {
+		if !o.isOpen() {
+			continue
+		}
+		if config.UserReferenceID != 0 && o.info.UserReferenceID != config.UserReferenceID {
+			continue
+		}
+		orders[txid] = o.info
+	}
+	return orders, nil
+}
+
+// ClosedOrders returns the simulated orders which are closed, canceled or expired.
+// Start and End filter on the closing time, Offset is ignored.
+func (t *Trader) ClosedOrders(config kraken.ClosedOrdersConfig) (map[string]kraken.Order, error) {
+	t.mu.Lock()
+	defer t.unlock()
+	t.tick()
+
+	orders := make(map[string]kraken.Order)
+	for txid, o := range t.orders {
+		if o.isOpen() {
+			continue
+		}
+		if config.UserReferenceID != 0 && o.info.UserReferenceID != config.UserReferenceID {
+			continue
+		}
+		if !config.Start.IsZero() && o.info.ClosedAt.Before(config.Start) {
+			continue
+		}
+		if !config.End.IsZero() && o.info.ClosedAt.After(config.End) {
+			continue
+		}
+		orders[txid] = o.info
+	}
+	return orders, nil
+}
+
+// Balance returns the simulated balances, including the funds reserved by open orders
+func (t *Trader) Balance() (kraken.Balances, error) {
+	return kraken.Balances(t.Balances()), nil
+}
+
+// Balances returns the simulated balances, including the funds reserved by open orders
+func (t *Trader) Balances() map[kraken.Asset]decimal.Decimal {
+	t.mu.Lock()
+	defer t.unlock()
+
+	balances := make(map[kraken.Asset]decimal.Decimal)
+	for asset, balance := range t.balances {
+		balances[asset] = balance
+	}
+	return balances
+}
+
+// Available returns the balance of an asset which is not reserved by open orders
+func (t *Trader) Available(asset kraken.Asset) decimal.Decimal {
+	t.mu.Lock()
+	defer t.unlock()
+
+	return t.available(asset)
+}
+
+func (t *Trader) available(asset kraken.Asset) decimal.Decimal {
+	available := t.balances[asset]
+	for _, o := range t.orders {
+		if o.isOpen() && o.reservedAsset == asset {
+			available = available.Sub(o.reserved)
+		}
+	}
+	return available
+}
+
+func (t *Trader) market(pair kraken.AssetPair) *market {
+	m, ok := t.markets[pair]
+	if !ok {
+		m = &market{}
+		t.markets[pair] = m
+	}
+	return m
+}
+
+// unlock releases the lock, then reports the fills made while it was held
+func (t *Trader) unlock() {
+	fills := t.fills
+	t.fills = nil
+	t.mu.Unlock()
+
+	if t.config.OnFill == nil {
+		return
+	}
+	for _, fill := range fills {
+		t.config.OnFill(fill)
+	}
+}
+
+func apiError(code string) error {
+	return &kraken.APIError{Errors: []string{code}}
+}
+
+func describe(info kraken.AssetPairsInfo, config kraken.AddOrderConfig) string {
+	pair := info.Altname
+	if pair == "" {
+		pair = string(config.AssetPair)
+	}
+
+	description := fmt.Sprintf("%s %s %s @ %s", config.Type, config.Volume, pair, config.OrderType)
+	if config.Price.IsPositive() {
+		description += " " + config.Price.String()
+	}
+	if config.Price2.IsPositive() {
+		description += " -> limit " + config.Price2.String()
+	}
+	return description
+}
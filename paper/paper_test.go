@@ -0,0 +1,446 @@
+package paper_test
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	kraken "github.com/astaluego/golang-kraken"
+	"github.com/astaluego/golang-kraken/paper"
+	"github.com/shopspring/decimal"
+)
+
+var d = decimal.RequireFromString
+
+var xbtUSD = kraken.AssetPairsInfo{
+	Altname:      "XBTUSD",
+	BaseAsset:    kraken.XBT,
+	QuoteAsset:   kraken.USD,
+	PairDecimals: 1,
+	CostDecimals: 5,
+	LotDecimals:  8,
+	Fees:         []kraken.Fee{{Volume: 0, Percent: d("0.26")}, {Volume: 50000, Percent: d("0.24")}},
+	FeesMaker:    []kraken.Fee{{Volume: 0, Percent: d("0.16")}, {Volume: 50000, Percent: d("0.14")}},
+	OrderMin:     d("0.0001"),
+	CostMin:      d("0.5"),
+	TickSize:     d("0.1"),
+	Status:       kraken.AssetPairOnline,
+}
+
+// clock is the time of a simulation, moved by the tests
+type clock struct {
+	now time.Time
+}
+
+func (c *clock) Now() time.Time {
+	return c.now
+}
+
+func newTrader(t *testing.T, config paper.Config) (*paper.Trader, *clock) {
+	t.Helper()
+
+	c := &clock{now: time.Date(2023, 7, 6, 10, 0, 0, 0, time.UTC)}
+	config.Pairs = map[kraken.AssetPair]kraken.AssetPairsInfo{kraken.XBT_USD: xbtUSD}
+	config.Now = c.Now
+	if config.Balances == nil {
+		config.Balances = map[kraken.Asset]decimal.Decimal{kraken.USD: d("100000"), kraken.XBT: d("1")}
+	}
+
+	trader, err := paper.New(config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return trader, c
+}
+
+func ticker(last, bid, ask string) kraken.AssetTickerInfo {
+	var ticker kraken.AssetTickerInfo
+	ticker.LastTradeClosed.Price = d(last)
+	ticker.Bid.Price = d(bid)
+	ticker.Ask.Price = d(ask)
+	return ticker
+}
+
+func book(asks, bids [][2]string) kraken.OrderBook {
+	book := kraken.OrderBook{}
+	for _, ask := range asks {
+		book.Asks = append(book.Asks, kraken.OrderBookEntry{Price: d(ask[0]), Volume: d(ask[1])})
+	}
+	for _, bid := range bids {
+		book.Bids = append(book.Bids, kraken.OrderBookEntry{Price: d(bid[0]), Volume: d(bid[1])})
+	}
+	return book
+}
+
+func place(t *testing.T, trader *paper.Trader, config kraken.AddOrderConfig) string {
+	t.Helper()
+
+	config.AssetPair = kraken.XBT_USD
+	result, err := trader.AddOrder(config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return result.TransactionIDs[0]
+}
+
+func order(t *testing.T, trader *paper.Trader, txid string) kraken.Order {
+	t.Helper()
+
+	open, err := trader.OpenOrders(kraken.OpenOrdersConfig{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if o, ok := open[txid]; ok {
+		return o
+	}
+	closed, err := trader.ClosedOrders(kraken.ClosedOrdersConfig{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	o, ok := closed[txid]
+	if !ok {
+		t.Fatalf("unknown order %s", txid)
+	}
+	return o
+}
+
+func TestFeeTiers(t *testing.T) {
+	tests := []struct {
+		name      string
+		feeVolume string
+		resting   bool
+		fee       string
+	}{
+		{"taker", "0", false, "78"},
+		{"taker of the second tier", "60000", false, "72"},
+		{"maker", "0", true, "48"},
+		{"maker of the second tier", "50000", true, "42"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fills := []paper.Fill{}
+			trader, _ := newTrader(t, paper.Config{
+				FeeVolume: d(tt.feeVolume),
+				OnFill:    func(fill paper.Fill) { fills = append(fills, fill) },
+			})
+			trader.UpdateTicker(kraken.XBT_USD, ticker("30000", "29999.9", "30000.1"))
+
+			// 1 XBT for 30000 USD, taken at once or resting until the market moves through it
+			if tt.resting {
+				place(t, trader, kraken.AddOrderConfig{Type: kraken.Buy, OrderType: kraken.Limit, Volume: d("1"), Price: d("30000")})
+				trader.UpdateTicker(kraken.XBT_USD, ticker("29990", "29980", "29990"))
+			} else {
+				trader.UpdateTicker(kraken.XBT_USD, ticker("30000", "29999.9", "30000"))
+				place(t, trader, kraken.AddOrderConfig{Type: kraken.Buy, OrderType: kraken.Market, Volume: d("1")})
+			}
+
+			if len(fills) != 1 {
+				t.Fatalf("expected 1 fill, got %+v", fills)
+			}
+			fill := fills[0]
+			if fill.Price.String() != "30000" || fill.Maker != tt.resting || fill.Fee.String() != tt.fee {
+				t.Errorf("unexpected fill: %+v", fill)
+			}
+			balances := trader.Balances()
+			if expected := d("70000").Sub(d(tt.fee)); !balances[kraken.USD].Equal(expected) || balances[kraken.XBT].String() != "2" {
+				t.Errorf("unexpected balances: %v", balances)
+			}
+		})
+	}
+}
+
+func TestReservations(t *testing.T) {
+	trader, _ := newTrader(t, paper.Config{
+		Balances: map[kraken.Asset]decimal.Decimal{kraken.USD: d("10000"), kraken.XBT: d("0.5")},
+	})
+	trader.UpdateTicker(kraken.XBT_USD, ticker("30000", "29999.9", "30000.1"))
+
+	// The cost and the taker fee are reserved for buys: 9000 * 1.0026
+	buy := place(t, trader, kraken.AddOrderConfig{Type: kraken.Buy, OrderType: kraken.Limit, Volume: d("0.3"), Price: d("30000")})
+	if available := trader.Available(kraken.USD); available.String() != "976.6" {
+		t.Errorf("unexpected available USD: %s", available)
+	}
+	// The volume is reserved for sells
+	sell := place(t, trader, kraken.AddOrderConfig{Type: kraken.Sell, OrderType: kraken.Limit, Volume: d("0.4"), Price: d("31000")})
+	if available := trader.Available(kraken.XBT); available.String() != "0.1" {
+		t.Errorf("unexpected available XBT: %s", available)
+	}
+	// The balances include the reserved funds
+	if balances, _ := trader.Balance(); balances[kraken.USD].String() != "10000" || balances[kraken.XBT].String() != "0.5" {
+		t.Errorf("unexpected balances: %v", balances)
+	}
+
+	tests := []struct {
+		name   string
+		config kraken.AddOrderConfig
+	}{
+		{"buy", kraken.AddOrderConfig{Type: kraken.Buy, OrderType: kraken.Limit, Volume: d("0.1"), Price: d("30000")}},
+		{"sell", kraken.AddOrderConfig{Type: kraken.Sell, OrderType: kraken.Limit, Volume: d("0.2"), Price: d("31000")}},
+		{"market buy", kraken.AddOrderConfig{Type: kraken.Buy, OrderType: kraken.Market, Volume: d("0.1")}},
+		{"viqc", kraken.AddOrderConfig{Type: kraken.Buy, OrderType: kraken.Market, Volume: d("1000"), Flags: []kraken.OrderFlag{kraken.Viqc}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.config.AssetPair = kraken.XBT_USD
+			_, err := trader.AddOrder(tt.config)
+			var apiErr *kraken.APIError
+			if !errors.As(err, &apiErr) || apiErr.Errors[0] != "EOrder:Insufficient funds" {
+				t.Errorf("expected EOrder:Insufficient funds, got %v", err)
+			}
+		})
+	}
+
+	// Cancelling releases the funds
+	if _, err := trader.CancelOrder(kraken.CancelOrderConfig{TransactionID: buy}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := trader.CancelOrder(kraken.CancelOrderConfig{TransactionID: sell}); err != nil {
+		t.Fatal(err)
+	}
+	if trader.Available(kraken.USD).String() != "10000" || trader.Available(kraken.XBT).String() != "0.5" {
+		t.Errorf("expected the funds to be released, got %s USD and %s XBT", trader.Available(kraken.USD), trader.Available(kraken.XBT))
+	}
+	if o := order(t, trader, buy); o.Status != kraken.Canceled || o.Reason != "User requested" {
+		t.Errorf("unexpected order: %+v", o)
+	}
+	if _, err := trader.CancelOrder(kraken.CancelOrderConfig{TransactionID: buy}); err == nil {
+		t.Error("expected an error for an order which is not open")
+	}
+}
+
+func TestPartialFills(t *testing.T) {
+	fills := []paper.Fill{}
+	trader, _ := newTrader(t, paper.Config{OnFill: func(fill paper.Fill) { fills = append(fills, fill) }})
+	trader.UpdateOrderBook(kraken.XBT_USD, book(
+		[][2]string{{"30100", "0.3"}, {"30000", "0.5"}},
+		[][2]string{{"29900", "1"}},
+	))
+
+	// A market order takes the levels in order, the volume left is lost
+	market := place(t, trader, kraken.AddOrderConfig{Type: kraken.Buy, OrderType: kraken.Market, Volume: d("1")})
+	o := order(t, trader, market)
+	if o.Status != kraken.Closed || o.Reason != "Insufficient liquidity" || o.Miscellaneous != "partial" ||
+		o.VolumeExecuted.String() != "0.8" || o.Cost.String() != "24030" || o.Price.String() != "30037.5" {
+		t.Errorf("unexpected market order: %+v", o)
+	}
+	if len(fills) != 2 || fills[0].Price.String() != "30000" || fills[1].Price.String() != "30100" || fills[1].Volume.String() != "0.3" {
+		t.Errorf("unexpected fills: %+v", fills)
+	}
+
+	// A limit order takes what crosses, then rests for the rest
+	trader.UpdateOrderBook(kraken.XBT_USD, book(
+		[][2]string{{"30000", "0.4"}, {"30050", "1"}},
+		[][2]string{{"29900", "1"}},
+	))
+	fills = fills[:0]
+	limit := place(t, trader, kraken.AddOrderConfig{Type: kraken.Buy, OrderType: kraken.Limit, Volume: d("1"), Price: d("30000")})
+	if o := order(t, trader, limit); o.Status != kraken.Open || o.VolumeExecuted.String() != "0.4" {
+		t.Errorf("unexpected limit order: %+v", o)
+	}
+	trader.UpdateOrderBook(kraken.XBT_USD, book(
+		[][2]string{{"29950", "0.25"}},
+		[][2]string{{"29900", "1"}},
+	))
+	trader.UpdateOrderBook(kraken.XBT_USD, book(
+		[][2]string{{"29990", "2"}},
+		[][2]string{{"29900", "1"}},
+	))
+	if o := order(t, trader, limit); o.Status != kraken.Closed || o.VolumeExecuted.String() != "1" || o.Miscellaneous != "" {
+		t.Errorf("unexpected limit order: %+v", o)
+	}
+	// The resting order executes at its own price as a maker
+	if len(fills) != 3 || fills[0].Maker || !fills[1].Maker || fills[1].Price.String() != "30000" || fills[1].Volume.String() != "0.25" || fills[2].Volume.String() != "0.35" {
+		t.Errorf("unexpected fills: %+v", fills)
+	}
+}
+
+func TestTriggers(t *testing.T) {
+	tests := []struct {
+		name          string
+		config        kraken.AddOrderConfig
+		moves         []string
+		status        kraken.OrderStatus
+		price         string
+		miscellaneous string
+	}{
+		{
+			name:   "sell stop-loss not reached",
+			config: kraken.AddOrderConfig{Type: kraken.Sell, OrderType: kraken.StopLoss, Volume: d("0.5"), Price: d("29000")},
+			moves:  []string{"29500"},
+			status: kraken.Open,
+		},
+		{
+			name:          "sell stop-loss",
+			config:        kraken.AddOrderConfig{Type: kraken.Sell, OrderType: kraken.StopLoss, Volume: d("0.5"), Price: d("29000")},
+			moves:         []string{"29500", "28900"},
+			status:        kraken.Closed,
+			price:         "28899.9",
+			miscellaneous: "stopped",
+		},
+		{
+			name:          "buy stop-loss",
+			config:        kraken.AddOrderConfig{Type: kraken.Buy, OrderType: kraken.StopLoss, Volume: d("0.5"), Price: d("31000")},
+			moves:         []string{"31000"},
+			status:        kraken.Closed,
+			price:         "31000.1",
+			miscellaneous: "stopped",
+		},
+		{
+			name:          "sell take-profit",
+			config:        kraken.AddOrderConfig{Type: kraken.Sell, OrderType: kraken.TakeProfit, Volume: d("0.5"), Price: d("31000")},
+			moves:         []string{"29000", "31200"},
+			status:        kraken.Closed,
+			price:         "31199.9",
+			miscellaneous: "touched",
+		},
+		{
+			name:   "buy take-profit not reached",
+			config: kraken.AddOrderConfig{Type: kraken.Buy, OrderType: kraken.TakeProfit, Volume: d("0.5"), Price: d("29000")},
+			moves:  []string{"29500", "31000"},
+			status: kraken.Open,
+		},
+		{
+			// Triggered, then resting at its limit price
+			name:          "sell stop-loss-limit",
+			config:        kraken.AddOrderConfig{Type: kraken.Sell, OrderType: kraken.StopLossLimit, Volume: d("0.5"), Price: d("29000"), Price2: d("28950")},
+			moves:         []string{"28900"},
+			status:        kraken.Open,
+			miscellaneous: "stopped",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			trader, _ := newTrader(t, paper.Config{})
+			trader.UpdateTicker(kraken.XBT_USD, ticker("30000", "29999.9", "30000.1"))
+			txid := place(t, trader, tt.config)
+
+			for _, last := range tt.moves {
+				price := d(last)
+				trader.UpdateTicker(kraken.XBT_USD, ticker(last, price.Sub(d("0.1")).String(), price.Add(d("0.1")).String()))
+			}
+
+			o := order(t, trader, txid)
+			if o.Status != tt.status || o.Miscellaneous != tt.miscellaneous {
+				t.Fatalf("unexpected order: %+v", o)
+			}
+			if tt.price != "" && o.Price.String() != tt.price {
+				t.Errorf("expected a fill at %s, got %s", tt.price, o.Price)
+			}
+			if tt.miscellaneous != "" && o.StopPrice.String() != tt.moves[len(tt.moves)-1] {
+				t.Errorf("expected the trigger price %s, got %s", tt.moves[len(tt.moves)-1], o.StopPrice)
+			}
+		})
+	}
+}
+
+func TestExpiry(t *testing.T) {
+	trader, clock := newTrader(t, paper.Config{})
+	trader.UpdateTicker(kraken.XBT_USD, ticker("30000", "29999.9", "30000.1"))
+
+	txid := place(t, trader, kraken.AddOrderConfig{
+		Type:      kraken.Buy,
+		OrderType: kraken.Limit,
+		Volume:    d("1"),
+		Price:     d("29000"),
+		StartAt:   clock.now.Add(time.Minute),
+		ExpireAt:  clock.now.Add(time.Hour),
+	})
+	if o := order(t, trader, txid); o.Status != kraken.Pending {
+		t.Errorf("expected a pending order, got %+v", o)
+	}
+	if available := trader.Available(kraken.USD); available.String() != "70924.6" {
+		t.Errorf("expected the funds to be reserved, got %s", available)
+	}
+
+	clock.now = clock.now.Add(time.Minute)
+	trader.UpdateTime()
+	if o := order(t, trader, txid); o.Status != kraken.Open {
+		t.Errorf("expected an open order, got %+v", o)
+	}
+
+	clock.now = clock.now.Add(time.Hour)
+	trader.UpdateTime()
+	o := order(t, trader, txid)
+	if o.Status != kraken.Expired || !o.ClosedAt.Equal(clock.now) {
+		t.Errorf("expected an expired order, got %+v", o)
+	}
+	if available := trader.Available(kraken.USD); available.String() != "100000" {
+		t.Errorf("expected the funds to be released, got %s", available)
+	}
+}
+
+func TestViqc(t *testing.T) {
+	tests := []struct {
+		name   string
+		volume string
+		// The volume bought is truncated to the lot decimals
+		executed string
+		cost     string
+	}{
+		{"exact", "3000", "0.1", "3000"},
+		{"residual below a lot", "1000", "0.03333333", "999.9999"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			trader, _ := newTrader(t, paper.Config{})
+			trader.UpdateTicker(kraken.XBT_USD, ticker("30000", "29999.9", "30000"))
+
+			txid := place(t, trader, kraken.AddOrderConfig{
+				Type:      kraken.Buy,
+				OrderType: kraken.Market,
+				Volume:    d(tt.volume),
+				Flags:     []kraken.OrderFlag{kraken.Viqc},
+			})
+
+			o := order(t, trader, txid)
+			if o.Status != kraken.Closed || o.Reason != "" || o.Miscellaneous != "" {
+				t.Errorf("expected a filled order, got %+v", o)
+			}
+			if o.VolumeExecuted.String() != tt.executed || o.Cost.String() != tt.cost {
+				t.Errorf("unexpected execution: %s for %s", o.VolumeExecuted, o.Cost)
+			}
+			if trader.Available(kraken.USD).Add(o.Cost).Add(o.Fee).String() != "100000" {
+				t.Errorf("unexpected balance: %s", trader.Available(kraken.USD))
+			}
+		})
+	}
+
+	trader, _ := newTrader(t, paper.Config{})
+	trader.UpdateTicker(kraken.XBT_USD, ticker("30000", "29999.9", "30000"))
+	_, err := trader.AddOrder(kraken.AddOrderConfig{
+		AssetPair: kraken.XBT_USD,
+		Type:      kraken.Sell,
+		OrderType: kraken.Market,
+		Volume:    d("1000"),
+		Flags:     []kraken.OrderFlag{kraken.Viqc},
+	})
+	if err == nil {
+		t.Error("expected an error for a Viqc sell")
+	}
+}
+
+func TestValidation(t *testing.T) {
+	trader, _ := newTrader(t, paper.Config{})
+
+	_, err := trader.AddOrder(kraken.AddOrderConfig{AssetPair: kraken.XBT_USD, Type: kraken.Buy, OrderType: kraken.Market, Volume: d("0.1")})
+	if !errors.Is(err, paper.ErrNoMarketData) {
+		t.Errorf("expected ErrNoMarketData, got %v", err)
+	}
+
+	trader.UpdateTicker(kraken.XBT_USD, ticker("30000", "29999.9", "30000.1"))
+	_, err = trader.AddOrder(kraken.AddOrderConfig{AssetPair: kraken.XBT_USD, Type: kraken.Buy, OrderType: kraken.Limit, Volume: d("0.00001"), Price: d("30000.05")})
+	var validationErr *kraken.ValidationError
+	if !errors.As(err, &validationErr) || !validationErr.Has(kraken.ViolationVolumeBelowMin) || !validationErr.Has(kraken.ViolationPriceOffTick) {
+		t.Errorf("expected a *kraken.ValidationError, got %v", err)
+	}
+
+	// Post-only orders crossing the spread are rejected
+	_, err = trader.AddOrder(kraken.AddOrderConfig{AssetPair: kraken.XBT_USD, Type: kraken.Buy, OrderType: kraken.Limit, Volume: d("0.1"), Price: d("30000.1"), Flags: []kraken.OrderFlag{kraken.Post}})
+	var apiErr *kraken.APIError
+	if !errors.As(err, &apiErr) || apiErr.Errors[0] != "EOrder:Post only order" {
+		t.Errorf("expected EOrder:Post only order, got %v", err)
+	}
+}
@@ -0,0 +1,313 @@
+package kraken
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+// ParseError is returned when a response from Kraken does not have the expected shape
+type ParseError struct {
+	// Type being parsed (e.g. OHLCData)
+	Type string
+	// Field of the type which failed to parse
+	Field string
+	// Raw value of the field
+	Value string
+	// Err is the underlying error
+	Err error
+}
+
+func (e *ParseError) Error() string {
+	return fmt.Sprintf("failed to parse %s.%s from %s: %s", e.Type, e.Field, truncate(e.Value, 64), e.Err.Error())
+}
+
+func (e *ParseError) Unwrap() error {
+	return e.Err
+}
+
+func truncate(value string, length int) string {
+	if len(value) <= length {
+		return value
+	}
+	return value[:length] + "..."
+}
+
+// parseTuple decodes a JSON array of at least n elements
+func parseTuple(data []byte, n int, typeName string) ([]json.RawMessage, error) {
+	var tuple []json.RawMessage
+	if err := json.Unmarshal(data, &tuple); err != nil {
+		return nil, &ParseError{Type: typeName, Field: "[]", Value: string(data), Err: err}
+	}
+	if len(tuple) < n {
+		return nil, &ParseError{Type: typeName, Field: "[]", Value: string(data), Err: fmt.Errorf("expected %d elements, got %d", n, len(tuple))}
+	}
+	return tuple, nil
+}
+
+// unquote returns the content of a JSON string, or the raw JSON number
+func unquote(raw json.RawMessage) (string, error) {
+	raw = bytes.TrimSpace(raw)
+	if len(raw) == 0 {
+		return "", fmt.Errorf("missing value")
+	}
+	if raw[0] == '"' {
+		var s string
+		if err := json.Unmarshal(raw, &s); err != nil {
+			return "", err
+		}
+		return s, nil
+	}
+
+	var n json.Number
+	if err := json.Unmarshal(raw, &n); err != nil {
+		return "", fmt.Errorf("expected a string or a number")
+	}
+	return n.String(), nil
+}
+
+func parseDecimal(raw json.RawMessage, typeName, field string) (decimal.Decimal, error) {
+	s, err := unquote(raw)
+	if err != nil {
+		return decimal.Decimal{}, &ParseError{Type: typeName, Field: field, Value: string(raw), Err: err}
+	}
+
+	d, err := decimal.NewFromString(s)
+	if err != nil {
+		return decimal.Decimal{}, &ParseError{Type: typeName, Field: field, Value: string(raw), Err: err}
+	}
+	return d, nil
+}
+
+func parseInt(raw json.RawMessage, typeName, field string) (int64, error) {
+	s, err := unquote(raw)
+	if err != nil {
+		return 0, &ParseError{Type: typeName, Field: field, Value: string(raw), Err: err}
+	}
+
+	i, err := strconv.ParseInt(s, 10, 64)
+	if err != nil {
+		return 0, &ParseError{Type: typeName, Field: field, Value: string(raw), Err: err}
+	}
+	return i, nil
+}
+
+func parseString(raw json.RawMessage, typeName, field string) (string, error) {
+	var s string
+	if err := json.Unmarshal(raw, &s); err != nil {
+		return "", &ParseError{Type: typeName, Field: field, Value: string(raw), Err: err}
+	}
+	return s, nil
+}
+
+// parseUnixTime parses a unix timestamp in seconds, with an optional fractional part
+func parseUnixTime(raw json.RawMessage, typeName, field string) (time.Time, error) {
+	d, err := parseDecimal(raw, typeName, field)
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	// Reject values which would overflow int64 nanoseconds
+	if d.Abs().GreaterThan(decimal.NewFromInt(1 << 33)) {
+		return time.Time{}, &ParseError{Type: typeName, Field: field, Value: string(raw), Err: fmt.Errorf("timestamp out of range")}
+	}
+
+	seconds := d.Truncate(0)
+	nanoseconds := d.Sub(seconds).Shift(9).Truncate(0)
+	return time.Unix(seconds.IntPart(), nanoseconds.IntPart()), nil
+}
+
+// parseUnixNanoTime parses a unix timestamp in nanoseconds, as used by the Trades cursor
+func parseUnixNanoTime(raw json.RawMessage, typeName, field string) (time.Time, error) {
+	i, err := parseInt(raw, typeName, field)
+	if err != nil {
+		return time.Time{}, err
+	}
+	return time.Unix(0, i), nil
+}
+
+// ohlcEntry decodes an OHLC tuple: [time, open, high, low, close, vwap, volume, count]
+type ohlcEntry OHLCData
+
+func (o *ohlcEntry) UnmarshalJSON(data []byte) error {
+	const typeName = "OHLCData"
+
+	tuple, err := parseTuple(data, 8, typeName)
+	if err != nil {
+		return err
+	}
+
+	if o.Time, err = parseUnixTime(tuple[0], typeName, "Time"); err != nil {
+		return err
+	}
+	if o.Open, err = parseDecimal(tuple[1], typeName, "Open"); err != nil {
+		return err
+	}
+	if o.High, err = parseDecimal(tuple[2], typeName, "High"); err != nil {
+		return err
+	}
+	if o.Low, err = parseDecimal(tuple[3], typeName, "Low"); err != nil {
+		return err
+	}
+	if o.Close, err = parseDecimal(tuple[4], typeName, "Close"); err != nil {
+		return err
+	}
+	if o.VWAP, err = parseDecimal(tuple[5], typeName, "VWAP"); err != nil {
+		return err
+	}
+	if o.Volume, err = parseDecimal(tuple[6], typeName, "Volume"); err != nil {
+		return err
+	}
+	if o.Count, err = parseInt(tuple[7], typeName, "Count"); err != nil {
+		return err
+	}
+	return nil
+}
+
+// orderBookEntry decodes an order book tuple: [price, volume, time]
+type orderBookEntry OrderBookEntry
+
+func (o *orderBookEntry) UnmarshalJSON(data []byte) error {
+	const typeName = "OrderBookEntry"
+
+	tuple, err := parseTuple(data, 3, typeName)
+	if err != nil {
+		return err
+	}
+
+	if o.Price, err = parseDecimal(tuple[0], typeName, "Price"); err != nil {
+		return err
+	}
+	if o.Volume, err = parseDecimal(tuple[1], typeName, "Volume"); err != nil {
+		return err
+	}
+	if o.Time, err = parseUnixTime(tuple[2], typeName, "Time"); err != nil {
+		return err
+	}
+	return nil
+}
+
+// orderBookSides decodes the asks and bids of an order book
+type orderBookSides struct {
+	Asks []orderBookEntry `json:"asks"`
+	Bids []orderBookEntry `json:"bids"`
+}
+
+// tradeEntry decodes a trade tuple: [price, volume, time, buy/sell, market/limit, miscellaneous, trade_id]
+type tradeEntry TradeData
+
+func (t *tradeEntry) UnmarshalJSON(data []byte) error {
+	const typeName = "TradeData"
+
+	tuple, err := parseTuple(data, 6, typeName)
+	if err != nil {
+		return err
+	}
+
+	if t.Price, err = parseDecimal(tuple[0], typeName, "Price"); err != nil {
+		return err
+	}
+	if t.Volume, err = parseDecimal(tuple[1], typeName, "Volume"); err != nil {
+		return err
+	}
+	if t.Time, err = parseUnixTime(tuple[2], typeName, "Time"); err != nil {
+		return err
+	}
+
+	side, err := parseString(tuple[3], typeName, "Type")
+	if err != nil {
+		return err
+	}
+	switch side {
+	case "b":
+		t.Type = Buy
+	case "s":
+		t.Type = Sell
+	default:
+		return &ParseError{Type: typeName, Field: "Type", Value: string(tuple[3]), Err: fmt.Errorf("unknown side")}
+	}
+
+	orderType, err := parseString(tuple[4], typeName, "OrderType")
+	if err != nil {
+		return err
+	}
+	switch orderType {
+	case "m":
+		t.OrderType = Market
+	case "l":
+		t.OrderType = Limit
+	default:
+		return &ParseError{Type: typeName, Field: "OrderType", Value: string(tuple[4]), Err: fmt.Errorf("unknown order type")}
+	}
+
+	if t.Miscellaneous, err = parseString(tuple[5], typeName, "Miscellaneous"); err != nil {
+		return err
+	}
+
+	// The trade ID was added later to the tuple
+	if len(tuple) > 6 {
+		if t.TradeID, err = parseInt(tuple[6], typeName, "TradeID"); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// spreadEntry decodes a spread tuple: [time, bid, ask]
+type spreadEntry SpreadData
+
+func (s *spreadEntry) UnmarshalJSON(data []byte) error {
+	const typeName = "SpreadData"
+
+	tuple, err := parseTuple(data, 3, typeName)
+	if err != nil {
+		return err
+	}
+
+	if s.Time, err = parseUnixTime(tuple[0], typeName, "Time"); err != nil {
+		return err
+	}
+	if s.Bid, err = parseDecimal(tuple[1], typeName, "Bid"); err != nil {
+		return err
+	}
+	if s.Ask, err = parseDecimal(tuple[2], typeName, "Ask"); err != nil {
+		return err
+	}
+	return nil
+}
+
+// pairResults splits a result keyed by asset pair, with an optional "last" cursor
+type pairResults struct {
+	Last  json.RawMessage
+	Pairs map[string]json.RawMessage
+}
+
+func (p *pairResults) UnmarshalJSON(data []byte) error {
+	var results map[string]json.RawMessage
+	if err := json.Unmarshal(data, &results); err != nil {
+		return &ParseError{Type: "result", Field: "{}", Value: string(data), Err: err}
+	}
+
+	p.Pairs = make(map[string]json.RawMessage)
+	for key, value := range results {
+		if strings.EqualFold(key, "last") {
+			p.Last = value
+			continue
+		}
+		p.Pairs[key] = value
+	}
+	return nil
+}
+
+// decodeSlice decodes a JSON array of tuples, adding the pair to the errors
+func decodeSlice(pair string, data json.RawMessage, v interface{}) error {
+	if err := json.Unmarshal(data, v); err != nil {
+		return fmt.Errorf("failed to parse %s: %w", pair, err)
+	}
+	return nil
+}
@@ -0,0 +1,119 @@
+package kraken
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+)
+
+var (
+	// ErrUnknownAsset is returned when an asset is not listed by Kraken
+	ErrUnknownAsset = errors.New("unknown asset")
+	// ErrUnknownAssetPair is returned when an asset pair is not listed by Kraken
+	ErrUnknownAssetPair = errors.New("unknown asset pair")
+)
+
+// defaultRegistry is loaded once by DefaultRegistry, then never changes
+var defaultRegistry struct {
+	mu       sync.Mutex
+	registry *Registry
+}
+
+// DefaultRegistry returns the registry used by ParseAsset and ParseAssetPair. It is loaded with
+// a client built by New on the first call, then never changes: to resolve the pairs listed
+// afterwards, use the methods of a Registry kept up to date by Run.
+// A failed load is tried again by the next call.
+func DefaultRegistry() (*Registry, error) {
+	defaultRegistry.mu.Lock()
+	defer defaultRegistry.mu.Unlock()
+
+	if defaultRegistry.registry == nil {
+		registry, err := New().NewRegistry(RegistryConfig{})
+		if err != nil {
+			return nil, err
+		}
+		defaultRegistry.registry = registry
+	}
+	return defaultRegistry.registry, nil
+}
+
+// ParsedAssetPair is an asset pair resolved by a Registry, split into its base and quote
+type ParsedAssetPair struct {
+	// AssetPair is the key of the pair in AssetPairs (e.g. XXBTZUSD)
+	AssetPair AssetPair
+	// Base is the key of the base asset in Assets (e.g. XXBT)
+	Base Asset
+	// Quote is the key of the quote asset in Assets (e.g. ZUSD)
+	Quote Asset
+	// Info of the pair
+	Info AssetPairsInfo
+}
+
+// ParseAsset validates the name of an asset (e.g. XBT, XXBT, BTC) against the DefaultRegistry,
+// and returns its key in Assets. Assets listed after the generation of the constants are supported.
+func ParseAsset(name string) (Asset, error) {
+	r, err := DefaultRegistry()
+	if err != nil {
+		return "", err
+	}
+	return r.ParseAsset(name)
+}
+
+// ParseAssetPair validates the name of a pair (e.g. XBTUSD, XBT/USD, XXBTZUSD, BTC-EUR) against the
+// DefaultRegistry, and splits it into its base and quote. Pairs listed after the generation of the
+// constants are supported.
+func ParseAssetPair(name string) (ParsedAssetPair, error) {
+	r, err := DefaultRegistry()
+	if err != nil {
+		return ParsedAssetPair{}, err
+	}
+	return r.ParseAssetPair(name)
+}
+
+// ParseAsset validates the name of an asset against the registry, and returns its key in Assets
+func (r *Registry) ParseAsset(name string) (Asset, error) {
+	asset, _, ok := r.Asset(strings.TrimSpace(name))
+	if !ok {
+		return "", fmt.Errorf("%w: %q", ErrUnknownAsset, name)
+	}
+	return asset, nil
+}
+
+// ParseAssetPair validates the name of a pair against the registry, and splits it into its base
+// and quote
+func (r *Registry) ParseAssetPair(name string) (ParsedAssetPair, error) {
+	name = strings.TrimSpace(name)
+
+	pair, info, ok := r.Pair(name)
+	if !ok {
+		pair, info, ok = r.splitPair(name)
+	}
+	if !ok {
+		return ParsedAssetPair{}, fmt.Errorf("%w: %q", ErrUnknownAssetPair, name)
+	}
+
+	return ParsedAssetPair{
+		AssetPair: pair,
+		Base:      info.BaseAsset,
+		Quote:     info.QuoteAsset,
+		Info:      info,
+	}, nil
+}
+
+// splitPair resolves the names made of the names of two assets, which Kraken does not know
+// as such, e.g. BTC/EUR or BTCEUR for XBTEUR
+func (r *Registry) splitPair(name string) (AssetPair, AssetPairsInfo, bool) {
+	for _, separator := range []string{"/", "-", "_"} {
+		if base, quote, found := strings.Cut(name, separator); found {
+			return r.PairOf(base, quote)
+		}
+	}
+
+	for i := 1; i < len(name); i++ {
+		if pair, info, ok := r.PairOf(name[:i], name[i:]); ok {
+			return pair, info, true
+		}
+	}
+	return "", AssetPairsInfo{}, false
+}
@@ -0,0 +1,43 @@
+package kraken_test
+
+import (
+	"errors"
+	"testing"
+
+	kraken "github.com/astaluego/golang-kraken"
+	"github.com/astaluego/golang-kraken/krakentest"
+)
+
+func TestRegistryParse(t *testing.T) {
+	server := krakentest.NewServer()
+	defer server.Close()
+
+	registry, err := server.NewClient().NewRegistry(kraken.RegistryConfig{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, name := range []string{"XBT", "XXBT", "BTC", " btc "} {
+		asset, err := registry.ParseAsset(name)
+		if err != nil || asset != kraken.XBT {
+			t.Errorf("%q: expected %s, got %s, %v", name, kraken.XBT, asset, err)
+		}
+	}
+	if _, err := registry.ParseAsset("NOPE"); !errors.Is(err, kraken.ErrUnknownAsset) {
+		t.Errorf("expected ErrUnknownAsset, got %v", err)
+	}
+
+	for _, name := range []string{"XBTUSD", "XBT/USD", "XXBTZUSD", "BTC-USD", "BTCUSD"} {
+		parsed, err := registry.ParseAssetPair(name)
+		if err != nil {
+			t.Errorf("%q: %v", name, err)
+			continue
+		}
+		if parsed.AssetPair != kraken.XBT_USD || parsed.Base != kraken.XBT || parsed.Quote != kraken.USD {
+			t.Errorf("%q: unexpected %+v", name, parsed)
+		}
+	}
+	if _, err := registry.ParseAssetPair("XBT/NOPE"); !errors.Is(err, kraken.ErrUnknownAssetPair) {
+		t.Errorf("expected ErrUnknownAssetPair, got %v", err)
+	}
+}
@@ -0,0 +1,219 @@
+package kraken
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+)
+
+// Real payloads returned by Kraken
+const (
+	ohlcPayload      = `[1688671200,"30306.1","30306.2","30305.7","30305.7","30306.1","3.39243896",23]`
+	tradePayload     = `["30243.40000","0.34507674",1688669597.8277369,"b","m","",61044952]`
+	tradeLegacy      = `["30243.40000","0.34507674",1688669597.8277369,"s","l",""]`
+	spreadPayload    = `[1688671834,"30292.10000","30297.50000"]`
+	orderBookPayload = `["30284.40000","0.500",1688671658]`
+	tickerPayload    = `{"a":["30300.10000","1","1.000"],"b":["30300.00000","1","1.000"],"c":["30303.20000","0.00067643"],"v":["4083.67001100","4412.73601799"],"p":["30706.77771","30689.13205"],"t":[34619,38907],"l":["29868.30000","29868.30000"],"h":["31631.00000","31631.00000"],"o":"30502.80000"}`
+)
+
+// Malformed payloads shared by the decoders: wrong arity, wrong types and nulls
+var malformedTuples = []string{
+	`null`,
+	`[]`,
+	`[null]`,
+	`{}`,
+	`"30306.1"`,
+	`1688671200`,
+	`[null,null,null,null,null,null,null,null]`,
+	`[{},{},{},{},{},{},{},{}]`,
+	`[[],[],[],[],[],[],[],[]]`,
+	`[true,"1","1","1","1","1","1",1]`,
+	`["abc","abc","abc","abc","abc","abc","abc","abc"]`,
+}
+
+func TestParseMalformed(t *testing.T) {
+	decoders := map[string]func(data []byte) error{
+		"OHLCData": func(data []byte) error {
+			var entry ohlcEntry
+			return json.Unmarshal(data, &entry)
+		},
+		"TradeData": func(data []byte) error {
+			var entry tradeEntry
+			return json.Unmarshal(data, &entry)
+		},
+		"SpreadData": func(data []byte) error {
+			var entry spreadEntry
+			return json.Unmarshal(data, &entry)
+		},
+		"OrderBookEntry": func(data []byte) error {
+			var entry orderBookEntry
+			return json.Unmarshal(data, &entry)
+		},
+	}
+
+	// Timestamps which would overflow time.Time
+	overflows := map[string]string{
+		"OHLCData":       `[1e300,"1","1","1","1","1","1",1]`,
+		"TradeData":      `["1","1",1e300,"b","m",""]`,
+		"SpreadData":     `[1e300,"1","1"]`,
+		"OrderBookEntry": `["1","1",1e300]`,
+	}
+
+	for name, decode := range decoders {
+		for _, payload := range append(malformedTuples, overflows[name]) {
+			err := decode([]byte(payload))
+			if err == nil {
+				t.Errorf("%s: expected an error for %s", name, payload)
+				continue
+			}
+			var parseErr *ParseError
+			if !errors.As(err, &parseErr) {
+				t.Errorf("%s: expected a *ParseError for %s, got %T: %v", name, payload, err, err)
+			}
+		}
+	}
+
+	tickers := []string{
+		`null`,
+		`{}`,
+		`{"a":["1","1"]}`,
+		`{"a":"1","b":"1","c":"1","v":"1","p":"1","t":"1","l":"1","h":"1"}`,
+		`{"a":["x","1","1"],"b":["1","1","1"],"c":["1","1"],"v":["1","1"],"p":["1","1"],"t":[1,1],"l":["1","1"],"h":["1","1"]}`,
+		`{"a":["1","1","1"],"b":["1","1","1"],"c":["1","1"],"v":["1","1"],"p":["1","1"],"t":["a","b"],"l":["1","1"],"h":["1","1"]}`,
+		`{"a":["1","1","1"],"b":["1","1","1"],"c":["1","1"],"v":["1","1"],"p":["1","1"],"t":[1,1],"l":["1","1"],"h":null}`,
+		`[]`,
+	}
+	for _, payload := range tickers {
+		var ticker AssetTickerInfo
+		if err := json.Unmarshal([]byte(payload), &ticker); err == nil {
+			t.Errorf("AssetTickerInfo: expected an error for %s", payload)
+		}
+	}
+}
+
+func TestParsePayloads(t *testing.T) {
+	var ohlc ohlcEntry
+	if err := json.Unmarshal([]byte(ohlcPayload), &ohlc); err != nil {
+		t.Fatal(err)
+	}
+	if ohlc.Time.Unix() != 1688671200 || ohlc.Close.String() != "30305.7" || ohlc.Count != 23 {
+		t.Errorf("unexpected OHLCData: %+v", ohlc)
+	}
+
+	var trade tradeEntry
+	if err := json.Unmarshal([]byte(tradePayload), &trade); err != nil {
+		t.Fatal(err)
+	}
+	if trade.Type != Buy || trade.OrderType != Market || trade.TradeID != 61044952 || trade.Time.UnixMilli() != 1688669597827 {
+		t.Errorf("unexpected TradeData: %+v", trade)
+	}
+
+	var legacy tradeEntry
+	if err := json.Unmarshal([]byte(tradeLegacy), &legacy); err != nil {
+		t.Fatal(err)
+	}
+	if legacy.Type != Sell || legacy.OrderType != Limit || legacy.TradeID != 0 {
+		t.Errorf("unexpected TradeData: %+v", legacy)
+	}
+
+	var spread spreadEntry
+	if err := json.Unmarshal([]byte(spreadPayload), &spread); err != nil {
+		t.Fatal(err)
+	}
+	if spread.Bid.String() != "30292.1" || spread.Ask.String() != "30297.5" {
+		t.Errorf("unexpected SpreadData: %+v", spread)
+	}
+
+	var book orderBookEntry
+	if err := json.Unmarshal([]byte(orderBookPayload), &book); err != nil {
+		t.Fatal(err)
+	}
+	if book.Price.String() != "30284.4" || book.Time.Unix() != 1688671658 {
+		t.Errorf("unexpected OrderBookEntry: %+v", book)
+	}
+
+	var ticker AssetTickerInfo
+	if err := json.Unmarshal([]byte(tickerPayload), &ticker); err != nil {
+		t.Fatal(err)
+	}
+	if ticker.Ask.Price.String() != "30300.1" || ticker.CountTrades.Last24hours != 38907 {
+		t.Errorf("unexpected AssetTickerInfo: %+v", ticker)
+	}
+}
+
+// checkDecodeError fails unless the error of a decoder is a syntax error of the JSON itself,
+// rejected before the decoder runs, or a *ParseError
+func checkDecodeError(t *testing.T, data []byte, err error) {
+	if err == nil {
+		return
+	}
+	var syntaxErr *json.SyntaxError
+	var parseErr *ParseError
+	if !errors.As(err, &syntaxErr) && !errors.As(err, &parseErr) {
+		t.Errorf("unexpected error for %q: %T: %v", data, err, err)
+	}
+}
+
+func addSeeds(f *testing.F, payloads ...string) {
+	for _, payload := range payloads {
+		f.Add([]byte(payload))
+	}
+	for _, payload := range malformedTuples {
+		f.Add([]byte(payload))
+	}
+}
+
+func FuzzOHLCEntry(f *testing.F) {
+	addSeeds(f, ohlcPayload)
+	f.Fuzz(func(t *testing.T, data []byte) {
+		var entry ohlcEntry
+		checkDecodeError(t, data, json.Unmarshal(data, &entry))
+	})
+}
+
+func FuzzTradeEntry(f *testing.F) {
+	addSeeds(f, tradePayload, tradeLegacy)
+	f.Fuzz(func(t *testing.T, data []byte) {
+		var entry tradeEntry
+		checkDecodeError(t, data, json.Unmarshal(data, &entry))
+	})
+}
+
+func FuzzSpreadEntry(f *testing.F) {
+	addSeeds(f, spreadPayload)
+	f.Fuzz(func(t *testing.T, data []byte) {
+		var entry spreadEntry
+		checkDecodeError(t, data, json.Unmarshal(data, &entry))
+	})
+}
+
+func FuzzOrderBookEntry(f *testing.F) {
+	addSeeds(f, orderBookPayload)
+	f.Fuzz(func(t *testing.T, data []byte) {
+		var entry orderBookEntry
+		checkDecodeError(t, data, json.Unmarshal(data, &entry))
+	})
+}
+
+func FuzzAssetTickerInfo(f *testing.F) {
+	f.Add([]byte(tickerPayload))
+	f.Add([]byte(`null`))
+	f.Add([]byte(`{}`))
+	f.Add([]byte(`{"a":["1","1"],"t":["a","b"]}`))
+	f.Fuzz(func(t *testing.T, data []byte) {
+		var ticker AssetTickerInfo
+		// The arrays are decoded by encoding/json, which reports type mismatches with its own errors
+		_ = json.Unmarshal(data, &ticker)
+	})
+}
+
+func TestParseNullObjects(t *testing.T) {
+	var pair AssetPairsInfo
+	if err := json.Unmarshal([]byte(`null`), &pair); err != nil {
+		t.Errorf("AssetPairsInfo: %v", err)
+	}
+	var order Order
+	if err := json.Unmarshal([]byte(`null`), &order); err != nil {
+		t.Errorf("Order: %v", err)
+	}
+}
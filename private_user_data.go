@@ -17,11 +17,37 @@ func (c *Client) AccountBalance() (*AccountBalance, error) {
 	return &response, err
 }
 
+// Balance
+// Retrieve all cash balances, net of pending withdrawals, like AccountBalance but as decimals.
+// https://docs.kraken.com/rest/#tag/User-Data/operation/getAccountBalance
+func (c *Client) Balance() (Balances, error) {
+	payload := Payload{}
+
+	response := Balances{}
+	err := c.doRequest("Balance", true, url.Values(payload), &response)
+	return response, err
+}
+
+// ExtendedBalance
+// Retrieve all extended account balances, including the funds held by the open orders.
+// https://docs.kraken.com/rest/#tag/User-Data/operation/getExtendedBalance
+func (c *Client) ExtendedBalance() (ExtendedBalances, error) {
+	payload := Payload{}
+
+	response := ExtendedBalances{}
+	err := c.doRequest("BalanceEx", true, url.Values(payload), &response)
+	return response, err
+}
+
 type TradeBalanceConfig struct {
 	// Asset is required
 	// Base asset used to determine balance
 	// Default: USD
 	Asset Asset
+
+	// OTP is optional
+	// Two-factor password, overrides the one configured on the client
+	OTP string
 }
 
 // TradeBalance
@@ -34,6 +60,7 @@ func (c *Client) TradeBalance(config TradeBalanceConfig) (*TradeBalance, error)
 
 	payload := Payload{}
 	payload.OptAssets(config.Asset)
+	payload.OptOTP(config.OTP)
 
 	response := TradeBalance{}
 	err := c.doRequest("TradeBalance", true, url.Values(payload), &response)
@@ -48,6 +75,10 @@ type OpenOrdersConfig struct {
 	// UserReferenceID is optional
 	// Restrict results to given user reference id
 	UserReferenceID int64
+
+	// OTP is optional
+	// Two-factor password, overrides the one configured on the client
+	OTP string
 }
 
 // OpenOrders
@@ -57,6 +88,7 @@ func (c *Client) OpenOrders(config OpenOrdersConfig) (map[string]Order, error) {
 	payload := Payload{}
 	payload.OptWithTrades(config.Trades)
 	payload.OptUserReferenceID(config.UserReferenceID)
+	payload.OptOTP(config.OTP)
 
 	type Response struct {
 		Opened map[string]Order `json:"open"`
@@ -64,6 +96,7 @@ func (c *Client) OpenOrders(config OpenOrdersConfig) (map[string]Order, error) {
 
 	response := Response{}
 	err := c.doRequest("OpenOrders", true, url.Values(payload), &response)
+	c.localizeOrders(response.Opened)
 
 	return response.Opened, err
 }
@@ -85,6 +118,10 @@ type ClosedOrdersConfig struct {
 
 	// Offset is optional
 	Offset int64
+
+	// OTP is optional
+	// Two-factor password, overrides the one configured on the client
+	OTP string
 }
 
 // ClosedOrders
@@ -94,6 +131,7 @@ func (c *Client) ClosedOrders(config ClosedOrdersConfig) (map[string]Order, erro
 	payload := Payload{}
 	payload.OptWithTrades(config.Trades)
 	payload.OptUserReferenceID(config.UserReferenceID)
+	payload.OptOTP(config.OTP)
 	payload.OptStart(config.Start)
 	payload.OptEnd(config.End)
 	payload.OptOffset(config.Offset)
@@ -105,6 +143,7 @@ func (c *Client) ClosedOrders(config ClosedOrdersConfig) (map[string]Order, erro
 
 	response := Response{}
 	err := c.doRequest("ClosedOrders", true, url.Values(payload), &response)
+	c.localizeOrders(response.Closed)
 
 	return response.Closed, err
 }
@@ -120,6 +159,10 @@ type OrdersConfig struct {
 
 	// TransactionIDs is required
 	TransactionIDs []string
+
+	// OTP is optional
+	// Two-factor password, overrides the one configured on the client
+	OTP string
 }
 
 // Orders
@@ -133,10 +176,23 @@ func (c *Client) Orders(config OrdersConfig) (map[string]Order, error) {
 	payload := Payload{}
 	payload.OptWithTrades(config.Trades)
 	payload.OptUserReferenceID(config.UserReferenceID)
+	payload.OptOTP(config.OTP)
 	payload.OptTransactionIDs(config.TransactionIDs)
 
 	response := make(map[string]Order)
 	err := c.doRequest("QueryOrders", true, url.Values(payload), &response)
+	c.localizeOrders(response)
 
 	return response, err
 }
+
+// localizeOrders converts the timestamps of the orders into the location of the client
+func (c *Client) localizeOrders(orders map[string]Order) {
+	for txid, order := range orders {
+		order.OpenedAt = c.in(order.OpenedAt)
+		order.StartAt = c.in(order.StartAt)
+		order.ExpireAt = c.in(order.ExpireAt)
+		order.ClosedAt = c.in(order.ClosedAt)
+		orders[txid] = order
+	}
+}
@@ -0,0 +1,27 @@
+package kraken_test
+
+import (
+	"testing"
+
+	kraken "github.com/astaluego/golang-kraken"
+	"github.com/astaluego/golang-kraken/krakentest"
+)
+
+func TestBalance(t *testing.T) {
+	server := krakentest.NewServer()
+	defer server.Close()
+
+	balances, err := server.NewClient().Balance()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(balances) != 4 {
+		t.Errorf("expected 4 balances, got %v", balances)
+	}
+	if balance := balances[kraken.XBT]; balance.String() != "1011.19088779" {
+		t.Errorf("unexpected XXBT balance: %s", balance)
+	}
+	if _, ok := balances[kraken.DOT]; ok {
+		t.Error("unexpected DOT balance")
+	}
+}
@@ -0,0 +1,207 @@
+package kraken
+
+import (
+	"fmt"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+// Trader is implemented by Client, which trades on Kraken, and by paper.Trader, which simulates
+// the trades, so a strategy can switch between them without code changes.
+//
+// The balances are read with Balance rather than AccountBalance: both call the Balance endpoint,
+// but AccountBalance decodes into the generated Assets struct of float64, which drops the assets
+// missing from it and rounds the amounts, when Balances keeps every asset as an exact decimal.
+type Trader interface {
+	AddOrder(config AddOrderConfig) (*AddOrderResult, error)
+	CancelOrder(config CancelOrderConfig) (*CancelOrderResult, error)
+	OpenOrders(config OpenOrdersConfig) (map[string]Order, error)
+	ClosedOrders(config ClosedOrdersConfig) (map[string]Order, error)
+	Balance() (Balances, error)
+}
+
+var _ Trader = (*Client)(nil)
+
+type AddOrderConfig struct {
+	// AssetPair is required
+	AssetPair AssetPair
+
+	// Type is required
+	// buy or sell
+	Type Type
+
+	// OrderType is required
+	OrderType OrderType
+
+	// Volume is required
+	// In base currency, or in quote currency with the Viqc flag
+	Volume decimal.Decimal
+
+	// Price is optional
+	// Limit price for limit orders, trigger price for stop-loss, take-profit,
+	// stop-loss-limit and take-profit-limit orders
+	Price decimal.Decimal
+
+	// Price2 is optional
+	// Limit price for stop-loss-limit and take-profit-limit orders
+	Price2 decimal.Decimal
+
+	// Trigger is optional
+	// Price signal used to trigger the stop-loss and take-profit orders
+	// Default: last
+	Trigger TriggerType
+
+	// Leverage is optional
+	// Default: none
+	Leverage int64
+
+	// ReduceOnly is optional
+	// The order can only reduce an existing margin position
+	ReduceOnly bool
+
+	// Flags is optional
+	Flags []OrderFlag
+
+	// UserReference is optional
+	UserReference int64
+
+	// ClientOrderID is optional
+	// Unique identifier of the order, chosen by the client
+	ClientOrderID string
+
+	// StartAt is optional
+	// Default: now
+	StartAt time.Time
+
+	// ExpireAt is optional
+	// Default: no expiration
+	ExpireAt time.Time
+
+	// Validate is optional
+	// Validates the inputs only, the order is not submitted
+	Validate bool
+
+	// OTP is optional
+	// Two-factor password, overrides the one configured on the client
+	OTP string
+}
+
+// HasFlag returns true if the order has the given flag
+func (config AddOrderConfig) HasFlag(flag OrderFlag) bool {
+	for _, f := range config.Flags {
+		if f == flag {
+			return true
+		}
+	}
+	return false
+}
+
+type AddOrderResult struct {
+	// Order description info
+	Description struct {
+		// Order description
+		Order string `json:"order"`
+		// Conditional close order description (if conditional close set)
+		Close string `json:"close"`
+	} `json:"descr"`
+	// Transaction IDs of the order (empty when Validate is set)
+	TransactionIDs []string `json:"txid"`
+}
+
+// AddOrder
+// Place a new order.
+// https://docs.kraken.com/rest/#tag/Trading/operation/addOrder
+func (c *Client) AddOrder(config AddOrderConfig) (*AddOrderResult, error) {
+	if config.AssetPair == "" {
+		return nil, fmt.Errorf("AssetPair is required")
+	}
+	if config.Type == "" {
+		return nil, fmt.Errorf("Type is required")
+	}
+	if config.OrderType == "" {
+		return nil, fmt.Errorf("OrderType is required")
+	}
+	if !config.Volume.IsPositive() {
+		return nil, fmt.Errorf("Volume is required")
+	}
+
+	payload := Payload{}
+	payload.OptAssetPairs(config.AssetPair)
+	payload["type"] = []string{string(config.Type)}
+	payload["ordertype"] = []string{string(config.OrderType)}
+	payload["volume"] = []string{config.Volume.String()}
+	payload.OptPrice(config.Price)
+	payload.OptPrice2(config.Price2)
+	payload.OptTrigger(config.Trigger)
+	payload.OptLeverage(config.Leverage)
+	payload.OptReduceOnly(config.ReduceOnly)
+	payload.OptOrderFlags(config.Flags)
+	payload.OptUserReferenceID(config.UserReference)
+	payload.OptClientOrderID(config.ClientOrderID)
+	payload.OptStartTime(config.StartAt)
+	payload.OptExpireTime(config.ExpireAt)
+	payload.OptValidate(config.Validate)
+	payload.OptOTP(config.OTP)
+
+	response := AddOrderResult{}
+	err := c.doRequest("AddOrder", true, url.Values(payload), &response)
+	if err != nil {
+		return nil, err
+	}
+	return &response, nil
+}
+
+type CancelOrderConfig struct {
+	// TransactionID is optional
+	// Transaction ID of the order, or user reference ID of the orders to cancel
+	// Either TransactionID or ClientOrderID is required
+	TransactionID string
+
+	// ClientOrderID is optional
+	ClientOrderID string
+
+	// OTP is optional
+	// Two-factor password, overrides the one configured on the client
+	OTP string
+}
+
+type CancelOrderResult struct {
+	// Number of orders cancelled
+	Count int64 `json:"count"`
+	// If set, the orders are pending cancellation
+	Pending bool `json:"pending"`
+}
+
+// CancelOrder
+// Cancel a particular open order (or set of open orders) by transaction ID, user reference ID
+// or client order ID.
+// https://docs.kraken.com/rest/#tag/Trading/operation/cancelOrder
+func (c *Client) CancelOrder(config CancelOrderConfig) (*CancelOrderResult, error) {
+	payload := Payload{}
+	switch {
+	case config.TransactionID != "":
+		payload["txid"] = []string{config.TransactionID}
+	case config.ClientOrderID != "":
+		payload.OptClientOrderID(config.ClientOrderID)
+	default:
+		return nil, fmt.Errorf("TransactionID or ClientOrderID is required")
+	}
+	payload.OptOTP(config.OTP)
+
+	response := CancelOrderResult{}
+	err := c.doRequest("CancelOrder", true, url.Values(payload), &response)
+	if err != nil {
+		return nil, err
+	}
+	return &response, nil
+}
+
+// IsUserReference returns true if the TransactionID is a user reference ID, which cancels
+// all the orders having it
+func (config CancelOrderConfig) IsUserReference() bool {
+	_, err := strconv.ParseInt(config.TransactionID, 10, 64)
+	return err == nil
+}
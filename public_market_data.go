@@ -1,13 +1,10 @@
 package kraken
 
 import (
+	"context"
 	"fmt"
-	"math"
 	"net/url"
-	"strconv"
 	"time"
-
-	"github.com/shopspring/decimal"
 )
 
 // ServerTime
@@ -29,6 +26,7 @@ func (c *Client) SystemStatus() (*SystemStatus, error) {
 
 	response := SystemStatus{}
 	err := c.doRequest("SystemStatus", false, url.Values(payload), &response)
+	response.Timestamp = c.in(response.Timestamp)
 	return &response, err
 }
 
@@ -112,10 +110,15 @@ type OHLCConfig struct {
 
 // OHLC
 // Means "Open-high-low-close chart"
+// Results are keyed by the requested AssetPair, whatever the name Kraken echoes back.
 // Note: the last entry in the OHLC array is for the last, not-yet-committed frame and will always
 // be present, regardless of the value of `since`.
 // https://docs.kraken.com/rest/#operation/getOHLCData
-func (c *Client) OHLC(config OHLCConfig) ([]OHLCData, time.Time, error) {
+func (c *Client) OHLC(config OHLCConfig) (map[AssetPair]OHLCResult, time.Time, error) {
+	return c.ohlc(context.Background(), config)
+}
+
+func (c *Client) ohlc(ctx context.Context, config OHLCConfig) (map[AssetPair]OHLCResult, time.Time, error) {
 	if config.AssetPair == "" {
 		return nil, time.Time{}, fmt.Errorf("AssetPair is required")
 	}
@@ -125,72 +128,36 @@ func (c *Client) OHLC(config OHLCConfig) ([]OHLCData, time.Time, error) {
 	payload.OptInterval(config.Interval)
 	payload.OptSince(config.Since)
 
-	var resp interface{}
-	err := c.doRequest("OHLC", false, url.Values(payload), &resp)
+	var resp pairResults
+	err := c.doRequestContext(ctx, "OHLC", false, url.Values(payload), &resp)
+	if err != nil {
+		return nil, time.Time{}, err
+	}
+
+	last, err := parseUnixTime(resp.Last, "OHLC", "last")
 	if err != nil {
 		return nil, time.Time{}, err
 	}
 
-	var last time.Time
-	response := []OHLCData{}
+	response := make(map[AssetPair]OHLCResult)
+	for pair, value := range resp.Pairs {
+		var entries []ohlcEntry
+		if err := decodeSlice(pair, value, &entries); err != nil {
+			return nil, time.Time{}, err
+		}
 
-	for key, value := range resp.(map[string]interface{}) {
-		if key == "last" {
-			last = time.Unix(int64(value.(float64)), 0)
-			continue
+		result := OHLCResult{
+			Name: pair,
+			Data: make([]OHLCData, 0, len(entries)),
 		}
-		for _, array := range value.([]interface{}) {
-			a := array.([]interface{})
-
-			if len(a) == 8 {
-				t := time.Unix(int64(a[0].(float64)), 0)
-
-				open, err := decimal.NewFromString(a[1].(string))
-				if err != nil {
-					continue
-				}
-
-				high, err := decimal.NewFromString(a[2].(string))
-				if err != nil {
-					continue
-				}
-
-				low, err := decimal.NewFromString(a[3].(string))
-				if err != nil {
-					continue
-				}
-
-				close, err := decimal.NewFromString(a[4].(string))
-				if err != nil {
-					continue
-				}
-
-				vwap, err := decimal.NewFromString(a[5].(string))
-				if err != nil {
-					continue
-				}
-
-				count, err := decimal.NewFromString(a[6].(string))
-				if err != nil {
-					continue
-				}
-
-				ohlcData := OHLCData{
-					Time:   t,
-					Open:   open,
-					High:   high,
-					Low:    low,
-					Close:  close,
-					VWAP:   vwap,
-					Volume: count,
-					Count:  int64(a[7].(float64)),
-				}
-				response = append(response, ohlcData)
-			}
+		for _, entry := range entries {
+			entry.Time = c.in(entry.Time)
+			result.Data = append(result.Data, OHLCData(entry))
 		}
+		response[resolveAssetPair([]AssetPair{config.AssetPair}, pair)] = result
 	}
 
-	return response, last, nil
+	return response, c.in(last), nil
 }
 
 type OrderBookConfig struct {
@@ -202,8 +169,9 @@ type OrderBookConfig struct {
 }
 
 // OrderBook
+// Results are keyed by the requested AssetPair, whatever the name Kraken echoes back.
 // https://docs.kraken.com/rest/#operation/getOrderBook
-func (c *Client) OrderBook(config OrderBookConfig) (*OrderBook, error) {
+func (c *Client) OrderBook(config OrderBookConfig) (map[AssetPair]OrderBook, error) {
 	if config.AssetPair == "" {
 		return nil, fmt.Errorf("AssetPair is required")
 	}
@@ -212,136 +180,88 @@ func (c *Client) OrderBook(config OrderBookConfig) (*OrderBook, error) {
 	payload.OptAssetPairs(config.AssetPair)
 	payload.OptCount(config.Count)
 
-	var resp interface{}
+	var resp map[string]orderBookSides
 	err := c.doRequest("Depth", false, url.Values(payload), &resp)
 	if err != nil {
 		return nil, err
 	}
 
-	response := OrderBook{}
-
-	for _, value := range resp.(map[string]interface{}) {
-		for side, array := range value.(map[string]interface{}) {
-			orderBookEntries := []OrderBookEntry{}
-			for _, a := range array.([]interface{}) {
-				obe := a.([]interface{})
-
-				if len(obe) == 3 {
-					t := time.Unix(int64(obe[2].(float64)), 0)
-
-					price, err := decimal.NewFromString(obe[0].(string))
-					if err != nil {
-						continue
-					}
-
-					volume, err := decimal.NewFromString(obe[1].(string))
-					if err != nil {
-						continue
-					}
-
-					orderBookEntry := OrderBookEntry{
-						Price:  price,
-						Volume: volume,
-						Time:   t,
-					}
-					orderBookEntries = append(orderBookEntries, orderBookEntry)
-				}
-			}
-
-			if side == "asks" {
-				response.Asks = orderBookEntries
-			} else if side == "bids" {
-				response.Bids = orderBookEntries
-			}
+	response := make(map[AssetPair]OrderBook)
+	for pair, value := range resp {
+		orderBook := OrderBook{
+			Name: pair,
+			Asks: make([]OrderBookEntry, 0, len(value.Asks)),
+			Bids: make([]OrderBookEntry, 0, len(value.Bids)),
 		}
-		break
+		for _, entry := range value.Asks {
+			entry.Time = c.in(entry.Time)
+			orderBook.Asks = append(orderBook.Asks, OrderBookEntry(entry))
+		}
+		for _, entry := range value.Bids {
+			entry.Time = c.in(entry.Time)
+			orderBook.Bids = append(orderBook.Bids, OrderBookEntry(entry))
+		}
+		response[resolveAssetPair([]AssetPair{config.AssetPair}, pair)] = orderBook
 	}
 
-	return &response, nil
+	return response, nil
 }
 
 type RecentTradesConfig struct {
 	// AssetPair is required
 	AssetPair AssetPair
 	// Since is optional
+	// Sent with a nanosecond precision, so the `last` cursor can be fed back as is
 	Since time.Time
 }
 
 // RecentTrades
 // Returns the last 1000 trades by default
+// Results are keyed by the requested AssetPair, whatever the name Kraken echoes back.
 // https://docs.kraken.com/rest/#operation/getRecentTrades
-func (c *Client) RecentTrades(config RecentTradesConfig) ([]TradeData, time.Time, error) {
+func (c *Client) RecentTrades(config RecentTradesConfig) (map[AssetPair]RecentTradesResult, time.Time, error) {
+	return c.recentTrades(context.Background(), config)
+}
+
+func (c *Client) recentTrades(ctx context.Context, config RecentTradesConfig) (map[AssetPair]RecentTradesResult, time.Time, error) {
 	if config.AssetPair == "" {
 		return nil, time.Time{}, fmt.Errorf("AssetPair is required")
 	}
 
 	payload := Payload{}
 	payload.OptAssetPairs(config.AssetPair)
-	payload.OptSince(config.Since)
+	payload.OptSinceNano(config.Since)
 
-	var resp interface{}
-	err := c.doRequest("Trades", false, url.Values(payload), &resp)
+	var resp pairResults
+	err := c.doRequestContext(ctx, "Trades", false, url.Values(payload), &resp)
 	if err != nil {
 		return nil, time.Time{}, err
 	}
 
-	var last time.Time
-	response := []TradeData{}
-
-	for key, value := range resp.(map[string]interface{}) {
-		if key == "last" {
-			i, err := strconv.ParseInt(value.(string), 10, 64)
-			if err != nil {
-				continue
-			}
-			last = time.Unix(0, i)
-			continue
+	last, err := parseUnixNanoTime(resp.Last, "Trades", "last")
+	if err != nil {
+		return nil, time.Time{}, err
+	}
+
+	response := make(map[AssetPair]RecentTradesResult)
+	for pair, value := range resp.Pairs {
+		var entries []tradeEntry
+		if err := decodeSlice(pair, value, &entries); err != nil {
+			return nil, time.Time{}, err
 		}
-		for _, array := range value.([]interface{}) {
-			a := array.([]interface{})
-
-			if len(a) == 7 {
-				price, err := decimal.NewFromString(a[0].(string))
-				if err != nil {
-					continue
-				}
-
-				volume, err := decimal.NewFromString(a[1].(string))
-				if err != nil {
-					continue
-				}
-
-				integer, decimal := math.Modf(a[2].(float64))
-				t := time.Unix(int64(integer), int64(decimal*10000))
-
-				var tradeType Type
-				if a[3].(string) == "s" {
-					tradeType = Sell
-				} else if a[3].(string) == "b" {
-					tradeType = Buy
-				}
-
-				var orderType OrderType
-				if a[4].(string) == "m" {
-					orderType = Market
-				} else if a[4].(string) == "l" {
-					orderType = Limit
-				}
-
-				tradeData := TradeData{
-					Price:         price,
-					Volume:        volume,
-					Time:          t,
-					Type:          tradeType,
-					OrderType:     orderType,
-					Miscellaneous: a[5].(string),
-					TradeID:       int64(a[6].(float64)),
-				}
-				response = append(response, tradeData)
-			}
+
+		result := RecentTradesResult{
+			Name: pair,
+			Data: make([]TradeData, 0, len(entries)),
 		}
+		for _, entry := range entries {
+			entry.Time = c.in(entry.Time)
+			result.Data = append(result.Data, TradeData(entry))
+		}
+		response[resolveAssetPair([]AssetPair{config.AssetPair}, pair)] = result
 	}
-	return response, last, err
+
+	return response, c.in(last), nil
 }
 
 type RecentSpreadsConfig struct {
@@ -353,8 +273,13 @@ type RecentSpreadsConfig struct {
 
 // RecentSpreads
 // Returns the last 1000 trades by default
+// Results are keyed by the requested AssetPair, whatever the name Kraken echoes back.
 // https://docs.kraken.com/rest/#operation/getRecentSpreads
-func (c *Client) RecentSpreads(config RecentSpreadsConfig) ([]SpreadData, time.Time, error) {
+func (c *Client) RecentSpreads(config RecentSpreadsConfig) (map[AssetPair]RecentSpreadsResult, time.Time, error) {
+	return c.recentSpreads(context.Background(), config)
+}
+
+func (c *Client) recentSpreads(ctx context.Context, config RecentSpreadsConfig) (map[AssetPair]RecentSpreadsResult, time.Time, error) {
 	if config.AssetPair == "" {
 		return nil, time.Time{}, fmt.Errorf("AssetPair is required")
 	}
@@ -363,43 +288,34 @@ func (c *Client) RecentSpreads(config RecentSpreadsConfig) ([]SpreadData, time.T
 	payload.OptAssetPairs(config.AssetPair)
 	payload.OptSince(config.Since)
 
-	var resp interface{}
-	err := c.doRequest("Spread", false, url.Values(payload), &resp)
+	var resp pairResults
+	err := c.doRequestContext(ctx, "Spread", false, url.Values(payload), &resp)
 	if err != nil {
 		return nil, time.Time{}, err
 	}
 
-	var last time.Time
-	response := []SpreadData{}
+	last, err := parseUnixTime(resp.Last, "Spread", "last")
+	if err != nil {
+		return nil, time.Time{}, err
+	}
 
-	for key, value := range resp.(map[string]interface{}) {
-		if key == "last" {
-			last = time.Unix(int64(value.(float64)), 0)
-			continue
+	response := make(map[AssetPair]RecentSpreadsResult)
+	for pair, value := range resp.Pairs {
+		var entries []spreadEntry
+		if err := decodeSlice(pair, value, &entries); err != nil {
+			return nil, time.Time{}, err
 		}
-		for _, array := range value.([]interface{}) {
-			a := array.([]interface{})
-			if len(a) == 3 {
-				t := time.Unix(int64(a[0].(float64)), 0)
-
-				bid, err := decimal.NewFromString(a[1].(string))
-				if err != nil {
-					continue
-				}
-
-				ask, err := decimal.NewFromString(a[2].(string))
-				if err != nil {
-					continue
-				}
-
-				spreadData := SpreadData{
-					Time: t,
-					Bid:  bid,
-					Ask:  ask,
-				}
-				response = append(response, spreadData)
-			}
+
+		result := RecentSpreadsResult{
+			Name: pair,
+			Data: make([]SpreadData, 0, len(entries)),
+		}
+		for _, entry := range entries {
+			entry.Time = c.in(entry.Time)
+			result.Data = append(result.Data, SpreadData(entry))
 		}
+		response[resolveAssetPair([]AssetPair{config.AssetPair}, pair)] = result
 	}
-	return response, last, nil
+
+	return response, c.in(last), nil
 }
@@ -0,0 +1,117 @@
+package kraken
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+type RateLimiterConfig struct {
+	// MaxCounter is optional
+	// Maximum of the API counter of private calls, which depends on the verification tier
+	// Default: 15 (Starter)
+	MaxCounter float64
+
+	// DecayRate is optional
+	// Points removed from the API counter every second
+	// Default: 0.33 (Starter)
+	DecayRate float64
+
+	// PublicInterval is optional
+	// Minimum pause between two public calls
+	// Default: 1s
+	PublicInterval time.Duration
+}
+
+// RateLimiter keeps the calls under the limits of the Kraken API: private calls are limited by
+// a counter increased by the cost of each call and decreased over time, public calls by a
+// minimum interval between calls.
+// https://docs.kraken.com/rest/#section/Rate-Limits
+//
+// A RateLimiter is safe for concurrent use, and can be shared by several clients using the same API key.
+type RateLimiter struct {
+	config RateLimiterConfig
+
+	mu         sync.Mutex
+	counter    float64
+	updatedAt  time.Time
+	nextPublic time.Time
+}
+
+// NewRateLimiter inits a new RateLimiter
+func NewRateLimiter(config RateLimiterConfig) *RateLimiter {
+	if config.MaxCounter == 0 {
+		config.MaxCounter = 15
+	}
+	if config.DecayRate == 0 {
+		config.DecayRate = 0.33
+	}
+	if config.PublicInterval == 0 {
+		config.PublicInterval = time.Second
+	}
+
+	return &RateLimiter{config: config}
+}
+
+// Wait blocks until a call can be made without exceeding the rate limits, or ctx is done
+func (l *RateLimiter) Wait(ctx context.Context, isPrivate bool, cost int) error {
+	for {
+		delay := l.reserve(time.Now(), isPrivate, cost)
+		if delay == 0 {
+			return nil
+		}
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		case <-timer.C:
+		}
+	}
+}
+
+// reserve takes the call into account and returns 0 if it can be made now,
+// otherwise it returns the delay to wait before trying again
+func (l *RateLimiter) reserve(now time.Time, isPrivate bool, cost int) time.Duration {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if !isPrivate {
+		if now.Before(l.nextPublic) {
+			return l.nextPublic.Sub(now)
+		}
+		l.nextPublic = now.Add(l.config.PublicInterval)
+		return 0
+	}
+
+	if !l.updatedAt.IsZero() {
+		l.counter -= now.Sub(l.updatedAt).Seconds() * l.config.DecayRate
+		if l.counter < 0 {
+			l.counter = 0
+		}
+	}
+	l.updatedAt = now
+
+	// A call costing more than the maximum is made once the counter is empty
+	if excess := l.counter + float64(cost) - l.config.MaxCounter; excess > 0 && l.counter > 0 {
+		if excess > l.counter {
+			excess = l.counter
+		}
+		return time.Duration(excess / l.config.DecayRate * float64(time.Second))
+	}
+	l.counter += float64(cost)
+	return 0
+}
+
+// RateLimit delays every call made by the Client until it fits in the rate limits of the limiter
+func RateLimit(limiter *RateLimiter) Middleware {
+	return func(next Handler) Handler {
+		return func(call *Call) error {
+			if err := limiter.Wait(call.Request.Context(), call.Private, call.Cost); err != nil {
+				return err
+			}
+			return next(call)
+		}
+	}
+}
@@ -0,0 +1,322 @@
+package kraken
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// RegistryEventType is the kind of change detected by a Registry refresh
+type RegistryEventType string
+
+const (
+	PairListed         RegistryEventType = "pair_listed"
+	PairDelisted       RegistryEventType = "pair_delisted"
+	PairStatusChanged  RegistryEventType = "pair_status_changed"
+	AssetListed        RegistryEventType = "asset_listed"
+	AssetDelisted      RegistryEventType = "asset_delisted"
+	AssetStatusChanged RegistryEventType = "asset_status_changed"
+)
+
+// RegistryEvent describes a change of the pairs or assets between two refreshes
+type RegistryEvent struct {
+	Type RegistryEventType
+	// AssetPair is set for the pair events
+	AssetPair AssetPair
+	// Asset is set for the asset events
+	Asset Asset
+	// PreviousStatus is empty when the pair or asset is listed
+	PreviousStatus string
+	// Status is empty when the pair or asset is delisted
+	Status string
+}
+
+type RegistryConfig struct {
+	// RefreshInterval is optional
+	// Pause between two refreshes done by Run
+	// Default: 1h
+	RefreshInterval time.Duration
+
+	// OnChange is optional
+	// Called for each change detected by a refresh, after the registry is updated.
+	// Nothing is reported by the first load.
+	OnChange func(event RegistryEvent)
+
+	// OnError is optional
+	// Called when a refresh done by Run fails, the previous data is kept
+	OnError func(err error)
+}
+
+// Registry caches the assets and asset pairs of Kraken, and resolves any of their names:
+// altname (XBTUSD), wsname (XBT/USD), legacy name (XXBTZUSD) or base and quote.
+// It is safe for concurrent use by multiple goroutines.
+type Registry struct {
+	client *Client
+	config RegistryConfig
+
+	// refreshMu serializes the refreshes, so their changes are reported once and in order
+	refreshMu sync.Mutex
+
+	mu        sync.RWMutex
+	assets    map[Asset]AssetInfo
+	pairs     map[AssetPair]AssetPairsInfo
+	assetKeys map[string]Asset
+	pairKeys  map[string]AssetPair
+	loadedAt  time.Time
+}
+
+// assetAliases are the common names of the assets Kraken names differently
+var assetAliases = map[string]string{
+	"BTC":  "XBT",
+	"DOGE": "XDG",
+}
+
+// NewRegistry inits a new Registry and loads the assets and asset pairs
+func (c *Client) NewRegistry(config RegistryConfig) (*Registry, error) {
+	if config.RefreshInterval == 0 {
+		config.RefreshInterval = time.Hour
+	}
+
+	r := &Registry{
+		client: c,
+		config: config,
+	}
+	if err := r.Refresh(); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// Refresh reloads the assets and asset pairs, and reports the changes to OnChange.
+// Concurrent calls are serialized.
+func (r *Registry) Refresh() error {
+	r.refreshMu.Lock()
+	defer r.refreshMu.Unlock()
+
+	assets, err := r.client.Assets(AssetsConfig{})
+	if err != nil {
+		return fmt.Errorf("failed to load assets: %w", err)
+	}
+	pairs, err := r.client.AssetPairs(AssetPairsConfig{})
+	if err != nil {
+		return fmt.Errorf("failed to load asset pairs: %w", err)
+	}
+
+	assetKeys := make(map[string]Asset)
+	for asset, info := range assets {
+		assetKeys[strings.ToUpper(string(asset))] = asset
+		if info.Altname != "" {
+			assetKeys[strings.ToUpper(info.Altname)] = asset
+		}
+	}
+	for alias, name := range assetAliases {
+		if asset, ok := assetKeys[name]; ok {
+			if _, exists := assetKeys[alias]; !exists {
+				assetKeys[alias] = asset
+			}
+		}
+	}
+
+	pairKeys := make(map[string]AssetPair)
+	for pair, info := range pairs {
+		names := []string{string(pair), info.Altname, info.WSname}
+		if base, quote := assets[info.BaseAsset], assets[info.QuoteAsset]; base.Altname != "" && quote.Altname != "" {
+			names = append(names, base.Altname+quote.Altname)
+		}
+		for _, name := range names {
+			if name == "" {
+				continue
+			}
+			key := pairKey(name)
+			// An exact name takes precedence over a normalized one
+			if _, exists := pairKeys[key]; !exists || name == string(pair) {
+				pairKeys[key] = pair
+			}
+		}
+	}
+	// Legacy names without their X/Z prefixes, e.g. XBTUSD for XXBTZUSD
+	for pair, info := range pairs {
+		for _, name := range []string{string(pair), info.Altname, info.WSname} {
+			if name == "" {
+				continue
+			}
+			key := normalizePairName(name)
+			if _, exists := pairKeys[key]; !exists {
+				pairKeys[key] = pair
+			}
+		}
+	}
+
+	r.mu.Lock()
+	previousAssets, previousPairs := r.assets, r.pairs
+	r.assets, r.pairs = assets, pairs
+	r.assetKeys, r.pairKeys = assetKeys, pairKeys
+	r.loadedAt = time.Now()
+	r.mu.Unlock()
+
+	if previousPairs != nil && r.config.OnChange != nil {
+		for _, event := range diffRegistry(previousAssets, assets, previousPairs, pairs) {
+			r.config.OnChange(event)
+		}
+	}
+	return nil
+}
+
+// Run refreshes the registry every RefreshInterval until ctx is done, and returns ctx.Err()
+func (r *Registry) Run(ctx context.Context) error {
+	ticker := time.NewTicker(r.config.RefreshInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+			if err := r.Refresh(); err != nil && r.config.OnError != nil {
+				r.config.OnError(err)
+			}
+		}
+	}
+}
+
+// LoadedAt returns the time of the last successful refresh
+func (r *Registry) LoadedAt() time.Time {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	return r.loadedAt
+}
+
+// Pair resolves the name of a pair (e.g. XBTUSD, XBT/USD, XXBTZUSD, xbt-usd)
+// into its key in AssetPairs, with its information
+func (r *Registry) Pair(name string) (AssetPair, AssetPairsInfo, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	pair, ok := r.pairKeys[pairKey(name)]
+	if !ok {
+		pair, ok = r.pairKeys[normalizePairName(name)]
+	}
+	if !ok {
+		return "", AssetPairsInfo{}, false
+	}
+	return pair, r.pairs[pair], true
+}
+
+// PairOf resolves the pair trading base against quote (e.g. BTC and EUR)
+func (r *Registry) PairOf(base, quote string) (AssetPair, AssetPairsInfo, bool) {
+	baseAsset, _, ok := r.Asset(base)
+	if !ok {
+		return "", AssetPairsInfo{}, false
+	}
+	quoteAsset, _, ok := r.Asset(quote)
+	if !ok {
+		return "", AssetPairsInfo{}, false
+	}
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	// Sorted so the result is stable, e.g. when a pair also has a dark pool (XBTUSD.d)
+	pairs := make([]string, 0, len(r.pairs))
+	for pair := range r.pairs {
+		pairs = append(pairs, string(pair))
+	}
+	sort.Strings(pairs)
+
+	for _, pair := range pairs {
+		info := r.pairs[AssetPair(pair)]
+		if info.BaseAsset == baseAsset && info.QuoteAsset == quoteAsset {
+			return AssetPair(pair), info, true
+		}
+	}
+	return "", AssetPairsInfo{}, false
+}
+
+// Asset resolves the name of an asset (e.g. XBT, XXBT, BTC) into its key in Assets,
+// with its information
+func (r *Registry) Asset(name string) (Asset, AssetInfo, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	asset, ok := r.assetKeys[strings.ToUpper(name)]
+	if !ok {
+		return "", AssetInfo{}, false
+	}
+	return asset, r.assets[asset], true
+}
+
+// Pairs returns a copy of all the asset pairs
+func (r *Registry) Pairs() map[AssetPair]AssetPairsInfo {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	pairs := make(map[AssetPair]AssetPairsInfo, len(r.pairs))
+	for pair, info := range r.pairs {
+		pairs[pair] = info
+	}
+	return pairs
+}
+
+// Assets returns a copy of all the assets
+func (r *Registry) Assets() map[Asset]AssetInfo {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	assets := make(map[Asset]AssetInfo, len(r.assets))
+	for asset, info := range r.assets {
+		assets[asset] = info
+	}
+	return assets
+}
+
+// pairKey is the name of a pair without separators, in upper case
+func pairKey(name string) string {
+	return strings.ToUpper(strings.NewReplacer("/", "", "_", "", "-", "").Replace(name))
+}
+
+// diffRegistry returns the changes between two loads, sorted by name
+func diffRegistry(previousAssets, assets map[Asset]AssetInfo, previousPairs, pairs map[AssetPair]AssetPairsInfo) []RegistryEvent {
+	events := []RegistryEvent{}
+
+	for asset, info := range assets {
+		previous, ok := previousAssets[asset]
+		switch {
+		case !ok:
+			events = append(events, RegistryEvent{Type: AssetListed, Asset: asset, Status: string(info.Status)})
+		case previous.Status != info.Status:
+			events = append(events, RegistryEvent{Type: AssetStatusChanged, Asset: asset, PreviousStatus: string(previous.Status), Status: string(info.Status)})
+		}
+	}
+	for asset, previous := range previousAssets {
+		if _, ok := assets[asset]; !ok {
+			events = append(events, RegistryEvent{Type: AssetDelisted, Asset: asset, PreviousStatus: string(previous.Status)})
+		}
+	}
+
+	for pair, info := range pairs {
+		previous, ok := previousPairs[pair]
+		switch {
+		case !ok:
+			events = append(events, RegistryEvent{Type: PairListed, AssetPair: pair, Status: string(info.Status)})
+		case previous.Status != info.Status:
+			events = append(events, RegistryEvent{Type: PairStatusChanged, AssetPair: pair, PreviousStatus: string(previous.Status), Status: string(info.Status)})
+		}
+	}
+	for pair, previous := range previousPairs {
+		if _, ok := pairs[pair]; !ok {
+			events = append(events, RegistryEvent{Type: PairDelisted, AssetPair: pair, PreviousStatus: string(previous.Status)})
+		}
+	}
+
+	sort.SliceStable(events, func(i, j int) bool {
+		if events[i].Asset != events[j].Asset {
+			return events[i].Asset < events[j].Asset
+		}
+		return events[i].AssetPair < events[j].AssetPair
+	})
+	return events
+}
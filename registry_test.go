@@ -0,0 +1,169 @@
+package kraken_test
+
+import (
+	"encoding/json"
+	"net/url"
+	"reflect"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	kraken "github.com/astaluego/golang-kraken"
+	"github.com/astaluego/golang-kraken/krakentest"
+)
+
+func TestRegistryOnChange(t *testing.T) {
+	server := krakentest.NewServer()
+	defer server.Close()
+
+	events := []kraken.RegistryEvent{}
+	registry, err := server.NewClient().NewRegistry(kraken.RegistryConfig{
+		OnChange: func(event kraken.RegistryEvent) {
+			events = append(events, event)
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(events) != 0 {
+		t.Fatalf("expected no change reported by the first load, got %+v", events)
+	}
+
+	// XETH is delisted, XXBT disabled and XXDG listed, with their pairs
+	server.Enqueue("Assets", krakentest.Response{Result: json.RawMessage(`{
+		"XXBT": {"aclass": "currency", "altname": "XBT", "decimals": 10, "display_decimals": 5, "status": "funding_temporarily_disabled"},
+		"XXDG": {"aclass": "currency", "altname": "XDG", "decimals": 8, "display_decimals": 2, "status": "enabled"},
+		"ZUSD": {"aclass": "currency", "altname": "USD", "decimals": 4, "display_decimals": 2, "status": "enabled"},
+		"ZEUR": {"aclass": "currency", "altname": "EUR", "decimals": 4, "display_decimals": 2, "status": "enabled"}
+	}`)})
+	server.Enqueue("AssetPairs", krakentest.Response{Result: json.RawMessage(`{
+		"XXBTZUSD": {"altname": "XBTUSD", "wsname": "XBT/USD", "base": "XXBT", "quote": "ZUSD", "status": "cancel_only"},
+		"XDGUSD": {"altname": "XDGUSD", "wsname": "XDG/USD", "base": "XXDG", "quote": "ZUSD", "status": "online"}
+	}`)})
+
+	if err := registry.Refresh(); err != nil {
+		t.Fatal(err)
+	}
+
+	// Sorted by asset then pair, the pair events have no asset
+	expected := []kraken.RegistryEvent{
+		{Type: kraken.PairListed, AssetPair: "XDGUSD", Status: "online"},
+		{Type: kraken.PairDelisted, AssetPair: "XETHZEUR", PreviousStatus: "online"},
+		{Type: kraken.PairStatusChanged, AssetPair: "XXBTZUSD", PreviousStatus: "online", Status: "cancel_only"},
+		{Type: kraken.AssetDelisted, Asset: "XETH", PreviousStatus: "enabled"},
+		{Type: kraken.AssetStatusChanged, Asset: "XXBT", PreviousStatus: "enabled", Status: "funding_temporarily_disabled"},
+		{Type: kraken.AssetListed, Asset: "XXDG", Status: "enabled"},
+	}
+	if !reflect.DeepEqual(events, expected) {
+		t.Errorf("expected %+v, got %+v", expected, events)
+	}
+
+	// The registry is updated before the changes are reported
+	if _, _, ok := registry.Pair("ETH/EUR"); ok {
+		t.Error("expected ETH/EUR to be removed")
+	}
+	if pair, _, ok := registry.PairOf("DOGE", "USD"); !ok || pair != "XDGUSD" {
+		t.Errorf("expected XDGUSD, got %q", pair)
+	}
+
+	// Nothing changed
+	events = events[:0]
+	server.Enqueue("Assets", krakentest.Response{Result: json.RawMessage(`{
+		"XXBT": {"aclass": "currency", "altname": "XBT", "status": "funding_temporarily_disabled"},
+		"XXDG": {"aclass": "currency", "altname": "XDG", "status": "enabled"},
+		"ZUSD": {"aclass": "currency", "altname": "USD", "status": "enabled"},
+		"ZEUR": {"aclass": "currency", "altname": "EUR", "status": "enabled"}
+	}`)})
+	server.Enqueue("AssetPairs", krakentest.Response{Result: json.RawMessage(`{
+		"XXBTZUSD": {"altname": "XBTUSD", "wsname": "XBT/USD", "base": "XXBT", "quote": "ZUSD", "status": "cancel_only"},
+		"XDGUSD": {"altname": "XDGUSD", "wsname": "XDG/USD", "base": "XXDG", "quote": "ZUSD", "status": "online"}
+	}`)})
+	if err := registry.Refresh(); err != nil {
+		t.Fatal(err)
+	}
+	if len(events) != 0 {
+		t.Errorf("expected no change, got %+v", events)
+	}
+}
+
+func TestRegistryRefreshKeepsPreviousDataOnError(t *testing.T) {
+	server := krakentest.NewServer()
+	defer server.Close()
+
+	changes := 0
+	registry, err := server.NewClient().NewRegistry(kraken.RegistryConfig{
+		OnChange: func(kraken.RegistryEvent) { changes++ },
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	server.Enqueue("AssetPairs", krakentest.Response{Errors: []string{"EService:Unavailable"}})
+	if err := registry.Refresh(); err == nil {
+		t.Fatal("expected an error")
+	}
+	if _, _, ok := registry.Pair("XBT/USD"); !ok || changes != 0 {
+		t.Errorf("expected the previous data to be kept, got %d changes", changes)
+	}
+}
+
+func TestRegistryConcurrentRefreshes(t *testing.T) {
+	server := krakentest.NewServer()
+	defer server.Close()
+
+	// Each refresh lists a new pair, so each one reports a single change
+	var inFlight, maxInFlight, refreshes int32
+	server.HandleFunc("AssetPairs", func(url.Values) krakentest.Response {
+		n := atomic.AddInt32(&inFlight, 1)
+		defer atomic.AddInt32(&inFlight, -1)
+		for {
+			max := atomic.LoadInt32(&maxInFlight)
+			if n <= max || atomic.CompareAndSwapInt32(&maxInFlight, max, n) {
+				break
+			}
+		}
+		time.Sleep(20 * time.Millisecond)
+
+		pairs := map[string]map[string]string{}
+		count := atomic.AddInt32(&refreshes, 1)
+		for i := int32(0); i <= count; i++ {
+			pairs[string(rune('A'+i))+"USD"] = map[string]string{"status": "online"}
+		}
+		return krakentest.Response{Result: pairs}
+	})
+
+	var (
+		mu     sync.Mutex
+		listed []kraken.AssetPair
+	)
+	registry, err := server.NewClient().NewRegistry(kraken.RegistryConfig{
+		OnChange: func(event kraken.RegistryEvent) {
+			mu.Lock()
+			defer mu.Unlock()
+			listed = append(listed, event.AssetPair)
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := registry.Refresh(); err != nil {
+				t.Error(err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if maxInFlight != 1 {
+		t.Errorf("expected the refreshes to be serialized, got %d in flight", maxInFlight)
+	}
+	if !reflect.DeepEqual(listed, []kraken.AssetPair{"CUSD", "DUSD", "EUSD", "FUSD"}) {
+		t.Errorf("expected each pair listed once and in order, got %v", listed)
+	}
+}
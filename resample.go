@@ -0,0 +1,199 @@
+package kraken
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+// GapFilling defines how the buckets without any candle are handled
+type GapFilling int
+
+const (
+	// SkipGaps omits the empty buckets
+	SkipGaps GapFilling = iota
+	// ForwardFill emits a flat candle at the previous close, without volume
+	ForwardFill
+)
+
+type ResampleConfig struct {
+	// Duration is required
+	// Duration of the resampled candles (e.g. 15 * time.Minute, 3 * time.Hour, 7 * 24 * time.Hour)
+	Duration time.Duration
+
+	// Anchor is optional
+	// Start of one of the buckets, e.g. a Monday at 00:00 for weekly candles anchored on Monday
+	// Default: 1970-01-01 00:00 in Location, like Kraken in UTC
+	Anchor time.Time
+
+	// Location is optional
+	// Buckets of whole days are aligned on the calendar of this location (daylight saving time
+	// included), and the resampled candles are returned in it
+	// Default: UTC
+	Location *time.Location
+
+	// Gaps is optional
+	// Default: SkipGaps
+	Gaps GapFilling
+
+	// DropUncommitted is optional
+	// Drops the last candle of the data, which is the not-yet-committed frame always returned by OHLC.
+	// Otherwise, the last resampled candle includes it and is itself uncommitted.
+	DropUncommitted bool
+}
+
+// Resample combines candles into candles of a longer duration: open of the first candle,
+// highest high, lowest low, close of the last candle, summed volume and count, and VWAP
+// weighted by the volume of each candle. Of the candles with the same time, only the last one
+// of data is used.
+func Resample(data []OHLCData, config ResampleConfig) ([]OHLCData, error) {
+	if config.Duration <= 0 {
+		return nil, fmt.Errorf("Duration is required")
+	}
+	if config.Location == nil {
+		config.Location = time.UTC
+	}
+	if config.Anchor.IsZero() {
+		config.Anchor = time.Date(1970, 1, 1, 0, 0, 0, 0, config.Location)
+	}
+	config.Anchor = config.Anchor.In(config.Location)
+
+	candles := append([]OHLCData{}, data...)
+	sort.SliceStable(candles, func(i, j int) bool {
+		return candles[i].Time.Before(candles[j].Time)
+	})
+	// A candle may be given twice, e.g. a live candle and its committed version: the last one wins
+	unique := candles[:0]
+	for _, candle := range candles {
+		if len(unique) > 0 && unique[len(unique)-1].Time.Equal(candle.Time) {
+			unique[len(unique)-1] = candle
+			continue
+		}
+		unique = append(unique, candle)
+	}
+	candles = unique
+	if config.DropUncommitted && len(candles) > 0 {
+		candles = candles[:len(candles)-1]
+	}
+
+	resampled := []OHLCData{}
+	// Sum of VWAP * volume of the current bucket
+	var notional decimal.Decimal
+
+	for _, candle := range candles {
+		start := config.bucket(candle.Time)
+
+		if len(resampled) > 0 && resampled[len(resampled)-1].Time.Equal(start) {
+			current := &resampled[len(resampled)-1]
+			if candle.High.GreaterThan(current.High) {
+				current.High = candle.High
+			}
+			if candle.Low.LessThan(current.Low) {
+				current.Low = candle.Low
+			}
+			current.Close = candle.Close
+			current.Volume = current.Volume.Add(candle.Volume)
+			current.Count += candle.Count
+
+			notional = notional.Add(candle.VWAP.Mul(candle.Volume))
+			current.VWAP = vwap(notional, current.Volume, current.Close)
+			continue
+		}
+
+		if config.Gaps == ForwardFill && len(resampled) > 0 {
+			previous := resampled[len(resampled)-1]
+			for gap := config.next(previous.Time); gap.Before(start); gap = config.next(gap) {
+				resampled = append(resampled, OHLCData{
+					Time:  gap,
+					Open:  previous.Close,
+					High:  previous.Close,
+					Low:   previous.Close,
+					Close: previous.Close,
+					VWAP:  previous.Close,
+				})
+			}
+		}
+
+		notional = candle.VWAP.Mul(candle.Volume)
+		resampled = append(resampled, OHLCData{
+			Time:   start,
+			Open:   candle.Open,
+			High:   candle.High,
+			Low:    candle.Low,
+			Close:  candle.Close,
+			VWAP:   vwap(notional, candle.Volume, candle.Close),
+			Volume: candle.Volume,
+			Count:  candle.Count,
+		})
+	}
+
+	return resampled, nil
+}
+
+func vwap(notional, volume, close decimal.Decimal) decimal.Decimal {
+	if volume.IsZero() {
+		return close
+	}
+	return notional.DivRound(volume, 10)
+}
+
+// isCalendar is true when the buckets are made of whole days, aligned on the calendar
+func (config ResampleConfig) isCalendar() bool {
+	return config.Duration%(24*time.Hour) == 0
+}
+
+// bucket returns the start of the bucket containing t
+func (config ResampleConfig) bucket(t time.Time) time.Time {
+	t = t.In(config.Location)
+
+	if config.isCalendar() {
+		days := int(config.Duration / (24 * time.Hour))
+
+		// Number of calendar days between the anchor and t, at the time of day of the anchor
+		anchor := config.Anchor
+		elapsed := civilDays(t) - civilDays(anchor)
+		if clock(t) < clock(anchor) {
+			elapsed--
+		}
+		offset := floorDiv(elapsed, days) * days
+
+		return time.Date(anchor.Year(), anchor.Month(), anchor.Day()+offset,
+			anchor.Hour(), anchor.Minute(), anchor.Second(), anchor.Nanosecond(), config.Location)
+	}
+
+	elapsed := t.Sub(config.Anchor)
+	offset := time.Duration(floorDiv(int(elapsed/time.Nanosecond), int(config.Duration)))
+	return config.Anchor.Add(offset * config.Duration)
+}
+
+// next returns the start of the bucket following the one starting at start
+func (config ResampleConfig) next(start time.Time) time.Time {
+	if config.isCalendar() {
+		days := int(config.Duration / (24 * time.Hour))
+		return start.AddDate(0, 0, days)
+	}
+	return start.Add(config.Duration)
+}
+
+// civilDays returns the number of days between the unix epoch and the date of t in its location
+func civilDays(t time.Time) int {
+	date := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+	return int(date.Unix() / 86400)
+}
+
+// clock returns the time of day of t in its location
+func clock(t time.Time) time.Duration {
+	hour, min, sec := t.Clock()
+	return time.Duration(hour)*time.Hour + time.Duration(min)*time.Minute +
+		time.Duration(sec)*time.Second + time.Duration(t.Nanosecond())
+}
+
+func floorDiv(a, b int) int {
+	q := a / b
+	if (a%b != 0) && ((a < 0) != (b < 0)) {
+		q--
+	}
+	return q
+}
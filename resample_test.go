@@ -0,0 +1,205 @@
+package kraken
+
+import (
+	"testing"
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+func TestResampleDuplicates(t *testing.T) {
+	d := decimal.RequireFromString
+	start := time.Unix(1688671200, 0)
+	candle := func(offset time.Duration, close, volume string, count int64) OHLCData {
+		return OHLCData{
+			Time:   start.Add(offset),
+			Open:   d("100"),
+			High:   d(close),
+			Low:    d("100"),
+			Close:  d(close),
+			VWAP:   d(close),
+			Volume: d(volume),
+			Count:  count,
+		}
+	}
+
+	data := []OHLCData{
+		candle(0, "101", "1", 1),
+		// Live candle, then its revised version
+		candle(time.Minute, "102", "2", 2),
+		candle(time.Minute, "103", "3", 3),
+	}
+	resampled, err := Resample(data, ResampleConfig{Duration: 5 * time.Minute})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(resampled) != 1 {
+		t.Fatalf("expected 1 candle, got %d", len(resampled))
+	}
+	got := resampled[0]
+	if got.Volume.String() != "4" || got.Count != 4 || got.Close.String() != "103" || got.High.String() != "103" {
+		t.Errorf("unexpected candle: %+v", got)
+	}
+}
+
+func TestResampleWeeklyAnchoredOnMonday(t *testing.T) {
+	data := []OHLCData{}
+	// Daily candles from Saturday 2023-07-01 to Tuesday 2023-07-11
+	for day := 1; day <= 11; day++ {
+		price := decimal.NewFromInt(int64(100 + day))
+		data = append(data, OHLCData{
+			Time: time.Date(2023, 7, day, 0, 0, 0, 0, time.UTC),
+			Open: price, High: price, Low: price, Close: price, VWAP: price,
+			Volume: decimal.NewFromInt(1),
+			Count:  1,
+		})
+	}
+
+	resampled, err := Resample(data, ResampleConfig{
+		Duration: 7 * 24 * time.Hour,
+		Anchor:   time.Date(2023, 7, 3, 0, 0, 0, 0, time.UTC),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []struct {
+		start       time.Time
+		open, close string
+		count       int64
+	}{
+		{time.Date(2023, 6, 26, 0, 0, 0, 0, time.UTC), "101", "102", 2},
+		{time.Date(2023, 7, 3, 0, 0, 0, 0, time.UTC), "103", "109", 7},
+		{time.Date(2023, 7, 10, 0, 0, 0, 0, time.UTC), "110", "111", 2},
+	}
+	if len(resampled) != len(expected) {
+		t.Fatalf("expected %d candles, got %d", len(expected), len(resampled))
+	}
+	for i, e := range expected {
+		got := resampled[i]
+		if !got.Time.Equal(e.start) || got.Time.Weekday() != time.Monday || got.Open.String() != e.open || got.Close.String() != e.close || got.Count != e.count {
+			t.Errorf("candle %d: unexpected %+v", i, got)
+		}
+	}
+}
+
+func TestResampleDaylightSavingTime(t *testing.T) {
+	newYork, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Skip(err)
+	}
+
+	// Hourly candles over 3 days, the second one is 23 hours long (2023-03-12)
+	data := []OHLCData{}
+	start := time.Date(2023, 3, 11, 0, 0, 0, 0, newYork)
+	end := time.Date(2023, 3, 14, 0, 0, 0, 0, newYork)
+	for at := start; at.Before(end); at = at.Add(time.Hour) {
+		data = append(data, OHLCData{Time: at.UTC(), Volume: decimal.NewFromInt(1), Count: 1})
+	}
+
+	resampled, err := Resample(data, ResampleConfig{Duration: 24 * time.Hour, Location: newYork})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(resampled) != 3 {
+		t.Fatalf("expected 3 candles, got %d", len(resampled))
+	}
+	for i, count := range []int64{24, 23, 24} {
+		got := resampled[i]
+		expected := time.Date(2023, 3, 11+i, 0, 0, 0, 0, newYork)
+		if !got.Time.Equal(expected) || got.Time.Location() != newYork || got.Count != count {
+			t.Errorf("candle %d: expected %d candles from %s, got %d from %s", i, count, expected, got.Count, got.Time)
+		}
+	}
+}
+
+func TestResampleForwardFill(t *testing.T) {
+	d := decimal.RequireFromString
+	start := time.Unix(1688671200, 0)
+	data := []OHLCData{
+		{Time: start, Open: d("100"), High: d("105"), Low: d("99"), Close: d("104"), VWAP: d("102"), Volume: d("2"), Count: 2},
+		{Time: start.Add(12 * time.Minute), Open: d("106"), High: d("106"), Low: d("106"), Close: d("106"), VWAP: d("106"), Volume: d("1"), Count: 1},
+	}
+
+	skipped, err := Resample(data, ResampleConfig{Duration: 5 * time.Minute})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(skipped) != 2 {
+		t.Errorf("expected the gap to be skipped, got %d candles", len(skipped))
+	}
+
+	filled, err := Resample(data, ResampleConfig{Duration: 5 * time.Minute, Gaps: ForwardFill})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(filled) != 3 {
+		t.Fatalf("expected 3 candles, got %d", len(filled))
+	}
+	gap := filled[1]
+	if !gap.Time.Equal(start.Add(5*time.Minute)) || gap.Open.String() != "104" || gap.High.String() != "104" ||
+		gap.Low.String() != "104" || gap.Close.String() != "104" || gap.VWAP.String() != "104" || !gap.Volume.IsZero() || gap.Count != 0 {
+		t.Errorf("unexpected gap candle: %+v", gap)
+	}
+	if !filled[2].Time.Equal(start.Add(10 * time.Minute)) {
+		t.Errorf("unexpected last candle: %+v", filled[2])
+	}
+}
+
+func TestResampleVWAP(t *testing.T) {
+	d := decimal.RequireFromString
+	start := time.Unix(1688671200, 0)
+	data := []OHLCData{
+		{Time: start, Open: d("100"), High: d("101"), Low: d("99"), Close: d("100"), VWAP: d("100"), Volume: d("1"), Count: 1},
+		{Time: start.Add(time.Minute), Open: d("100"), High: d("112"), Low: d("100"), Close: d("111"), VWAP: d("110"), Volume: d("3"), Count: 4},
+		// Without volume, the VWAP of the bucket is its close
+		{Time: start.Add(5 * time.Minute), Open: d("111"), High: d("111"), Low: d("111"), Close: d("111"), VWAP: d("0"), Volume: d("0")},
+	}
+
+	resampled, err := Resample(data, ResampleConfig{Duration: 5 * time.Minute})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(resampled) != 2 {
+		t.Fatalf("expected 2 candles, got %d", len(resampled))
+	}
+	// (100 * 1 + 110 * 3) / 4
+	first := resampled[0]
+	if first.VWAP.String() != "107.5" || first.Open.String() != "100" || first.High.String() != "112" ||
+		first.Low.String() != "99" || first.Close.String() != "111" || first.Volume.String() != "4" || first.Count != 5 {
+		t.Errorf("unexpected candle: %+v", first)
+	}
+	if resampled[1].VWAP.String() != "111" {
+		t.Errorf("expected the close as VWAP, got %s", resampled[1].VWAP)
+	}
+}
+
+func TestResampleDropUncommitted(t *testing.T) {
+	d := decimal.RequireFromString
+	start := time.Unix(1688671200, 0)
+	data := []OHLCData{
+		{Time: start, Close: d("100"), Volume: d("1"), Count: 1},
+		{Time: start.Add(time.Minute), Close: d("101"), Volume: d("1"), Count: 1},
+		// Not-yet-committed frame
+		{Time: start.Add(2 * time.Minute), Close: d("150"), Volume: d("1"), Count: 1},
+	}
+
+	kept, err := Resample(data, ResampleConfig{Duration: 5 * time.Minute})
+	if err != nil {
+		t.Fatal(err)
+	}
+	dropped, err := Resample(data, ResampleConfig{Duration: 5 * time.Minute, DropUncommitted: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if kept[0].Close.String() != "150" || kept[0].Count != 3 {
+		t.Errorf("unexpected candle: %+v", kept[0])
+	}
+	if dropped[0].Close.String() != "101" || dropped[0].Count != 2 {
+		t.Errorf("unexpected candle: %+v", dropped[0])
+	}
+
+	if _, err := Resample(data, ResampleConfig{}); err == nil {
+		t.Error("expected an error without Duration")
+	}
+}
@@ -0,0 +1,261 @@
+package store
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"time"
+
+	kraken "github.com/astaluego/golang-kraken"
+	"github.com/shopspring/decimal"
+)
+
+// Data files start with a header: magic, format version and kind of records.
+// Then each record is a uvarint length followed by the payload.
+//
+//	trade:  time (varint ns) | trade id (varint) | type (1 byte) | order type (1 byte) |
+//	        price (string) | volume (string) | miscellaneous (string)
+//	spread: time (varint ns) | bid (string) | ask (string)
+//	candle: time (varint ns) | open | high | low | close | vwap | volume (strings) | count (varint)
+//
+// Strings are a uvarint length followed by the bytes, decimals are stored as strings
+// so they are exact.
+const (
+	magic         = "KRKS"
+	formatVersion = 1
+	headerSize    = len(magic) + 2
+)
+
+type kind byte
+
+const (
+	kindTrade  kind = 't'
+	kindSpread kind = 's'
+	kindCandle kind = 'c'
+)
+
+var errCorrupted = errors.New("corrupted record")
+
+func header(k kind) []byte {
+	return append([]byte(magic), formatVersion, byte(k))
+}
+
+func checkHeader(data []byte, k kind) error {
+	if len(data) < headerSize || string(data[:len(magic)]) != magic {
+		return fmt.Errorf("not a data file")
+	}
+	if data[len(magic)] != formatVersion {
+		return fmt.Errorf("unsupported format version %d", data[len(magic)])
+	}
+	if kind(data[len(magic)+1]) != k {
+		return fmt.Errorf("expected records of kind %q, got %q", k, data[len(magic)+1])
+	}
+	return nil
+}
+
+// record is a record of any kind, with the key used to dedupe it
+type record interface {
+	time() time.Time
+	key() string
+	encode(buf []byte) []byte
+}
+
+type tradeRecord kraken.TradeData
+
+func (r tradeRecord) time() time.Time {
+	return r.Time
+}
+
+// key dedupes trades by TradeID, or by content for the trades without ID
+func (r tradeRecord) key() string {
+	if r.TradeID != 0 {
+		return fmt.Sprintf("%d", r.TradeID)
+	}
+	return fmt.Sprintf("%d/%s/%s/%s", r.Time.UnixNano(), r.Price, r.Volume, r.Type)
+}
+
+func (r tradeRecord) encode(buf []byte) []byte {
+	buf = binary.AppendVarint(buf, r.Time.UnixNano())
+	buf = binary.AppendVarint(buf, r.TradeID)
+	buf = append(buf, encodeType(r.Type), encodeOrderType(r.OrderType))
+	buf = appendString(buf, r.Price.String())
+	buf = appendString(buf, r.Volume.String())
+	buf = appendString(buf, r.Miscellaneous)
+	return buf
+}
+
+func decodeTrade(payload []byte) (record, error) {
+	d := decoder{data: payload}
+	r := tradeRecord{}
+	r.Time = time.Unix(0, d.varint()).UTC()
+	r.TradeID = d.varint()
+	r.Type = decodeType(d.byte())
+	r.OrderType = decodeOrderType(d.byte())
+	r.Price = d.decimal()
+	r.Volume = d.decimal()
+	r.Miscellaneous = d.string()
+	return r, d.err
+}
+
+type spreadRecord kraken.SpreadData
+
+func (r spreadRecord) time() time.Time {
+	return r.Time
+}
+
+// key dedupes spreads by content
+func (r spreadRecord) key() string {
+	return fmt.Sprintf("%d/%s/%s", r.Time.UnixNano(), r.Bid, r.Ask)
+}
+
+func (r spreadRecord) encode(buf []byte) []byte {
+	buf = binary.AppendVarint(buf, r.Time.UnixNano())
+	buf = appendString(buf, r.Bid.String())
+	buf = appendString(buf, r.Ask.String())
+	return buf
+}
+
+func decodeSpread(payload []byte) (record, error) {
+	d := decoder{data: payload}
+	r := spreadRecord{}
+	r.Time = time.Unix(0, d.varint()).UTC()
+	r.Bid = d.decimal()
+	r.Ask = d.decimal()
+	return r, d.err
+}
+
+type candleRecord kraken.OHLCData
+
+func (r candleRecord) time() time.Time {
+	return r.Time
+}
+
+// key dedupes candles by time, the last version written wins
+func (r candleRecord) key() string {
+	return fmt.Sprintf("%d", r.Time.UnixNano())
+}
+
+func (r candleRecord) encode(buf []byte) []byte {
+	buf = binary.AppendVarint(buf, r.Time.UnixNano())
+	for _, d := range []decimal.Decimal{r.Open, r.High, r.Low, r.Close, r.VWAP, r.Volume} {
+		buf = appendString(buf, d.String())
+	}
+	buf = binary.AppendVarint(buf, r.Count)
+	return buf
+}
+
+func decodeCandle(payload []byte) (record, error) {
+	d := decoder{data: payload}
+	r := candleRecord{}
+	r.Time = time.Unix(0, d.varint()).UTC()
+	r.Open = d.decimal()
+	r.High = d.decimal()
+	r.Low = d.decimal()
+	r.Close = d.decimal()
+	r.VWAP = d.decimal()
+	r.Volume = d.decimal()
+	r.Count = d.varint()
+	return r, d.err
+}
+
+func decodeFunc(k kind) func(payload []byte) (record, error) {
+	switch k {
+	case kindTrade:
+		return decodeTrade
+	case kindSpread:
+		return decodeSpread
+	default:
+		return decodeCandle
+	}
+}
+
+func appendString(buf []byte, s string) []byte {
+	buf = binary.AppendUvarint(buf, uint64(len(s)))
+	return append(buf, s...)
+}
+
+func encodeType(t kraken.Type) byte {
+	if t == kraken.Sell {
+		return 's'
+	}
+	return 'b'
+}
+
+func decodeType(b byte) kraken.Type {
+	if b == 's' {
+		return kraken.Sell
+	}
+	return kraken.Buy
+}
+
+func encodeOrderType(t kraken.OrderType) byte {
+	if t == kraken.Limit {
+		return 'l'
+	}
+	return 'm'
+}
+
+func decodeOrderType(b byte) kraken.OrderType {
+	if b == 'l' {
+		return kraken.Limit
+	}
+	return kraken.Market
+}
+
+// decoder reads the fields of a payload, keeping the first error
+type decoder struct {
+	data []byte
+	err  error
+}
+
+func (d *decoder) varint() int64 {
+	if d.err != nil {
+		return 0
+	}
+	v, n := binary.Varint(d.data)
+	if n <= 0 {
+		d.err = errCorrupted
+		return 0
+	}
+	d.data = d.data[n:]
+	return v
+}
+
+func (d *decoder) byte() byte {
+	if d.err != nil {
+		return 0
+	}
+	if len(d.data) == 0 {
+		d.err = errCorrupted
+		return 0
+	}
+	b := d.data[0]
+	d.data = d.data[1:]
+	return b
+}
+
+func (d *decoder) string() string {
+	if d.err != nil {
+		return ""
+	}
+	length, n := binary.Uvarint(d.data)
+	if n <= 0 || uint64(len(d.data)-n) < length {
+		d.err = errCorrupted
+		return ""
+	}
+	s := string(d.data[n : n+int(length)])
+	d.data = d.data[n+int(length):]
+	return s
+}
+
+func (d *decoder) decimal() decimal.Decimal {
+	s := d.string()
+	if d.err != nil {
+		return decimal.Decimal{}
+	}
+	value, err := decimal.NewFromString(s)
+	if err != nil {
+		d.err = errCorrupted
+	}
+	return value
+}
@@ -0,0 +1,321 @@
+package store
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"os"
+	"time"
+)
+
+// indexEntrySize is the size of an index entry: min time, max time, offset, length and count
+const indexEntrySize = 5 * 8
+
+// block is a batch of records appended at once, as referenced by the index
+type block struct {
+	min    int64
+	max    int64
+	offset int64
+	length int64
+	count  int64
+}
+
+func (b block) encode() []byte {
+	buf := make([]byte, indexEntrySize)
+	binary.BigEndian.PutUint64(buf[0:], uint64(b.min))
+	binary.BigEndian.PutUint64(buf[8:], uint64(b.max))
+	binary.BigEndian.PutUint64(buf[16:], uint64(b.offset))
+	binary.BigEndian.PutUint64(buf[24:], uint64(b.length))
+	binary.BigEndian.PutUint64(buf[32:], uint64(b.count))
+	return buf
+}
+
+func decodeBlock(buf []byte) block {
+	return block{
+		min:    int64(binary.BigEndian.Uint64(buf[0:])),
+		max:    int64(binary.BigEndian.Uint64(buf[8:])),
+		offset: int64(binary.BigEndian.Uint64(buf[16:])),
+		length: int64(binary.BigEndian.Uint64(buf[24:])),
+		count:  int64(binary.BigEndian.Uint64(buf[32:])),
+	}
+}
+
+// segment is the append-only data file of a day, with its index
+type segment struct {
+	path   string
+	kind   kind
+	size   int64
+	blocks []block
+
+	// keys of the records stored, with their encoded payload, loaded on the first append
+	keys map[string]string
+}
+
+// openSegment loads the index of a data file, and repairs them after a crash:
+// records written without their index entry are indexed, a truncated record is removed,
+// and a file truncated within its header is dropped as it holds no record.
+func openSegment(path string, k kind) (*segment, error) {
+	s := &segment{path: path, kind: k}
+
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return s, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if len(data) < headerSize && bytes.HasPrefix(header(k), data) {
+		if err := s.drop(); err != nil {
+			return nil, fmt.Errorf("failed to repair %s: %s", path, err.Error())
+		}
+		return s, nil
+	}
+	if err := checkHeader(data, k); err != nil {
+		return nil, fmt.Errorf("failed to open %s: %s", path, err.Error())
+	}
+	s.size = int64(len(data))
+
+	index, err := os.ReadFile(s.indexPath())
+	if err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+
+	indexed := int64(headerSize)
+	for i := 0; i+indexEntrySize <= len(index); i += indexEntrySize {
+		b := decodeBlock(index[i : i+indexEntrySize])
+		// The index was persisted, but not the data
+		if b.offset+b.length > s.size {
+			break
+		}
+		s.blocks = append(s.blocks, b)
+		indexed = b.offset + b.length
+	}
+
+	if indexed < s.size || len(index) != len(s.blocks)*indexEntrySize {
+		if err := s.repair(data, indexed); err != nil {
+			return nil, fmt.Errorf("failed to repair %s: %s", path, err.Error())
+		}
+	}
+
+	return s, nil
+}
+
+func (s *segment) indexPath() string {
+	return s.path + ".idx"
+}
+
+// drop removes a data file without records, and its index
+func (s *segment) drop() error {
+	for _, path := range []string{s.path, s.indexPath()} {
+		if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	}
+	return nil
+}
+
+// repair indexes the records stored after the indexed ones, and truncates a partial record
+func (s *segment) repair(data []byte, indexed int64) error {
+	b := block{offset: indexed}
+	decode := decodeFunc(s.kind)
+
+	err := scan(data[indexed:], func(r record, length int) {
+		t := r.time().UnixNano()
+		if b.count == 0 || t < b.min {
+			b.min = t
+		}
+		if b.count == 0 || t > b.max {
+			b.max = t
+		}
+		b.length += int64(length)
+		b.count++
+	}, decode)
+	if err != nil && err != errCorrupted {
+		return err
+	}
+
+	if end := b.offset + b.length; end < s.size {
+		if err := os.Truncate(s.path, end); err != nil {
+			return err
+		}
+		s.size = end
+	}
+	if b.count > 0 {
+		s.blocks = append(s.blocks, b)
+	}
+
+	index := make([]byte, 0, len(s.blocks)*indexEntrySize)
+	for _, b := range s.blocks {
+		index = append(index, b.encode()...)
+	}
+	return writeFile(s.indexPath(), index)
+}
+
+// loadKeys reads all the records to dedupe the next appends
+func (s *segment) loadKeys() error {
+	s.keys = make(map[string]string)
+	if s.size == 0 {
+		return nil
+	}
+
+	data, err := os.ReadFile(s.path)
+	if err != nil {
+		return err
+	}
+	return scan(data[headerSize:s.size], func(r record, _ int) {
+		s.keys[r.key()] = string(r.encode(nil))
+	}, decodeFunc(s.kind))
+}
+
+// append writes the records which are not stored yet, as a new block, and returns their number
+func (s *segment) append(records []record) (int, error) {
+	if s.keys == nil {
+		if err := s.loadKeys(); err != nil {
+			return 0, err
+		}
+	}
+
+	var (
+		buf []byte
+		b   = block{offset: s.size}
+	)
+	if s.size == 0 {
+		buf = header(s.kind)
+		b.offset = int64(headerSize)
+	}
+	start := len(buf)
+
+	for _, r := range records {
+		payload := r.encode(nil)
+		if stored, ok := s.keys[r.key()]; ok && stored == string(payload) {
+			continue
+		}
+		s.keys[r.key()] = string(payload)
+
+		t := r.time().UnixNano()
+		if b.count == 0 || t < b.min {
+			b.min = t
+		}
+		if b.count == 0 || t > b.max {
+			b.max = t
+		}
+		b.count++
+
+		buf = binary.AppendUvarint(buf, uint64(len(payload)))
+		buf = append(buf, payload...)
+	}
+	if b.count == 0 {
+		return 0, nil
+	}
+	b.length = int64(len(buf) - start)
+
+	// Data first, then the index: a crash in between is repaired on the next open
+	if err := appendFile(s.path, buf); err != nil {
+		return 0, err
+	}
+	s.size += int64(len(buf))
+
+	if err := appendFile(s.indexPath(), b.encode()); err != nil {
+		return 0, err
+	}
+	s.blocks = append(s.blocks, b)
+
+	return int(b.count), nil
+}
+
+// read returns the records of the blocks overlapping [from, to], in the order they were written
+func (s *segment) read(from, to time.Time) ([]record, error) {
+	if s.size == 0 {
+		return nil, nil
+	}
+
+	f, err := os.Open(s.path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	records := []record{}
+	decode := decodeFunc(s.kind)
+	for _, b := range s.blocks {
+		if !to.IsZero() && b.min > to.UnixNano() {
+			continue
+		}
+		if !from.IsZero() && b.max < from.UnixNano() {
+			continue
+		}
+
+		data := make([]byte, b.length)
+		if _, err := f.ReadAt(data, b.offset); err != nil && err != io.EOF {
+			return nil, err
+		}
+
+		err := scan(data, func(r record, _ int) {
+			t := r.time()
+			if (!from.IsZero() && t.Before(from)) || (!to.IsZero() && t.After(to)) {
+				return
+			}
+			records = append(records, r)
+		}, decode)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read %s: %s", s.path, err.Error())
+		}
+	}
+	return records, nil
+}
+
+// scan decodes the length-prefixed records of data
+func scan(data []byte, fn func(r record, length int), decode func(payload []byte) (record, error)) error {
+	for len(data) > 0 {
+		length, n := binary.Uvarint(data)
+		if n <= 0 || uint64(len(data)-n) < length {
+			return errCorrupted
+		}
+
+		r, err := decode(data[n : n+int(length)])
+		if err != nil {
+			return err
+		}
+		fn(r, n+int(length))
+		data = data[n+int(length):]
+	}
+	return nil
+}
+
+func appendFile(path string, data []byte) error {
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(data); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Sync(); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// writeFile replaces a file atomically
+func writeFile(path string, data []byte) error {
+	tmp := path + ".tmp"
+	f, err := os.Create(tmp)
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(data); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Sync(); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
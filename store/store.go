@@ -0,0 +1,303 @@
+// Package store persists the market data returned by Kraken (trades, spreads and candles)
+// in compact append-only files, one per pair and UTC day, with an index used for time-range reads.
+//
+// Trades are deduped by TradeID, spreads by content and candles by time: the last version
+// of a candle written wins, so the uncommitted candle returned by OHLC can be stored and
+// replaced later. The `last` cursors returned by Kraken are persisted with SetCursor, so
+// collectors resume where they stopped.
+package store
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	kraken "github.com/astaluego/golang-kraken"
+)
+
+const (
+	dayLayout   = "2006-01-02"
+	dataExt     = ".dat"
+	cursorsFile = "cursors.json"
+)
+
+// Store is safe for concurrent use by multiple goroutines of the same process
+type Store struct {
+	dir string
+
+	mu       sync.Mutex
+	segments map[string]*segment
+}
+
+// Open opens the store located in dir, creating it if needed
+func Open(dir string) (*Store, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create store: %s", err.Error())
+	}
+
+	return &Store{
+		dir:      dir,
+		segments: make(map[string]*segment),
+	}, nil
+}
+
+// Close releases the segments loaded in memory.
+// Every append is already synced on disk.
+func (s *Store) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.segments = make(map[string]*segment)
+	return nil
+}
+
+// AppendTrades stores the trades not stored yet, and returns their number
+func (s *Store) AppendTrades(pair kraken.AssetPair, trades []kraken.TradeData) (int, error) {
+	records := make([]record, 0, len(trades))
+	for _, trade := range trades {
+		records = append(records, tradeRecord(trade))
+	}
+	return s.append(kindTrade, s.pairDir("trades", pair), records)
+}
+
+// AppendSpreads stores the spreads not stored yet, and returns their number
+func (s *Store) AppendSpreads(pair kraken.AssetPair, spreads []kraken.SpreadData) (int, error) {
+	records := make([]record, 0, len(spreads))
+	for _, spread := range spreads {
+		records = append(records, spreadRecord(spread))
+	}
+	return s.append(kindSpread, s.pairDir("spreads", pair), records)
+}
+
+// AppendCandles stores the candles which are new or have changed, and returns their number
+func (s *Store) AppendCandles(pair kraken.AssetPair, interval kraken.Interval, candles []kraken.OHLCData) (int, error) {
+	if interval.Duration() == 0 {
+		return 0, fmt.Errorf("Interval %q is invalid", interval)
+	}
+
+	records := make([]record, 0, len(candles))
+	for _, candle := range candles {
+		records = append(records, candleRecord(candle))
+	}
+	return s.append(kindCandle, filepath.Join(s.pairDir("candles", pair), string(interval)), records)
+}
+
+// Trades returns the trades stored within [from, to], sorted by time.
+// A zero from or to is unbounded.
+func (s *Store) Trades(pair kraken.AssetPair, from, to time.Time) ([]kraken.TradeData, error) {
+	records, err := s.read(kindTrade, s.pairDir("trades", pair), from, to)
+	if err != nil {
+		return nil, err
+	}
+
+	trades := make([]kraken.TradeData, 0, len(records))
+	for _, r := range records {
+		trades = append(trades, kraken.TradeData(r.(tradeRecord)))
+	}
+	return trades, nil
+}
+
+// Spreads returns the spreads stored within [from, to], sorted by time.
+// A zero from or to is unbounded.
+func (s *Store) Spreads(pair kraken.AssetPair, from, to time.Time) ([]kraken.SpreadData, error) {
+	records, err := s.read(kindSpread, s.pairDir("spreads", pair), from, to)
+	if err != nil {
+		return nil, err
+	}
+
+	spreads := make([]kraken.SpreadData, 0, len(records))
+	for _, r := range records {
+		spreads = append(spreads, kraken.SpreadData(r.(spreadRecord)))
+	}
+	return spreads, nil
+}
+
+// Candles returns the last version of the candles stored within [from, to], sorted by time.
+// A zero from or to is unbounded.
+func (s *Store) Candles(pair kraken.AssetPair, interval kraken.Interval, from, to time.Time) ([]kraken.OHLCData, error) {
+	records, err := s.read(kindCandle, filepath.Join(s.pairDir("candles", pair), string(interval)), from, to)
+	if err != nil {
+		return nil, err
+	}
+
+	candles := make([]kraken.OHLCData, 0, len(records))
+	for _, r := range records {
+		candles = append(candles, kraken.OHLCData(r.(candleRecord)))
+	}
+	return candles, nil
+}
+
+// Cursor returns the cursor saved under name, or a zero time if there is none
+func (s *Store) Cursor(name string) (time.Time, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	cursors, err := s.loadCursors()
+	if err != nil {
+		return time.Time{}, err
+	}
+	return cursors[name], nil
+}
+
+// SetCursor saves durably the cursor under name, e.g. the `last` cursor returned by
+// RecentTrades for a pair, so the next run resumes from it
+func (s *Store) SetCursor(name string, cursor time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	cursors, err := s.loadCursors()
+	if err != nil {
+		return err
+	}
+	cursors[name] = cursor.UTC()
+
+	data, err := json.MarshalIndent(cursors, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to save cursor: %s", err.Error())
+	}
+	if err := writeFile(filepath.Join(s.dir, cursorsFile), data); err != nil {
+		return fmt.Errorf("failed to save cursor: %s", err.Error())
+	}
+	return nil
+}
+
+func (s *Store) loadCursors() (map[string]time.Time, error) {
+	cursors := make(map[string]time.Time)
+
+	data, err := os.ReadFile(filepath.Join(s.dir, cursorsFile))
+	if os.IsNotExist(err) {
+		return cursors, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to load cursors: %s", err.Error())
+	}
+	if err := json.Unmarshal(data, &cursors); err != nil {
+		return nil, fmt.Errorf("failed to load cursors: %s", err.Error())
+	}
+	return cursors, nil
+}
+
+// pairDir returns the directory of a pair, with a name safe for the file system (e.g. XBT/USD)
+func (s *Store) pairDir(category string, pair kraken.AssetPair) string {
+	name := strings.NewReplacer("/", "_", "\\", "_", ":", "_").Replace(string(pair))
+	return filepath.Join(s.dir, category, name)
+}
+
+// segment returns the segment of a day, loaded and repaired on first use
+func (s *Store) segment(k kind, dir string, day string) (*segment, error) {
+	path := filepath.Join(dir, day+dataExt)
+	if seg, ok := s.segments[path]; ok {
+		return seg, nil
+	}
+
+	seg, err := openSegment(path, k)
+	if err != nil {
+		return nil, err
+	}
+	s.segments[path] = seg
+	return seg, nil
+}
+
+func (s *Store) append(k kind, dir string, records []record) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return 0, fmt.Errorf("failed to append: %s", err.Error())
+	}
+
+	// Records are partitioned by UTC day, keeping their order within each day
+	days := []string{}
+	byDay := make(map[string][]record)
+	for _, r := range records {
+		day := r.time().UTC().Format(dayLayout)
+		if _, ok := byDay[day]; !ok {
+			days = append(days, day)
+		}
+		byDay[day] = append(byDay[day], r)
+	}
+
+	appended := 0
+	for _, day := range days {
+		seg, err := s.segment(k, dir, day)
+		if err != nil {
+			return appended, err
+		}
+		n, err := seg.append(byDay[day])
+		if err != nil {
+			return appended, fmt.Errorf("failed to append to %s: %s", seg.path, err.Error())
+		}
+		appended += n
+	}
+	return appended, nil
+}
+
+func (s *Store) read(k kind, dir string, from, to time.Time) ([]record, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	entries, err := os.ReadDir(dir)
+	if os.IsNotExist(err) {
+		return []record{}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read: %s", err.Error())
+	}
+
+	// Days overlapping [from, to]
+	days := []string{}
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || !strings.HasSuffix(name, dataExt) {
+			continue
+		}
+		day := strings.TrimSuffix(name, dataExt)
+		date, err := time.Parse(dayLayout, day)
+		if err != nil {
+			continue
+		}
+		if !from.IsZero() && !date.Add(24*time.Hour).After(from) {
+			continue
+		}
+		if !to.IsZero() && date.After(to) {
+			continue
+		}
+		days = append(days, day)
+	}
+	sort.Strings(days)
+
+	records := []record{}
+	for _, day := range days {
+		seg, err := s.segment(k, dir, day)
+		if err != nil {
+			return nil, err
+		}
+
+		// The last version of a record wins
+		dayRecords, err := seg.read(from, to)
+		if err != nil {
+			return nil, err
+		}
+		positions := make(map[string]int)
+		deduped := []record{}
+		for _, r := range dayRecords {
+			if i, ok := positions[r.key()]; ok {
+				deduped[i] = r
+				continue
+			}
+			positions[r.key()] = len(deduped)
+			deduped = append(deduped, r)
+		}
+
+		sort.SliceStable(deduped, func(i, j int) bool {
+			return deduped[i].time().Before(deduped[j].time())
+		})
+		records = append(records, deduped...)
+	}
+	return records, nil
+}
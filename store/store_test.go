@@ -0,0 +1,340 @@
+package store_test
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	kraken "github.com/astaluego/golang-kraken"
+	"github.com/astaluego/golang-kraken/store"
+	"github.com/shopspring/decimal"
+)
+
+var day = time.Date(2023, 7, 6, 0, 0, 0, 0, time.UTC)
+
+func trade(id int64, offset time.Duration, price string) kraken.TradeData {
+	return kraken.TradeData{
+		Price:         decimal.RequireFromString(price),
+		Volume:        decimal.RequireFromString("0.125"),
+		Time:          day.Add(offset),
+		Type:          kraken.Sell,
+		OrderType:     kraken.Limit,
+		Miscellaneous: "",
+		TradeID:       id,
+	}
+}
+
+// tradesPath returns the data file of the XBT/USD trades of day
+func tradesPath(dir string) string {
+	return filepath.Join(dir, "trades", string(kraken.XBT_USD), day.Format("2006-01-02")+".dat")
+}
+
+func tradeIDs(t *testing.T, s *store.Store, from, to time.Time) []int64 {
+	t.Helper()
+	trades, err := s.Trades(kraken.XBT_USD, from, to)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ids := []int64{}
+	for _, trade := range trades {
+		ids = append(ids, trade.TradeID)
+	}
+	return ids
+}
+
+func TestRoundTrip(t *testing.T) {
+	dir := t.TempDir()
+	s, err := store.Open(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	trades := []kraken.TradeData{trade(1, time.Second, "30000.1"), trade(2, 2*time.Second, "30000.25")}
+	trades[1].Type = kraken.Buy
+	trades[1].OrderType = kraken.Market
+	trades[1].Miscellaneous = "i"
+	spreads := []kraken.SpreadData{{Time: day, Bid: decimal.RequireFromString("30000.1"), Ask: decimal.RequireFromString("30000.2")}}
+	candles := []kraken.OHLCData{{
+		Time:   day,
+		Open:   decimal.RequireFromString("30000"),
+		High:   decimal.RequireFromString("30100"),
+		Low:    decimal.RequireFromString("29900"),
+		Close:  decimal.RequireFromString("30050"),
+		VWAP:   decimal.RequireFromString("30010.123"),
+		Volume: decimal.RequireFromString("12.5"),
+		Count:  42,
+	}}
+
+	if n, err := s.AppendTrades(kraken.XBT_USD, trades); err != nil || n != 2 {
+		t.Fatalf("expected 2 trades appended, got %d, %v", n, err)
+	}
+	if n, err := s.AppendSpreads(kraken.XBT_USD, spreads); err != nil || n != 1 {
+		t.Fatalf("expected 1 spread appended, got %d, %v", n, err)
+	}
+	if n, err := s.AppendCandles(kraken.XBT_USD, kraken.Interval1min, candles); err != nil || n != 1 {
+		t.Fatalf("expected 1 candle appended, got %d, %v", n, err)
+	}
+	s.Close()
+
+	// Reopened from the files
+	s, err = store.Open(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	storedTrades, err := s.Trades(kraken.XBT_USD, time.Time{}, time.Time{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(storedTrades, trades) {
+		t.Errorf("expected %+v, got %+v", trades, storedTrades)
+	}
+	storedSpreads, err := s.Spreads(kraken.XBT_USD, time.Time{}, time.Time{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(storedSpreads, spreads) {
+		t.Errorf("expected %+v, got %+v", spreads, storedSpreads)
+	}
+	storedCandles, err := s.Candles(kraken.XBT_USD, kraken.Interval1min, time.Time{}, time.Time{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(storedCandles, candles) {
+		t.Errorf("expected %+v, got %+v", candles, storedCandles)
+	}
+}
+
+func TestDedupe(t *testing.T) {
+	dir := t.TempDir()
+	s, err := store.Open(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := s.AppendTrades(kraken.XBT_USD, []kraken.TradeData{trade(1, time.Second, "30000"), trade(2, 2*time.Second, "30001")}); err != nil {
+		t.Fatal(err)
+	}
+	s.Close()
+
+	// Overlapping with the trades stored before the store was reopened
+	s, err = store.Open(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	n, err := s.AppendTrades(kraken.XBT_USD, []kraken.TradeData{trade(2, 2*time.Second, "30001"), trade(3, 3*time.Second, "30002")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != 1 {
+		t.Errorf("expected 1 trade appended, got %d", n)
+	}
+	if ids := tradeIDs(t, s, time.Time{}, time.Time{}); !reflect.DeepEqual(ids, []int64{1, 2, 3}) {
+		t.Errorf("unexpected trades: %v", ids)
+	}
+
+	// Trades without ID are deduped by content
+	anonymous := []kraken.TradeData{trade(0, 4*time.Second, "30003"), trade(0, 4*time.Second, "30003"), trade(0, 4*time.Second, "30004")}
+	if n, err := s.AppendTrades(kraken.XBT_USD, anonymous); err != nil || n != 2 {
+		t.Errorf("expected 2 trades appended, got %d, %v", n, err)
+	}
+
+	// The last version of a candle wins
+	candle := kraken.OHLCData{Time: day, Close: decimal.RequireFromString("30000"), Count: 1}
+	if n, err := s.AppendCandles(kraken.XBT_USD, kraken.Interval1min, []kraken.OHLCData{candle}); err != nil || n != 1 {
+		t.Fatalf("expected 1 candle appended, got %d, %v", n, err)
+	}
+	if n, err := s.AppendCandles(kraken.XBT_USD, kraken.Interval1min, []kraken.OHLCData{candle}); err != nil || n != 0 {
+		t.Errorf("expected no candle appended, got %d, %v", n, err)
+	}
+	candle.Close = decimal.RequireFromString("30010")
+	candle.Count = 3
+	if n, err := s.AppendCandles(kraken.XBT_USD, kraken.Interval1min, []kraken.OHLCData{candle}); err != nil || n != 1 {
+		t.Errorf("expected 1 candle appended, got %d, %v", n, err)
+	}
+	candles, err := s.Candles(kraken.XBT_USD, kraken.Interval1min, time.Time{}, time.Time{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(candles) != 1 || candles[0].Close.String() != "30010" || candles[0].Count != 3 {
+		t.Errorf("expected the last version of the candle, got %+v", candles)
+	}
+}
+
+func TestRepair(t *testing.T) {
+	tests := []struct {
+		name string
+		// crash alters the files of a store holding the trades 1 and 2 then 3 and 4, appended at once
+		crash    func(t *testing.T, path string, size int64)
+		expected []int64
+	}{
+		{
+			name: "index not persisted",
+			crash: func(t *testing.T, path string, size int64) {
+				if err := os.Truncate(path+".idx", 40); err != nil {
+					t.Fatal(err)
+				}
+			},
+			expected: []int64{1, 2, 3, 4},
+		},
+		{
+			name: "data not persisted",
+			crash: func(t *testing.T, path string, size int64) {
+				if err := os.Truncate(path, size); err != nil {
+					t.Fatal(err)
+				}
+			},
+			expected: []int64{1, 2},
+		},
+		{
+			name: "torn record",
+			crash: func(t *testing.T, path string, size int64) {
+				info, err := os.Stat(path)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if err := os.Truncate(path, info.Size()-3); err != nil {
+					t.Fatal(err)
+				}
+				if err := os.Truncate(path+".idx", 40); err != nil {
+					t.Fatal(err)
+				}
+			},
+			expected: []int64{1, 2, 3},
+		},
+		{
+			name: "torn index entry",
+			crash: func(t *testing.T, path string, size int64) {
+				if err := os.Truncate(path+".idx", 50); err != nil {
+					t.Fatal(err)
+				}
+			},
+			expected: []int64{1, 2, 3, 4},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			s, err := store.Open(dir)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if _, err := s.AppendTrades(kraken.XBT_USD, []kraken.TradeData{trade(1, time.Second, "30000"), trade(2, 2*time.Second, "30001")}); err != nil {
+				t.Fatal(err)
+			}
+			info, err := os.Stat(tradesPath(dir))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if _, err := s.AppendTrades(kraken.XBT_USD, []kraken.TradeData{trade(3, 3*time.Second, "30002"), trade(4, 4*time.Second, "30003")}); err != nil {
+				t.Fatal(err)
+			}
+			s.Close()
+
+			tt.crash(t, tradesPath(dir), info.Size())
+
+			s, err = store.Open(dir)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if ids := tradeIDs(t, s, time.Time{}, time.Time{}); !reflect.DeepEqual(ids, tt.expected) {
+				t.Fatalf("expected %v, got %v", tt.expected, ids)
+			}
+
+			// The repaired segment accepts new records
+			if _, err := s.AppendTrades(kraken.XBT_USD, []kraken.TradeData{trade(3, 3*time.Second, "30002"), trade(4, 4*time.Second, "30003"), trade(5, 5*time.Second, "30004")}); err != nil {
+				t.Fatal(err)
+			}
+			s.Close()
+			s, err = store.Open(dir)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if ids := tradeIDs(t, s, time.Time{}, time.Time{}); !reflect.DeepEqual(ids, []int64{1, 2, 3, 4, 5}) {
+				t.Errorf("expected [1 2 3 4 5], got %v", ids)
+			}
+		})
+	}
+}
+
+func TestRepairTornHeader(t *testing.T) {
+	dir := t.TempDir()
+	path := tradesPath(dir)
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatal(err)
+	}
+	// Crash while the header of the first block was written
+	if err := os.WriteFile(path, []byte("KRK"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	s, err := store.Open(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ids := tradeIDs(t, s, time.Time{}, time.Time{}); len(ids) != 0 {
+		t.Fatalf("expected no trades, got %v", ids)
+	}
+	if n, err := s.AppendTrades(kraken.XBT_USD, []kraken.TradeData{trade(1, time.Second, "30000")}); err != nil || n != 1 {
+		t.Fatalf("expected 1 trade appended, got %d, %v", n, err)
+	}
+	s.Close()
+
+	s, err = store.Open(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ids := tradeIDs(t, s, time.Time{}, time.Time{}); !reflect.DeepEqual(ids, []int64{1}) {
+		t.Errorf("expected [1], got %v", ids)
+	}
+
+	// Other files are not data files of the store
+	if err := os.WriteFile(path, []byte("abc"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	s.Close()
+	if _, err := s.Trades(kraken.XBT_USD, time.Time{}, time.Time{}); err == nil {
+		t.Error("expected an error for a file which is not a data file")
+	}
+}
+
+func TestRangeReads(t *testing.T) {
+	s, err := store.Open(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Trades over 3 days, in 2 blocks per day, appended out of order
+	trades := []kraken.TradeData{}
+	for i, offset := range []time.Duration{26 * time.Hour, 2 * time.Hour, 50 * time.Hour, time.Hour, 25 * time.Hour, 49 * time.Hour} {
+		trades = append(trades, trade(int64(i+1), offset, "30000"))
+	}
+	if _, err := s.AppendTrades(kraken.XBT_USD, trades[:3]); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.AppendTrades(kraken.XBT_USD, trades[3:]); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name     string
+		from, to time.Time
+		expected []int64
+	}{
+		{"unbounded", time.Time{}, time.Time{}, []int64{4, 2, 5, 1, 6, 3}},
+		{"bounds are inclusive", day.Add(2 * time.Hour), day.Add(26 * time.Hour), []int64{2, 5, 1}},
+		{"from only", day.Add(25*time.Hour + time.Nanosecond), time.Time{}, []int64{1, 6, 3}},
+		{"to only", time.Time{}, day.Add(25 * time.Hour), []int64{4, 2, 5}},
+		{"within a day", day.Add(90 * time.Minute), day.Add(3 * time.Hour), []int64{2}},
+		{"empty", day.Add(10 * time.Hour), day.Add(20 * time.Hour), []int64{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if ids := tradeIDs(t, s, tt.from, tt.to); !reflect.DeepEqual(ids, tt.expected) {
+				t.Errorf("expected %v, got %v", tt.expected, ids)
+			}
+		})
+	}
+}
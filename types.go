@@ -265,7 +265,16 @@ type OHLCData struct {
 	Count int64 `json:"count"`
 }
 
+type OHLCResult struct {
+	// Name of the asset pair echoed back by Kraken (e.g. XXBTZUSD)
+	Name string `json:"name"`
+	// OHLC entries
+	Data []OHLCData `json:"data"`
+}
+
 type OrderBook struct {
+	// Name of the asset pair echoed back by Kraken (e.g. XXBTZUSD)
+	Name string `json:"name"`
 	// Asks
 	Asks []OrderBookEntry `json:"asks"`
 	// Bids
@@ -298,6 +307,13 @@ type TradeData struct {
 	TradeID int64 `json:"trade_id"`
 }
 
+type RecentTradesResult struct {
+	// Name of the asset pair echoed back by Kraken (e.g. XXBTZUSD)
+	Name string `json:"name"`
+	// Trades
+	Data []TradeData `json:"data"`
+}
+
 type SpreadData struct {
 	// Time
 	Time time.Time `json:"time"`
@@ -307,10 +323,36 @@ type SpreadData struct {
 	Ask decimal.Decimal `json:"ask"`
 }
 
+type RecentSpreadsResult struct {
+	// Name of the asset pair echoed back by Kraken (e.g. XXBTZUSD)
+	Name string `json:"name"`
+	// Spreads
+	Data []SpreadData `json:"data"`
+}
+
 type AccountBalance struct {
 	Assets
 }
 
+// Balances are the cash balances of the account by asset, decoded as decimals so every asset
+// returned by Kraken is kept, at its full precision
+type Balances map[Asset]decimal.Decimal
+
+type ExtendedBalance struct {
+	// Total balance of the asset
+	Balance decimal.Decimal `json:"balance"`
+	// Balance held by the open orders
+	HoldTrade decimal.Decimal `json:"hold_trade"`
+}
+
+// Available returns the balance which is not held by the open orders
+func (b ExtendedBalance) Available() decimal.Decimal {
+	return b.Balance.Sub(b.HoldTrade)
+}
+
+// ExtendedBalances are the balances of the account by asset, with the funds held by the open orders
+type ExtendedBalances map[Asset]ExtendedBalance
+
 type TradeBalance struct {
 	// Equivalent balance (combined balance of all currencies)
 	EquivalentBalance decimal.Decimal `json:"eb"`
@@ -339,6 +381,8 @@ type Order struct {
 	ReferralOrderTxID string `json:"refid"`
 	// User reference id
 	UserReferenceID int64 `json:"userref"`
+	// Client order id
+	ClientOrderID string `json:"cl_ord_id"`
 	// Status of order
 	Status OrderStatus `json:"status"`
 	// Unix timestamp of when order was placed